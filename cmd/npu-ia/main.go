@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -11,12 +15,23 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/agents"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/assistant"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/audio"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/npu"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/productivity"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/router"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/server"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/services"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tts"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/vad"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/wakeword"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
@@ -38,16 +53,23 @@ const banner = `
 
 // Application estrutura principal da aplicação
 type Application struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	cfg    *config.Config
+	ctx      context.Context
+	cancel   context.CancelFunc
+	cfg      *config.Config
+	textMode bool // Pedidos digitados e respostas impressas: sem microfone nem TTS
 
 	// Core
+	backend backend.Backend
 	router  *router.Router
 	speaker *tts.Piper
 	mic     *audio.Capture
+	wake    *wakeword.Gate // nil: toda fala vai para o STT
 	dm      *npu.DirectML
 
+	// Busca semântica
+	embedder  *embeddings.Model
+	retrieval *embeddings.Store
+
 	// Assistant
 	memory   *assistant.Memory
 	briefing *assistant.DailyBriefing
@@ -60,45 +82,75 @@ type Application struct {
 	zettel      *productivity.Zettelkasten
 	audioPlayer *productivity.AudioPlayer
 
+	// Agents
+	email *agents.EmailAgent
+
+	// Serviços externos (Gmail, GitHub, Spotify...)
+	hub *services.Hub
+
+	// API local
+	api *server.Server
+
 	// State
-	conversationHistory []string
-	lastInteraction     time.Time
+	startedAt       time.Time
+	lastInteraction time.Time
 }
 
 func main() {
-	fmt.Println(banner)
+	textMode := flag.Bool("text", false, "lê pedidos da entrada padrão e imprime as respostas (sem microfone nem TTS); o mesmo que o comando repl")
+	flag.Parse()
+	if flag.Arg(0) == "repl" {
+		*textMode = true
+	}
+
+	// No modo texto a saída padrão é só das respostas (scripts, SSH)
+	if !*textMode {
+		fmt.Println(banner)
+	}
 	log.Println("Iniciando NPU-IA...")
 
 	// Carrega configurações
 	cfg, err := config.Load("configs/config.yaml")
 	if err != nil {
+		// Arquivo ausente usa o padrão; arquivo com erro não
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("Erro na configuração: %v", err)
+		}
 		log.Printf("Usando configurações padrão: %v", err)
 		cfg = config.Default()
 	}
 
 	// Cria aplicação
-	app, err := NewApplication(cfg)
+	app, err := NewApplication(cfg, *textMode)
 	if err != nil {
 		log.Fatalf("Erro ao inicializar aplicação: %v", err)
 	}
 	defer app.Close()
 
 	// Executa
+	if *textMode {
+		app.RunText(os.Stdin, os.Stdout)
+		return
+	}
 	app.Run()
 }
 
-// NewApplication cria nova instância da aplicação
-func NewApplication(cfg *config.Config) (*Application, error) {
+// NewApplication cria nova instância da aplicação. No modo texto, microfone
+// e TTS não são inicializados.
+func NewApplication(cfg *config.Config, textMode bool) (*Application, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	app := &Application{
-		ctx:                 ctx,
-		cancel:              cancel,
-		cfg:                 cfg,
-		conversationHistory: make([]string, 0),
-		lastInteraction:     time.Now(),
+		ctx:             ctx,
+		cancel:          cancel,
+		cfg:             cfg,
+		textMode:        textMode,
+		startedAt:       time.Now(),
+		lastInteraction: time.Now(),
 	}
 
+	log.Printf("Plataforma: %s", platform.Describe(platform.Current()))
+
 	// Inicializa NPU/DirectML
 	log.Println("Detectando NPU...")
 	dm, err := npu.NewDirectML()
@@ -109,39 +161,102 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 		app.dm = dm
 	}
 
+	// Inicializa runtime de inferência
+	be, err := backend.New(cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inicializar backend: %w", err)
+	}
+	app.backend = be
+
+	// Cria diretório de dados
+	dataDir := filepath.Join(getHomeDir(), ".npu-ia")
+	os.MkdirAll(dataDir, 0755)
+
+	// Inicializa busca semântica (embeddings + índice vetorial local)
+	indexDir := cfg.Embeddings.IndexDir
+	if indexDir == "" {
+		indexDir = filepath.Join(dataDir, "index")
+	}
+	embedder, err := embeddings.New(be, cfg.Embeddings)
+	if err != nil {
+		log.Printf("Aviso: busca semântica indisponível, usando busca por texto: %v", err)
+	} else {
+		app.embedder = embedder
+		app.retrieval = embeddings.NewStore(indexDir, embedder)
+	}
+
 	// Inicializa Router (carrega modelos)
 	log.Println("Carregando modelos na NPU...")
-	r, err := router.New(ctx, cfg)
+	if cfg.Intent.LogPath == "" {
+		cfg.Intent.LogPath = filepath.Join(dataDir, "intents.jsonl")
+	}
+	if cfg.Session.Path == "" {
+		cfg.Session.Path = filepath.Join(dataDir, "session.json")
+	}
+	if cfg.Agent.TracePath == "" {
+		cfg.Agent.TracePath = filepath.Join(dataDir, "agent.jsonl")
+	}
+	if cfg.Actions.WorkDir == "" {
+		cfg.Actions.WorkDir = filepath.Join(dataDir, "workspace")
+	}
+	if cfg.Policy.AuditPath == "" {
+		cfg.Policy.AuditPath = filepath.Join(dataDir, "audit.jsonl")
+	}
+	if cfg.Personal.AuditPath == "" {
+		cfg.Personal.AuditPath = filepath.Join(dataDir, "learned.jsonl")
+	}
+	r, err := router.New(ctx, cfg, be)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao inicializar router: %w", err)
 	}
 	app.router = r
 
-	// Inicializa TTS
-	log.Println("Inicializando TTS...")
-	speaker, err := tts.New(cfg.TTS)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao inicializar TTS: %w", err)
+	// Classificador de intenção por embeddings (senão fica o Phi)
+	if cfg.Intent.Classifier != "llm" {
+		if err := app.useEmbeddingClassifier(); err != nil {
+			if cfg.Intent.Classifier == "embedding" {
+				return nil, err
+			}
+			log.Printf("Aviso: classificando intenções com o Phi: %v", err)
+		}
 	}
-	app.speaker = speaker
 
-	// Inicializa captura de áudio
-	log.Println("Inicializando microfone...")
-	mic, err := audio.NewCapture(cfg.Audio)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao inicializar microfone: %w", err)
-	}
-	app.mic = mic
+	// Inicializa TTS e captura de áudio (o modo texto não fala nem ouve)
+	var speaker *tts.Piper
+	if !textMode {
+		log.Println("Inicializando TTS...")
+		speaker, err = tts.New(cfg.TTS)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao inicializar TTS: %w", err)
+		}
+		app.speaker = speaker
 
-	// Cria diretório de dados
-	dataDir := filepath.Join(getHomeDir(), ".npu-ia")
-	os.MkdirAll(dataDir, 0755)
+		log.Println("Inicializando microfone...")
+		detector, err := vad.New(app.backend, cfg.Audio.VAD)
+		if err != nil {
+			log.Printf("Aviso: VAD %s indisponível, usando energia: %v", cfg.Audio.VAD.Engine, err)
+			detector = vad.NewEnergy(cfg.Audio.VAD.MarginDB)
+		}
+		mic, err := audio.NewCapture(cfg.Audio, detector)
+		if err != nil {
+			detector.Close()
+			return nil, fmt.Errorf("erro ao inicializar microfone: %w", err)
+		}
+		app.mic = mic
+
+		if cfg.WakeWord.Enabled {
+			app.wake = app.newWakeGate()
+		}
+	}
 
 	// Inicializa Memory
 	log.Println("Carregando memória...")
-	memory, err := assistant.NewMemory(filepath.Join(dataDir, "memory"), nil)
+	memory, err := assistant.NewMemory(filepath.Join(dataDir, "memory"), app.router.FastModel())
 	if err != nil {
 		log.Printf("Aviso: não foi possível carregar memória: %v", err)
+	} else {
+		// O que se sabe do usuário entra nos prompts do router
+		app.router.SetMemory(&memoryWrapper{memory})
 	}
 	app.memory = memory
 
@@ -185,6 +300,42 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 	os.MkdirAll(ebookDir, 0755)
 	app.ebook = assistant.NewEbookReader(ebookDir, &ttsWrapper{speaker})
 
+	// Inicializa agente de e-mail
+	if cfg.Actions.EmailEnabled {
+		gmail, err := actions.NewGmailClient(cfg.Google.CredentialsPath)
+		if err != nil {
+			log.Printf("Aviso: e-mail indisponível: %v", err)
+		} else {
+			app.email = agents.NewEmailAgent(app.router.FastModel(), &gmailService{gmail})
+		}
+	}
+
+	// Conecta os módulos ao índice vetorial
+	if app.retrieval != nil {
+		app.attachRetrieval()
+	}
+
+	// Comandos de produtividade respondem no lugar do LLM
+	app.router.SetCommandHandler(app.specialCommand)
+
+	// Operações dos serviços conectados viram ações do agente
+	hub, err := services.NewHub(services.Config(cfg.Services))
+	if err != nil {
+		log.Printf("Aviso: serviços indisponíveis: %v", err)
+	} else {
+		app.hub = hub
+		if n := hub.RegisterActions(app.router.Executor()); n > 0 {
+			log.Printf("✓ %d ações de serviços registradas", n)
+		}
+	}
+
+	// API local (interface web, editores)
+	if cfg.Server.Enabled {
+		if err := app.startServer(); err != nil {
+			log.Printf("Aviso: API local indisponível: %v", err)
+		}
+	}
+
 	log.Println("✓ Todos os módulos inicializados!")
 	return app, nil
 }
@@ -193,6 +344,13 @@ func NewApplication(cfg *config.Config) (*Application, error) {
 func (app *Application) Run() {
 	// Sinal de pronto
 	log.Println("✓ NPU-IA pronto! Ouvindo...")
+	if app.wake != nil {
+		log.Printf("Diga \"%s\" para ativar", app.cfg.WakeWord.Phrase)
+		if app.cfg.WakeWord.PushToTalk {
+			log.Println("Ou pressione Enter para falar (push-to-talk)")
+			go app.readPushToTalk(os.Stdin)
+		}
+	}
 
 	// Daily Briefing ao iniciar (se for horário apropriado)
 	hour := time.Now().Hour()
@@ -216,13 +374,76 @@ func (app *Application) Run() {
 	<-sigChan
 
 	log.Println("\nDesligando NPU-IA...")
+	app.summarizeSession()
+	app.speaker.Speak("Até logo!")
+}
+
+// RunText lê um pedido por linha de in e escreve as respostas em out, até o
+// fim da entrada ou "sair". Os pedidos passam pelos mesmos comandos e
+// intenções da voz.
+func (app *Application) RunText(in io.Reader, out io.Writer) {
+	log.Println("✓ NPU-IA pronto! Digite um pedido (\"sair\" ou Ctrl-D encerra).")
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "sair" || line == "exit" {
+			break
+		}
+
+		var streamed strings.Builder
+		response, err := app.router.ProcessTextStream(app.ctx, line, func(text string) error {
+			streamed.WriteString(text)
+			_, err := io.WriteString(out, text)
+			return err
+		})
+		if err != nil {
+			fmt.Fprintf(out, "Erro: %v\n", err)
+			continue
+		}
 
-	// Salva contexto da conversa
-	if app.memory != nil && len(app.conversationHistory) > 0 {
-		app.memory.SummarizeConversation(app.ctx, app.conversationHistory)
+		// Respostas que não vieram pelo stream (comandos, ações, visão)
+		if streamed.Len() == 0 {
+			io.WriteString(out, response.Text)
+		}
+		io.WriteString(out, "\n")
+		app.lastInteraction = time.Now()
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Erro ao ler a entrada: %v", err)
 	}
 
-	app.speaker.Speak("Até logo!")
+	app.summarizeSession()
+}
+
+// summarizeSession salva na memória o resumo da conversa desta execução
+func (app *Application) summarizeSession() {
+	turns := app.router.Session().Since(app.startedAt)
+	if app.memory == nil || len(turns) == 0 {
+		return
+	}
+
+	lines := make([]string, len(turns))
+	for i, turn := range turns {
+		speaker := "Usuário"
+		if turn.Role == chat.RoleAssistant {
+			speaker = "Assistente"
+		}
+		lines[i] = speaker + ": " + turn.Content
+	}
+	app.memory.SummarizeConversation(app.ctx, lines)
+}
+
+// say fala o texto ou, no modo texto, imprime
+func (app *Application) say(text string) {
+	if app.speaker == nil {
+		fmt.Println(text)
+		return
+	}
+	app.speaker.Speak(text)
 }
 
 // mainLoop loop principal de escuta e processamento
@@ -232,64 +453,148 @@ func (app *Application) mainLoop() {
 		case <-app.ctx.Done():
 			return
 		default:
-			// Captura áudio
-			audioData, err := app.mic.Listen()
-			if err != nil {
-				log.Printf("Erro ao capturar áudio: %v", err)
+			// Com wake word, só ouve depois da ativação ou na continuação
+			if app.wake != nil && !app.wake.Engaged() && !app.waitWake() {
 				continue
 			}
 
-			if len(audioData) == 0 {
+			// Captura áudio; com stt.stream a transcrição começa enquanto o
+			// usuário fala
+			var (
+				utterance *router.Utterance
+				onAudio   func([]float32)
+			)
+			if app.cfg.STT.Stream.Enabled {
+				utterance = app.router.StartUtterance(app.ctx, showHypothesis)
+				onAudio = utterance.Write
+			}
+			audioData, err := app.mic.ListenStream(onAudio)
+			if err != nil || len(audioData) == 0 {
+				if utterance != nil {
+					utterance.Cancel()
+				}
+				if err != nil {
+					log.Printf("Erro ao capturar áudio: %v", err)
+				}
+				if app.wake != nil {
+					app.wake.Missed()
+				}
 				continue
 			}
 
-			// Processa comando
-			response, err := app.processCommand(audioData)
+			// Processa comando, falando cada frase assim que é gerada
+			stream := tts.NewSentenceStream(app.speaker)
+			response, err := app.processCommand(audioData, utterance, stream.Write)
+			stream.Close()
 			if err != nil {
 				log.Printf("Erro ao processar: %v", err)
 				app.speaker.Speak("Desculpe, não entendi.")
-				continue
-			}
-
-			// Responde
-			if response != "" {
+			} else if response != "" && response != strings.TrimSpace(stream.Text()) {
+				// Responde (se ainda não foi falado pelo stream)
 				app.speaker.Speak(response)
 			}
 
+			// A continuação conta a partir do fim da resposta
+			if app.wake != nil {
+				app.wake.Done()
+			}
 			app.lastInteraction = time.Now()
 		}
 	}
 }
 
-// processCommand processa comando de áudio
-func (app *Application) processCommand(audioData []float32) (string, error) {
-	// Processa com o router
-	response, err := app.router.Process(app.ctx, audioData)
+// waitWake espera a palavra de ativação ou o push-to-talk; false quando
+// não houve ativação (erro ou desligando)
+func (app *Application) waitWake() bool {
+	app.wake.Reset()
+	triggered := false
+	err := app.mic.Wait(func(samples []float32) (bool, error) {
+		if app.ctx.Err() != nil {
+			return true, nil
+		}
+		ok, err := app.wake.Write(samples)
+		triggered = ok
+		return ok, err
+	})
 	if err != nil {
-		return "", err
+		log.Printf("Erro na wake word: %v", err)
+		return false
 	}
+	return triggered
+}
 
-	if response.Text == "" {
-		return "", nil
+// newWakeGate carrega a palavra de ativação. Sem o modelo fica só o
+// push-to-talk, ou nenhum gate (toda fala ouvida) se ele estiver desligado.
+func (app *Application) newWakeGate() *wakeword.Gate {
+	cfg := app.cfg.WakeWord
+	log.Printf("Carregando wake word (%q)...", cfg.Phrase)
+	spotter, err := wakeword.NewSpotter(app.backend, cfg)
+	if err != nil {
+		if !cfg.PushToTalk {
+			log.Printf("Aviso: wake word indisponível, ouvindo toda fala: %v", err)
+			return nil
+		}
+		log.Printf("Aviso: wake word indisponível, só push-to-talk: %v", err)
 	}
 
-	// Guarda no histórico
-	app.conversationHistory = append(app.conversationHistory, "Usuário: "+response.Text)
+	gate := wakeword.NewGate(spotter, cfg)
+	gate.SetEventHandler(func(e wakeword.Event) {
+		switch e.Kind {
+		case wakeword.EventDetection:
+			fmt.Print("\r\033[K👂 Ouvindo...\n")
+		case wakeword.EventFalseAccept, wakeword.EventFalseReject:
+			log.Printf("Wake word: %s (%.2f)", e.Kind, e.Score)
+		}
+	})
+	return gate
+}
 
-	// Verifica comandos especiais primeiro
-	text := strings.ToLower(response.Text)
+// readPushToTalk cada Enter em in ativa o assistente sem a frase
+func (app *Application) readPushToTalk(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		app.wake.PushToTalk()
+	}
+}
 
-	// Comandos de produtividade
-	if specialResponse := app.handleSpecialCommands(text); specialResponse != "" {
-		app.conversationHistory = append(app.conversationHistory, "Assistente: "+specialResponse)
-		return specialResponse, nil
+// showHypothesis mostra no terminal a transcrição em andamento
+func showHypothesis(h stt.Hypothesis) {
+	if h.Final {
+		fmt.Print("\r\033[K")
+		return
 	}
+	fmt.Printf("\r\033[K🎤 %s…", h.Text())
+}
 
-	// Resposta normal do LLM
-	app.conversationHistory = append(app.conversationHistory, "Assistente: "+response.Text)
+// processCommand processa comando de áudio; com utterance (stt.stream), o
+// texto já transcrito durante a fala é aproveitado. onToken recebe o texto
+// da resposta conforme é gerado.
+func (app *Application) processCommand(audioData []float32, utterance *router.Utterance, onToken func(string) error) (string, error) {
+	// Processa com o router (comandos especiais são atendidos por ele antes
+	// dos modelos, via SetCommandHandler)
+	var (
+		response *router.Response
+		err      error
+	)
+	if utterance != nil {
+		response, err = utterance.Finish(onToken)
+	} else {
+		response, err = app.router.ProcessStream(app.ctx, audioData, onToken)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// O router já guardou o turno na conversa
 	return response.Text, nil
 }
 
+// specialCommand adapta handleSpecialCommands ao router.CommandHandler
+func (app *Application) specialCommand(text string) (string, bool) {
+	reply := app.handleSpecialCommands(strings.ToLower(text))
+	return reply, reply != ""
+}
+
 // handleSpecialCommands processa comandos especiais
 func (app *Application) handleSpecialCommands(text string) string {
 	// === PRODUTIVIDADE ===
@@ -452,14 +757,14 @@ func (app *Application) handleSpecialCommands(text string) string {
 // runDailyBriefing executa o briefing diário
 func (app *Application) runDailyBriefing() {
 	if app.briefing == nil {
-		app.speaker.Speak("Briefing não disponível.")
+		app.say("Briefing não disponível.")
 		return
 	}
 
 	briefing, err := app.briefing.Generate(app.ctx)
 	if err != nil {
 		log.Printf("Erro ao gerar briefing: %v", err)
-		app.speaker.Speak("Não foi possível gerar o briefing.")
+		app.say("Não foi possível gerar o briefing.")
 		return
 	}
 
@@ -470,19 +775,136 @@ func (app *Application) runDailyBriefing() {
 	app.briefing.Speak(briefing)
 }
 
+// useEmbeddingClassifier classifica intenções pelos exemplos rotulados
+func (app *Application) useEmbeddingClassifier() error {
+	if app.embedder == nil {
+		return fmt.Errorf("modelo de embeddings não carregado")
+	}
+	classifier, err := router.NewEmbeddingClassifier(app.ctx, app.embedder, app.cfg.Intent.ExamplesPath)
+	if err != nil {
+		return fmt.Errorf("erro no classificador de intenção: %w", err)
+	}
+	app.router.SetClassifier(classifier)
+	return nil
+}
+
+// startServer sobe a API local com leitura de hábitos, notas e memória
+func (app *Application) startServer() error {
+	api, err := server.New(app.cfg.Server, app.router, app.router.Executor())
+	if err != nil {
+		return err
+	}
+
+	// /v1 compatível com a OpenAI, pelos nomes do registro de modelos
+	api.SetCompleter(app.router)
+	if app.embedder != nil {
+		name, ok := app.cfg.Models.ForRole(config.RoleEmbed)
+		if !ok {
+			name = "embeddings"
+		}
+		api.SetEmbedder(name, app.embedder)
+	}
+
+	if app.habits != nil {
+		api.SetReader("habits", func(string) (interface{}, error) {
+			return app.habits.GetAllHabits(), nil
+		})
+	}
+	if app.zettel != nil {
+		api.SetReader("notes", func(query string) (interface{}, error) {
+			if query == "" {
+				return app.zettel.GetStats(), nil
+			}
+			return app.zettel.Search(query), nil
+		})
+	}
+	if app.wake != nil {
+		api.SetReader("wakeword", func(string) (interface{}, error) {
+			return map[string]interface{}{
+				"stats":  app.wake.Stats(),
+				"events": app.wake.Events(),
+			}, nil
+		})
+		if app.cfg.WakeWord.PushToTalk {
+			api.SetPushToTalk(app.wake.PushToTalk)
+		}
+	}
+	if app.memory != nil {
+		api.SetReader("memory", func(query string) (interface{}, error) {
+			if query == "" {
+				return app.memory.GetStats(), nil
+			}
+			return app.memory.RecallFacts(query, apiRecallLimit), nil
+		})
+	}
+
+	if err := api.Start(); err != nil {
+		return err
+	}
+	app.api = api
+	return nil
+}
+
+// apiRecallLimit fatos devolvidos por consulta à memória pela API
+const apiRecallLimit = 20
+
+// attachRetrieval dá a cada módulo a sua coleção no índice vetorial
+func (app *Application) attachRetrieval() {
+	collection := func(name string) *embeddings.Collection {
+		c, err := app.retrieval.Collection(name)
+		if err != nil {
+			log.Printf("Aviso: índice %s indisponível: %v", name, err)
+			return nil
+		}
+		return c
+	}
+
+	if c := collection("memory"); c != nil && app.memory != nil {
+		if err := app.memory.SetRetriever(app.ctx, c); err != nil {
+			log.Printf("Aviso: erro ao indexar memória: %v", err)
+		}
+	}
+	if c := collection("notes"); c != nil && app.zettel != nil {
+		if err := app.zettel.SetRetriever(app.ctx, c); err != nil {
+			log.Printf("Aviso: erro ao indexar notas: %v", err)
+		}
+	}
+	if c := collection("books"); c != nil && app.ebook != nil {
+		app.ebook.SetRetriever(c)
+	}
+	if c := collection("email"); c != nil && app.email != nil {
+		app.email.SetRetriever(c)
+	}
+}
+
 // Close fecha todos os recursos
 func (app *Application) Close() error {
 	app.cancel()
 
+	if app.api != nil {
+		app.api.Close()
+	}
 	if app.router != nil {
 		app.router.Close()
 	}
+	if app.retrieval != nil {
+		app.retrieval.Close()
+	}
+	if app.embedder != nil {
+		app.embedder.Close()
+	}
+	if app.backend != nil {
+		app.backend.Close()
+	}
 	if app.speaker != nil {
 		app.speaker.Close()
 	}
 	if app.mic != nil {
 		app.mic.Close()
 	}
+	if app.wake != nil {
+		app.wake.Close()
+	}
 	if app.dm != nil {
 		app.dm.Close()
 	}
@@ -498,18 +920,82 @@ func (app *Application) Close() error {
 
 // === HELPERS ===
 
-// ttsWrapper wrapper para implementar interface TTSInterface
+// ttsWrapper wrapper para implementar interface TTSInterface; sem Piper
+// (modo texto) imprime o que seria falado
 type ttsWrapper struct {
 	piper *tts.Piper
 }
 
 func (w *ttsWrapper) Speak(text string) error {
-	if w.piper != nil {
-		w.piper.Speak(text)
+	if w.piper == nil {
+		fmt.Println(text)
+		return nil
 	}
+	w.piper.Speak(text)
 	return nil
 }
 
+// memoryWrapper adapta assistant.Memory à memória pessoal do router
+type memoryWrapper struct {
+	memory *assistant.Memory
+}
+
+func (w *memoryWrapper) Context(query string) string {
+	return w.memory.GetContext(query)
+}
+
+func (w *memoryWrapper) Learn(fact router.LearnedFact) string {
+	return w.memory.LearnFact(fact.Category, fact.Subject, fact.Content, "conversa", fact.Confidence).ID
+}
+
+// gmailService adapta actions.GmailClient ao serviço de e-mail dos agentes
+type gmailService struct {
+	client *actions.GmailClient
+}
+
+// gmailSearchLimit e-mails retornados por busca
+const gmailSearchLimit = 20
+
+func (s *gmailService) ListEmails(query string, max int64) ([]map[string]string, error) {
+	emails, err := s.client.Search(query, max)
+	if err != nil {
+		return nil, err
+	}
+	return emailMaps(emails), nil
+}
+
+func (s *gmailService) GetEmailContent(id string) (string, error) {
+	email, err := s.client.GetMessage(id)
+	if err != nil {
+		return "", err
+	}
+	return email.Body, nil
+}
+
+func (s *gmailService) SendEmail(to, subject, body string) error {
+	return s.client.SendEmail(to, subject, body)
+}
+
+func (s *gmailService) SearchEmails(query string) ([]map[string]string, error) {
+	return s.ListEmails(query, gmailSearchLimit)
+}
+
+// emailMaps converte os e-mails para o formato dos agentes
+func emailMaps(emails []*actions.Email) []map[string]string {
+	out := make([]map[string]string, 0, len(emails))
+	for _, email := range emails {
+		out = append(out, map[string]string{
+			"id":      email.ID,
+			"from":    email.From,
+			"to":      strings.Join(email.To, ", "),
+			"subject": email.Subject,
+			"body":    email.Body,
+			"date":    email.Date,
+		})
+	}
+	return out
+}
+
 // contains verifica se texto contém alguma das palavras
 func contains(text string, words ...string) bool {
 	for _, word := range words {
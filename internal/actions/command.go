@@ -0,0 +1,262 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// cmdBuiltins comandos que no Windows só existem dentro do cmd.exe
+var cmdBuiltins = map[string]bool{"dir": true, "echo": true, "date": true, "time": true, "type": true, "ver": true}
+
+// cmdMetachars caracteres que o cmd.exe interpreta; proibidos nos argumentos
+// dos builtins, os únicos que passam por ele
+const cmdMetachars = "&|<>^%!\"()"
+
+// CommandResult saída estruturada de run_command
+type CommandResult struct {
+	Command    []string `json:"command"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr"`
+	ExitCode   int      `json:"exit_code"`
+	Truncated  bool     `json:"truncated,omitempty"` // Alguma saída passou de max_output_bytes
+	TimedOut   bool     `json:"timed_out,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// CommandRunner executa os programas permitidos no config, sem shell, com
+// limite de tempo, de saída e de diretório
+type CommandRunner struct {
+	cfg     config.ActionsConfig
+	rules   map[string]commandRule
+	workDir string
+}
+
+// commandRule regra do config com as regex compiladas
+type commandRule struct {
+	args    []*regexp.Regexp
+	maxArgs int
+}
+
+// NewCommandRunner compila as regras de actions.allowed_commands e cria o
+// diretório de trabalho
+func NewCommandRunner(cfg config.ActionsConfig) (*CommandRunner, error) {
+	defaults := config.Default().Actions
+	if cfg.CommandTimeout <= 0 {
+		cfg.CommandTimeout = defaults.CommandTimeout
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = defaults.MaxOutputBytes
+	}
+
+	r := &CommandRunner{cfg: cfg, rules: make(map[string]commandRule)}
+	for _, rule := range cfg.AllowedCommands {
+		compiled := commandRule{maxArgs: rule.MaxArgs}
+		for _, pattern := range rule.Args {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("regra de %s: %w", rule.Command, err)
+			}
+			compiled.args = append(compiled.args, re)
+		}
+		r.rules[rule.Command] = compiled
+	}
+
+	workDir := cfg.WorkDir
+	if workDir == "" {
+		var err error
+		if workDir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório dos comandos: %w", err)
+	}
+	r.workDir = workDir
+	return r, nil
+}
+
+// Run valida e executa a linha de comando. Código de saída diferente de zero
+// e tempo esgotado não são erro: vão no resultado.
+func (r *CommandRunner) Run(ctx context.Context, command string) (*CommandResult, error) {
+	argv, err := splitArgs(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("comando vazio")
+	}
+	if err := r.check(argv); err != nil {
+		return nil, err
+	}
+
+	// Builtins do cmd rodam nele, com os argumentos já sem metacaracteres
+	run := argv
+	if runtime.GOOS == "windows" && cmdBuiltins[strings.ToLower(argv[0])] {
+		run = append([]string{"cmd", "/d", "/c"}, argv...)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.CommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, run[0], run[1:]...)
+	cmd.Dir = r.workDir
+	cmd.WaitDelay = time.Second // Não espera netos que herdaram a saída
+	stdout := &limitedBuffer{max: r.cfg.MaxOutputBytes}
+	stderr := &limitedBuffer{max: r.cfg.MaxOutputBytes}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if r.cfg.Sandbox {
+		sandbox(cmd, r.workDir)
+	}
+
+	started := time.Now()
+	err = cmd.Run()
+	result := &CommandResult{
+		Command:    argv,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		Truncated:  stdout.truncated || stderr.truncated,
+		TimedOut:   errors.Is(ctx.Err(), context.DeadlineExceeded),
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return nil, fmt.Errorf("erro ao executar %s: %w", argv[0], err)
+	}
+	return result, nil
+}
+
+// check aplica a regra do programa: número e formato dos argumentos e
+// caminhos dentro do diretório de trabalho
+func (r *CommandRunner) check(argv []string) error {
+	rule, ok := r.rules[argv[0]]
+	if !ok {
+		return fmt.Errorf("comando não permitido por segurança: %s", argv[0])
+	}
+	args := argv[1:]
+	if len(rule.args) == 0 && len(args) > 0 {
+		return fmt.Errorf("%s não aceita argumentos", argv[0])
+	}
+	if rule.maxArgs > 0 && len(args) > rule.maxArgs {
+		return fmt.Errorf("%s aceita no máximo %d argumentos", argv[0], rule.maxArgs)
+	}
+
+	builtin := runtime.GOOS == "windows" && cmdBuiltins[strings.ToLower(argv[0])]
+	for _, arg := range args {
+		if !matchesAny(rule.args, arg) {
+			return fmt.Errorf("argumento não permitido para %s: %q", argv[0], arg)
+		}
+		if builtin && strings.ContainsAny(arg, cmdMetachars) {
+			return fmt.Errorf("argumento não permitido para %s: %q", argv[0], arg)
+		}
+		if !r.insideWorkDir(arg) {
+			return fmt.Errorf("caminho fora do diretório dos comandos: %q", arg)
+		}
+	}
+	return nil
+}
+
+// insideWorkDir indica se o argumento, quando é caminho absoluto ou sobe
+// diretórios, continua dentro do diretório de trabalho
+func (r *CommandRunner) insideWorkDir(arg string) bool {
+	if !filepath.IsAbs(arg) && !strings.Contains(arg, "..") {
+		return true
+	}
+	path := arg
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.workDir, path)
+	}
+	rel, err := filepath.Rel(r.workDir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// matchesAny indica se s casa com alguma das regex
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitArgs separa a linha em argumentos como um shell separaria palavras,
+// com aspas simples ou duplas agrupando, mas sem expandir nada
+func splitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quote   rune
+		inWord  bool
+	)
+	for _, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("aspas sem fechar no comando")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// limitedBuffer guarda os primeiros max bytes e descarta o resto sem falhar
+// a escrita (o processo continua até o fim)
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
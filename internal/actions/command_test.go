@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// newTestRunner runner com as regras dadas num diretório temporário
+func newTestRunner(t *testing.T, edit func(cfg *config.ActionsConfig), rules ...config.CommandRule) *CommandRunner {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("comandos de teste são do Unix")
+	}
+	cfg := config.ActionsConfig{AllowedCommands: rules, WorkDir: t.TempDir()}
+	if edit != nil {
+		edit(&cfg)
+	}
+	r, err := NewCommandRunner(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCommandRules(t *testing.T) {
+	r := newTestRunner(t, nil,
+		config.CommandRule{Command: "ls", Args: []string{`-l`, `[\w./]+`}, MaxArgs: 2},
+		config.CommandRule{Command: "pwd"},
+	)
+	if err := os.WriteFile(filepath.Join(r.workDir, "notas.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		wantErr string
+	}{
+		{"ls -l notas.txt", ""},
+		{"pwd", ""},
+		{"pwd /", "não aceita argumentos"},
+		{"ls -la", `argumento não permitido para ls: "-la"`},
+		{"ls -l a b", "no máximo 2"},
+		{"ls ../..", "fora do diretório"},
+		{"ls /etc", "fora do diretório"},
+		{"ls 'notas.txt", "aspas sem fechar"},
+		{"cat notas.txt", "não permitido"},
+	}
+	for _, tt := range tests {
+		_, err := r.Run(context.Background(), tt.command)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Run(%q) erro = %v", tt.command, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Run(%q) erro = %v, want %q", tt.command, err, tt.wantErr)
+		}
+	}
+
+	// Roda no diretório de trabalho
+	result, err := r.Run(context.Background(), "pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(r.workDir); strings.TrimSpace(result.Stdout) != r.workDir && strings.TrimSpace(result.Stdout) != want {
+		t.Errorf("pwd = %q, want %q", result.Stdout, r.workDir)
+	}
+}
+
+func TestCommandResult(t *testing.T) {
+	for _, program := range []string{"ls", "seq", "sleep"} {
+		if _, err := exec.LookPath(program); err != nil {
+			t.Skipf("%s não está no PATH", program)
+		}
+	}
+	r := newTestRunner(t, func(cfg *config.ActionsConfig) {
+		cfg.CommandTimeout = 200 * time.Millisecond
+		cfg.MaxOutputBytes = 100
+	},
+		config.CommandRule{Command: "ls", Args: []string{".*"}},
+		config.CommandRule{Command: "seq", Args: []string{`\d+`}},
+		config.CommandRule{Command: "sleep", Args: []string{`\d+`}},
+	)
+
+	// Código de saída e stderr vêm no resultado, sem erro
+	result, err := r.Run(context.Background(), "ls nao-existe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ExitCode == 0 || result.Stderr == "" {
+		t.Errorf("ls nao-existe = %+v", result)
+	}
+
+	result, err = r.Run(context.Background(), "seq 100000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Stdout) != 100 || !result.Truncated || result.ExitCode != 0 {
+		t.Errorf("seq: %d bytes, truncated %v, código %d", len(result.Stdout), result.Truncated, result.ExitCode)
+	}
+
+	result, err = r.Run(context.Background(), "sleep 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut || result.ExitCode == 0 || result.DurationMs > 2000 {
+		t.Errorf("sleep = %+v", result)
+	}
+}
+
+func TestCommandSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("isolamento de rede só no Linux")
+	}
+	r := newTestRunner(t, func(cfg *config.ActionsConfig) {
+		cfg.Sandbox = true
+		cfg.WorkDir = "/proc/self/net" // Interfaces de rede do próprio processo
+	},
+		config.CommandRule{Command: "cat", Args: []string{"dev"}},
+		config.CommandRule{Command: "env"},
+	)
+
+	result, err := r.Run(context.Background(), "cat dev")
+	if err != nil {
+		t.Skipf("user namespaces indisponíveis: %v", err)
+	}
+	for _, line := range strings.Split(result.Stdout, "\n")[2:] {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name != "lo" {
+			t.Errorf("interface %q visível no sandbox", name)
+		}
+	}
+
+	result, err = r.Run(context.Background(), "env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, variable := range strings.Fields(result.Stdout) {
+		name, _, _ := strings.Cut(variable, "=")
+		if name != "PATH" && name != "HOME" && name != "LANG" {
+			t.Errorf("variável %s no ambiente limpo", name)
+		}
+	}
+}
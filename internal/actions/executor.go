@@ -1,10 +1,15 @@
 package actions
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
 // Action representa uma ação executada
@@ -13,11 +18,16 @@ type Action struct {
 	Params   map[string]interface{} `json:"params"`
 	Response string                 `json:"response,omitempty"`
 	Success  bool                   `json:"success"`
+	Pending  string                 `json:"pending,omitempty"` // Id da confirmação, se a política perguntou
 }
 
 // Executor executa ações no sistema
 type Executor struct {
 	handlers map[string]ActionHandler
+	specs    map[string]ActionSpec
+	policy   *Policy        // Nil = executa tudo
+	commands *CommandRunner // run_command
+	platform platform.Platform
 }
 
 // ActionHandler função que executa uma ação
@@ -27,18 +37,79 @@ type ActionHandler func(params map[string]interface{}) (string, error)
 func NewExecutor() *Executor {
 	e := &Executor{
 		handlers: make(map[string]ActionHandler),
+		specs:    make(map[string]ActionSpec),
+		platform: platform.Current(),
+	}
+
+	// run_command com as regras padrão até SetCommandRunner
+	if commands, err := NewCommandRunner(config.Default().Actions); err == nil {
+		e.commands = commands
 	}
 
 	// Registra handlers padrão
-	e.RegisterHandler("open_app", e.openApp)
-	e.RegisterHandler("open_url", e.openURL)
-	e.RegisterHandler("type_text", e.typeText)
-	e.RegisterHandler("read_email", e.readEmail)
-	e.RegisterHandler("send_email", e.sendEmail)
-	e.RegisterHandler("volume", e.setVolume)
-	e.RegisterHandler("screenshot", e.takeScreenshot)
-	e.RegisterHandler("search", e.search)
-	e.RegisterHandler("run_command", e.runCommand)
+	e.RegisterAction(ActionSpec{
+		Name:        "open_app",
+		Description: "abre aplicativo",
+		Params:      []ParamSpec{{Name: "app", Type: "string", Required: true}},
+		SideEffects: true,
+		Risk:        RiskReversible,
+	}, e.openApp)
+	e.RegisterAction(ActionSpec{
+		Name:        "open_url",
+		Description: "abre URL",
+		Params:      []ParamSpec{{Name: "url", Type: "string", Required: true}},
+		SideEffects: true,
+		Risk:        RiskReversible,
+	}, e.openURL)
+	e.RegisterAction(ActionSpec{
+		Name:        "type_text",
+		Description: "digita texto",
+		Params:      []ParamSpec{{Name: "text", Type: "string", Required: true}},
+		SideEffects: true,
+		Risk:        RiskReversible,
+	}, e.typeText)
+	e.RegisterAction(ActionSpec{
+		Name:        "read_email",
+		Description: "lê emails",
+		Params:      []ParamSpec{},
+	}, e.readEmail)
+	e.RegisterAction(ActionSpec{
+		Name:        "send_email",
+		Description: "envia email",
+		Params: []ParamSpec{
+			{Name: "to", Type: "string", Required: true},
+			{Name: "subject", Type: "string", Required: true},
+			{Name: "body", Type: "string", Required: true},
+		},
+		SideEffects: true,
+		Risk:        RiskExternal,
+	}, e.sendEmail)
+	e.RegisterAction(ActionSpec{
+		Name:        "volume",
+		Description: "ajusta volume de 0 a 100",
+		Params:      []ParamSpec{{Name: "level", Type: "integer", Required: true}},
+		SideEffects: true,
+		Risk:        RiskReversible,
+	}, e.setVolume)
+	e.RegisterAction(ActionSpec{
+		Name:        "screenshot",
+		Description: "captura tela",
+		Params:      []ParamSpec{},
+	}, e.takeScreenshot)
+	e.RegisterAction(ActionSpec{
+		Name:        "search",
+		Description: "pesquisa na web",
+		Params:      []ParamSpec{{Name: "query", Type: "string", Required: true}},
+		SideEffects: true,
+		Risk:        RiskReversible,
+	}, e.search)
+	e.RegisterAction(ActionSpec{
+		Name:        "run_command",
+		Description: "executa comando permitido do sistema, sem shell; devolve stdout, stderr e exit_code",
+		Params:      []ParamSpec{{Name: "command", Type: "string", Required: true, Description: "programa e argumentos"}},
+		SideEffects: true,
+		Risk:        RiskExternal,
+	}, e.runCommand)
 
 	return e
 }
@@ -60,16 +131,44 @@ func (e *Executor) Execute(actionJSON string) (*Action, error) {
 		return nil, fmt.Errorf("ação desconhecida: %s", action.Type)
 	}
 
-	response, err := handler(action.Params)
+	if e.policy != nil {
+		spec, ok := e.specs[action.Type]
+		if !ok {
+			// Registrada sem descrição (RegisterHandler): risco desconhecido
+			spec = ActionSpec{Name: action.Type, Risk: RiskExternal}
+		}
+		return e.policy.Run(spec, action.Params, handler)
+	}
+	return invoke(action.Type, action.Params, handler)
+}
+
+// invoke executa o handler e monta a ação com o resultado
+func invoke(name string, params map[string]interface{}, handler ActionHandler) (*Action, error) {
+	action := &Action{Type: name, Params: params}
+	response, err := handler(params)
 	if err != nil {
-		action.Success = false
 		action.Response = fmt.Sprintf("Erro: %v", err)
-		return &action, err
+		return action, err
 	}
 
 	action.Success = true
 	action.Response = response
-	return &action, nil
+	return action, nil
+}
+
+// SetPolicy passa as ações pela política de confirmação
+func (e *Executor) SetPolicy(p *Policy) {
+	e.policy = p
+}
+
+// SetPlatform troca a plataforma das ações do sistema (testes)
+func (e *Executor) SetPlatform(p platform.Platform) {
+	e.platform = p
+}
+
+// Policy política de confirmação das ações (nil se não houver)
+func (e *Executor) Policy() *Policy {
+	return e.policy
 }
 
 // === Handlers de Ações ===
@@ -81,37 +180,7 @@ func (e *Executor) openApp(params map[string]interface{}) (string, error) {
 		return "", fmt.Errorf("parâmetro 'app' não fornecido")
 	}
 
-	// Mapeia nomes comuns para executáveis
-	appMap := map[string]string{
-		"chrome":      "chrome",
-		"navegador":   "chrome",
-		"firefox":     "firefox",
-		"edge":        "msedge",
-		"notepad":     "notepad",
-		"bloco":       "notepad",
-		"calculadora": "calc",
-		"calc":        "calc",
-		"explorer":    "explorer",
-		"arquivos":    "explorer",
-		"terminal":    "wt",
-		"cmd":         "cmd",
-		"vscode":      "code",
-		"code":        "code",
-		"spotify":     "spotify",
-		"discord":     "discord",
-		"outlook":     "outlook",
-		"word":        "winword",
-		"excel":       "excel",
-		"powerpoint":  "powerpnt",
-	}
-
-	executable := app
-	if mapped, ok := appMap[strings.ToLower(app)]; ok {
-		executable = mapped
-	}
-
-	cmd := exec.Command("cmd", "/c", "start", executable)
-	if err := cmd.Start(); err != nil {
+	if err := e.platform.OpenApp(app); err != nil {
 		return "", fmt.Errorf("erro ao abrir %s: %w", app, err)
 	}
 
@@ -130,8 +199,7 @@ func (e *Executor) openURL(params map[string]interface{}) (string, error) {
 		url = "https://" + url
 	}
 
-	cmd := exec.Command("cmd", "/c", "start", url)
-	if err := cmd.Start(); err != nil {
+	if err := e.platform.OpenURL(url); err != nil {
 		return "", fmt.Errorf("erro ao abrir URL: %w", err)
 	}
 
@@ -223,37 +291,30 @@ func (e *Executor) setVolume(params map[string]interface{}) (string, error) {
 		return "", fmt.Errorf("parâmetro 'level' não fornecido")
 	}
 
-	// Ajusta volume via nircmd ou PowerShell
-	script := fmt.Sprintf(`
-		$volume = [math]::Round(%f * 655.35)
-		$wshShell = New-Object -ComObject WScript.Shell
-		1..50 | ForEach-Object { $wshShell.SendKeys([char]174) }
-		1..%d | ForEach-Object { $wshShell.SendKeys([char]175) }
-	`, level, int(level/2))
-
-	cmd := exec.Command("powershell", "-Command", script)
-	if err := cmd.Run(); err != nil {
+	if err := e.platform.SetVolume(int(level)); err != nil {
 		return "", fmt.Errorf("erro ao ajustar volume: %w", err)
 	}
 
 	return fmt.Sprintf("Volume ajustado para %d%%", int(level)), nil
 }
 
-// takeScreenshot captura a tela
+// takeScreenshot captura a tela num PNG temporário e devolve o caminho
 func (e *Executor) takeScreenshot(params map[string]interface{}) (string, error) {
-	// Usa snippingtool ou PowerShell
-	cmd := exec.Command("snippingtool", "/clip")
-	if err := cmd.Run(); err != nil {
-		// Fallback: PrintScreen key
-		script := `
-			Add-Type -AssemblyName System.Windows.Forms
-			[System.Windows.Forms.SendKeys]::SendWait('{PRTSC}')
-		`
-		cmd = exec.Command("powershell", "-Command", script)
-		cmd.Run()
+	image, err := e.platform.Screenshot()
+	if err != nil {
+		return "", fmt.Errorf("erro ao capturar tela: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "npu-ia-screenshot-*.png")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(image); err != nil {
+		return "", fmt.Errorf("erro ao salvar screenshot: %w", err)
 	}
 
-	return "Screenshot capturada", nil
+	return fmt.Sprintf("Screenshot salva em %s", file.Name()), nil
 }
 
 // search faz uma busca na web
@@ -267,32 +328,30 @@ func (e *Executor) search(params map[string]interface{}) (string, error) {
 	return e.openURL(map[string]interface{}{"url": url})
 }
 
-// runCommand executa um comando
+// runCommand executa um comando permitido pelo CommandRunner e devolve
+// stdout, stderr e código de saída em JSON
 func (e *Executor) runCommand(params map[string]interface{}) (string, error) {
 	command, ok := params["command"].(string)
 	if !ok {
 		return "", fmt.Errorf("parâmetro 'command' não fornecido")
 	}
-
-	// Por segurança, lista de comandos permitidos
-	allowedCommands := []string{"dir", "echo", "date", "time", "hostname", "whoami"}
-	allowed := false
-	for _, c := range allowedCommands {
-		if strings.HasPrefix(strings.ToLower(command), c) {
-			allowed = true
-			break
-		}
+	if e.commands == nil {
+		return "", fmt.Errorf("run_command indisponível")
 	}
 
-	if !allowed {
-		return "", fmt.Errorf("comando não permitido por segurança")
+	result, err := e.commands.Run(context.Background(), command)
+	if err != nil {
+		return "", err
 	}
-
-	cmd := exec.Command("cmd", "/c", command)
-	output, err := cmd.Output()
+	data, err := json.Marshal(result)
 	if err != nil {
 		return "", err
 	}
+	return string(data), nil
+}
 
-	return string(output), nil
+// SetCommandRunner troca as regras de run_command (as do config em vez das
+// padrão)
+func (e *Executor) SetCommandRunner(r *CommandRunner) {
+	e.commands = r
 }
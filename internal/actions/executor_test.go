@@ -0,0 +1,224 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
+)
+
+func TestExecute(t *testing.T) {
+	e := NewExecutor()
+
+	var got map[string]interface{}
+	e.RegisterAction(ActionSpec{
+		Name:   "echo_test",
+		Params: []ParamSpec{{Name: "text", Type: "string", Required: true}},
+	}, func(params map[string]interface{}) (string, error) {
+		got = params
+		text, _ := params["text"].(string)
+		return "eco: " + text, nil
+	})
+	e.RegisterAction(ActionSpec{Name: "fail_test"}, func(map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("falhou")
+	})
+
+	tests := []struct {
+		name        string
+		json        string
+		wantErr     string // substring do erro; vazio = sucesso
+		wantAction  bool   // Execute retorna a ação mesmo com erro do handler
+		wantSuccess bool
+		wantResp    string
+	}{
+		{
+			name:        "handler executado",
+			json:        `{"action": "echo_test", "params": {"text": "oi"}}`,
+			wantAction:  true,
+			wantSuccess: true,
+			wantResp:    "eco: oi",
+		},
+		{
+			name:    "JSON inválido",
+			json:    `{"action": "echo_test"`,
+			wantErr: "JSON inválido",
+		},
+		{
+			name:    "ação desconhecida",
+			json:    `{"action": "nao_existe", "params": {}}`,
+			wantErr: "ação desconhecida: nao_existe",
+		},
+		{
+			name:       "erro do handler",
+			json:       `{"action": "fail_test", "params": {}}`,
+			wantErr:    "falhou",
+			wantAction: true,
+			wantResp:   "Erro: falhou",
+		},
+		{
+			name:       "parâmetro obrigatório ausente",
+			json:       `{"action": "open_app", "params": {}}`,
+			wantErr:    "parâmetro 'app' não fornecido",
+			wantAction: true,
+			wantResp:   "Erro: parâmetro 'app' não fornecido",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, err := e.Execute(tt.json)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Execute() erro inesperado: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Execute() erro = %v, want %q", err, tt.wantErr)
+			}
+			if !tt.wantAction {
+				if action != nil {
+					t.Errorf("Execute() ação = %+v, want nil", action)
+				}
+				return
+			}
+			if action == nil {
+				t.Fatal("Execute() ação = nil")
+			}
+			if action.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v", action.Success, tt.wantSuccess)
+			}
+			if action.Response != tt.wantResp {
+				t.Errorf("Response = %q, want %q", action.Response, tt.wantResp)
+			}
+		})
+	}
+
+	if got["text"] != "oi" {
+		t.Errorf("handler recebeu params %v", got)
+	}
+}
+
+func TestRegisterHandlerWithoutSpec(t *testing.T) {
+	e := NewExecutor()
+	e.RegisterHandler("custom", func(map[string]interface{}) (string, error) {
+		return "ok", nil
+	})
+
+	action, err := e.Execute(`{"action": "custom", "params": {}}`)
+	if err != nil {
+		t.Fatalf("Execute() erro: %v", err)
+	}
+	if action.Type != "custom" || !action.Success || action.Response != "ok" {
+		t.Errorf("Execute() = %+v", action)
+	}
+
+	if !strings.Contains(string(e.Schema()), `"custom"`) {
+		t.Errorf("Schema() sem a ação registrada sem spec: %s", e.Schema())
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo não está no PATH")
+	}
+	e := NewExecutor()
+
+	tests := []struct {
+		name    string
+		command string
+		want    string // stdout
+		wantErr bool
+	}{
+		{"comando permitido", "echo oi", "oi\n", false},
+		{"sem shell, o resto é argumento", "echo oi & whoami; rm -rf x", "oi & whoami; rm -rf x\n", false},
+		{"aspas agrupam", `echo "oi  tudo" bem`, "oi  tudo bem\n", false},
+		{"prefixo não basta", "echox oi", "", true},
+		{"comando fora da lista", "rm -rf x", "", true},
+		{"vazio", "  ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := e.runCommand(map[string]interface{}{"command": tt.command})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runCommand(%q) erro = %v, wantErr %v", tt.command, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var result CommandResult
+			if err := json.Unmarshal([]byte(got), &result); err != nil {
+				t.Fatalf("runCommand(%q) = %q: %v", tt.command, got, err)
+			}
+			if result.Stdout != tt.want || result.ExitCode != 0 {
+				t.Errorf("runCommand(%q) = %+v, want stdout %q", tt.command, result, tt.want)
+			}
+		})
+	}
+}
+
+// fakePlatform grava as chamadas em vez de mexer no sistema
+type fakePlatform struct {
+	calls []string
+}
+
+func (f *fakePlatform) Name() string                          { return "fake" }
+func (f *fakePlatform) Tools() map[string]string              { return nil }
+func (f *fakePlatform) PlayCommand(string) (*exec.Cmd, error) { return nil, platform.ErrUnsupported }
+
+func (f *fakePlatform) OpenApp(name string) error {
+	f.calls = append(f.calls, "app "+name)
+	return nil
+}
+
+func (f *fakePlatform) OpenURL(url string) error {
+	f.calls = append(f.calls, "url "+url)
+	return nil
+}
+
+func (f *fakePlatform) SetVolume(percent int) error {
+	f.calls = append(f.calls, fmt.Sprintf("volume %d", percent))
+	return nil
+}
+
+func (f *fakePlatform) Screenshot() ([]byte, error) {
+	return []byte("\x89PNG"), nil
+}
+
+func TestPlatformActions(t *testing.T) {
+	e := NewExecutor()
+	fake := &fakePlatform{}
+	e.SetPlatform(fake)
+
+	for _, action := range []string{
+		`{"action": "open_app", "params": {"app": "navegador"}}`,
+		`{"action": "open_url", "params": {"url": "example.com"}}`,
+		`{"action": "volume", "params": {"level": 40}}`,
+		`{"action": "search", "params": {"query": "clima hoje"}}`,
+	} {
+		if _, err := e.Execute(action); err != nil {
+			t.Fatalf("Execute(%s) erro: %v", action, err)
+		}
+	}
+	want := "app navegador|url https://example.com|volume 40|url https://www.google.com/search?q=clima+hoje"
+	if got := strings.Join(fake.calls, "|"); got != want {
+		t.Errorf("chamadas = %q, want %q", got, want)
+	}
+
+	// A captura vira um PNG temporário
+	action, err := e.Execute(`{"action": "screenshot", "params": {}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, ok := strings.CutPrefix(action.Response, "Screenshot salva em ")
+	if !ok {
+		t.Fatalf("resposta = %q", action.Response)
+	}
+	defer os.Remove(path)
+	if data, err := os.ReadFile(path); err != nil || string(data) != "\x89PNG" {
+		t.Errorf("arquivo = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,328 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Risk nível de risco de uma ação, base da decisão quando nenhuma regra casa
+type Risk string
+
+const (
+	RiskReadOnly   Risk = "read_only"  // Só lê (emails, tela, listas)
+	RiskReversible Risk = "reversible" // Muda algo que se desfaz (volume, abrir app)
+	RiskExternal   Risk = "external"   // Sai do computador ou não se desfaz (email, post, comando)
+)
+
+// Decisões da política (policy.defaults e policy.rules)
+const (
+	DecisionAllow = "allow"
+	DecisionAsk   = "ask"
+	DecisionDeny  = "deny"
+	DecisionLimit = "limit" // allow até o limite da janela, depois ask
+)
+
+var (
+	// ErrConfirmationRequired a ação ficou na fila esperando o usuário;
+	// Action.Pending traz o id e Action.Response a pergunta
+	ErrConfirmationRequired = errors.New("ação aguardando confirmação")
+	// ErrDenied a política não permite a ação
+	ErrDenied = errors.New("ação bloqueada pela política")
+	// ErrNoPending a confirmação não existe mais (respondida ou expirada)
+	ErrNoPending = errors.New("confirmação não encontrada")
+)
+
+// RiskLevel risco da ação: o declarado, ou external para ações com efeito
+// colateral e read_only para as demais
+func (s ActionSpec) RiskLevel() Risk {
+	switch {
+	case s.Risk != "":
+		return s.Risk
+	case s.SideEffects:
+		return RiskExternal
+	}
+	return RiskReadOnly
+}
+
+// Pending ação esperando confirmação
+type Pending struct {
+	ID      string                 `json:"id"`
+	Action  string                 `json:"action"`
+	Params  map[string]interface{} `json:"params"`
+	Risk    Risk                   `json:"risk"`
+	Reason  string                 `json:"reason"` // Regra ou risco que pediu a confirmação
+	Created time.Time              `json:"created"`
+	Expires time.Time              `json:"expires"`
+
+	handler ActionHandler
+}
+
+// Question pergunta feita ao usuário (voz, REPL)
+func (p Pending) Question() string {
+	return fmt.Sprintf("Posso executar %s%s? Diga \"confirmar\" ou \"cancelar\".", p.Action, describeParams(p.Params))
+}
+
+// describeParams parâmetros em ordem de nome: " (to: ana@x.com, subject: oi)"
+func describeParams(params map[string]interface{}) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, params[name])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// Policy decide se cada ação roda direto, espera confirmação ou é negada, e
+// registra toda decisão no log de auditoria
+type Policy struct {
+	cfg config.PolicyConfig
+
+	mu      sync.Mutex
+	runs    map[string][]time.Time // Execuções recentes por regra limit
+	pending []*Pending             // Ordem de chegada
+	nextID  int
+
+	audit *os.File
+	now   func() time.Time
+}
+
+// auditEntry uma decisão no log de auditoria
+type auditEntry struct {
+	Time     time.Time              `json:"time"`
+	Action   string                 `json:"action"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Risk     Risk                   `json:"risk"`
+	Decision string                 `json:"decision"` // allow, ask, deny, approved, rejected, expired
+	Reason   string                 `json:"reason"`
+	Pending  string                 `json:"pending,omitempty"`
+	Result   string                 `json:"result,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// NewPolicy cria a política e abre o log de auditoria (só acrescenta)
+func NewPolicy(cfg config.PolicyConfig) (*Policy, error) {
+	p := &Policy{
+		cfg:  cfg,
+		runs: make(map[string][]time.Time),
+		now:  time.Now,
+	}
+	if cfg.AuditPath != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.AuditPath), 0755); err != nil {
+			return nil, fmt.Errorf("erro ao criar log de auditoria: %w", err)
+		}
+		file, err := os.OpenFile(cfg.AuditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao abrir log de auditoria: %w", err)
+		}
+		p.audit = file
+	}
+	return p, nil
+}
+
+// Close fecha o log de auditoria
+func (p *Policy) Close() error {
+	if p.audit == nil {
+		return nil
+	}
+	return p.audit.Close()
+}
+
+// Run aplica a política à ação: executa o handler, enfileira a confirmação
+// (ErrConfirmationRequired) ou nega (ErrDenied)
+func (p *Policy) Run(spec ActionSpec, params map[string]interface{}, handler ActionHandler) (*Action, error) {
+	risk := spec.RiskLevel()
+
+	p.mu.Lock()
+	decision, reason := p.decideLocked(spec.Name, risk)
+	switch decision {
+	case DecisionDeny:
+		p.mu.Unlock()
+		p.record(auditEntry{Action: spec.Name, Params: params, Risk: risk, Decision: DecisionDeny, Reason: reason})
+		err := fmt.Errorf("%w: %s (%s)", ErrDenied, spec.Name, reason)
+		return &Action{Type: spec.Name, Params: params, Response: "Erro: " + err.Error()}, err
+
+	case DecisionAsk:
+		p.nextID++
+		now := p.now()
+		pending := &Pending{
+			ID:      strconv.Itoa(p.nextID),
+			Action:  spec.Name,
+			Params:  params,
+			Risk:    risk,
+			Reason:  reason,
+			Created: now,
+			Expires: now.Add(p.cfg.ConfirmTimeout),
+			handler: handler,
+		}
+		p.pending = append(p.pending, pending)
+		p.mu.Unlock()
+		p.record(auditEntry{Action: spec.Name, Params: params, Risk: risk, Decision: DecisionAsk, Reason: reason, Pending: pending.ID})
+		return &Action{Type: spec.Name, Params: params, Pending: pending.ID, Response: pending.Question()},
+			fmt.Errorf("%w: %s", ErrConfirmationRequired, spec.Name)
+	}
+	p.mu.Unlock()
+
+	action, err := invoke(spec.Name, params, handler)
+	p.record(result(auditEntry{Action: spec.Name, Params: params, Risk: risk, Decision: DecisionAllow, Reason: reason}, action, err))
+	return action, err
+}
+
+// decideLocked decisão e motivo para a ação: a primeira regra que casa com o
+// nome ou o padrão do risco. Uma regra limit que permite já conta a execução.
+func (p *Policy) decideLocked(name string, risk Risk) (decision, reason string) {
+	for _, rule := range p.cfg.Rules {
+		if ok, _ := path.Match(rule.Action, name); !ok {
+			continue
+		}
+		reason = "regra " + rule.Action
+		if rule.Decision != DecisionLimit {
+			return rule.Decision, reason
+		}
+
+		// Janela deslizante: descarta as execuções antigas e conta as outras
+		now := p.now()
+		recent := p.runs[rule.Action][:0]
+		for _, at := range p.runs[rule.Action] {
+			if now.Sub(at) < rule.Window {
+				recent = append(recent, at)
+			}
+		}
+		if len(recent) >= rule.Limit {
+			p.runs[rule.Action] = recent
+			return DecisionAsk, fmt.Sprintf("%s: limite de %d por %s", reason, rule.Limit, rule.Window)
+		}
+		p.runs[rule.Action] = append(recent, now)
+		return DecisionAllow, reason
+	}
+
+	decision = p.cfg.Defaults[string(risk)]
+	if decision == "" {
+		decision = DecisionAsk
+	}
+	return decision, "risco " + string(risk)
+}
+
+// Pending confirmações em aberto, da mais antiga para a mais recente
+func (p *Policy) Pending() []Pending {
+	p.mu.Lock()
+	expired := p.expireLocked()
+	list := make([]Pending, len(p.pending))
+	for i, pending := range p.pending {
+		list[i] = *pending
+	}
+	p.mu.Unlock()
+
+	p.recordExpired(expired)
+	return list
+}
+
+// Approve executa a ação confirmada pelo usuário
+func (p *Policy) Approve(id string) (*Action, error) {
+	pending, err := p.take(id)
+	if err != nil {
+		return nil, err
+	}
+	action, err := invoke(pending.Action, pending.Params, pending.handler)
+	p.record(result(auditEntry{Action: pending.Action, Params: pending.Params, Risk: pending.Risk, Decision: "approved", Reason: pending.Reason, Pending: pending.ID}, action, err))
+	return action, err
+}
+
+// Reject descarta a ação recusada pelo usuário
+func (p *Policy) Reject(id string) error {
+	pending, err := p.take(id)
+	if err != nil {
+		return err
+	}
+	p.record(auditEntry{Action: pending.Action, Params: pending.Params, Risk: pending.Risk, Decision: "rejected", Reason: pending.Reason, Pending: pending.ID})
+	return nil
+}
+
+// take tira a confirmação da fila
+func (p *Policy) take(id string) (*Pending, error) {
+	p.mu.Lock()
+	expired := p.expireLocked()
+	var found *Pending
+	for i, pending := range p.pending {
+		if pending.ID == id {
+			found = pending
+			p.pending = append(p.pending[:i], p.pending[i+1:]...)
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	p.recordExpired(expired)
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoPending, id)
+	}
+	return found, nil
+}
+
+// expireLocked tira da fila as confirmações vencidas e as retorna
+func (p *Policy) expireLocked() []*Pending {
+	now := p.now()
+	var expired []*Pending
+	kept := p.pending[:0]
+	for _, pending := range p.pending {
+		if now.After(pending.Expires) {
+			expired = append(expired, pending)
+		} else {
+			kept = append(kept, pending)
+		}
+	}
+	p.pending = kept
+	return expired
+}
+
+// recordExpired audita as confirmações que ninguém respondeu
+func (p *Policy) recordExpired(expired []*Pending) {
+	for _, pending := range expired {
+		p.record(auditEntry{Action: pending.Action, Params: pending.Params, Risk: pending.Risk, Decision: "expired", Reason: pending.Reason, Pending: pending.ID})
+	}
+}
+
+// result completa o registro com o resultado da execução
+func result(entry auditEntry, action *Action, err error) auditEntry {
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Result = action.Response
+	}
+	return entry
+}
+
+// record acrescenta a decisão ao log de auditoria
+func (p *Policy) record(entry auditEntry) {
+	if p.audit == nil {
+		return
+	}
+	entry.Time = p.now()
+	data, err := json.Marshal(entry)
+	if err == nil {
+		p.mu.Lock()
+		_, err = p.audit.Write(append(data, '\n'))
+		p.mu.Unlock()
+	}
+	if err != nil {
+		log.Printf("Aviso: erro ao gravar auditoria de %s: %v", entry.Action, err)
+	}
+}
@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// newTestPolicy política com os padrões do config, as regras dadas e um
+// relógio controlado pelo teste
+func newTestPolicy(t *testing.T, rules ...config.PolicyRule) (*Policy, *time.Time, string) {
+	t.Helper()
+	cfg := config.Default().Policy
+	cfg.Rules = rules
+	cfg.AuditPath = filepath.Join(t.TempDir(), "audit.jsonl")
+
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	return p, &now, cfg.AuditPath
+}
+
+func TestPolicyDecisions(t *testing.T) {
+	p, _, _ := newTestPolicy(t,
+		config.PolicyRule{Action: "send_email", Decision: DecisionDeny},
+		config.PolicyRule{Action: "slack_*", Decision: DecisionAllow},
+	)
+
+	calls := 0
+	handler := func(map[string]interface{}) (string, error) {
+		calls++
+		return "ok", nil
+	}
+
+	tests := []struct {
+		spec    ActionSpec
+		wantErr error
+	}{
+		{ActionSpec{Name: "screenshot"}, nil},                                         // read_only: allow
+		{ActionSpec{Name: "volume", SideEffects: true, Risk: RiskReversible}, nil},    // reversible: allow
+		{ActionSpec{Name: "run_command", SideEffects: true}, ErrConfirmationRequired}, // external: ask
+		{ActionSpec{Name: "send_email", SideEffects: true}, ErrDenied},
+		{ActionSpec{Name: "slack_send", SideEffects: true}, nil}, // Regra antes do risco
+	}
+	for _, tt := range tests {
+		action, err := p.Run(tt.spec, map[string]interface{}{}, handler)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: erro = %v, want %v", tt.spec.Name, err, tt.wantErr)
+		}
+		if action == nil || action.Success != (tt.wantErr == nil) {
+			t.Errorf("%s: ação = %+v", tt.spec.Name, action)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("handler chamado %d vezes, want 3", calls)
+	}
+}
+
+func TestPolicyQueue(t *testing.T) {
+	p, now, auditPath := newTestPolicy(t)
+	spec := ActionSpec{Name: "send_email", SideEffects: true}
+
+	sent := ""
+	handler := func(params map[string]interface{}) (string, error) {
+		sent, _ = params["to"].(string)
+		return "Email enviado para " + sent, nil
+	}
+
+	first, err := p.Run(spec, map[string]interface{}{"to": "ana@x.com"}, handler)
+	if !errors.Is(err, ErrConfirmationRequired) || !strings.Contains(first.Response, "to: ana@x.com") {
+		t.Fatalf("Run = %+v, %v", first, err)
+	}
+	second, _ := p.Run(spec, map[string]interface{}{"to": "bia@x.com"}, handler)
+	third, _ := p.Run(spec, map[string]interface{}{"to": "caio@x.com"}, handler)
+
+	action, err := p.Approve(first.Pending)
+	if err != nil || sent != "ana@x.com" || !action.Success {
+		t.Fatalf("Approve = %+v, %v (enviado %q)", action, err, sent)
+	}
+	if _, err := p.Approve(first.Pending); !errors.Is(err, ErrNoPending) {
+		t.Errorf("segunda aprovação: erro = %v, want ErrNoPending", err)
+	}
+	if err := p.Reject(second.Pending); err != nil {
+		t.Fatal(err)
+	}
+
+	// A terceira expira sem resposta
+	*now = now.Add(3 * time.Minute)
+	if pending := p.Pending(); len(pending) != 0 {
+		t.Errorf("fila = %+v", pending)
+	}
+	if _, err := p.Approve(third.Pending); !errors.Is(err, ErrNoPending) || sent != "ana@x.com" {
+		t.Errorf("aprovar expirada: erro = %v, enviado %q", err, sent)
+	}
+
+	// Toda decisão fica registrada, na ordem
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decisions []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, entry.Decision)
+	}
+	want := "ask ask ask approved rejected expired"
+	if got := strings.Join(decisions, " "); got != want {
+		t.Errorf("auditoria = %q, want %q", got, want)
+	}
+}
+
+func TestPolicyRateLimit(t *testing.T) {
+	p, now, _ := newTestPolicy(t, config.PolicyRule{Action: "spotify_*", Decision: DecisionLimit, Limit: 2, Window: time.Hour})
+	handler := func(map[string]interface{}) (string, error) { return "ok", nil }
+
+	run := func(name string) error {
+		_, err := p.Run(ActionSpec{Name: name, SideEffects: true}, nil, handler)
+		return err
+	}
+
+	// O limite vale para todas as ações da regra
+	if err := run("spotify_next"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("spotify_pause"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("spotify_next"); !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("acima do limite: erro = %v, want confirmação", err)
+	}
+
+	// Passada a janela, volta a rodar sem perguntar
+	*now = now.Add(time.Hour)
+	if err := run("spotify_next"); err != nil {
+		t.Errorf("nova janela: erro = %v", err)
+	}
+}
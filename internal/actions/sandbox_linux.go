@@ -0,0 +1,22 @@
+//go:build linux
+
+package actions
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandbox roda o comando com ambiente limpo, num user namespace sem rede (só
+// loopback) e morto junto com o assistente. Sem user namespaces sem
+// privilégio (sysctl kernel.unprivileged_userns_clone), o Start falha.
+func sandbox(cmd *exec.Cmd, workDir string) {
+	cmd.Env = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=" + workDir, "LANG=C.UTF-8"}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package actions
+
+import (
+	"os"
+	"os/exec"
+)
+
+// sandbox roda o comando com ambiente limpo; isolar a rede só é suportado no
+// Linux
+func sandbox(cmd *exec.Cmd, workDir string) {
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + workDir, "USERPROFILE=" + workDir}
+	if root := os.Getenv("SystemRoot"); root != "" {
+		cmd.Env = append(cmd.Env, "SystemRoot="+root) // Sem ele nada roda no Windows
+	}
+}
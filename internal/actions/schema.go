@@ -0,0 +1,195 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParamSpec descreve um parâmetro de uma ação
+type ParamSpec struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // string, integer, number, boolean
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// ActionSpec descreve uma ação registrada e seus parâmetros
+type ActionSpec struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Params      []ParamSpec `json:"params,omitempty"`
+	SideEffects bool        `json:"side_effects"`   // Muda algo fora do assistente (envia, publica, apaga)
+	Risk        Risk        `json:"risk,omitempty"` // Vazio = pelo SideEffects (veja RiskLevel)
+}
+
+// RegisterAction registra um handler junto com a descrição da ação, usada no
+// prompt e no JSON schema da decodificação restrita
+func (e *Executor) RegisterAction(spec ActionSpec, handler ActionHandler) {
+	e.handlers[spec.Name] = handler
+	e.specs[spec.Name] = spec
+}
+
+// Specs retorna as ações registradas, ordenadas por nome
+func (e *Executor) Specs() []ActionSpec {
+	specs := make([]ActionSpec, 0, len(e.handlers))
+	for name := range e.handlers {
+		spec, ok := e.specs[name]
+		if !ok {
+			spec = ActionSpec{Name: name}
+		}
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Spec descrição de uma ação registrada
+func (e *Executor) Spec(name string) (ActionSpec, bool) {
+	if _, ok := e.handlers[name]; !ok {
+		return ActionSpec{}, false
+	}
+	spec, ok := e.specs[name]
+	if !ok {
+		spec = ActionSpec{Name: name}
+	}
+	return spec, true
+}
+
+// Describe lista as ações disponíveis para o prompt do modelo
+func (e *Executor) Describe() string {
+	return Catalog(e.Specs()).Describe()
+}
+
+// Schema gera o JSON schema de uma ação: {"action": nome, "params": {...}},
+// com os parâmetros de cada ação registrada
+func (e *Executor) Schema() []byte {
+	return Catalog(e.Specs()).Schema()
+}
+
+// ReplyAction pseudo-ação oferecida junto com as ferramentas para o modelo
+// responder em texto em vez de usar uma delas; não tem handler
+const ReplyAction = "responder"
+
+// ReplySpec descrição de ReplyAction; o texto vai em params.texto
+var ReplySpec = ActionSpec{
+	Name:        ReplyAction,
+	Description: "responde ao usuário em texto, sem usar ferramenta",
+	Params:      []ParamSpec{{Name: "texto", Type: "string", Required: true}},
+}
+
+// Catalog conjunto de ações oferecido ao modelo, quando não são todas as do
+// executor (as ferramentas de um pedido da API /v1, por exemplo)
+type Catalog []ActionSpec
+
+// Describe lista as ações do catálogo para o prompt do modelo, com os
+// parâmetros e as que têm efeito colateral
+func (c Catalog) Describe() string {
+	var b strings.Builder
+	for _, spec := range c {
+		params := make([]string, 0, len(spec.Params))
+		for _, p := range spec.Params {
+			param := fmt.Sprintf("%q: %s", p.Name, p.Type)
+			var notes []string
+			if !p.Required {
+				notes = append(notes, "opcional")
+			}
+			if p.Description != "" {
+				notes = append(notes, p.Description)
+			}
+			if len(notes) > 0 {
+				param += " (" + strings.Join(notes, ", ") + ")"
+			}
+			params = append(params, param)
+		}
+		description := spec.Description
+		if spec.SideEffects {
+			description += " [efeito colateral]"
+		}
+		fmt.Fprintf(&b, "- %s: %s {%s}\n", spec.Name, description, strings.Join(params, ", "))
+	}
+	return b.String()
+}
+
+// Schema JSON schema de uma ação do catálogo
+func (c Catalog) Schema() []byte {
+	options := make([]schemaNode, 0, len(c))
+	for _, spec := range c {
+		options = append(options, schemaNode{
+			Type: "object",
+			Properties: orderedProps{
+				{"action", schemaNode{Const: spec.Name}},
+				{"params", paramsSchema(spec)},
+			},
+			Required: []string{"action", "params"},
+		})
+	}
+
+	data, _ := json.Marshal(schemaNode{OneOf: options})
+	return data
+}
+
+// paramsSchema schema do objeto params de uma ação. Ações registradas só com
+// RegisterHandler aceitam qualquer objeto.
+func paramsSchema(spec ActionSpec) schemaNode {
+	node := schemaNode{Type: "object"}
+	if spec.Params == nil {
+		return node
+	}
+
+	node.Properties = orderedProps{}
+	node.Required = []string{}
+	for _, p := range spec.Params {
+		node.Properties = append(node.Properties, prop{p.Name, schemaNode{
+			Type:        p.Type,
+			Description: p.Description,
+			Enum:        p.Enum,
+		}})
+		if p.Required {
+			node.Required = append(node.Required, p.Name)
+		}
+	}
+	return node
+}
+
+// schemaNode subconjunto de JSON schema usado pelas ações
+type schemaNode struct {
+	Type        string       `json:"type,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Const       string       `json:"const,omitempty"`
+	Enum        []string     `json:"enum,omitempty"`
+	Properties  orderedProps `json:"properties,omitempty"`
+	Required    []string     `json:"required,omitempty"`
+	OneOf       []schemaNode `json:"oneOf,omitempty"`
+}
+
+type prop struct {
+	name   string
+	schema schemaNode
+}
+
+// orderedProps mantém a ordem das propriedades no JSON (map ordenaria por nome)
+type orderedProps []prop
+
+func (p orderedProps) MarshalJSON() ([]byte, error) {
+	out := []byte{'{'}
+	for i, item := range p {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		name, err := json.Marshal(item.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(item.schema)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, name...)
+		out = append(out, ':')
+		out = append(out, value...)
+	}
+	return append(out, '}'), nil
+}
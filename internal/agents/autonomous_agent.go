@@ -3,9 +3,12 @@ package agents
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
 )
 
 // AutonomousAgent agente autônomo que executa ações proativamente
@@ -15,6 +18,21 @@ type AutonomousAgent struct {
 	calendarService CalendarServiceInterface
 	taskService     TaskServiceInterface
 	config          AutonomousConfig
+	gate            ActionGate
+}
+
+// ActionGate política de confirmação (actions.Policy): decide se a ação roda
+// já, espera o usuário ou é negada
+type ActionGate interface {
+	Run(spec actions.ActionSpec, params map[string]interface{}, handler actions.ActionHandler) (*actions.Action, error)
+}
+
+// autoReplySpec resposta automática a um e-mail: sai do computador
+var autoReplySpec = actions.ActionSpec{
+	Name:        "auto_reply",
+	Description: "responde e-mail automaticamente",
+	SideEffects: true,
+	Risk:        actions.RiskExternal,
 }
 
 // AutonomousConfig configuração do agente autônomo
@@ -57,6 +75,12 @@ func NewAutonomousAgent(llm LLMInterface, email EmailServiceInterface, calendar
 	}
 }
 
+// SetGate passa as respostas automáticas pela política de confirmação; sem
+// ela, nenhuma resposta sai e todas ficam registradas para aprovação
+func (a *AutonomousAgent) SetGate(gate ActionGate) {
+	a.gate = gate
+}
+
 // ==================== 26. ATENDIMENTO DE PRIMEIRO NÍVEL ====================
 
 // HandleIncomingEmail responde automaticamente a e-mails comuns
@@ -74,25 +98,8 @@ func (a *AutonomousAgent) HandleIncomingEmail(ctx context.Context, emailID strin
 	for _, faq := range a.config.AutoReplyFAQ {
 		for _, keyword := range faq.Keywords {
 			if strings.Contains(strings.ToLower(content), strings.ToLower(keyword)) {
-				// Responde automaticamente
-				err := a.emailService.SendEmail("", "Re: ", faq.Answer)
-				if err != nil {
-					return &ActionLog{
-						Timestamp:   time.Now(),
-						ActionType:  "auto_reply",
-						Description: "Resposta automática FAQ",
-						Status:      "error",
-						Details:     err.Error(),
-					}, err
-				}
-
-				return &ActionLog{
-					Timestamp:   time.Now(),
-					ActionType:  "auto_reply",
-					Description: "Resposta automática FAQ",
-					Status:      "success",
-					Details:     fmt.Sprintf("Respondido com FAQ sobre: %s", keyword),
-				}, nil
+				// Responde automaticamente (ou pede confirmação, pela política)
+				return a.autoReply("Resposta automática FAQ", faq.Answer, fmt.Sprintf("FAQ sobre: %s", keyword))
 			}
 		}
 	}
@@ -123,19 +130,48 @@ Responda em JSON:
 	json.Unmarshal([]byte(response), &analysis)
 
 	if analysis.CanAutoReply && analysis.SuggestedReply != "" {
-		// TODO: Implementar envio da resposta
-		return &ActionLog{
-			Timestamp:   time.Now(),
-			ActionType:  "auto_reply",
-			Description: "Resposta automática LLM",
-			Status:      "pending_approval",
-			Details:     analysis.SuggestedReply,
-		}, nil
+		return a.autoReply("Resposta automática LLM", analysis.SuggestedReply, analysis.SuggestedReply)
 	}
 
 	return nil, nil
 }
 
+// autoReply envia a resposta pela política de confirmação: a resposta pode
+// ficar esperando o usuário (pending_approval) ou ser negada. Sem política,
+// só fica registrada (pending_approval).
+func (a *AutonomousAgent) autoReply(description, body, details string) (*ActionLog, error) {
+	entry := &ActionLog{
+		Timestamp:   time.Now(),
+		ActionType:  "auto_reply",
+		Description: description,
+		Status:      "success",
+		Details:     details,
+	}
+	send := func(map[string]interface{}) (string, error) {
+		if err := a.emailService.SendEmail("", "Re: ", body); err != nil {
+			return "", err
+		}
+		return "Resposta enviada", nil
+	}
+
+	if a.gate == nil {
+		entry.Status = "pending_approval"
+		return entry, nil
+	}
+	action, err := a.gate.Run(autoReplySpec, map[string]interface{}{"subject": "Re: ", "body": body}, send)
+	if errors.Is(err, actions.ErrConfirmationRequired) {
+		entry.Status = "pending_approval"
+		entry.Details = fmt.Sprintf("%s (confirmação %s)", details, action.Pending)
+		return entry, nil
+	}
+	if err != nil {
+		entry.Status = "error"
+		entry.Details = err.Error()
+		return entry, err
+	}
+	return entry, nil
+}
+
 // ==================== 27. SINCRONIZAÇÃO ENTRE APPS ====================
 
 // SyncChanges sincroniza mudanças entre e-mail e tarefas
@@ -256,16 +292,12 @@ Resposta:`, content, alternateContact)
 		return nil, err
 	}
 
-	// Envia resposta
-	// TODO: Implementar envio
-
-	return &ActionLog{
-		Timestamp:   time.Now(),
-		ActionType:  "out_of_office",
-		Description: "Resposta fora do escritório inteligente",
-		Status:      "success",
-		Details:     fmt.Sprintf("Direcionado para: %s", alternateContact),
-	}, nil
+	// Envia resposta, pela política como as respostas automáticas
+	entry, err := a.autoReply("Resposta fora do escritório inteligente", response, fmt.Sprintf("Direcionado para: %s", alternateContact))
+	if entry != nil {
+		entry.ActionType = "out_of_office"
+	}
+	return entry, err
 }
 
 // ==================== 29. ALERTAS DE CONFLITO ====================
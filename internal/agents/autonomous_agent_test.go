@@ -0,0 +1,91 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// fakeLLM responde sempre o mesmo texto
+type fakeLLM string
+
+func (l fakeLLM) Generate(context.Context, string) (string, error) { return string(l), nil }
+
+// fakeEmail caixa com um e-mail que guarda o que foi enviado
+type fakeEmail struct {
+	content string
+	sent    []string
+}
+
+func (e *fakeEmail) ListEmails(string, int64) ([]map[string]string, error) { return nil, nil }
+func (e *fakeEmail) GetEmailContent(string) (string, error)                { return e.content, nil }
+func (e *fakeEmail) SearchEmails(string) ([]map[string]string, error)      { return nil, nil }
+
+func (e *fakeEmail) SendEmail(to, subject, body string) error {
+	e.sent = append(e.sent, body)
+	return nil
+}
+
+func TestHandleIncomingEmailNeedsApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		gated   bool
+		want    string
+	}{
+		{"FAQ sem política", "Qual o horário de funcionamento?", false, "Abrimos às 9h."},
+		{"FAQ com política", "Qual o horário de funcionamento?", true, "Abrimos às 9h."},
+		{"LLM sem política", "Vocês têm estacionamento?", false, "Temos, sim."},
+		{"LLM com política", "Vocês têm estacionamento?", true, "Temos, sim."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email := &fakeEmail{content: tt.content}
+			agent := NewAutonomousAgent(fakeLLM(`{"can_auto_reply": true, "suggested_reply": "Temos, sim."}`), email, nil, nil, AutonomousConfig{
+				AutoReplyEnabled: true,
+				AutoReplyFAQ:     []FAQ{{Keywords: []string{"horário"}, Answer: "Abrimos às 9h."}},
+			})
+
+			var policy *actions.Policy
+			if tt.gated {
+				cfg := config.Default().Policy
+				cfg.AuditPath = filepath.Join(t.TempDir(), "audit.jsonl")
+				var err error
+				if policy, err = actions.NewPolicy(cfg); err != nil {
+					t.Fatal(err)
+				}
+				defer policy.Close()
+				agent.SetGate(policy)
+			}
+
+			entry, err := agent.HandleIncomingEmail(context.Background(), "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry == nil || entry.Status != "pending_approval" {
+				t.Fatalf("HandleIncomingEmail() = %+v, want pending_approval", entry)
+			}
+			if len(email.sent) != 0 {
+				t.Fatalf("enviou %q sem aprovação", email.sent)
+			}
+			if !tt.gated {
+				return
+			}
+
+			// Aprovada pelo usuário, a resposta sai
+			pending := policy.Pending()
+			if len(pending) != 1 {
+				t.Fatalf("Pending() = %+v, want uma confirmação", pending)
+			}
+			if _, err := policy.Approve(pending[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			if len(email.sent) != 1 || email.sent[0] != tt.want {
+				t.Errorf("enviado = %q, want %q", email.sent, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,20 @@ package agents
 import (
 	"context"
 	"fmt"
+	"log"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
 )
 
 // EmailAgent agente inteligente de e-mail
 type EmailAgent struct {
 	llm          LLMInterface
 	emailService EmailServiceInterface
-	userStyle    string // Estilo de escrita do usuário
+	userStyle    string               // Estilo de escrita do usuário
+	retriever    embeddings.Retriever // Índice local dos e-mails já vistos
 }
 
 // EmailSummary resumo de thread
@@ -220,6 +224,14 @@ Descreva o estilo em uma frase:`, samples.String())
 
 // ==================== 4. BUSCA SEMÂNTICA ====================
 
+// SetRetriever ativa o ranking semântico em SemanticSearch
+func (e *EmailAgent) SetRetriever(r embeddings.Retriever) {
+	e.retriever = r
+}
+
+// semanticSearchLimit e-mails retornados pela busca semântica
+const semanticSearchLimit = 20
+
 // SemanticSearch busca por contexto
 func (e *EmailAgent) SemanticSearch(ctx context.Context, query string) ([]map[string]string, error) {
 	// Primeiro, expande a query para termos relacionados
@@ -233,8 +245,9 @@ Gere 5 termos de busca que podem ajudar a encontrar este e-mail:`, query)
 		return nil, err
 	}
 
-	// Busca usando termos expandidos
+	// Busca usando termos expandidos, sem repetir e-mails
 	allResults := make([]map[string]string, 0)
+	seen := make(map[string]bool)
 	searchTerms := strings.Split(terms, "\n")
 	for _, term := range searchTerms {
 		term = strings.TrimSpace(term)
@@ -242,13 +255,94 @@ Gere 5 termos de busca que podem ajudar a encontrar este e-mail:`, query)
 			continue
 		}
 		results, _ := e.emailService.SearchEmails(term)
-		allResults = append(allResults, results...)
+		for _, email := range results {
+			if id := email["id"]; id != "" {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			allResults = append(allResults, email)
+		}
+	}
+
+	if e.retriever == nil {
+		return allResults, nil
+	}
+
+	// Rankeia por similaridade com a descrição; o índice guarda os e-mails
+	// de buscas anteriores, que também podem aparecer no resultado
+	ranked, err := e.rankEmails(ctx, query, allResults)
+	if err != nil {
+		log.Printf("Aviso: erro na busca semântica de e-mails: %v", err)
+		return allResults, nil
+	}
+	return ranked, nil
+}
+
+// rankEmails indexa os candidatos e retorna os e-mails mais similares à query
+func (e *EmailAgent) rankEmails(ctx context.Context, query string, emails []map[string]string) ([]map[string]string, error) {
+	docs := make([]embeddings.Document, 0, len(emails))
+	for _, email := range emails {
+		if email["id"] == "" {
+			continue
+		}
+		docs = append(docs, embeddings.Document{
+			ID:   email["id"],
+			Text: fmt.Sprintf("%s\n%s\n%s", email["subject"], email["from"], email["body"]),
+			Metadata: map[string]string{
+				"subject": email["subject"],
+				"from":    email["from"],
+				"date":    email["date"],
+				"snippet": emailSnippet(email),
+			},
+		})
+	}
+	if err := e.retriever.Upsert(ctx, docs...); err != nil {
+		return nil, err
 	}
 
-	// Remove duplicatas e rankeia por relevância
-	// TODO: Implementar deduplicação e ranking
+	results, err := e.retriever.Search(ctx, query, semanticSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]map[string]string, len(emails))
+	for _, email := range emails {
+		byID[email["id"]] = email
+	}
 
-	return allResults, nil
+	ranked := make([]map[string]string, 0, len(results))
+	for _, r := range results {
+		if email, ok := byID[r.ID]; ok {
+			ranked = append(ranked, email)
+			continue
+		}
+		ranked = append(ranked, map[string]string{
+			"id":      r.ID,
+			"subject": r.Metadata["subject"],
+			"from":    r.Metadata["from"],
+			"date":    r.Metadata["date"],
+			"snippet": r.Metadata["snippet"],
+		})
+	}
+	return ranked, nil
+}
+
+// snippetChars tamanho do trecho do corpo guardado no índice
+const snippetChars = 300
+
+// emailSnippet início do corpo do e-mail, para mostrar os resultados que só
+// existem no índice
+func emailSnippet(email map[string]string) string {
+	if snippet := email["snippet"]; snippet != "" {
+		return snippet
+	}
+	body := strings.Join(strings.Fields(email["body"]), " ")
+	if runes := []rune(body); len(runes) > snippetChars {
+		return string(runes[:snippetChars]) + "..."
+	}
+	return body
 }
 
 // ==================== 5. EXTRAÇÃO DE PRAZOS ====================
@@ -7,6 +7,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
 	"golang.org/x/net/html"
 )
 
@@ -30,6 +32,8 @@ type EbookReader struct {
 	highlights    map[string][]*Highlight
 	llm           LLMInterface
 	tts           TTSInterface
+	retriever     embeddings.Retriever // Trechos dos livros (nil = capítulos vizinhos)
+	indexed       map[string]bool      // Livros já enviados ao índice
 	mu            sync.RWMutex
 	basePath      string
 }
@@ -343,6 +347,7 @@ func (er *EbookReader) OpenBook(bookID string) (*Book, error) {
 	}
 
 	er.currentBook = book
+	er.indexBookLocked(book)
 
 	// Atualiza progresso
 	if _, ok := er.readingState[bookID]; !ok {
@@ -540,13 +545,69 @@ Forneça em JSON:
 	return &summary, nil
 }
 
+// SetRetriever ativa a busca semântica em AskAboutBook. Cada livro é
+// indexado uma vez, em segundo plano, quando é aberto.
+func (er *EbookReader) SetRetriever(r embeddings.Retriever) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.retriever = r
+	er.indexed = make(map[string]bool)
+	if er.currentBook != nil {
+		er.indexBookLocked(er.currentBook)
+	}
+}
+
+// indexBookLocked indexa os trechos do livro em segundo plano, se ainda não
+// foi feito. Enquanto isso as perguntas usam o que já está no índice ou os
+// capítulos vizinhos. Chamado com er.mu travado.
+func (er *EbookReader) indexBookLocked(book *Book) {
+	if er.retriever == nil || er.indexed[book.ID] {
+		return
+	}
+	er.indexed[book.ID] = true
+
+	retriever := er.retriever
+	go func() {
+		if err := retriever.Upsert(context.Background(), bookDocuments(book)...); err != nil {
+			log.Printf("Aviso: erro ao indexar livro %s: %v", book.Title, err)
+
+			// Tenta de novo na próxima abertura
+			er.mu.Lock()
+			delete(er.indexed, book.ID)
+			er.mu.Unlock()
+		}
+	}()
+}
+
+// bookChunkChars tamanho aproximado de cada trecho indexado
+const bookChunkChars = 1000
+
 // AskAboutBook pergunta sobre o livro
 func (er *EbookReader) AskAboutBook(ctx context.Context, question string) (string, error) {
 	if er.currentBook == nil {
 		return "", fmt.Errorf("nenhum livro aberto")
 	}
 
-	// Coleta contexto do capítulo atual e adjacentes
+	// Trechos mais relevantes do livro inteiro; sem índice, o capítulo
+	// atual e os adjacentes
+	context, ok := er.relevantPassages(ctx, question)
+	if !ok {
+		context = er.nearbyChapters()
+	}
+
+	prompt := fmt.Sprintf(`Baseado neste trecho do livro "%s":
+
+%s
+
+Pergunta: %s
+
+Resposta:`, er.currentBook.Title, context, question)
+
+	return er.llm.Generate(ctx, prompt)
+}
+
+// nearbyChapters início do capítulo atual e dos adjacentes
+func (er *EbookReader) nearbyChapters() string {
 	progress := er.readingState[er.currentBook.ID]
 	var context strings.Builder
 
@@ -560,15 +621,69 @@ func (er *EbookReader) AskAboutBook(ctx context.Context, question string) (strin
 		context.WriteString("\n\n")
 	}
 
-	prompt := fmt.Sprintf(`Baseado neste trecho do livro "%s":
+	return context.String()
+}
 
-%s
+// relevantPassages busca no índice os trechos do livro atual mais próximos
+// da pergunta
+func (er *EbookReader) relevantPassages(ctx context.Context, question string) (string, bool) {
+	er.mu.RLock()
+	retriever := er.retriever
+	er.mu.RUnlock()
+	if retriever == nil {
+		return "", false
+	}
 
-Pergunta: %s
+	book := er.currentBook
+	results, err := retriever.SearchWhere(ctx, question, 5, map[string]string{"book": book.ID})
+	if err != nil || len(results) == 0 {
+		return "", false
+	}
+
+	var context strings.Builder
+	for _, r := range results {
+		context.WriteString(fmt.Sprintf("=== %s ===\n", r.Metadata["chapter"]))
+		context.WriteString(r.Text)
+		context.WriteString("\n\n")
+	}
+	return context.String(), true
+}
 
-Resposta:`, er.currentBook.Title, context.String(), question)
+// bookDocuments divide os capítulos em trechos de ~bookChunkChars
+// caracteres, quebrando entre palavras
+func bookDocuments(book *Book) []embeddings.Document {
+	docs := make([]embeddings.Document, 0)
+	for _, ch := range book.Chapters {
+		var chunk strings.Builder
+		n := 0
+		flush := func() {
+			if chunk.Len() == 0 {
+				return
+			}
+			docs = append(docs, embeddings.Document{
+				ID:   fmt.Sprintf("%s/%d/%d", book.ID, ch.Index, n),
+				Text: chunk.String(),
+				Metadata: map[string]string{
+					"book":    book.ID,
+					"chapter": ch.Title,
+				},
+			})
+			chunk.Reset()
+			n++
+		}
 
-	return er.llm.Generate(ctx, prompt)
+		for _, word := range strings.Fields(ch.Content) {
+			if chunk.Len() > 0 {
+				chunk.WriteByte(' ')
+			}
+			chunk.WriteString(word)
+			if chunk.Len() >= bookChunkChars {
+				flush()
+			}
+		}
+		flush()
+	}
+	return docs
 }
 
 // GenerateFlashcards gera flashcards do capítulo
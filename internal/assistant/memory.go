@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
 )
 
 // ==================== MEMÓRIA PERSISTENTE ====================
@@ -22,6 +25,7 @@ type Memory struct {
 	conversations []ConversationSummary
 	patterns    map[string]*Pattern
 	llm         LLMInterface
+	retriever   embeddings.Retriever // Busca semântica de fatos (nil = substring)
 	mu          sync.RWMutex
 }
 
@@ -163,10 +167,43 @@ func (m *Memory) GetUserName() string {
 
 // ==================== FATOS ====================
 
-// LearnFact aprende um novo fato
+// SetRetriever ativa a busca semântica de fatos e indexa os já conhecidos
+func (m *Memory) SetRetriever(ctx context.Context, r embeddings.Retriever) error {
+	m.mu.Lock()
+	m.retriever = r
+	docs := make([]embeddings.Document, 0, len(m.facts))
+	for _, fact := range m.facts {
+		docs = append(docs, factDocument(fact))
+	}
+	m.mu.Unlock()
+
+	return r.Upsert(ctx, docs...)
+}
+
+// factDocument texto indexado de um fato
+func factDocument(f *Fact) embeddings.Document {
+	return embeddings.Document{
+		ID:       f.ID,
+		Text:     fmt.Sprintf("%s: %s", f.Subject, f.Content),
+		Metadata: map[string]string{"category": f.Category},
+	}
+}
+
+// LearnFact aprende um novo fato. Se o mesmo fato já é conhecido, só reforça
+// a confiança.
 func (m *Memory) LearnFact(category, subject, content, source string, confidence float64) *Fact {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+
+	for _, known := range m.facts {
+		if strings.EqualFold(known.Subject, subject) && strings.EqualFold(known.Content, content) {
+			if confidence > known.Confidence {
+				known.Confidence = confidence
+				m.save()
+			}
+			m.mu.Unlock()
+			return known
+		}
+	}
 
 	id := fmt.Sprintf("fact_%d", time.Now().UnixNano())
 	fact := &Fact{
@@ -183,12 +220,25 @@ func (m *Memory) LearnFact(category, subject, content, source string, confidence
 
 	m.facts[id] = fact
 	m.save()
+	retriever := m.retriever
+	m.mu.Unlock()
+
+	if retriever != nil {
+		if err := retriever.Upsert(context.Background(), factDocument(fact)); err != nil {
+			log.Printf("Aviso: erro ao indexar fato %s: %v", fact.ID, err)
+		}
+	}
 
 	return fact
 }
 
-// RecallFacts busca fatos relevantes
+// RecallFacts busca fatos relevantes. Com retriever a busca é semântica;
+// sem ele (ou se falhar), por substring.
 func (m *Memory) RecallFacts(query string, limit int) []*Fact {
+	if facts, ok := m.recallSemantic(query, limit); ok {
+		return facts
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -217,6 +267,43 @@ func (m *Memory) RecallFacts(query string, limit int) []*Fact {
 	return relevant
 }
 
+// recallMinScore similaridade mínima de um fato lembrado. Na escala do E5
+// textos sem relação ficam perto de 0.75.
+const recallMinScore = 0.8
+
+// recallSemantic busca fatos pelo índice vetorial, na ordem de similaridade,
+// descartando os pouco similares
+func (m *Memory) recallSemantic(query string, limit int) ([]*Fact, bool) {
+	m.mu.RLock()
+	retriever := m.retriever
+	m.mu.RUnlock()
+	if retriever == nil {
+		return nil, false
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+	results, err := retriever.SearchWith(context.Background(), query, embeddings.SearchOptions{
+		K:        limit,
+		MinScore: recallMinScore,
+	})
+	if err != nil {
+		return nil, false // Cai para a busca por substring
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	facts := make([]*Fact, 0, len(results))
+	for _, r := range results {
+		if fact, ok := m.facts[r.ID]; ok {
+			facts = append(facts, fact)
+		}
+	}
+	return facts, true
+}
+
 // GetFactsByCategory busca por categoria
 func (m *Memory) GetFactsByCategory(category string) []*Fact {
 	m.mu.RLock()
@@ -357,31 +444,40 @@ func (m *Memory) GetPatterns(patternType string) []*Pattern {
 
 // ==================== CONTEXTO ====================
 
-// GetContext gera contexto para o LLM
+// GetContext gera contexto para o LLM, do mais para o menos importante (quem
+// corta por tamanho pode descartar as últimas linhas). Sem nada conhecido,
+// retorna vazio.
 func (m *Memory) GetContext(query string) string {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	var context strings.Builder
 
 	// Adiciona informações do usuário
-	context.WriteString("## Sobre o usuário:\n")
+	m.mu.RLock()
+	var about []string
 	if m.preferences.Name != "" {
-		context.WriteString(fmt.Sprintf("- Nome: %s\n", m.preferences.Name))
+		about = append(about, fmt.Sprintf("- Nome: %s\n", m.preferences.Name))
 	}
 	if m.preferences.WakeUpTime != "" {
-		context.WriteString(fmt.Sprintf("- Acorda às: %s\n", m.preferences.WakeUpTime))
+		about = append(about, fmt.Sprintf("- Acorda às: %s\n", m.preferences.WakeUpTime))
 	}
 	if len(m.preferences.Interests) > 0 {
-		context.WriteString(fmt.Sprintf("- Interesses: %s\n", strings.Join(m.preferences.Interests, ", ")))
+		about = append(about, fmt.Sprintf("- Interesses: %s\n", strings.Join(m.preferences.Interests, ", ")))
+	}
+	m.mu.RUnlock()
+	if len(about) > 0 {
+		context.WriteString("## Sobre o usuário:\n")
+		context.WriteString(strings.Join(about, ""))
 	}
 
-	// Adiciona fatos relevantes
+	// Adiciona fatos relevantes (RecallFacts e GetPatterns pegam o lock)
 	facts := m.RecallFacts(query, 5)
 	if len(facts) > 0 {
-		context.WriteString("\n## Fatos relevantes:\n")
+		if context.Len() > 0 {
+			context.WriteString("\n")
+		}
+		context.WriteString("## Fatos relevantes:\n")
 		for _, fact := range facts {
 			context.WriteString(fmt.Sprintf("- %s: %s\n", fact.Subject, fact.Content))
+			m.UseFact(fact.ID)
 		}
 	}
 
@@ -394,7 +490,10 @@ func (m *Memory) GetContext(query string) string {
 		}
 	}
 	if len(highConfPatterns) > 0 {
-		context.WriteString("\n## Padrões observados:\n")
+		if context.Len() > 0 {
+			context.WriteString("\n")
+		}
+		context.WriteString("## Padrões observados:\n")
 		for _, p := range highConfPatterns {
 			context.WriteString(fmt.Sprintf("- %s\n", p.Description))
 		}
@@ -5,50 +5,57 @@ import (
 	"sync"
 	"time"
 
-	"github.com/gordonklaus/portaudio"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/vad"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+	"github.com/gordonklaus/portaudio"
 )
 
 // Capture gerencia captura de áudio do microfone
 type Capture struct {
-	stream     *portaudio.Stream
-	buffer     []float32
-	config     config.AudioConfig
-	mu         sync.Mutex
+	stream *portaudio.Stream
+	config config.AudioConfig
+
+	mu          sync.Mutex
+	incoming    []float32 // Áudio do callback ainda não analisado
 	isListening bool
+	threshold   float32
 
-	// Voice Activity Detection
-	vadThreshold float32
-	silenceTime  time.Duration
-	maxDuration  time.Duration
+	// Voice Activity Detection e endpointing
+	detector    vad.Detector
+	endpoint    *vad.Endpointer
+	maxDuration time.Duration
+	wait        time.Duration
 }
 
-// NewCapture cria uma nova instância de captura
-func NewCapture(cfg config.AudioConfig) (*Capture, error) {
+// NewCapture cria uma nova instância de captura; o detector (veja vad.New)
+// passa a ser da Capture e Close o libera
+func NewCapture(cfg config.AudioConfig, detector vad.Detector) (*Capture, error) {
+	if cfg.SampleRate != vad.SampleRate {
+		return nil, fmt.Errorf("audio.sample_rate deve ser %d (Whisper e VAD), não %d", vad.SampleRate, cfg.SampleRate)
+	}
+
 	// Inicializa PortAudio
 	if err := portaudio.Initialize(); err != nil {
 		return nil, fmt.Errorf("erro ao inicializar PortAudio: %w", err)
 	}
 
 	c := &Capture{
-		config:       cfg,
-		vadThreshold: cfg.VADThreshold,
-		silenceTime:  time.Duration(cfg.SilenceMs) * time.Millisecond,
-		maxDuration:  time.Duration(cfg.MaxDurationMs) * time.Millisecond,
+		config:      cfg,
+		threshold:   cfg.VAD.Threshold,
+		detector:    detector,
+		endpoint:    vad.NewEndpointer(detector, cfg),
+		maxDuration: time.Duration(cfg.MaxDurationMs) * time.Millisecond,
+		wait:        time.Duration(cfg.VAD.WaitMs) * time.Millisecond,
 	}
 
 	// Configura stream
 	inputChannels := 1
-	sampleRate := cfg.SampleRate
-	if sampleRate == 0 {
-		sampleRate = 16000
-	}
 	framesPerBuffer := 1024
 
 	stream, err := portaudio.OpenDefaultStream(
-		inputChannels,    // input channels
-		0,                // output channels
-		float64(sampleRate),
+		inputChannels, // input channels
+		0,             // output channels
+		float64(cfg.SampleRate),
 		framesPerBuffer,
 		c.processAudio,
 	)
@@ -62,94 +69,99 @@ func NewCapture(cfg config.AudioConfig) (*Capture, error) {
 	return c, nil
 }
 
-// processAudio callback do PortAudio
+// processAudio callback do PortAudio; só guarda o áudio, a análise é no Listen
 func (c *Capture) processAudio(in []float32) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.isListening {
-		c.buffer = append(c.buffer, in...)
+		c.incoming = append(c.incoming, in...)
 	}
 }
 
 // Listen aguarda fala e retorna áudio capturado
 func (c *Capture) Listen() ([]float32, error) {
+	return c.ListenStream(nil)
+}
+
+// ListenStream escuta como Listen, entregando a onAudio (pode ser nil) o
+// áudio da fala conforme chega, a começar pelo pré-roll. Sem fala em
+// audio.vad.wait_ms, devolve nil.
+func (c *Capture) ListenStream(onAudio func(samples []float32)) ([]float32, error) {
 	c.mu.Lock()
-	c.buffer = make([]float32, 0, c.config.SampleRate*10) // 10 segundos max
-	c.isListening = true
+	c.endpoint.SetThreshold(c.threshold)
 	c.mu.Unlock()
+	c.endpoint.Reset()
 
-	// Inicia stream
-	if err := c.stream.Start(); err != nil {
-		return nil, err
-	}
-	defer c.stream.Stop()
-
-	// Aguarda atividade de voz
+	maxSamples := int(c.maxDuration.Seconds() * vad.SampleRate)
 	startTime := time.Now()
-	lastActivity := time.Now()
-	speechDetected := false
-
-	for {
-		time.Sleep(50 * time.Millisecond)
 
-		// Verifica timeout máximo
-		if time.Since(startTime) > c.maxDuration {
-			break
+	var speech []float32
+	err := c.Wait(func(samples []float32) (bool, error) {
+		chunk, done, err := c.endpoint.Write(samples)
+		if err != nil {
+			return false, err
 		}
-
-		// Analisa energia do áudio
-		c.mu.Lock()
-		energy := c.calculateEnergy(c.buffer[max(0, len(c.buffer)-1600):]) // últimos 100ms
-		c.mu.Unlock()
-
-		if energy > c.vadThreshold {
-			speechDetected = true
-			lastActivity = time.Now()
-		} else if speechDetected && time.Since(lastActivity) > c.silenceTime {
-			// Silêncio após fala detectada
-			break
+		if len(chunk) > 0 && onAudio != nil {
+			onAudio(chunk)
 		}
 
-		// Se não detectou fala por muito tempo, reseta
-		if !speechDetected && time.Since(startTime) > 5*time.Second {
-			return nil, nil
+		// Fim da fala (hangover) ou duração máxima
+		if done || len(c.endpoint.Speech()) >= maxSamples {
+			speech = c.endpoint.Speech()
+			return true, nil
 		}
+
+		// Sem fala, devolve o controle para o loop principal
+		return !c.endpoint.Speaking() && time.Since(startTime) > c.wait, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return speech, nil
+}
 
-	// Para de escutar
+// Wait entrega a until o áudio do microfone a cada 30 ms (às vezes vazio),
+// sem VAD, até until devolver true ou um erro. É como a palavra de ativação
+// ouve.
+func (c *Capture) Wait(until func(samples []float32) (bool, error)) error {
 	c.mu.Lock()
-	c.isListening = false
-	result := make([]float32, len(c.buffer))
-	copy(result, c.buffer)
+	c.incoming = nil
+	c.isListening = true
 	c.mu.Unlock()
 
-	if len(result) < c.config.SampleRate/2 { // menos de 0.5s
-		return nil, nil
+	defer func() {
+		c.mu.Lock()
+		c.isListening = false
+		c.mu.Unlock()
+	}()
+
+	// Inicia stream
+	if err := c.stream.Start(); err != nil {
+		return err
 	}
+	defer c.stream.Stop()
 
-	return result, nil
-}
+	for {
+		time.Sleep(30 * time.Millisecond)
 
-// calculateEnergy calcula energia RMS do áudio
-func (c *Capture) calculateEnergy(samples []float32) float32 {
-	if len(samples) == 0 {
-		return 0
-	}
+		c.mu.Lock()
+		samples := c.incoming
+		c.incoming = nil
+		c.mu.Unlock()
 
-	var sum float32
-	for _, s := range samples {
-		sum += s * s
+		if done, err := until(samples); err != nil || done {
+			return err
+		}
 	}
-
-	return sum / float32(len(samples))
 }
 
-// SetVADThreshold ajusta sensibilidade do VAD
+// SetVADThreshold ajusta sensibilidade do VAD (probabilidade de fala, 0 a 1);
+// vale a partir do próximo Listen
 func (c *Capture) SetVADThreshold(threshold float32) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.vadThreshold = threshold
+	c.threshold = threshold
 }
 
 // Close libera recursos
@@ -158,13 +170,5 @@ func (c *Capture) Close() error {
 		c.stream.Close()
 	}
 	portaudio.Terminate()
-	return nil
-}
-
-// helper function
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
+	return c.detector.Close()
 }
@@ -0,0 +1,206 @@
+// Package backend isola o runtime de inferência (ONNX Runtime, DirectML...)
+// dos modelos. llm, stt, vision e coder só conhecem Backend, Session e
+// Tensor; a criação de sessões, a E/S de tensores e a escolha do execution
+// provider ficam aqui.
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// DataType tipo dos elementos de um tensor
+type DataType int
+
+const (
+	Float32 DataType = iota + 1
+	Float16          // Dados como []byte (2 bytes little-endian por elemento)
+	Int64
+	Int32
+	Bool   // Dados como []byte (0 ou 1)
+	String // Saídas de texto (ex.: Whisper com pós-processamento no grafo)
+)
+
+// String nome do tipo
+func (t DataType) String() string {
+	switch t {
+	case Float32:
+		return "float32"
+	case Float16:
+		return "float16"
+	case Int64:
+		return "int64"
+	case Int32:
+		return "int32"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	}
+	return fmt.Sprintf("DataType(%d)", int(t))
+}
+
+// Shape dimensões de um tensor; -1 indica dimensão dinâmica nos metadados
+type Shape []int64
+
+// Size número de elementos; o shape vazio é um escalar
+func (s Shape) Size() int64 {
+	n := int64(1)
+	for _, d := range s {
+		n *= d
+	}
+	return n
+}
+
+// Clone cópia independente
+func (s Shape) Clone() Shape {
+	return append(Shape(nil), s...)
+}
+
+// TensorInfo descreve uma entrada ou saída do modelo
+type TensorInfo struct {
+	Name  string
+	Type  DataType
+	Shape Shape
+}
+
+// Tensor dados de entrada ou saída de uma sessão. Data retorna []float32,
+// []int64, []int32, []string ou []byte (Float16 e Bool) conforme o tipo.
+type Tensor interface {
+	Type() DataType
+	Shape() Shape
+	Data() any
+	Destroy()
+}
+
+// Session modelo carregado pronto para inferência
+type Session interface {
+	// Run executa o modelo. inputs segue a ordem dos nomes passados em Open e
+	// a saída também; os tensores retornados pertencem ao chamador.
+	Run(inputs []Tensor) ([]Tensor, error)
+	Close() error
+}
+
+// Backend runtime de inferência
+type Backend interface {
+	Name() string
+
+	// Inspect lê os metadados de entrada/saída do modelo sem carregá-lo
+	Inspect(path string) (inputs, outputs []TensorInfo, err error)
+
+	// Open carrega o modelo usando as entradas e saídas indicadas
+	Open(path string, inputs, outputs []string) (Session, error)
+
+	// NewTensor cria um tensor de entrada; data deve ter o tipo descrito em
+	// Tensor.Data e exatamente shape.Size() elementos
+	NewTensor(dt DataType, shape Shape, data any) (Tensor, error)
+
+	Close() error
+}
+
+// New cria o backend configurado
+func New(cfg config.BackendConfig) (Backend, error) {
+	switch strings.ToLower(cfg.Name) {
+	case "", "onnx", "onnxruntime":
+		return NewONNX(cfg)
+	}
+	return nil, fmt.Errorf("backend desconhecido: %s", cfg.Name)
+}
+
+// HostTensor tensor em memória Go, usado por backends sem memória própria
+// (como o fake dos testes)
+type HostTensor struct {
+	dt    DataType
+	shape Shape
+	data  any
+}
+
+// NewHostTensor cria um HostTensor validando tipo e tamanho dos dados
+func NewHostTensor(dt DataType, shape Shape, data any) (*HostTensor, error) {
+	n, err := dataLen(dt, data)
+	if err != nil {
+		return nil, err
+	}
+	if want := shape.Size() * elementBytes(dt); int64(n) != want {
+		return nil, fmt.Errorf("tensor %s %v: esperados %d elementos, recebidos %d", dt, shape, want, n)
+	}
+	return &HostTensor{dt: dt, shape: shape.Clone(), data: data}, nil
+}
+
+func (t *HostTensor) Type() DataType { return t.dt }
+func (t *HostTensor) Shape() Shape   { return t.shape }
+func (t *HostTensor) Data() any      { return t.data }
+func (t *HostTensor) Destroy()       {}
+
+// dataLen confere o tipo Go dos dados e retorna o comprimento do slice
+func dataLen(dt DataType, data any) (int, error) {
+	var (
+		n  int
+		ok bool
+	)
+	switch dt {
+	case Float32:
+		var v []float32
+		v, ok = data.([]float32)
+		n = len(v)
+	case Int64:
+		var v []int64
+		v, ok = data.([]int64)
+		n = len(v)
+	case Int32:
+		var v []int32
+		v, ok = data.([]int32)
+		n = len(v)
+	case String:
+		var v []string
+		v, ok = data.([]string)
+		n = len(v)
+	case Float16, Bool:
+		var v []byte
+		v, ok = data.([]byte)
+		n = len(v)
+	default:
+		return 0, fmt.Errorf("tipo de tensor não suportado: %s", dt)
+	}
+	if !ok {
+		return 0, fmt.Errorf("dados %T incompatíveis com tensor %s", data, dt)
+	}
+	return n, nil
+}
+
+// elementBytes quantos itens do slice de dados formam um elemento
+func elementBytes(dt DataType) int64 {
+	if dt == Float16 {
+		return 2
+	}
+	return 1
+}
+
+// Float32s retorna os dados de um tensor float32
+func Float32s(t Tensor) ([]float32, error) {
+	data, ok := t.Data().([]float32)
+	if !ok || t.Type() != Float32 {
+		return nil, fmt.Errorf("tensor %s, esperado float32", t.Type())
+	}
+	return data, nil
+}
+
+// Strings retorna os dados de um tensor de texto
+func Strings(t Tensor) ([]string, error) {
+	data, ok := t.Data().([]string)
+	if !ok || t.Type() != String {
+		return nil, fmt.Errorf("tensor %s, esperado string", t.Type())
+	}
+	return data, nil
+}
+
+// DestroyAll libera uma lista de tensores, ignorando nil
+func DestroyAll(tensors []Tensor) {
+	for _, t := range tensors {
+		if t != nil {
+			t.Destroy()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package fake implementa um backend.Backend roteirizado, sem runtime nem
+// arquivos de modelo, para testar llm, stt, router etc. em qualquer máquina.
+package fake
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+)
+
+// RunFunc responde a uma execução do modelo. inputs e a saída seguem a ordem
+// dos nomes passados em Open.
+type RunFunc func(inputs []backend.Tensor) ([]backend.Tensor, error)
+
+// Model roteiro de um modelo registrado no Backend
+type Model struct {
+	Inputs  []backend.TensorInfo
+	Outputs []backend.TensorInfo
+	Run     RunFunc
+}
+
+// Backend backend em memória; cada caminho de modelo responde com o roteiro
+// registrado em Add
+type Backend struct {
+	mu     sync.Mutex
+	models map[string]*Model
+	opened map[string]int
+	runs   map[string]int
+}
+
+// New cria um backend sem modelos
+func New() *Backend {
+	return &Backend{
+		models: make(map[string]*Model),
+		opened: make(map[string]int),
+		runs:   make(map[string]int),
+	}
+}
+
+// Add registra o roteiro do modelo em path
+func (b *Backend) Add(path string, m *Model) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.models[path] = m
+}
+
+// Opened quantas sessões foram abertas para path
+func (b *Backend) Opened(path string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opened[path]
+}
+
+// Runs quantas inferências foram feitas em path
+func (b *Backend) Runs(path string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.runs[path]
+}
+
+func (b *Backend) Name() string { return "fake" }
+
+func (b *Backend) Inspect(path string) ([]backend.TensorInfo, []backend.TensorInfo, error) {
+	m, err := b.model(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.Inputs, m.Outputs, nil
+}
+
+func (b *Backend) Open(path string, inputs, outputs []string) (backend.Session, error) {
+	m, err := b.model(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := hasNames(m.Inputs, inputs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := hasNames(m.Outputs, outputs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	b.mu.Lock()
+	b.opened[path]++
+	b.mu.Unlock()
+
+	return &session{backend: b, path: path, model: m, outputs: len(outputs)}, nil
+}
+
+func (b *Backend) NewTensor(dt backend.DataType, shape backend.Shape, data any) (backend.Tensor, error) {
+	return backend.NewHostTensor(dt, shape, data)
+}
+
+func (b *Backend) Close() error { return nil }
+
+func (b *Backend) model(path string) (*Model, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m, ok := b.models[path]
+	if !ok {
+		return nil, fmt.Errorf("modelo não registrado no backend fake: %s", path)
+	}
+	return m, nil
+}
+
+// hasNames confere se todos os nomes pedidos existem no modelo
+func hasNames(infos []backend.TensorInfo, names []string) error {
+	known := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		known[info.Name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("entrada/saída inexistente: %s", name)
+		}
+	}
+	return nil
+}
+
+type session struct {
+	backend *Backend
+	path    string
+	model   *Model
+	outputs int
+}
+
+func (s *session) Run(inputs []backend.Tensor) ([]backend.Tensor, error) {
+	s.backend.mu.Lock()
+	s.backend.runs[s.path]++
+	s.backend.mu.Unlock()
+
+	out, err := s.model.Run(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != s.outputs {
+		return nil, fmt.Errorf("%s: roteiro retornou %d saídas, esperadas %d", s.path, len(out), s.outputs)
+	}
+	return out, nil
+}
+
+func (s *session) Close() error { return nil }
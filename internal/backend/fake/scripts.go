@@ -0,0 +1,154 @@
+package fake
+
+import (
+	"sync"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+)
+
+// Peak valor do logit do token escolhido em OneHot; alto o bastante para
+// dominar temperature, top-k/top-p e repetition penalty
+const Peak = 100
+
+// OneHot logits de tamanho vocab com o pico em id
+func OneHot(vocab int, id int64) []float32 {
+	logits := make([]float32, vocab)
+	logits[id] = Peak
+	return logits
+}
+
+// Logits modelo decoder (input_ids, attention_mask → logits) que responde a
+// cada passo com a próxima linha de logits do roteiro, repetindo a última
+// quando o roteiro acaba
+func Logits(rows ...[]float32) *Model {
+	vocab := int64(len(rows[0]))
+	var (
+		mu   sync.Mutex
+		step int
+	)
+
+	return &Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "attention_mask", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+		},
+		Outputs: []backend.TensorInfo{
+			{Name: "logits", Type: backend.Float32, Shape: backend.Shape{-1, -1, vocab}},
+		},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			mu.Lock()
+			row := rows[len(rows)-1]
+			if step < len(rows) {
+				row = rows[step]
+			}
+			step++
+			mu.Unlock()
+
+			// [1, seq, vocab] com a linha do roteiro na última posição
+			seq := inputs[0].Shape()[1]
+			data := make([]float32, seq*vocab)
+			copy(data[(seq-1)*vocab:], row)
+
+			t, err := backend.NewHostTensor(backend.Float32, backend.Shape{1, seq, vocab}, data)
+			if err != nil {
+				return nil, err
+			}
+			return []backend.Tensor{t}, nil
+		},
+	}
+}
+
+// Tokens modelo decoder que gera exatamente a sequência ids (termine com o
+// EOS do tokenizer para encerrar a geração)
+func Tokens(vocab int, ids ...int64) *Model {
+	rows := make([][]float32, len(ids))
+	for i, id := range ids {
+		rows[i] = OneHot(vocab, id)
+	}
+	return Logits(rows...)
+}
+
+// Steer modelo decoder cujo token preferido em cada passo é o byte de wish,
+// com o byte de want (e depois end) como segunda opção: testa decodificação
+// restrita, em que a gramática descarta o que o modelo quer. Os IDs dos
+// bytes são os do ByteLevelTokenizer.
+func Steer(vocab int, end int64, wish, want string) *Model {
+	rows := make([][]float32, len(want)+1)
+	for i := range rows {
+		row := make([]float32, vocab)
+		if i < len(wish) {
+			row[wish[i]] = Peak
+		}
+		if i < len(want) {
+			row[want[i]] += Peak / 2
+		} else {
+			row[end] += Peak / 2
+		}
+		rows[i] = row
+	}
+	return Logits(rows...)
+}
+
+// Transcripts modelo Whisper ponta a ponta (áudio PCM → texto) que devolve
+// as transcrições em ordem e depois texto vazio
+func Transcripts(texts ...string) *Model {
+	var (
+		mu   sync.Mutex
+		next int
+	)
+	scalar := backend.Shape{1}
+
+	return &Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "audio_pcm", Type: backend.Float32, Shape: backend.Shape{1, -1}},
+			{Name: "min_length", Type: backend.Int32, Shape: scalar},
+			{Name: "max_length", Type: backend.Int32, Shape: scalar},
+			{Name: "num_beams", Type: backend.Int32, Shape: scalar},
+			{Name: "num_return_sequences", Type: backend.Int32, Shape: scalar},
+			{Name: "length_penalty", Type: backend.Float32, Shape: scalar},
+			{Name: "repetition_penalty", Type: backend.Float32, Shape: scalar},
+		},
+		Outputs: []backend.TensorInfo{
+			{Name: "str", Type: backend.String, Shape: backend.Shape{1, 1}},
+		},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			mu.Lock()
+			text := ""
+			if next < len(texts) {
+				text = texts[next]
+				next++
+			}
+			mu.Unlock()
+
+			t, err := backend.NewHostTensor(backend.String, backend.Shape{1, 1}, []string{text})
+			if err != nil {
+				return nil, err
+			}
+			return []backend.Tensor{t}, nil
+		},
+	}
+}
+
+// Static modelo que só precisa abrir (vision, coder): Run devolve um tensor
+// float32 vazio por saída
+func Static(inputs, outputs []string) *Model {
+	m := &Model{}
+	for _, name := range inputs {
+		m.Inputs = append(m.Inputs, backend.TensorInfo{Name: name, Type: backend.Float32})
+	}
+	for _, name := range outputs {
+		m.Outputs = append(m.Outputs, backend.TensorInfo{Name: name, Type: backend.Float32})
+	}
+	m.Run = func([]backend.Tensor) ([]backend.Tensor, error) {
+		out := make([]backend.Tensor, len(outputs))
+		for i := range out {
+			t, err := backend.NewHostTensor(backend.Float32, backend.Shape{1}, []float32{0})
+			if err != nil {
+				return nil, err
+			}
+			out[i] = t
+		}
+		return out, nil
+	}
+	return m
+}
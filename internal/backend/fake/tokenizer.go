@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ByteLevelTokenizer grava num diretório temporário um tokenizer.json BPE
+// byte-level sem merges em que o ID de cada token é o próprio byte; os
+// especiais vêm em seguida (256, 257...). Devolve o caminho do arquivo.
+func ByteLevelTokenizer(t testing.TB, specials ...string) string {
+	t.Helper()
+
+	vocab := make(map[string]int64, 256)
+	for b, r := range byteLevelAlphabet() {
+		vocab[string(r)] = int64(b)
+	}
+	added := make([]map[string]interface{}, len(specials))
+	for i, content := range specials {
+		added[i] = map[string]interface{}{"id": 256 + i, "content": content, "special": true}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"added_tokens":  added,
+		"pre_tokenizer": map[string]interface{}{"type": "ByteLevel", "add_prefix_space": false},
+		"decoder":       map[string]interface{}{"type": "ByteLevel"},
+		"model":         map[string]interface{}{"type": "BPE", "vocab": vocab, "merges": []string{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// byteLevelAlphabet caractere usado pelo byte-level do GPT-2 para cada byte
+func byteLevelAlphabet() [256]rune {
+	var alphabet [256]rune
+	n := 0
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			alphabet[b] = rune(b)
+		} else {
+			alphabet[b] = rune(256 + n)
+			n++
+		}
+	}
+	return alphabet
+}
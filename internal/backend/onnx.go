@@ -0,0 +1,280 @@
+package backend
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// ortEnv o ambiente do ONNX Runtime é global ao processo: inicializa uma vez
+// e é destruído quando o último backend fecha
+var ortEnv struct {
+	sync.Mutex
+	refs int
+}
+
+// ONNX backend sobre o ONNX Runtime. Provider "cpu" roda em qualquer
+// plataforma; "directml" usa a NPU/GPU no Windows e cai para CPU se não
+// estiver disponível.
+type ONNX struct {
+	provider string
+	device   int
+	threads  int
+	closed   bool
+}
+
+// NewONNX inicializa o ONNX Runtime
+func NewONNX(cfg config.BackendConfig) (*ONNX, error) {
+	provider := strings.ToLower(cfg.Provider)
+	switch provider {
+	case "":
+		provider = "cpu"
+	case "cpu", "directml":
+	default:
+		return nil, fmt.Errorf("execution provider não suportado: %s", cfg.Provider)
+	}
+
+	ortEnv.Lock()
+	defer ortEnv.Unlock()
+	if ortEnv.refs == 0 {
+		if cfg.LibraryPath != "" {
+			ort.SetSharedLibraryPath(cfg.LibraryPath)
+		}
+		if err := ort.InitializeEnvironment(); err != nil {
+			return nil, fmt.Errorf("erro ao inicializar ONNX: %w", err)
+		}
+	}
+	ortEnv.refs++
+
+	return &ONNX{
+		provider: provider,
+		device:   cfg.Device,
+		threads:  cfg.Threads,
+	}, nil
+}
+
+// Name nome do backend com o provider
+func (b *ONNX) Name() string {
+	return "onnx/" + b.provider
+}
+
+// Inspect lê os metadados de entrada/saída do modelo
+func (b *ONNX) Inspect(path string) ([]TensorInfo, []TensorInfo, error) {
+	inputs, outputs, err := ort.GetInputOutputInfo(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return infoFromORT(inputs), infoFromORT(outputs), nil
+}
+
+// Open carrega o modelo com o execution provider configurado
+func (b *ONNX) Open(path string, inputs, outputs []string) (Session, error) {
+	options, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, err
+	}
+	defer options.Destroy()
+
+	if b.threads > 0 {
+		if err := options.SetIntraOpNumThreads(b.threads); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.provider == "directml" {
+		if err := options.AppendExecutionProviderDirectML(b.device); err != nil {
+			log.Printf("DirectML não disponível para %s, usando CPU", path)
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(path, inputs, outputs, options)
+	if err != nil {
+		return nil, err
+	}
+	return &onnxSession{session: session, outputs: len(outputs)}, nil
+}
+
+// NewTensor cria um tensor na memória do ONNX Runtime
+func (b *ONNX) NewTensor(dt DataType, shape Shape, data any) (Tensor, error) {
+	return newONNXTensor(dt, shape, data)
+}
+
+func newONNXTensor(dt DataType, shape Shape, data any) (*onnxTensor, error) {
+	if _, err := NewHostTensor(dt, shape, data); err != nil {
+		return nil, err
+	}
+
+	s := ort.NewShape(shape...)
+	var (
+		t   ort.ArbitraryTensor
+		err error
+	)
+	switch dt {
+	case Float32:
+		t, err = ort.NewTensor(s, data.([]float32))
+	case Int64:
+		t, err = ort.NewTensor(s, data.([]int64))
+	case Int32:
+		t, err = ort.NewTensor(s, data.([]int32))
+	case Float16:
+		t, err = ort.NewCustomDataTensor(s, data.([]byte), ort.TensorElementDataTypeFloat16)
+	case Bool:
+		t, err = ort.NewCustomDataTensor(s, data.([]byte), ort.TensorElementDataTypeBool)
+	default:
+		return nil, fmt.Errorf("onnxruntime_go não suporta tensores %s", dt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &onnxTensor{t: t, dt: dt}, nil
+}
+
+// Close libera o ambiente do ONNX Runtime quando não há mais backends
+func (b *ONNX) Close() error {
+	ortEnv.Lock()
+	defer ortEnv.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	ortEnv.refs--
+	if ortEnv.refs == 0 {
+		return ort.DestroyEnvironment()
+	}
+	return nil
+}
+
+type onnxSession struct {
+	session *ort.DynamicAdvancedSession
+	outputs int
+}
+
+func (s *onnxSession) Run(inputs []Tensor) ([]Tensor, error) {
+	in := make([]ort.ArbitraryTensor, len(inputs))
+	var converted []ort.ArbitraryTensor
+	defer func() {
+		for _, t := range converted {
+			t.Destroy()
+		}
+	}()
+
+	for i, t := range inputs {
+		if ot, ok := t.(*onnxTensor); ok {
+			in[i] = ot.t
+			continue
+		}
+		// Tensores de outro backend são copiados
+		ot, err := newONNXTensor(t.Type(), t.Shape(), t.Data())
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, ot.t)
+		in[i] = ot.t
+	}
+
+	out := make([]ort.ArbitraryTensor, s.outputs)
+	if err := s.session.Run(in, out); err != nil {
+		for _, t := range out {
+			if t != nil {
+				t.Destroy()
+			}
+		}
+		return nil, err
+	}
+
+	result := make([]Tensor, len(out))
+	for i, t := range out {
+		dt, err := typeFromORT(tensorElementType(t))
+		if err == nil && dt == String {
+			err = fmt.Errorf("onnxruntime_go não lê tensores de string")
+		}
+		if err != nil {
+			for _, t := range out {
+				t.Destroy()
+			}
+			return nil, err
+		}
+		result[i] = &onnxTensor{t: t, dt: dt}
+	}
+	return result, nil
+}
+
+func (s *onnxSession) Close() error {
+	return s.session.Destroy()
+}
+
+// onnxTensor tensor alocado pelo ONNX Runtime
+type onnxTensor struct {
+	t  ort.ArbitraryTensor
+	dt DataType
+}
+
+func (t *onnxTensor) Type() DataType { return t.dt }
+func (t *onnxTensor) Shape() Shape   { return Shape(t.t.GetShape()) }
+func (t *onnxTensor) Destroy()       { t.t.Destroy() }
+
+func (t *onnxTensor) Data() any {
+	switch v := t.t.(type) {
+	case *ort.Tensor[float32]:
+		return v.GetData()
+	case *ort.Tensor[int64]:
+		return v.GetData()
+	case *ort.Tensor[int32]:
+		return v.GetData()
+	case *ort.CustomDataTensor:
+		return v.GetData()
+	}
+	return nil
+}
+
+// tensorElementType tipo ONNX dos elementos de um tensor
+func tensorElementType(t ort.ArbitraryTensor) ort.TensorElementDataType {
+	switch v := t.(type) {
+	case *ort.Tensor[float32]:
+		return ort.TensorElementDataTypeFloat
+	case *ort.Tensor[int64]:
+		return ort.TensorElementDataTypeInt64
+	case *ort.Tensor[int32]:
+		return ort.TensorElementDataTypeInt32
+	case *ort.CustomDataTensor:
+		return ort.TensorElementDataType(v.DataType())
+	}
+	return ort.TensorElementDataTypeUndefined
+}
+
+// typeFromORT converte o tipo de elemento do ONNX Runtime
+func typeFromORT(t ort.TensorElementDataType) (DataType, error) {
+	switch t {
+	case ort.TensorElementDataTypeFloat:
+		return Float32, nil
+	case ort.TensorElementDataTypeFloat16:
+		return Float16, nil
+	case ort.TensorElementDataTypeInt64:
+		return Int64, nil
+	case ort.TensorElementDataTypeInt32:
+		return Int32, nil
+	case ort.TensorElementDataTypeBool:
+		return Bool, nil
+	case ort.TensorElementDataTypeString:
+		return String, nil
+	}
+	return 0, fmt.Errorf("tipo de tensor não suportado: %s", t)
+}
+
+func infoFromORT(infos []ort.InputOutputInfo) []TensorInfo {
+	out := make([]TensorInfo, len(infos))
+	for i, info := range infos {
+		dt, _ := typeFromORT(info.DataType)
+		out[i] = TensorInfo{
+			Name:  info.Name,
+			Type:  dt,
+			Shape: Shape(info.Dimensions.Clone()),
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+// Package chat monta prompts de conversa no formato esperado por cada família
+// de modelo (Phi-3, Llama 3, ChatML/Qwen).
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tokenizer"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Role papel de uma mensagem na conversa
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool" // Resultado de uma ação/ferramenta
+)
+
+// Message uma mensagem da conversa
+type Message struct {
+	Role    Role
+	Content string
+	Name    string // Nome da ferramenta (RoleTool)
+}
+
+// Template formato de prompt de uma família de modelos
+type Template struct {
+	Name string
+
+	// StopTokens tokens que encerram a resposta do assistente (inclui o EOS)
+	StopTokens []string
+
+	render func(messages []Message, addGenerationPrompt bool) string
+}
+
+// Render monta o prompt da conversa. Com addGenerationPrompt, termina abrindo
+// o turno do assistente. O BOS não é incluído: ele vem do pós-processador do
+// tokenizer.
+func (t *Template) Render(messages []Message, addGenerationPrompt bool) string {
+	return t.render(messages, addGenerationPrompt)
+}
+
+// Resolve escolhe o template de um modelo. cfg.ChatTemplate pode ser o nome de
+// um preset ou "auto"/vazio; nesse caso o formato é detectado pelo
+// chat_template do tokenizer_config.json e, na falta dele, pela família ou
+// pelo nome do modelo.
+func Resolve(cfg config.ModelConfig) (*Template, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.ChatTemplate))
+	if name != "" && name != "auto" {
+		t, ok := Preset(name)
+		if !ok {
+			return nil, fmt.Errorf("chat_template desconhecido: %s", cfg.ChatTemplate)
+		}
+		return t, nil
+	}
+
+	if cfg.TokenizerPath != "" {
+		if tc, err := tokenizer.LoadConfig(cfg.TokenizerPath); err == nil {
+			if t, ok := Detect(tc.ChatTemplate); ok {
+				return withEOS(t, tc.EOSToken), nil
+			}
+		}
+	}
+
+	preset := "phi3"
+	lower := strings.ToLower(cfg.Family + " " + cfg.Name)
+	switch {
+	case strings.Contains(lower, "llama"):
+		preset = "llama3"
+	case strings.Contains(lower, "qwen"):
+		preset = "chatml"
+	}
+	t, _ := Preset(preset)
+	return t, nil
+}
+
+// Detect identifica a família pelo chat_template (Jinja) do tokenizer
+func Detect(chatTemplate string) (*Template, bool) {
+	switch {
+	case strings.Contains(chatTemplate, "<|start_header_id|>"):
+		return Preset("llama3")
+	case strings.Contains(chatTemplate, "<|im_start|>"):
+		return Preset("chatml")
+	case strings.Contains(chatTemplate, "<|assistant|>"):
+		return Preset("phi3")
+	}
+	return nil, false
+}
+
+// withEOS garante que o EOS do tokenizer esteja entre os tokens de parada
+func withEOS(t *Template, eos string) *Template {
+	if eos == "" {
+		return t
+	}
+	for _, s := range t.StopTokens {
+		if s == eos {
+			return t
+		}
+	}
+	out := *t
+	out.StopTokens = append(append([]string(nil), t.StopTokens...), eos)
+	return &out
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// conversation system, dois turnos e uma resposta de ferramenta
+var conversation = []Message{
+	{Role: RoleSystem, Content: "Seja breve."},
+	{Role: RoleUser, Content: "Que horas são?"},
+	{Role: RoleAssistant, Content: "São 10h."},
+	{Role: RoleUser, Content: "E o tempo?"},
+	{Role: RoleTool, Name: "clima", Content: "22°C, sol"},
+}
+
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		preset string
+		want   string
+	}{
+		{"phi3", "<|system|>\nSeja breve.<|end|>\n" +
+			"<|user|>\nQue horas são?<|end|>\n" +
+			"<|assistant|>\nSão 10h.<|end|>\n" +
+			"<|user|>\nE o tempo?<|end|>\n" +
+			"<|user|>\n<tool_response name=\"clima\">\n22°C, sol\n</tool_response><|end|>\n" +
+			"<|assistant|>\n"},
+		{"llama3", "<|start_header_id|>system<|end_header_id|>\n\nSeja breve.<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nQue horas são?<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\nSão 10h.<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nE o tempo?<|eot_id|>" +
+			"<|start_header_id|>ipython<|end_header_id|>\n\n22°C, sol<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n"},
+		{"chatml", "<|im_start|>system\nSeja breve.<|im_end|>\n" +
+			"<|im_start|>user\nQue horas são?<|im_end|>\n" +
+			"<|im_start|>assistant\nSão 10h.<|im_end|>\n" +
+			"<|im_start|>user\nE o tempo?<|im_end|>\n" +
+			"<|im_start|>user\n<tool_response name=\"clima\">\n22°C, sol\n</tool_response><|im_end|>\n" +
+			"<|im_start|>assistant\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			tmpl, ok := Preset(tt.preset)
+			if !ok {
+				t.Fatalf("Preset(%q) não existe", tt.preset)
+			}
+			if got := tmpl.Render(conversation, true); got != tt.want {
+				t.Errorf("Render() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutGenerationPrompt(t *testing.T) {
+	tmpl, _ := Preset("chatml")
+	got := tmpl.Render(conversation[:3], false)
+	want := "<|im_start|>system\nSeja breve.<|im_end|>\n" +
+		"<|im_start|>user\nQue horas são?<|im_end|>\n" +
+		"<|im_start|>assistant\nSão 10h.<|im_end|>\n"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestChatMLGroupsToolResponses(t *testing.T) {
+	tmpl, _ := Preset("chatml")
+	got := tmpl.Render([]Message{
+		{Role: RoleTool, Content: "a"},
+		{Role: RoleTool, Content: "b"},
+	}, false)
+	want := "<|im_start|>user\n<tool_response>\na\n</tool_response>\n<tool_response>\nb\n</tool_response><|im_end|>\n"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	tokenizerPath := filepath.Join(dir, "tokenizer.json")
+	tokenizerConfig := `{"chat_template": "{% for m in messages %}<|im_start|>{{ m.role }}{% endfor %}", "eos_token": "<|fim_pad|>"}`
+	if err := os.WriteFile(filepath.Join(dir, "tokenizer_config.json"), []byte(tokenizerConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.ModelConfig
+		want      string
+		wantStops []string
+	}{
+		{"preset explícito", config.ModelConfig{Name: "qwen", ChatTemplate: "llama3"}, "llama3",
+			[]string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>"}},
+		{"pelo nome", config.ModelConfig{Name: "Llama-3.2-3B"}, "llama3",
+			[]string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>"}},
+		{"pela família", config.ModelConfig{Name: "modelo", Family: "qwen"}, "chatml",
+			[]string{"<|im_end|>", "<|endoftext|>"}},
+		{"padrão é o phi", config.ModelConfig{Name: "modelo"}, "phi3",
+			[]string{"<|end|>", "<|endoftext|>"}},
+		{"chat_template do tokenizer, com o EOS dele", config.ModelConfig{Name: "phi", TokenizerPath: tokenizerPath}, "chatml",
+			[]string{"<|im_end|>", "<|endoftext|>", "<|fim_pad|>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Resolve(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl.Name != tt.want || !reflect.DeepEqual(tmpl.StopTokens, tt.wantStops) {
+				t.Errorf("Resolve() = %s %v, want %s %v", tmpl.Name, tmpl.StopTokens, tt.want, tt.wantStops)
+			}
+		})
+	}
+
+	if _, err := Resolve(config.ModelConfig{ChatTemplate: "gemma"}); err == nil {
+		t.Error("Resolve() com preset desconhecido deveria falhar")
+	}
+}
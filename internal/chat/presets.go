@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preset retorna um template pelo nome: "phi3", "llama3" ou "chatml" (Qwen)
+func Preset(name string) (*Template, bool) {
+	switch name {
+	case "phi3", "phi":
+		return &Template{
+			Name:       "phi3",
+			StopTokens: []string{"<|end|>", "<|endoftext|>"},
+			render:     renderPhi3,
+		}, true
+	case "llama3", "llama":
+		return &Template{
+			Name:       "llama3",
+			StopTokens: []string{"<|eot_id|>", "<|eom_id|>", "<|end_of_text|>"},
+			render:     renderLlama3,
+		}, true
+	case "chatml", "qwen":
+		return &Template{
+			Name:       "chatml",
+			StopTokens: []string{"<|im_end|>", "<|endoftext|>"},
+			render:     renderChatML,
+		}, true
+	}
+	return nil, false
+}
+
+// renderPhi3 formato do Phi-3/3.5: <|role|>\nconteúdo<|end|>\n. O Phi não tem
+// papel de ferramenta; o resultado vai num turno de usuário.
+func renderPhi3(messages []Message, addGenerationPrompt bool) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		role, content := msg.Role, msg.Content
+		if role == RoleTool {
+			role, content = RoleUser, toolResponse(msg)
+		}
+		fmt.Fprintf(&b, "<|%s|>\n%s<|end|>\n", role, content)
+	}
+	if addGenerationPrompt {
+		b.WriteString("<|assistant|>\n")
+	}
+	return b.String()
+}
+
+// renderLlama3 formato do Llama 3.x com cabeçalhos por papel; ferramentas
+// respondem no papel "ipython"
+func renderLlama3(messages []Message, addGenerationPrompt bool) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		role := string(msg.Role)
+		if msg.Role == RoleTool {
+			role = "ipython"
+		}
+		fmt.Fprintf(&b, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>",
+			role, strings.TrimSpace(msg.Content))
+	}
+	if addGenerationPrompt {
+		b.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+	}
+	return b.String()
+}
+
+// renderChatML formato ChatML do Qwen 2.5. Mensagens de ferramenta seguidas
+// são agrupadas num único turno de usuário com <tool_response>.
+func renderChatML(messages []Message, addGenerationPrompt bool) string {
+	var b strings.Builder
+	for i, msg := range messages {
+		if msg.Role != RoleTool {
+			fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", msg.Role, msg.Content)
+			continue
+		}
+
+		if i == 0 || messages[i-1].Role != RoleTool {
+			b.WriteString("<|im_start|>user")
+		}
+		b.WriteString("\n" + toolResponse(msg))
+		if i == len(messages)-1 || messages[i+1].Role != RoleTool {
+			b.WriteString("<|im_end|>\n")
+		}
+	}
+	if addGenerationPrompt {
+		b.WriteString("<|im_start|>assistant\n")
+	}
+	return b.String()
+}
+
+// toolResponse envolve o resultado de uma ferramenta
+func toolResponse(msg Message) string {
+	if msg.Name != "" {
+		return fmt.Sprintf("<tool_response name=%q>\n%s\n</tool_response>", msg.Name, msg.Content)
+	}
+	return fmt.Sprintf("<tool_response>\n%s\n</tool_response>", msg.Content)
+}
@@ -5,48 +5,39 @@ import (
 	"fmt"
 	"strings"
 
-	ort "github.com/yalue/onnxruntime_go"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
 // Model representa o modelo de código Qwen-Coder
 type Model struct {
-	session *ort.DynamicAdvancedSession
-	config  config.ModelConfig
+	llm *llm.Model
 }
 
-// New cria um novo modelo de código
-func New(cfg config.ModelConfig) (*Model, error) {
-	options, err := ort.NewSessionOptions()
-	if err != nil {
-		return nil, err
-	}
+// defaultSystemPrompt system message do modelo; cada tarefa acrescenta a sua
+// instrução
+const defaultSystemPrompt = `Você é um assistente de programação.
+Ajude com qualquer tarefa relacionada a código.`
 
-	// Usa DirectML para NPU AMD
-	err = options.AppendExecutionProviderDirectML(0)
-	if err != nil {
-		fmt.Println("DirectML não disponível para Coder, usando CPU")
+// New cria um novo modelo de código executado pelo backend. A geração é a
+// mesma dos LLMs (tokenizer, template ChatML, KV-cache e sampling); aqui
+// ficam só os prompts de cada tarefa.
+func New(be backend.Backend, cfg config.ModelConfig) (*Model, error) {
+	if cfg.SystemPrompt == "" {
+		cfg.SystemPrompt = defaultSystemPrompt
 	}
-
-	// Carrega modelo Qwen-Coder ONNX
-	session, err := ort.NewDynamicAdvancedSession(
-		cfg.Path,
-		[]string{"input_ids", "attention_mask"},
-		[]string{"logits"},
-		options,
-	)
+	m, err := llm.New(be, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao carregar modelo de código: %w", err)
 	}
-
-	return &Model{
-		session: session,
-		config:  cfg,
-	}, nil
+	return &Model{llm: m}, nil
 }
 
-// Generate gera código ou analisa código existente
-func (m *Model) Generate(ctx context.Context, prompt string) (string, error) {
+// Generate gera código ou analisa código existente. history são os turnos
+// anteriores da conversa, para pedidos como "agora adiciona testes nisso".
+func (m *Model) Generate(ctx context.Context, history []chat.Message, prompt string) (string, error) {
 	// Detecta tipo de tarefa
 	taskType := m.detectTask(prompt)
 
@@ -76,22 +67,12 @@ Analise o código e forneça feedback sobre:
 - Sugestões de melhoria`
 
 	default:
-		systemPrompt = `Você é um assistente de programação.
-Ajude com qualquer tarefa relacionada a código.`
+		systemPrompt = "" // Só a system message do modelo
 	}
 
-	fullPrompt := fmt.Sprintf(`<|system|>
-%s
-<|end|>
-<|user|>
-%s
-<|end|>
-<|assistant|>
-`, systemPrompt, prompt)
-
-	// TODO: Implementar inferência real
-	// Por enquanto, retorna placeholder
-	return m.mockGenerate(fullPrompt), nil
+	// A instrução da tarefa vai no fim da system message e os turnos mais
+	// antigos saem se a conversa não couber na janela
+	return m.llm.GenerateChat(ctx, m.llm.Conversation(history, systemPrompt, prompt), nil)
 }
 
 // detectTask detecta o tipo de tarefa de código
@@ -121,11 +102,6 @@ func (m *Model) detectTask(prompt string) string {
 	return "general"
 }
 
-// mockGenerate simula geração (placeholder)
-func (m *Model) mockGenerate(prompt string) string {
-	return "// Código gerado pelo NPU-IA\n// TODO: Implementar inferência real"
-}
-
 // GenerateFromScreenshot gera/analisa código de uma imagem
 func (m *Model) GenerateFromScreenshot(ctx context.Context, imageData []byte, prompt string) (string, error) {
 	// TODO: Integrar com modelo de visão para OCR/análise de código na tela
@@ -134,8 +110,5 @@ func (m *Model) GenerateFromScreenshot(ctx context.Context, imageData []byte, pr
 
 // Close libera recursos
 func (m *Model) Close() error {
-	if m.session != nil {
-		return m.session.Destroy()
-	}
-	return nil
+	return m.llm.Close()
 }
@@ -0,0 +1,249 @@
+// Package embeddings gera embeddings de frases com um modelo ONNX pequeno
+// (MiniLM, E5) e mantém índices vetoriais locais para busca semântica.
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tokenizer"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Mode papel do texto na busca. Modelos como o E5 foram treinados com
+// prefixos diferentes para consultas e para os documentos indexados.
+type Mode int
+
+const (
+	Query Mode = iota
+	Passage
+)
+
+// Embedder gera um vetor normalizado (norma 1) por texto
+type Embedder interface {
+	Embed(ctx context.Context, texts []string, mode Mode) ([][]float32, error)
+}
+
+// Model modelo de embeddings de frases executado pelo backend
+type Model struct {
+	session   backend.Session
+	backend   backend.Backend
+	tokenizer *tokenizer.Tokenizer
+	config    config.EmbeddingsConfig
+	inputs    []string
+	padID     int64
+	pooled    bool // saída sentence_embedding já vem agregada [batch, dim]
+	mu        sync.Mutex
+}
+
+// New carrega o modelo de embeddings
+func New(be backend.Backend, cfg config.EmbeddingsConfig) (*Model, error) {
+	inputs, outputs, err := be.Inspect(cfg.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inspecionar modelo de embeddings: %w", err)
+	}
+
+	m := &Model{
+		backend: be,
+		config:  cfg,
+	}
+
+	for _, in := range inputs {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			m.inputs = append(m.inputs, in.Name)
+		default:
+			return nil, fmt.Errorf("entrada não suportada no modelo de embeddings: %s", in.Name)
+		}
+	}
+
+	// Prefere a saída já agregada; senão faz mean pooling dos estados ocultos
+	output := ""
+	for _, out := range outputs {
+		if out.Name == "sentence_embedding" {
+			output = out.Name
+			m.pooled = true
+			break
+		}
+		if out.Name == "last_hidden_state" {
+			output = out.Name
+		}
+	}
+	if output == "" {
+		return nil, fmt.Errorf("modelo de embeddings sem saída last_hidden_state ou sentence_embedding")
+	}
+
+	m.session, err = be.Open(cfg.ModelPath, m.inputs, []string{output})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar modelo de embeddings: %w", err)
+	}
+
+	m.tokenizer, err = tokenizer.Load(cfg.TokenizerPath)
+	if err != nil {
+		m.session.Close()
+		return nil, fmt.Errorf("erro ao carregar tokenizer de embeddings: %w", err)
+	}
+
+	for _, pad := range []string{"[PAD]", "<pad>"} {
+		if id, ok := m.tokenizer.TokenToID(pad); ok {
+			m.padID = id
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// Embed gera os embeddings de um lote de textos
+func (m *Model) Embed(ctx context.Context, texts []string, mode Mode) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	prefix := m.config.PassagePrefix
+	if mode == Query {
+		prefix = m.config.QueryPrefix
+	}
+
+	batch := make([][]int64, len(texts))
+	seq := 0
+	for i, text := range texts {
+		batch[i] = m.truncate(m.tokenizer.Encode(prefix+text, true))
+		seq = max(seq, len(batch[i]))
+	}
+
+	// Lote com padding à direita; a attention_mask esconde o preenchimento
+	ids := make([]int64, len(texts)*seq)
+	mask := make([]int64, len(texts)*seq)
+	for i, row := range batch {
+		for j := 0; j < seq; j++ {
+			if j < len(row) {
+				ids[i*seq+j] = row[j]
+				mask[i*seq+j] = 1
+			} else {
+				ids[i*seq+j] = m.padID
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	shape := backend.Shape{int64(len(texts)), int64(seq)}
+	inputs := make([]backend.Tensor, len(m.inputs))
+	defer func() { backend.DestroyAll(inputs) }()
+
+	for i, name := range m.inputs {
+		data := ids
+		switch name {
+		case "attention_mask":
+			data = mask
+		case "token_type_ids":
+			data = make([]int64, len(ids))
+		}
+		t, err := m.backend.NewTensor(backend.Int64, shape, data)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = t
+	}
+
+	outputs, err := m.session.Run(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("erro na inferência de embeddings: %w", err)
+	}
+	defer backend.DestroyAll(outputs)
+
+	data, err := backend.Float32s(outputs[0])
+	if err != nil {
+		return nil, err
+	}
+	outShape := outputs[0].Shape()
+	dim := int(outShape[len(outShape)-1])
+
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		if m.pooled {
+			vectors[i] = append([]float32(nil), data[i*dim:(i+1)*dim]...)
+		} else {
+			vectors[i] = meanPool(data[i*seq*dim:(i+1)*seq*dim], mask[i*seq:(i+1)*seq], dim)
+		}
+		Normalize(vectors[i])
+	}
+
+	return vectors, nil
+}
+
+// Close libera o modelo
+func (m *Model) Close() error {
+	if m.session != nil {
+		return m.session.Close()
+	}
+	return nil
+}
+
+// truncate corta a sequência em MaxLength preservando o token especial final
+// ([SEP] ou </s>)
+func (m *Model) truncate(ids []int64) []int64 {
+	limit := m.config.MaxLength
+	if limit <= 0 || len(ids) <= limit {
+		return ids
+	}
+	last := ids[len(ids)-1]
+	if m.tokenizer.IsSpecial(last) {
+		return append(ids[:limit-1:limit-1], last)
+	}
+	return ids[:limit]
+}
+
+// meanPool média dos estados ocultos [seq, dim] nas posições da máscara
+func meanPool(hidden []float32, mask []int64, dim int) []float32 {
+	out := make([]float32, dim)
+	count := 0
+	for j, on := range mask {
+		if on == 0 {
+			continue
+		}
+		count++
+		for k := 0; k < dim; k++ {
+			out[k] += hidden[j*dim+k]
+		}
+	}
+	if count > 0 {
+		for k := range out {
+			out[k] /= float32(count)
+		}
+	}
+	return out
+}
+
+// Normalize escala o vetor para norma 1, de forma que o produto escalar seja
+// a similaridade de cosseno
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+// Dot produto escalar; com vetores normalizados é a similaridade de cosseno
+func Dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
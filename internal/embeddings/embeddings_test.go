@@ -0,0 +1,181 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Vocabulário do tokenizer WordPiece de teste
+var testVocab = map[string]int64{
+	"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3,
+	"gato": 4, "cachorro": 5, "carro": 6, "query": 7, ":": 8,
+}
+
+const padValue = 1000 // estado oculto do padding; não pode entrar na média
+
+// hiddenStates encoder cujo estado oculto de cada token é o one-hot do seu ID;
+// registra os input_ids recebidos
+func hiddenStates(seen *[][]int64) *fake.Model {
+	dim := int64(len(testVocab))
+	var mu sync.Mutex
+
+	return &fake.Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "attention_mask", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "token_type_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+		},
+		Outputs: []backend.TensorInfo{
+			{Name: "last_hidden_state", Type: backend.Float32, Shape: backend.Shape{-1, -1, dim}},
+		},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			shape := inputs[0].Shape()
+			ids := inputs[0].Data().([]int64)
+			mask := inputs[1].Data().([]int64)
+
+			mu.Lock()
+			*seen = append(*seen, append([]int64(nil), ids...))
+			mu.Unlock()
+
+			hidden := make([]float32, int64(len(ids))*dim)
+			for i, id := range ids {
+				if mask[i] == 0 {
+					hidden[int64(i)*dim] = padValue
+					continue
+				}
+				hidden[int64(i)*dim+id] = 1
+			}
+			t, err := backend.NewHostTensor(backend.Float32, backend.Shape{shape[0], shape[1], dim}, hidden)
+			if err != nil {
+				return nil, err
+			}
+			return []backend.Tensor{t}, nil
+		},
+	}
+}
+
+func TestModelEmbed(t *testing.T) {
+	var seen [][]int64
+	be := fake.New()
+	be.Add("e5.onnx", hiddenStates(&seen))
+
+	m, err := New(be, config.EmbeddingsConfig{
+		ModelPath:     "e5.onnx",
+		TokenizerPath: writeWordPiece(t),
+		MaxLength:     16,
+		QueryPrefix:   "query: ",
+	})
+	if err != nil {
+		t.Fatalf("New() erro: %v", err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+
+	// Lote com padding: "gato" deve sair igual ao embedding calculado sozinho
+	batch, err := m.Embed(ctx, []string{"gato", "cachorro gato carro"}, Passage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alone, err := m.Embed(ctx, []string{"gato"}, Passage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVector(t, batch[0], alone[0])
+
+	// Média de [CLS], gato e [SEP], normalizada
+	third := float32(1 / math.Sqrt(3))
+	want := make([]float32, len(testVocab))
+	want[2], want[4], want[3] = third, third, third
+	assertVector(t, alone[0], want)
+
+	// Consultas recebem o prefixo configurado
+	seen = nil
+	if _, err := m.Embed(ctx, []string{"gato"}, Query); err != nil {
+		t.Fatal(err)
+	}
+	if got := seen[0]; len(got) != 5 || got[1] != 7 || got[2] != 8 {
+		t.Errorf("input_ids da consulta = %v, want [CLS] query : gato [SEP]", got)
+	}
+}
+
+func TestModelTruncate(t *testing.T) {
+	var seen [][]int64
+	be := fake.New()
+	be.Add("e5.onnx", hiddenStates(&seen))
+
+	m, err := New(be, config.EmbeddingsConfig{
+		ModelPath:     "e5.onnx",
+		TokenizerPath: writeWordPiece(t),
+		MaxLength:     3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, err := m.Embed(context.Background(), []string{"gato carro cachorro"}, Passage); err != nil {
+		t.Fatal(err)
+	}
+	if got := seen[0]; len(got) != 3 || got[0] != 2 || got[1] != 4 || got[2] != 3 {
+		t.Errorf("input_ids = %v, want [2 4 3]", got)
+	}
+}
+
+func assertVector(t *testing.T, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-5 {
+			t.Fatalf("vetor = %v, want %v", got, want)
+		}
+	}
+}
+
+// writeWordPiece grava um tokenizer.json WordPiece no formato do BERT
+func writeWordPiece(t *testing.T) string {
+	t.Helper()
+
+	spec := map[string]interface{}{
+		"added_tokens": []map[string]interface{}{
+			{"id": 0, "content": "[PAD]", "special": true},
+			{"id": 1, "content": "[UNK]", "special": true},
+			{"id": 2, "content": "[CLS]", "special": true},
+			{"id": 3, "content": "[SEP]", "special": true},
+		},
+		"normalizer":    map[string]interface{}{"type": "BertNormalizer", "lowercase": true},
+		"pre_tokenizer": map[string]interface{}{"type": "BertPreTokenizer"},
+		"post_processor": map[string]interface{}{
+			"type": "BertProcessing",
+			"sep":  []interface{}{"[SEP]", 3},
+			"cls":  []interface{}{"[CLS]", 2},
+		},
+		"decoder": map[string]interface{}{"type": "WordPiece", "prefix": "##"},
+		"model": map[string]interface{}{
+			"type":      "WordPiece",
+			"unk_token": "[UNK]",
+			"vocab":     testVocab,
+		},
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,338 @@
+package embeddings
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Document texto indexado
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Result documento encontrado e sua similaridade de cosseno com a consulta
+type Result struct {
+	Document
+	Score float32 `json:"score"`
+}
+
+// SearchOptions filtros de uma busca
+type SearchOptions struct {
+	K        int               // Documentos retornados (0 = todos)
+	Where    map[string]string // Só documentos cujos metadados contêm esses pares
+	MinScore float32           // Descarta similaridades menores (0 = nenhuma)
+}
+
+// record linha do arquivo do índice: upsert (com vetor) ou delete
+type record struct {
+	Op     string    `json:"op"`
+	Doc    *Document `json:"doc,omitempty"`
+	ID     string    `json:"id,omitempty"`
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+type entry struct {
+	doc    Document
+	vector []float32
+}
+
+// Index índice vetorial em memória com busca exata por cosseno, persistido
+// como um log JSON Lines: cada upsert/delete acrescenta uma linha e o
+// arquivo é reescrito (compactado) quando o lixo supera as entradas vivas.
+type Index struct {
+	path    string
+	file    *os.File
+	entries map[string]*entry
+	dim     int
+	garbage int // linhas do log que não correspondem a entradas vivas
+	mu      sync.RWMutex
+}
+
+// OpenIndex abre (ou cria) o índice em path
+func OpenIndex(path string) (*Index, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	ix := &Index{
+		path:    path,
+		entries: make(map[string]*entry),
+	}
+	corrupted, err := ix.load()
+	if err != nil {
+		return nil, err
+	}
+	if corrupted {
+		// Reescreve para que a próxima linha não se junte à linha quebrada
+		if err := ix.compact(); err != nil {
+			return nil, err
+		}
+		return ix, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	ix.file = file
+
+	return ix, nil
+}
+
+// Upsert insere ou substitui um documento. O vetor é normalizado.
+func (ix *Index) Upsert(doc Document, vector []float32) error {
+	if doc.ID == "" {
+		return fmt.Errorf("documento sem ID")
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.dim != 0 && len(vector) != ix.dim {
+		return fmt.Errorf("vetor com dimensão %d, índice usa %d", len(vector), ix.dim)
+	}
+
+	v := append([]float32(nil), vector...)
+	Normalize(v)
+
+	if err := ix.append(record{Op: "upsert", Doc: &doc, Vector: v}); err != nil {
+		return err
+	}
+	if _, ok := ix.entries[doc.ID]; ok {
+		ix.garbage++
+	}
+	ix.entries[doc.ID] = &entry{doc: doc, vector: v}
+	ix.dim = len(v)
+
+	return ix.maybeCompact()
+}
+
+// Delete remove documentos; IDs inexistentes são ignorados
+func (ix *Index) Delete(ids ...string) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := ix.entries[id]; !ok {
+			continue
+		}
+		if err := ix.append(record{Op: "delete", ID: id}); err != nil {
+			return err
+		}
+		delete(ix.entries, id)
+		ix.garbage += 2 // o upsert anterior e a própria linha de delete
+	}
+
+	return ix.maybeCompact()
+}
+
+// Get retorna um documento pelo ID
+func (ix *Index) Get(id string) (Document, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	e, ok := ix.entries[id]
+	if !ok {
+		return Document{}, false
+	}
+	return e.doc, true
+}
+
+// Search retorna os documentos mais similares ao vetor, do mais ao menos
+// similar, filtrados por opts
+func (ix *Index) Search(vector []float32, opts SearchOptions) ([]Result, error) {
+	q := append([]float32(nil), vector...)
+	Normalize(q)
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if len(ix.entries) == 0 {
+		return nil, nil
+	}
+	if len(q) != ix.dim {
+		return nil, fmt.Errorf("consulta com dimensão %d, índice usa %d", len(q), ix.dim)
+	}
+
+	results := make([]Result, 0, len(ix.entries))
+	for _, e := range ix.entries {
+		if !matches(e.doc.Metadata, opts.Where) {
+			continue
+		}
+		score := Dot(q, e.vector)
+		if opts.MinScore != 0 && score < opts.MinScore {
+			continue
+		}
+		results = append(results, Result{Document: e.doc, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if opts.K > 0 && len(results) > opts.K {
+		results = results[:opts.K]
+	}
+	return results, nil
+}
+
+// matches indica se metadata contém todos os pares de where
+func matches(metadata, where map[string]string) bool {
+	for k, v := range where {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Len quantidade de documentos
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.entries)
+}
+
+// Compact reescreve o arquivo só com as entradas vivas
+func (ix *Index) Compact() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.compact()
+}
+
+// Close fecha o arquivo do índice
+func (ix *Index) Close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.file == nil {
+		return nil
+	}
+	err := ix.file.Close()
+	ix.file = nil
+	return err
+}
+
+// append grava uma linha no log
+func (ix *Index) append(rec record) error {
+	if ix.file == nil {
+		return fmt.Errorf("índice fechado: %s", ix.path)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = ix.file.Write(append(data, '\n'))
+	return err
+}
+
+// maybeCompact compacta quando o log tem mais lixo que entradas vivas
+func (ix *Index) maybeCompact() error {
+	if ix.garbage < 64 || ix.garbage < len(ix.entries) {
+		return nil
+	}
+	return ix.compact()
+}
+
+// compact grava as entradas vivas num arquivo temporário e o troca pelo log
+func (ix *Index) compact() error {
+	tmp := ix.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(ix.entries))
+	for id := range ix.entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		e := ix.entries[id]
+		if err := enc.Encode(record{Op: "upsert", Doc: &e.doc, Vector: e.vector}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if ix.file != nil {
+		ix.file.Close()
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return err
+	}
+	ix.file, err = os.OpenFile(ix.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	ix.garbage = 0
+	return nil
+}
+
+// load reproduz o log do disco; corrupted indica linhas ilegíveis
+func (ix *Index) load() (corrupted bool, err error) {
+	file, err := os.Open(ix.path)
+	if os.IsNotExist(err) {
+		return false, nil // Índice novo
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Linha truncada por uma gravação interrompida
+			corrupted = true
+			continue
+		}
+
+		switch rec.Op {
+		case "upsert":
+			if rec.Doc == nil {
+				return false, fmt.Errorf("%s:%d: upsert sem documento", ix.path, line)
+			}
+			if _, ok := ix.entries[rec.Doc.ID]; ok {
+				ix.garbage++
+			}
+			ix.entries[rec.Doc.ID] = &entry{doc: *rec.Doc, vector: rec.Vector}
+			ix.dim = len(rec.Vector)
+		case "delete":
+			if _, ok := ix.entries[rec.ID]; ok {
+				delete(ix.entries, rec.ID)
+				ix.garbage++
+			}
+			ix.garbage++
+		default:
+			return false, fmt.Errorf("%s:%d: operação desconhecida %q", ix.path, line, rec.Op)
+		}
+	}
+
+	return corrupted, scanner.Err()
+}
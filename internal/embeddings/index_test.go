@@ -0,0 +1,186 @@
+package embeddings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexSearch(t *testing.T) {
+	ix, err := OpenIndex(filepath.Join(t.TempDir(), "idx.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+
+	docs := map[string][]float32{
+		"norte": {0, 1, 0},
+		"leste": {1, 0, 0},
+		"ne":    {1, 1, 0},
+		"cima":  {0, 0, 5},
+	}
+	for id, v := range docs {
+		doc := Document{ID: id, Text: id}
+		if v[2] == 0 && v[0] != 0 {
+			doc.Metadata = map[string]string{"plano": "xy"}
+		}
+		if err := ix.Upsert(doc, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   []float32
+		opts    SearchOptions
+		want    []string
+		wantErr bool
+	}{
+		{"mais próximo primeiro", []float32{0.1, 1, 0}, SearchOptions{K: 2}, []string{"norte", "ne"}, false},
+		{"escala não importa", []float32{30, 0, 0}, SearchOptions{K: 1}, []string{"leste"}, false},
+		{"k zero retorna todos", []float32{0, 0, 1}, SearchOptions{}, []string{"cima", "leste", "ne", "norte"}, false},
+		{"filtro por metadados", []float32{0, 1, 0}, SearchOptions{Where: map[string]string{"plano": "xy"}}, []string{"ne", "leste"}, false},
+		{"similaridade mínima", []float32{0, 1, 0}, SearchOptions{MinScore: 0.5}, []string{"norte", "ne"}, false},
+		{"dimensão diferente", []float32{1, 0}, SearchOptions{K: 3}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ix.Search(tt.query, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Search() erro = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search() = %v, want %v", ids(got), tt.want)
+			}
+			for i := range tt.want {
+				if got[i].ID != tt.want[i] {
+					t.Errorf("Search() = %v, want %v", ids(got), tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if err := ix.Upsert(Document{ID: "x"}, []float32{1, 2}); err == nil {
+		t.Error("Upsert com dimensão diferente deveria falhar")
+	}
+}
+
+func TestIndexPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx.jsonl")
+
+	ix, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Upsert(Document{ID: "a", Text: "primeiro", Metadata: map[string]string{"k": "v"}}, []float32{1, 0})
+	ix.Upsert(Document{ID: "b", Text: "segundo"}, []float32{0, 1})
+	ix.Upsert(Document{ID: "a", Text: "primeiro editado"}, []float32{1, 1})
+	ix.Delete("b", "inexistente")
+	ix.Close()
+
+	ix, err = OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+
+	if ix.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", ix.Len())
+	}
+	doc, ok := ix.Get("a")
+	if !ok || doc.Text != "primeiro editado" || len(doc.Metadata) != 0 {
+		t.Errorf("Get(a) = %+v, %v", doc, ok)
+	}
+	if _, ok := ix.Get("b"); ok {
+		t.Error("b deveria ter sido removido")
+	}
+	if got, _ := ix.Search([]float32{1, 1}, SearchOptions{K: 1}); len(got) != 1 || got[0].Score < 0.999 {
+		t.Errorf("Search() após reabrir = %+v", got)
+	}
+}
+
+func TestIndexCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx.jsonl")
+
+	ix, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if err := ix.Upsert(Document{ID: "mesmo"}, []float32{float32(i), 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ix.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := countLines(data); lines >= 200 {
+		t.Errorf("log com %d linhas, esperava compactação", lines)
+	}
+
+	ix, err = OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	if ix.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", ix.Len())
+	}
+}
+
+func TestIndexTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx.jsonl")
+
+	ix, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Upsert(Document{ID: "a"}, []float32{1, 0})
+	ix.Close()
+
+	// Simula uma gravação interrompida no meio da linha
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"op":"upsert","doc":{"id":"b"`)
+	f.Close()
+
+	ix, err = OpenIndex(path)
+	if err != nil {
+		t.Fatalf("OpenIndex() com linha truncada: %v", err)
+	}
+	if err := ix.Upsert(Document{ID: "c"}, []float32{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	ix.Close()
+
+	ix, err = OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	if _, ok := ix.Get("c"); !ok || ix.Len() != 2 {
+		t.Errorf("Len() = %d, want a e c", ix.Len())
+	}
+}
+
+func ids(results []Result) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
+
+func countLines(data []byte) int {
+	n := 0
+	for _, b := range data {
+		if b == '\n' {
+			n++
+		}
+	}
+	return n
+}
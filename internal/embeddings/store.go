@@ -0,0 +1,168 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// Retriever API de busca semântica compartilhada pelos módulos (memória,
+// notas, e-mails, livros). Cada módulo usa a sua própria coleção.
+type Retriever interface {
+	// Upsert indexa documentos; textos inalterados não são recalculados
+	Upsert(ctx context.Context, docs ...Document) error
+	// Delete remove documentos pelo ID
+	Delete(ids ...string) error
+	// Search retorna os k documentos mais similares à consulta
+	Search(ctx context.Context, query string, k int) ([]Result, error)
+	// SearchWhere como Search, só entre documentos cujos metadados contêm where
+	SearchWhere(ctx context.Context, query string, k int, where map[string]string) ([]Result, error)
+	// SearchWith busca com todos os filtros (quantidade, metadados e
+	// similaridade mínima)
+	SearchWith(ctx context.Context, query string, opts SearchOptions) ([]Result, error)
+}
+
+var collectionName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Store conjunto de coleções de um mesmo modelo de embeddings, cada uma
+// persistida em dir/<nome>.jsonl
+type Store struct {
+	dir         string
+	embedder    Embedder
+	collections map[string]*Collection
+	mu          sync.Mutex
+}
+
+// NewStore cria o store em dir
+func NewStore(dir string, embedder Embedder) *Store {
+	return &Store{
+		dir:         dir,
+		embedder:    embedder,
+		collections: make(map[string]*Collection),
+	}
+}
+
+// Collection abre (ou cria) a coleção name
+func (s *Store) Collection(name string) (*Collection, error) {
+	if !collectionName.MatchString(name) {
+		return nil, fmt.Errorf("nome de coleção inválido: %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.collections[name]; ok {
+		return c, nil
+	}
+
+	index, err := OpenIndex(filepath.Join(s.dir, name+".jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir coleção %s: %w", name, err)
+	}
+
+	c := &Collection{index: index, embedder: s.embedder}
+	s.collections[name] = c
+	return c, nil
+}
+
+// Close fecha todas as coleções
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for name, c := range s.collections {
+		if err := c.index.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.collections, name)
+	}
+	return firstErr
+}
+
+// Collection índice de um módulo com o embedder do store
+type Collection struct {
+	index    *Index
+	embedder Embedder
+}
+
+// embedBatchSize textos por chamada ao modelo de embeddings
+const embedBatchSize = 32
+
+// Upsert gera os embeddings dos documentos novos ou alterados e os grava
+func (c *Collection) Upsert(ctx context.Context, docs ...Document) error {
+	pending := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		if old, ok := c.index.Get(doc.ID); ok && old.Text == doc.Text && sameMetadata(old.Metadata, doc.Metadata) {
+			continue
+		}
+		pending = append(pending, doc)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Em lotes: um livro inteiro numa chamada estouraria a memória do
+	// runtime, e o que já foi indexado fica gravado se um lote falhar
+	for start := 0; start < len(pending); start += embedBatchSize {
+		batch := pending[start:min(start+embedBatchSize, len(pending))]
+
+		texts := make([]string, len(batch))
+		for i, doc := range batch {
+			texts[i] = doc.Text
+		}
+
+		vectors, err := c.embedder.Embed(ctx, texts, Passage)
+		if err != nil {
+			return err
+		}
+
+		for i, doc := range batch {
+			if err := c.index.Upsert(doc, vectors[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delete remove documentos
+func (c *Collection) Delete(ids ...string) error {
+	return c.index.Delete(ids...)
+}
+
+// Search busca os k documentos mais próximos da consulta
+func (c *Collection) Search(ctx context.Context, query string, k int) ([]Result, error) {
+	return c.SearchWith(ctx, query, SearchOptions{K: k})
+}
+
+// SearchWhere busca os k documentos mais próximos entre os que têm os
+// metadados em where
+func (c *Collection) SearchWhere(ctx context.Context, query string, k int, where map[string]string) ([]Result, error) {
+	return c.SearchWith(ctx, query, SearchOptions{K: k, Where: where})
+}
+
+// SearchWith busca os documentos mais próximos da consulta com os filtros
+// de opts
+func (c *Collection) SearchWith(ctx context.Context, query string, opts SearchOptions) ([]Result, error) {
+	if c.index.Len() == 0 {
+		return nil, nil
+	}
+
+	vectors, err := c.embedder.Embed(ctx, []string{query}, Query)
+	if err != nil {
+		return nil, err
+	}
+	return c.index.Search(vectors[0], opts)
+}
+
+// Len quantidade de documentos indexados
+func (c *Collection) Len() int {
+	return c.index.Len()
+}
+
+func sameMetadata(a, b map[string]string) bool {
+	return len(a) == len(b) && matches(a, b)
+}
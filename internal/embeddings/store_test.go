@@ -0,0 +1,162 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder bag-of-words sobre um vocabulário fixo
+type wordEmbedder struct {
+	vocab []string
+	calls int
+	texts int
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, texts []string, mode Mode) ([][]float32, error) {
+	e.calls++
+	e.texts += len(texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(e.vocab))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for j, w := range e.vocab {
+				if w == word {
+					v[j]++
+				}
+			}
+		}
+		Normalize(v)
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestCollection(t *testing.T) {
+	ctx := context.Background()
+	embedder := &wordEmbedder{vocab: []string{"café", "chá", "leite", "pão"}}
+	dir := t.TempDir()
+
+	store := NewStore(dir, embedder)
+	c, err := store.Collection("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := []Document{
+		{ID: "1", Text: "gosto de café com leite"},
+		{ID: "2", Text: "chá de manhã"},
+		{ID: "3", Text: "pão com manteiga"},
+	}
+	if err := c.Upsert(ctx, docs...); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Search(ctx, "café", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "1" {
+		t.Errorf("Search(café) = %v", ids(results))
+	}
+
+	// Reindexar o mesmo texto não recalcula embeddings
+	embedded := embedder.texts
+	if err := c.Upsert(ctx, docs[0], Document{ID: "2", Text: "chá com leite"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := embedder.texts - embedded; got != 1 {
+		t.Errorf("Upsert recalculou %d textos, want 1", got)
+	}
+
+	if err := c.Delete("3"); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Reabre do disco
+	store = NewStore(dir, embedder)
+	defer store.Close()
+	c, err = store.Collection("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	results, _ = c.Search(ctx, "chá", 1)
+	if len(results) != 1 || results[0].Text != "chá com leite" {
+		t.Errorf("Search(chá) após reabrir = %+v", results)
+	}
+
+	if _, err := store.Collection("../fora"); err == nil {
+		t.Error("Collection com nome inválido deveria falhar")
+	}
+}
+
+func TestCollectionEmpty(t *testing.T) {
+	embedder := &wordEmbedder{vocab: []string{"a"}}
+	c, err := NewStore(t.TempDir(), embedder).Collection("vazia")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Search(context.Background(), "a", 5)
+	if err != nil || len(results) != 0 {
+		t.Errorf("Search() = %v, %v", results, err)
+	}
+	if embedder.calls != 0 {
+		t.Error("coleção vazia não deveria gerar embedding da consulta")
+	}
+}
+
+func TestCollectionUpsertBatches(t *testing.T) {
+	embedder := &wordEmbedder{vocab: []string{"café", "chá"}}
+	c, err := NewStore(t.TempDir(), embedder).Collection("livro")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make([]Document, 2*embedBatchSize+1)
+	for i := range docs {
+		docs[i] = Document{ID: fmt.Sprint(i), Text: fmt.Sprintf("café %d", i)}
+	}
+	if err := c.Upsert(context.Background(), docs...); err != nil {
+		t.Fatal(err)
+	}
+	if embedder.calls != 3 || embedder.texts != len(docs) {
+		t.Errorf("%d chamadas com %d textos, want 3 com %d", embedder.calls, embedder.texts, len(docs))
+	}
+	if c.Len() != len(docs) {
+		t.Errorf("Len() = %d, want %d", c.Len(), len(docs))
+	}
+}
+
+func TestCollectionSearchWith(t *testing.T) {
+	ctx := context.Background()
+	embedder := &wordEmbedder{vocab: []string{"café", "chá", "leite"}}
+	c, err := NewStore(t.TempDir(), embedder).Collection("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Upsert(ctx,
+		Document{ID: "1", Text: "café"},
+		Document{ID: "2", Text: "café com leite"},
+		Document{ID: "3", Text: "chá"},
+	)
+
+	// Sem mínimo, até o que não tem relação volta
+	results, err := c.Search(ctx, "café", 0)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Search() = %v, %v", ids(results), err)
+	}
+
+	results, err = c.SearchWith(ctx, "café", SearchOptions{MinScore: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(results); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("SearchWith(MinScore 0.5) = %v, want [1 2]", got)
+	}
+}
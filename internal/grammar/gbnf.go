@@ -0,0 +1,339 @@
+package grammar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse compila uma gramática GBNF. A regra inicial é "root".
+//
+//	root   ::= "{" ws pair ("," ws pair)* "}"
+//	pair   ::= string ":" ws [0-9]+
+//	ws     ::= [ \t\n]*
+//
+// Suporta literais, classes [a-z] e [^...], ".", grupos, |, *, + e ?, e
+// comentários com #. Quebras de linha só continuam uma regra dentro de
+// parênteses.
+func Parse(src string) (*Grammar, error) {
+	p := &parser{
+		src:   src,
+		index: make(map[string]int),
+		g:     &Grammar{root: -1},
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.g, nil
+}
+
+// Choice compila uma gramática que aceita exatamente uma das opções
+func Choice(options ...string) (*Grammar, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("nenhuma opção")
+	}
+	quoted := make([]string, len(options))
+	for i, opt := range options {
+		if opt == "" {
+			return nil, fmt.Errorf("opção vazia")
+		}
+		quoted[i] = quote(opt)
+	}
+	return Parse("root ::= " + strings.Join(quoted, " | "))
+}
+
+type parser struct {
+	src     string
+	pos     int
+	g       *Grammar
+	index   map[string]int
+	defined []bool
+}
+
+// ruleID retorna o índice da regra, criando uma entrada se necessário
+func (p *parser) ruleID(name string) int {
+	if id, ok := p.index[name]; ok {
+		return id
+	}
+	id := len(p.g.rules)
+	p.index[name] = id
+	p.g.rules = append(p.g.rules, nil)
+	p.g.names = append(p.g.names, name)
+	p.defined = append(p.defined, false)
+	return id
+}
+
+// newRule cria uma regra auxiliar (grupos e repetições)
+func (p *parser) newRule(base string) int {
+	for i := len(p.g.rules); ; i++ {
+		name := fmt.Sprintf("%s-%d", base, i)
+		if _, ok := p.index[name]; !ok {
+			id := p.ruleID(name)
+			p.defined[id] = true
+			return id
+		}
+	}
+}
+
+func (p *parser) parse() error {
+	p.skipSpace(true)
+	for p.pos < len(p.src) {
+		name := p.name()
+		if name == "" {
+			return p.errorf("nome de regra esperado")
+		}
+		p.skipSpace(false)
+		if !strings.HasPrefix(p.src[p.pos:], "::=") {
+			return p.errorf("'::=' esperado após %s", name)
+		}
+		p.pos += 3
+		p.skipSpace(true)
+
+		id := p.ruleID(name)
+		if p.defined[id] {
+			return p.errorf("regra %s definida duas vezes", name)
+		}
+		p.defined[id] = true
+
+		alts, err := p.alternatives(name, false)
+		if err != nil {
+			return err
+		}
+		p.g.rules[id] = alts
+
+		if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+			return p.errorf("fim de regra esperado")
+		}
+		p.skipSpace(true)
+	}
+
+	for id, ok := range p.defined {
+		if !ok {
+			return fmt.Errorf("regra não definida: %s", p.g.names[id])
+		}
+	}
+	root, ok := p.index["root"]
+	if !ok {
+		return fmt.Errorf("gramática sem regra root")
+	}
+	p.g.root = root
+	return nil
+}
+
+// alternatives lê "seq | seq | ..."
+func (p *parser) alternatives(name string, nested bool) ([]alternative, error) {
+	var alts []alternative
+	for {
+		seq, err := p.sequence(name, nested)
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, seq)
+		if p.pos >= len(p.src) || p.src[p.pos] != '|' {
+			return alts, nil
+		}
+		p.pos++
+		p.skipSpace(true)
+	}
+}
+
+// sequence lê uma sequência de elementos até |, ) ou fim de linha
+func (p *parser) sequence(name string, nested bool) (alternative, error) {
+	var seq alternative
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '|' || c == ')' || c == '\n' || c == '\r' {
+			break
+		}
+
+		start := len(seq)
+		switch {
+		case c == '"':
+			p.pos++
+			for {
+				if p.pos >= len(p.src) {
+					return nil, p.errorf("literal não terminado")
+				}
+				if p.src[p.pos] == '"' {
+					p.pos++
+					break
+				}
+				r, err := p.char()
+				if err != nil {
+					return nil, err
+				}
+				seq = append(seq, symbol{rule: -1, ranges: []runeRange{{r, r}}})
+			}
+
+		case c == '[':
+			sym, err := p.class()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, sym)
+
+		case c == '.':
+			p.pos++
+			seq = append(seq, symbol{rule: -1, negate: true})
+
+		case c == '(':
+			p.pos++
+			p.skipSpace(true)
+			alts, err := p.alternatives(name, true)
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.src) || p.src[p.pos] != ')' {
+				return nil, p.errorf("')' esperado")
+			}
+			p.pos++
+			id := p.newRule(name)
+			p.g.rules[id] = alts
+			seq = append(seq, symbol{rule: id})
+
+		case isNameChar(c):
+			seq = append(seq, symbol{rule: p.ruleID(p.name())})
+
+		default:
+			return nil, p.errorf("caractere inesperado %q", c)
+		}
+
+		p.skipSpace(nested)
+
+		// Operadores de repetição valem para o último elemento
+		if p.pos < len(p.src) && strings.IndexByte("*+?", p.src[p.pos]) >= 0 {
+			if start == len(seq) {
+				return nil, p.errorf("operador sem elemento")
+			}
+			// Num literal de vários caracteres vale só para o último (como no llama.cpp)
+			elem := alternative{seq[len(seq)-1]}
+			seq = seq[:len(seq)-1]
+			seq = append(seq, p.repeat(name, elem, p.src[p.pos]))
+			p.pos++
+			p.skipSpace(nested)
+		}
+	}
+	return seq, nil
+}
+
+// repeat cria a regra auxiliar de x*, x+ ou x?
+func (p *parser) repeat(name string, elem alternative, op byte) symbol {
+	id := p.newRule(name)
+	switch op {
+	case '*':
+		// r ::= x r |
+		p.g.rules[id] = []alternative{append(append(alternative(nil), elem...), symbol{rule: id}), nil}
+	case '+':
+		// r ::= x r | x
+		p.g.rules[id] = []alternative{append(append(alternative(nil), elem...), symbol{rule: id}), elem}
+	case '?':
+		// r ::= x |
+		p.g.rules[id] = []alternative{elem, nil}
+	}
+	return symbol{rule: id}
+}
+
+// class lê [abc], [a-z] ou [^...]
+func (p *parser) class() (symbol, error) {
+	p.pos++ // [
+	sym := symbol{rule: -1}
+	if p.pos < len(p.src) && p.src[p.pos] == '^' {
+		sym.negate = true
+		p.pos++
+	}
+	for {
+		if p.pos >= len(p.src) {
+			return sym, p.errorf("classe de caracteres não terminada")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return sym, nil
+		}
+		lo, err := p.char()
+		if err != nil {
+			return sym, err
+		}
+		hi := lo
+		if p.pos+1 < len(p.src) && p.src[p.pos] == '-' && p.src[p.pos+1] != ']' {
+			p.pos++
+			if hi, err = p.char(); err != nil {
+				return sym, err
+			}
+		}
+		sym.ranges = append(sym.ranges, runeRange{lo, hi})
+	}
+}
+
+// char lê um caractere, tratando escapes
+func (p *parser) char() (rune, error) {
+	if p.src[p.pos] != '\\' {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		p.pos += size
+		return r, nil
+	}
+
+	p.pos++
+	if p.pos >= len(p.src) {
+		return 0, p.errorf("escape incompleto")
+	}
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'x', 'u', 'U':
+		size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		if p.pos+size > len(p.src) {
+			return 0, p.errorf("escape \\%c incompleto", c)
+		}
+		v, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil {
+			return 0, p.errorf("escape \\%c inválido", c)
+		}
+		p.pos += size
+		return rune(v), nil
+	case '\\', '"', '[', ']', '-', '^':
+		return rune(c), nil
+	}
+	return 0, p.errorf("escape desconhecido \\%c", c)
+}
+
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// skipSpace pula espaços e comentários; newlines só se multiline
+func (p *parser) skipSpace(multiline bool) {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case (c == '\n' || c == '\r') && multiline:
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("gbnf linha %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,263 @@
+// Package grammar implementa gramáticas no estilo GBNF (llama.cpp), a
+// conversão de JSON schema para GBNF e um matcher incremental usado para
+// restringir a decodificação dos LLMs.
+package grammar
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// maxStackDepth limita a expansão de regras (protege contra recursão à
+// esquerda ou repetições de regras vazias)
+const maxStackDepth = 256
+
+// runeRange intervalo fechado de caracteres
+type runeRange struct {
+	lo, hi rune
+}
+
+// symbol elemento de uma alternativa: referência a regra ou classe de caracteres
+type symbol struct {
+	rule   int // >= 0: referência a regra; -1: classe de caracteres
+	ranges []runeRange
+	negate bool
+}
+
+func (s symbol) matches(r rune) bool {
+	for _, rr := range s.ranges {
+		if r >= rr.lo && r <= rr.hi {
+			return !s.negate
+		}
+	}
+	return s.negate
+}
+
+// matchesRange indica se a classe aceita algum caractere entre lo e hi
+func (s symbol) matchesRange(lo, hi rune) bool {
+	for _, rr := range s.ranges {
+		if rr.lo <= hi && rr.hi >= lo {
+			if !s.negate {
+				return true
+			}
+			if rr.lo <= lo && rr.hi >= hi {
+				return false // a negação exclui o intervalo inteiro
+			}
+		}
+	}
+	return s.negate
+}
+
+// alternative sequência de símbolos
+type alternative []symbol
+
+// Grammar gramática compilada
+type Grammar struct {
+	rules [][]alternative
+	names []string
+	root  int
+}
+
+// pos posição dentro de uma alternativa: próximo símbolo a casar
+type pos struct {
+	rule, alt, idx int
+}
+
+// State estado imutável do reconhecimento: o conjunto de pilhas possíveis
+type State struct {
+	g      *Grammar
+	stacks [][]pos
+}
+
+// Start retorna o estado inicial (nada consumido)
+func (g *Grammar) Start() *State {
+	var stacks [][]pos
+	for a := range g.rules[g.root] {
+		g.expand([]pos{{rule: g.root, alt: a}}, &stacks)
+	}
+	return &State{g: g, stacks: dedupe(stacks)}
+}
+
+// expand avança a pilha até o topo ser uma classe de caracteres (ou a pilha
+// esvaziar, o que indica uma entrada completa)
+func (g *Grammar) expand(stack []pos, out *[][]pos) {
+	if len(stack) == 0 {
+		*out = append(*out, nil)
+		return
+	}
+	if len(stack) > maxStackDepth {
+		return
+	}
+
+	top := stack[len(stack)-1]
+	alt := g.rules[top.rule][top.alt]
+	if top.idx == len(alt) {
+		// Fim da alternativa: continua no chamador
+		g.expand(stack[:len(stack)-1], out)
+		return
+	}
+
+	sym := alt[top.idx]
+	if sym.rule < 0 {
+		*out = append(*out, stack)
+		return
+	}
+
+	// Referência: o chamador já aponta para o símbolo seguinte
+	parent := make([]pos, len(stack), len(stack)+1)
+	copy(parent, stack)
+	parent[len(parent)-1].idx++
+	for a := range g.rules[sym.rule] {
+		next := make([]pos, len(parent), len(parent)+1)
+		copy(next, parent)
+		g.expand(append(next, pos{rule: sym.rule, alt: a}), out)
+	}
+}
+
+// Accept consome um caractere; ok é false se a gramática não permite
+func (s *State) Accept(r rune) (*State, bool) {
+	var stacks [][]pos
+	for _, stack := range s.stacks {
+		if len(stack) == 0 {
+			continue
+		}
+		top := stack[len(stack)-1]
+		if !s.g.rules[top.rule][top.alt][top.idx].matches(r) {
+			continue
+		}
+		next := make([]pos, len(stack))
+		copy(next, stack)
+		next[len(next)-1].idx++
+		s.g.expand(next, &stacks)
+	}
+	if len(stacks) == 0 {
+		return s, false
+	}
+	return &State{g: s.g, stacks: dedupe(stacks)}, true
+}
+
+// AcceptString consome uma sequência de caracteres
+func (s *State) AcceptString(text string) (*State, bool) {
+	state := s
+	for _, r := range text {
+		var ok bool
+		if state, ok = state.Accept(r); !ok {
+			return s, false
+		}
+	}
+	return state, true
+}
+
+// Complete indica se o texto consumido já é uma sentença válida
+func (s *State) Complete() bool {
+	for _, stack := range s.stacks {
+		if len(stack) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Done indica que nenhum caractere a mais é aceito
+func (s *State) Done() bool {
+	for _, stack := range s.stacks {
+		if len(stack) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsRange indica se algum próximo caractere entre lo e hi é aceito
+func (s *State) acceptsRange(lo, hi rune) bool {
+	for _, stack := range s.stacks {
+		if len(stack) == 0 {
+			continue
+		}
+		top := stack[len(stack)-1]
+		if s.g.rules[top.rule][top.alt][top.idx].matchesRange(lo, hi) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe remove pilhas repetidas (gramáticas ambíguas as multiplicam)
+func dedupe(stacks [][]pos) [][]pos {
+	if len(stacks) < 2 {
+		return stacks
+	}
+	seen := make(map[string]bool, len(stacks))
+	out := stacks[:0]
+	buf := make([]byte, 0, 64)
+	for _, stack := range stacks {
+		buf = buf[:0]
+		for _, p := range stack {
+			buf = binary.AppendUvarint(buf, uint64(p.rule))
+			buf = binary.AppendUvarint(buf, uint64(p.alt))
+			buf = binary.AppendUvarint(buf, uint64(p.idx))
+		}
+		key := string(buf)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, stack)
+	}
+	return out
+}
+
+// String retorna a gramática no formato GBNF
+func (g *Grammar) String() string {
+	var out []byte
+	for i, alts := range g.rules {
+		out = append(out, g.names[i]...)
+		out = append(out, " ::="...)
+		for a, alt := range alts {
+			if a > 0 {
+				out = append(out, " |"...)
+			}
+			for _, sym := range alt {
+				out = append(out, ' ')
+				if sym.rule >= 0 {
+					out = append(out, g.names[sym.rule]...)
+					continue
+				}
+				out = append(out, formatClass(sym)...)
+			}
+		}
+		out = append(out, '\n')
+	}
+	return string(out)
+}
+
+func formatClass(sym symbol) string {
+	s := "["
+	if sym.negate {
+		s += "^"
+	}
+	for _, rr := range sym.ranges {
+		s += escapeClassRune(rr.lo)
+		if rr.hi != rr.lo {
+			s += "-" + escapeClassRune(rr.hi)
+		}
+	}
+	return s + "]"
+}
+
+func escapeClassRune(r rune) string {
+	switch r {
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\\', ']', '[', '^', '-':
+		return `\` + string(r)
+	}
+	if r < 0x20 {
+		return fmt.Sprintf(`\x%02X`, r)
+	}
+	return string(r)
+}
@@ -0,0 +1,110 @@
+package grammar
+
+import "unicode/utf8"
+
+// Matcher consome bytes (tokens podem terminar no meio de um caractere UTF-8)
+// e acompanha o estado da gramática. É imutável: cada Accept retorna um novo.
+type Matcher struct {
+	state   *State
+	partial []byte // início de um caractere UTF-8 ainda incompleto
+}
+
+// NewMatcher cria o matcher no estado inicial da gramática
+func (g *Grammar) NewMatcher() *Matcher {
+	return &Matcher{state: g.Start()}
+}
+
+// AcceptByte consome um byte
+func (m *Matcher) AcceptByte(b byte) (*Matcher, bool) {
+	if len(m.partial) == 0 && b < utf8.RuneSelf {
+		state, ok := m.state.Accept(rune(b))
+		if !ok {
+			return m, false
+		}
+		return &Matcher{state: state}, true
+	}
+
+	partial := make([]byte, len(m.partial)+1)
+	copy(partial, m.partial)
+	partial[len(m.partial)] = b
+
+	if !utf8.FullRune(partial) {
+		// Prefixo de um caractere multibyte que a gramática aceite
+		lo, hi, ok := prefixRange(partial)
+		if !ok || !m.state.acceptsRange(lo, hi) {
+			return m, false
+		}
+		return &Matcher{state: m.state, partial: partial}, true
+	}
+
+	r, size := utf8.DecodeRune(partial)
+	if r == utf8.RuneError || size != len(partial) {
+		return m, false
+	}
+	state, ok := m.state.Accept(r)
+	if !ok {
+		return m, false
+	}
+	return &Matcher{state: state}, true
+}
+
+// AcceptBytes consome uma sequência de bytes
+func (m *Matcher) AcceptBytes(data []byte) (*Matcher, bool) {
+	cur := m
+	for _, b := range data {
+		var ok bool
+		if cur, ok = cur.AcceptByte(b); !ok {
+			return m, false
+		}
+	}
+	return cur, true
+}
+
+// Complete indica se o texto consumido é uma sentença completa
+func (m *Matcher) Complete() bool {
+	return len(m.partial) == 0 && m.state.Complete()
+}
+
+// Done indica que a gramática não aceita mais nada
+func (m *Matcher) Done() bool {
+	return len(m.partial) == 0 && m.state.Done()
+}
+
+// prefixRange intervalo dos caracteres UTF-8 que começam com os bytes p;
+// ok é false se p não pode iniciar um caractere
+func prefixRange(p []byte) (lo, hi rune, ok bool) {
+	lead := p[0]
+	var size int
+	var min rune
+	switch {
+	case lead&0xE0 == 0xC0:
+		size, min, lo = 2, 0x80, rune(lead&0x1F)
+	case lead&0xF0 == 0xE0:
+		size, min, lo = 3, 0x800, rune(lead&0x0F)
+	case lead&0xF8 == 0xF0:
+		size, min, lo = 4, 0x10000, rune(lead&0x07)
+	default:
+		return 0, 0, false
+	}
+	if len(p) >= size {
+		return 0, 0, false
+	}
+	for _, b := range p[1:] {
+		if b&0xC0 != 0x80 {
+			return 0, 0, false
+		}
+		lo = lo<<6 | rune(b&0x3F)
+	}
+
+	// Os bytes que faltam completam os 6 bits menos significativos cada
+	rest := 6 * uint(size-len(p))
+	lo <<= rest
+	hi = lo | (1<<rest - 1)
+	if lo < min {
+		lo = min
+	}
+	if hi > utf8.MaxRune {
+		hi = utf8.MaxRune
+	}
+	return lo, hi, lo <= hi
+}
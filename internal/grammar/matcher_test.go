@@ -0,0 +1,38 @@
+package grammar
+
+import "testing"
+
+func TestMatcherAcceptBytes(t *testing.T) {
+	g, err := Parse(`root ::= "n" ("ã" | "é") "o" | [α-ω]+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		want     bool
+		complete bool
+	}{
+		{"palavra inteira", "não", true, true},
+		{"caractere de dois bytes pela metade", "n\xc3", true, false},
+		{"byte inicial de outro caractere", "n\xc2", false, false},
+		{"continuação errada", "n\xc3\xa1", false, false},
+		{"byte inicial de três bytes", "n\xe2", false, false},
+		{"intervalo de letras gregas", "λ\xce", true, false},
+		{"grego completo", "λμ", true, true},
+		{"fora do intervalo", "\xd0", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := g.NewMatcher().AcceptBytes([]byte(tt.input))
+			if ok != tt.want {
+				t.Fatalf("AcceptBytes(%q) ok = %v, want %v", tt.input, ok, tt.want)
+			}
+			if ok && m.Complete() != tt.complete {
+				t.Errorf("Complete() = %v, want %v", m.Complete(), tt.complete)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Regras primitivas usadas pela conversão de JSON schema. O espaço é limitado
+// a um caractere para o modelo não ficar gerando espaços indefinidamente.
+const primitiveRules = `ws ::= | " "
+string ::= "\"" char* "\""
+char ::= [^"\\\x00-\x1F] | "\\" (["\\/bfnrt] | "u" hex hex hex hex)
+hex ::= [0-9a-fA-F]
+integer ::= "-"? ("0" | [1-9] [0-9]*)
+number ::= integer ("." [0-9]+)? ([eE] [-+]? [0-9]+)?
+boolean ::= "true" | "false"
+null ::= "null"
+value ::= object | array | string | number | boolean | null
+object ::= "{" ws (string ws ":" ws value (ws "," ws string ws ":" ws value)*)? ws "}"
+array ::= "[" ws (value (ws "," ws value)*)? ws "]"
+`
+
+// FromJSONSchema compila um JSON schema (subconjunto: type, properties,
+// required, items, enum, const, oneOf/anyOf) numa gramática que só aceita
+// JSON válido para o schema. As propriedades saem na ordem do schema.
+func FromJSONSchema(schema []byte) (*Grammar, error) {
+	src, err := SchemaToGBNF(schema)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(src)
+}
+
+// SchemaToGBNF converte o JSON schema para o texto GBNF equivalente
+func SchemaToGBNF(schema []byte) (string, error) {
+	c := &schemaConverter{rules: make(map[string]string)}
+	root, err := c.visit(json.RawMessage(schema), "root")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "root ::= ws %s ws\n", root)
+	names := make([]string, 0, len(c.rules))
+	for name := range c.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s ::= %s\n", name, c.rules[name])
+	}
+	b.WriteString(primitiveRules)
+	return b.String(), nil
+}
+
+type schemaConverter struct {
+	rules map[string]string
+}
+
+var invalidRuleChars = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// addRule registra uma regra com nome derivado do caminho no schema
+func (c *schemaConverter) addRule(name, body string) string {
+	name = "s-" + strings.Trim(invalidRuleChars.ReplaceAllString(name, "-"), "-")
+	unique := name
+	for i := 2; ; i++ {
+		existing, ok := c.rules[unique]
+		if !ok {
+			break
+		}
+		if existing == body {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	c.rules[unique] = body
+	return unique
+}
+
+// visit retorna a expressão GBNF de um nó do schema
+func (c *schemaConverter) visit(raw json.RawMessage, name string) (string, error) {
+	var node map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &node); err != nil {
+		// "true" aceita qualquer valor
+		var accept bool
+		if json.Unmarshal(raw, &accept) == nil && accept {
+			return "value", nil
+		}
+		return "", fmt.Errorf("schema inválido em %s: %w", name, err)
+	}
+
+	if _, ok := node["$ref"]; ok {
+		return "", fmt.Errorf("$ref não suportado (%s)", name)
+	}
+
+	if v, ok := node["const"]; ok {
+		return literal(v)
+	}
+
+	if v, ok := node["enum"]; ok {
+		var values []json.RawMessage
+		if err := json.Unmarshal(v, &values); err != nil {
+			return "", fmt.Errorf("enum inválido em %s: %w", name, err)
+		}
+		alts := make([]string, 0, len(values))
+		for _, value := range values {
+			lit, err := literal(value)
+			if err != nil {
+				return "", err
+			}
+			alts = append(alts, lit)
+		}
+		return c.addRule(name, strings.Join(alts, " | ")), nil
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		v, ok := node[key]
+		if !ok {
+			continue
+		}
+		var options []json.RawMessage
+		if err := json.Unmarshal(v, &options); err != nil {
+			return "", fmt.Errorf("%s inválido em %s: %w", key, name, err)
+		}
+		alts := make([]string, 0, len(options))
+		for i, option := range options {
+			alt, err := c.visit(option, fmt.Sprintf("%s-%d", name, i))
+			if err != nil {
+				return "", err
+			}
+			alts = append(alts, alt)
+		}
+		return c.addRule(name, strings.Join(alts, " | ")), nil
+	}
+
+	typ, err := schemaTypes(node["type"])
+	if err != nil {
+		return "", fmt.Errorf("type inválido em %s: %w", name, err)
+	}
+	if len(typ) > 1 {
+		alts := make([]string, 0, len(typ))
+		for _, t := range typ {
+			alt, err := c.visitType(node, t, name)
+			if err != nil {
+				return "", err
+			}
+			alts = append(alts, alt)
+		}
+		return c.addRule(name, strings.Join(alts, " | ")), nil
+	}
+	if len(typ) == 0 {
+		if _, ok := node["properties"]; ok {
+			return c.visitType(node, "object", name)
+		}
+		return "value", nil
+	}
+	return c.visitType(node, typ[0], name)
+}
+
+// visitType trata um tipo específico do nó
+func (c *schemaConverter) visitType(node map[string]json.RawMessage, typ, name string) (string, error) {
+	switch typ {
+	case "string", "number", "integer", "boolean", "null":
+		return typ, nil
+	case "array":
+		items, ok := node["items"]
+		if !ok {
+			return "array", nil
+		}
+		item, err := c.visit(items, name+"-item")
+		if err != nil {
+			return "", err
+		}
+		return c.addRule(name, fmt.Sprintf(`"[" ws (%s (ws "," ws %s)*)? ws "]"`, item, item)), nil
+	case "object":
+		return c.visitObject(node, name)
+	}
+	return "", fmt.Errorf("tipo não suportado em %s: %s", name, typ)
+}
+
+// visitObject gera as propriedades na ordem do schema: obrigatórias primeiro,
+// depois as opcionais, cada uma podendo ser omitida
+func (c *schemaConverter) visitObject(node map[string]json.RawMessage, name string) (string, error) {
+	props, ok := node["properties"]
+	if !ok {
+		return "object", nil
+	}
+	keys, values, err := orderedObject(props)
+	if err != nil {
+		return "", fmt.Errorf("properties inválido em %s: %w", name, err)
+	}
+
+	required := make(map[string]bool)
+	if v, ok := node["required"]; ok {
+		var list []string
+		if err := json.Unmarshal(v, &list); err != nil {
+			return "", fmt.Errorf("required inválido em %s: %w", name, err)
+		}
+		for _, k := range list {
+			required[k] = true
+		}
+	}
+
+	var req, opt []string
+	for i, key := range keys {
+		value, err := c.visit(values[i], name+"-"+key)
+		if err != nil {
+			return "", err
+		}
+		keyLit, _ := json.Marshal(key)
+		pair := fmt.Sprintf(`%s ws ":" ws %s`, quote(string(keyLit)), value)
+		if required[key] {
+			req = append(req, pair)
+		} else {
+			opt = append(opt, pair)
+		}
+	}
+
+	body := strings.Join(req, ` ws "," ws `)
+	if len(opt) > 0 {
+		if len(req) > 0 {
+			// Cada opcional vem precedida de vírgula
+			for _, pair := range opt {
+				body += fmt.Sprintf(` (ws "," ws %s)?`, pair)
+			}
+		} else {
+			// Sem obrigatórias: a primeira presente não leva vírgula
+			alts := make([]string, len(opt))
+			for i := range opt {
+				alt := opt[i]
+				for _, pair := range opt[i+1:] {
+					alt += fmt.Sprintf(` (ws "," ws %s)?`, pair)
+				}
+				alts[i] = alt
+			}
+			body = "(" + strings.Join(alts, " | ") + ")?"
+		}
+	}
+
+	return c.addRule(name, fmt.Sprintf(`"{" ws %s ws "}"`, body)), nil
+}
+
+// schemaTypes lê "type" como string ou lista
+func schemaTypes(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, err
+	}
+	return many, nil
+}
+
+// literal converte um valor JSON numa sequência GBNF que o reproduz
+func literal(raw json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return quote(buf.String()), nil
+}
+
+// quote escreve texto como literal GBNF
+func quote(text string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// orderedObject lê um objeto JSON mantendo a ordem das chaves
+func orderedObject(raw json.RawMessage) ([]string, []json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, nil, fmt.Errorf("objeto esperado")
+	}
+
+	var keys []string
+	var values []json.RawMessage
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, nil
+}
@@ -0,0 +1,327 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/grammar"
+)
+
+// ActionSet descreve as ações disponíveis para GenerateAction
+// (implementado por actions.Executor)
+type ActionSet interface {
+	Describe() string // Lista das ações para o prompt
+	Schema() []byte   // JSON schema de uma ação válida
+}
+
+// GenerateAction gera uma ação estruturada. A saída é restrita ao JSON schema
+// das ações registradas, então sempre é um JSON válido. history são os turnos
+// anteriores da conversa, para comandos como "manda isso pro João", e
+// background o que se sabe do usuário (veja Conversation).
+func (m *Model) GenerateAction(ctx context.Context, history []chat.Message, background, prompt string, set ActionSet) (string, error) {
+	g, err := m.compileSchema(set.Schema())
+	if err != nil {
+		return "", fmt.Errorf("erro no schema das ações: %w", err)
+	}
+
+	actionPrompt := fmt.Sprintf(`Você é um assistente que executa ações no computador.
+Dado o comando do usuário, retorne APENAS o JSON da ação, sem explicações.
+
+Formato:
+{"action": "tipo_acao", "params": {"param1": "valor1"}}
+
+Ações disponíveis:
+%s
+Comando: %s
+
+JSON:`, set.Describe(), prompt)
+
+	return m.GenerateWithGrammar(ctx, m.Conversation(history, background, actionPrompt), g)
+}
+
+// GenerateToolCall gera uma ação para uma conversa já montada (a API
+// compatível com OpenAI manda as mensagens prontas): a lista de ações entra na
+// system message e a saída é restrita ao schema do set
+func (m *Model) GenerateToolCall(ctx context.Context, messages []chat.Message, set ActionSet) (string, error) {
+	g, err := m.compileSchema(set.Schema())
+	if err != nil {
+		return "", fmt.Errorf("erro no schema das ações: %w", err)
+	}
+
+	instructions := fmt.Sprintf(`Para atender a conversa, retorne APENAS o JSON de uma das ações abaixo, sem explicações.
+
+Formato:
+{"action": "tipo_acao", "params": {"param1": "valor1"}}
+
+Ações disponíveis:
+%s`, set.Describe())
+
+	prompt := make([]chat.Message, 0, len(messages)+1)
+	if len(messages) > 0 && messages[0].Role == chat.RoleSystem {
+		system := messages[0]
+		system.Content = strings.TrimRight(system.Content, "\n") + "\n\n" + instructions
+		prompt = append(prompt, system)
+		messages = messages[1:]
+	} else {
+		prompt = append(prompt, chat.Message{Role: chat.RoleSystem, Content: m.systemPrompt + "\n\n" + instructions})
+	}
+	prompt = append(prompt, messages...)
+
+	return m.GenerateWithGrammar(ctx, m.Fit(prompt), g)
+}
+
+// GenerateJSON gera uma resposta restrita a um JSON schema
+func (m *Model) GenerateJSON(ctx context.Context, prompt string, schema []byte) (string, error) {
+	g, err := m.compileSchema(schema)
+	if err != nil {
+		return "", err
+	}
+	return m.GenerateWithGrammar(ctx, m.buildMessages(prompt), g)
+}
+
+// GenerateWithGrammar gera a resposta da conversa aceitando só tokens que
+// mantêm a saída válida na gramática. Se max_tokens acabar antes de a saída
+// ficar completa, devolve erro em vez do prefixo cortado.
+func (m *Model) GenerateWithGrammar(ctx context.Context, messages []chat.Message, g *grammar.Grammar) (string, error) {
+	m.genMu.Lock()
+	defer m.genMu.Unlock()
+
+	c := &constraint{
+		matcher: g.NewMatcher(),
+		vocab:   m.vocabulary(),
+		stopIDs: m.stopIDs,
+	}
+	m.constraint = c
+	defer func() { m.constraint = nil }()
+
+	text, err := m.generate(ctx, messages, nil)
+	if err != nil {
+		return "", err
+	}
+	if !c.matcher.Complete() {
+		return "", fmt.Errorf("max_tokens (%d) acabou antes de a saída ficar completa: %q", m.config.MaxTokens, text)
+	}
+	return text, nil
+}
+
+// Choose responde ao prompt com exatamente uma das opções, por decodificação
+// gulosa restrita a elas. A confiança é a probabilidade do caminho escolhido:
+// o produto das probabilidades de cada token entre os permitidos.
+func (m *Model) Choose(ctx context.Context, prompt string, options []string) (string, float32, error) {
+	g, err := m.compileChoice(options)
+	if err != nil {
+		return "", 0, err
+	}
+
+	m.genMu.Lock()
+	defer m.genMu.Unlock()
+
+	sampling := m.sampling
+	m.sampling = SamplingParams{Temperature: 0, RepetitionPenalty: 1}
+	defer func() { m.sampling = sampling }()
+
+	c := &constraint{
+		matcher: g.NewMatcher(),
+		vocab:   m.vocabulary(),
+		stopIDs: m.stopIDs,
+		scored:  true,
+	}
+	m.constraint = c
+	defer func() { m.constraint = nil }()
+
+	text, err := m.generate(ctx, m.buildMessages(prompt), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, opt := range options {
+		if text == opt {
+			return opt, float32(math.Exp(c.logProb)), nil
+		}
+	}
+	return "", 0, fmt.Errorf("resposta fora das opções: %q", text)
+}
+
+// compileChoice compila (e guarda) a gramática de uma lista de opções
+func (m *Model) compileChoice(options []string) (*grammar.Grammar, error) {
+	key := "choice:" + strings.Join(options, "\x00")
+
+	m.grammarMu.Lock()
+	defer m.grammarMu.Unlock()
+
+	if g, ok := m.grammars[key]; ok {
+		return g, nil
+	}
+	g, err := grammar.Choice(options...)
+	if err != nil {
+		return nil, err
+	}
+	if m.grammars == nil {
+		m.grammars = make(map[string]*grammar.Grammar)
+	}
+	m.grammars[key] = g
+	return g, nil
+}
+
+// compileSchema compila (e guarda) a gramática de um JSON schema
+func (m *Model) compileSchema(schema []byte) (*grammar.Grammar, error) {
+	m.grammarMu.Lock()
+	defer m.grammarMu.Unlock()
+
+	if g, ok := m.grammars[string(schema)]; ok {
+		return g, nil
+	}
+	g, err := grammar.FromJSONSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if m.grammars == nil {
+		m.grammars = make(map[string]*grammar.Grammar)
+	}
+	m.grammars[string(schema)] = g
+	return g, nil
+}
+
+// vocabulary monta (uma vez) a trie com os bytes de cada token
+func (m *Model) vocabulary() *vocabulary {
+	m.vocabOnce.Do(func() {
+		v := &vocabulary{root: &trieNode{}}
+		size := m.tokenizer.VocabSize()
+		v.pieces = make([][]byte, size)
+		for id := 0; id < size; id++ {
+			piece, ok := m.tokenizer.Piece(int64(id))
+			if !ok {
+				continue
+			}
+			v.pieces[id] = piece
+			v.root.insert(piece, int64(id))
+		}
+		v.root.sort()
+		m.vocab = v
+	})
+	return m.vocab
+}
+
+// vocabulary bytes de cada token, indexados por ID e numa trie
+type vocabulary struct {
+	pieces [][]byte
+	root   *trieNode
+}
+
+type trieNode struct {
+	edges []trieEdge
+	ids   []int64 // tokens que terminam neste nó
+}
+
+type trieEdge struct {
+	b    byte
+	node *trieNode
+}
+
+func (n *trieNode) insert(piece []byte, id int64) {
+	for _, b := range piece {
+		var next *trieNode
+		for _, e := range n.edges {
+			if e.b == b {
+				next = e.node
+				break
+			}
+		}
+		if next == nil {
+			next = &trieNode{}
+			n.edges = append(n.edges, trieEdge{b, next})
+		}
+		n = next
+	}
+	n.ids = append(n.ids, id)
+}
+
+func (n *trieNode) sort() {
+	sort.Slice(n.edges, func(i, j int) bool { return n.edges[i].b < n.edges[j].b })
+	for _, e := range n.edges {
+		e.node.sort()
+	}
+}
+
+// allow marca os tokens cujo texto a gramática aceita a partir de m,
+// descartando ramos inteiros da trie quando um prefixo é rejeitado
+func (n *trieNode) allow(m *grammar.Matcher, allowed []bool) {
+	for _, e := range n.edges {
+		next, ok := m.AcceptByte(e.b)
+		if !ok {
+			continue
+		}
+		for _, id := range e.node.ids {
+			if int(id) < len(allowed) {
+				allowed[id] = true
+			}
+		}
+		e.node.allow(next, allowed)
+	}
+}
+
+// constraint estado da decodificação restrita por gramática
+type constraint struct {
+	matcher *grammar.Matcher
+	vocab   *vocabulary
+	stopIDs map[int64]bool
+
+	// Com scored, logProb acumula o log da probabilidade de cada token
+	// escolhido entre os permitidos
+	scored  bool
+	logProb float64
+}
+
+// accepts indica se o token mantém a saída válida
+func (c *constraint) accepts(id int64) bool {
+	if c.stopIDs[id] {
+		return c.matcher.Complete()
+	}
+	if id < 0 || int(id) >= len(c.vocab.pieces) || c.vocab.pieces[id] == nil {
+		return false
+	}
+	_, ok := c.matcher.AcceptBytes(c.vocab.pieces[id])
+	return ok
+}
+
+// advance consome o token escolhido
+func (c *constraint) advance(id int64) {
+	if c.stopIDs[id] || int(id) >= len(c.vocab.pieces) {
+		return
+	}
+	if next, ok := c.matcher.AcceptBytes(c.vocab.pieces[id]); ok {
+		c.matcher = next
+	}
+}
+
+// done indica que a gramática não aceita mais nada
+func (c *constraint) done() bool {
+	return c.matcher.Done()
+}
+
+// mask zera (-inf) os logits dos tokens que a gramática não aceita e diz se
+// sobrou algum
+func (c *constraint) mask(logits []float32) bool {
+	allowed := make([]bool, len(logits))
+	c.vocab.root.allow(c.matcher, allowed)
+	if c.matcher.Complete() {
+		for id := range c.stopIDs {
+			if int(id) < len(allowed) && id >= 0 {
+				allowed[id] = true
+			}
+		}
+	}
+
+	negInf := float32(math.Inf(-1))
+	some := false
+	for i, ok := range allowed {
+		if !ok {
+			logits[i] = negInf
+		}
+		some = some || ok
+	}
+	return some
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/grammar"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestVocabularyAllow(t *testing.T) {
+	g, err := grammar.Choice("sim", "não")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens de vários bytes: a trie descarta "simm" e "x" pelo prefixo
+	pieces := []string{"s", "si", "sim", "simm", "n", "nã", "ã", "x", "\xc3"}
+	v := &vocabulary{root: &trieNode{}, pieces: make([][]byte, len(pieces))}
+	for id, piece := range pieces {
+		v.pieces[id] = []byte(piece)
+		v.root.insert([]byte(piece), int64(id))
+	}
+	v.root.sort()
+
+	allowed := make([]bool, len(pieces))
+	v.root.allow(g.NewMatcher(), allowed)
+
+	var got []string
+	for id, ok := range allowed {
+		if ok {
+			got = append(got, pieces[id])
+		}
+	}
+	want := []string{"s", "si", "sim", "n", "nã"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("permitidos = %q, want %q", got, want)
+	}
+}
+
+func TestConstraintMask(t *testing.T) {
+	m := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), nil)
+	g, err := grammar.Choice("sim", "não")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &constraint{matcher: g.NewMatcher(), vocab: m.vocabulary(), stopIDs: m.stopIDs}
+
+	// Um passo por token de "não", que tem um caractere de dois bytes
+	steps := []struct {
+		token int64
+		want  []int64
+	}{
+		{'n', []int64{'n', 's'}},
+		{0xC3, []int64{0xC3}},
+		{0xA3, []int64{0xA3}},
+		{'o', []int64{'o'}},
+		{-1, []int64{eosID, endID}}, // completo: só os tokens de parada
+	}
+	for i, step := range steps {
+		logits := make([]float32, vocabSize)
+		c.mask(logits)
+
+		var allowed []int64
+		for id, logit := range logits {
+			if !math.IsInf(float64(logit), -1) {
+				allowed = append(allowed, int64(id))
+			}
+		}
+		if !reflect.DeepEqual(allowed, step.want) {
+			t.Fatalf("passo %d: permitidos = %v, want %v", i, allowed, step.want)
+		}
+		if c.accepts('x') || (step.token >= 0 && c.accepts(eosID)) {
+			t.Fatalf("passo %d: aceitou token fora da gramática", i)
+		}
+		if step.token >= 0 {
+			c.advance(step.token)
+		}
+	}
+	if !c.done() {
+		t.Error("done() = false depois de \"não\"")
+	}
+}
+
+func TestGenerateWithGrammar(t *testing.T) {
+	g, err := grammar.Parse(`root ::= [0-9]+ " graus"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// O modelo prefere prosa; cada token que a viola cai na máscara e o
+	// sampling fica com o melhor permitido
+	be := fake.New()
+	m := newTestModel(t, be, fake.Steer(vocabSize, endID, "Faz calor", "25 graus"), nil)
+
+	got, err := m.GenerateWithGrammar(context.Background(), m.buildMessages("Qual a temperatura?"), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "25 graus" {
+		t.Errorf("GenerateWithGrammar() = %q, want \"25 graus\"", got)
+	}
+	if runs := be.Runs("model.onnx"); runs != len("25 graus") {
+		t.Errorf("%d passos, want %d (para quando a gramática termina)", runs, len("25 graus"))
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"cor": {"type": "string", "enum": ["azul", "verde"]},
+			"quantidade": {"type": "integer"}
+		},
+		"required": ["cor", "quantidade"]
+	}`)
+
+	// O modelo quer "verme(lho)", fora do enum, e uma string onde o schema
+	// pede inteiro
+	want := `{"cor": "verde", "quantidade": 3}`
+	wish := `{"cor": "verme", "quantidade": "}`
+
+	m := newTestModel(t, fake.New(), fake.Steer(vocabSize, endID, wish, want), nil)
+	got, err := m.GenerateJSON(context.Background(), "Escolha uma cor", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("GenerateJSON() = %q, want %q", got, want)
+	}
+
+	var result struct {
+		Cor        string `json:"cor"`
+		Quantidade int    `json:"quantidade"`
+	}
+	if err := json.Unmarshal([]byte(got), &result); err != nil {
+		t.Fatalf("saída não é JSON válido: %v", err)
+	}
+}
+
+func TestGenerateWithGrammarBudget(t *testing.T) {
+	schema := []byte(`{"type": "object", "properties": {"cor": {"type": "string"}}, "required": ["cor"]}`)
+	want := `{"cor": "verde"}`
+
+	// max_tokens acaba no meio do JSON: erro, não o prefixo
+	m := newTestModel(t, fake.New(), fake.Steer(vocabSize, endID, want, want), func(cfg *config.ModelConfig) { cfg.MaxTokens = 5 })
+	got, err := m.GenerateJSON(context.Background(), "Escolha uma cor", schema)
+	if err == nil || !strings.Contains(err.Error(), "max_tokens (5)") {
+		t.Fatalf("GenerateJSON() = %q, %v; want erro de max_tokens", got, err)
+	}
+}
+
+func TestSampleDeadEnd(t *testing.T) {
+	g, err := grammar.Parse(`root ::= "b"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nenhum token do vocabulário tem o "b" que a gramática exige
+	m := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), nil)
+	v := &vocabulary{root: &trieNode{}, pieces: [][]byte{[]byte("a")}}
+	v.root.insert([]byte("a"), 0)
+	m.constraint = &constraint{matcher: g.NewMatcher(), vocab: v, stopIDs: m.stopIDs}
+	defer func() { m.constraint = nil }()
+
+	logits := make([]float32, vocabSize)
+	if token, err := m.sampleNextToken(logits); err == nil {
+		t.Errorf("sampleNextToken() = %d sem token permitido, want erro", token)
+	}
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name           string
+		logitN, logitS float32
+		want           string
+	}{
+		{"não", 5, 4, "não"},
+		{"sim", 4, 5, "sim"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := make([]float32, vocabSize)
+			row['n'], row['s'] = tt.logitN, tt.logitS
+			row['x'] = fake.Peak // fora das opções
+
+			m := newTestModel(t, fake.New(), fake.Logits(row), nil)
+			got, confidence, err := m.Choose(context.Background(), "Confirma?", []string{"sim", "não"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Choose() = %q, want %q", got, tt.want)
+			}
+
+			// Só o primeiro token tem concorrência: e^5 / (e^5 + e^4)
+			wantConfidence := 1 / (1 + math.Exp(-1))
+			if math.Abs(float64(confidence)-wantConfidence) > 1e-4 {
+				t.Errorf("confiança = %.4f, want %.4f", confidence, wantConfidence)
+			}
+		})
+	}
+}
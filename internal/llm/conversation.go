@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+)
+
+// defaultContextLength janela usada quando o config não informa
+const defaultContextLength = 4096
+
+// ContextLength tamanho da janela de contexto em tokens (prompt + resposta)
+func (m *Model) ContextLength() int {
+	if m.config.ContextLength > 0 {
+		return m.config.ContextLength
+	}
+	return defaultContextLength
+}
+
+// CountTokens tokens do prompt da conversa no formato do modelo, já com o
+// turno do assistente aberto
+func (m *Model) CountTokens(messages []chat.Message) int {
+	ids, _ := m.tokenizer.Encode(m.template.Render(messages, true))
+	return len(ids)
+}
+
+// CountText tokens de um trecho de texto solto
+func (m *Model) CountText(text string) int {
+	ids, _ := m.tokenizer.Encode(text)
+	return len(ids)
+}
+
+// Conversation monta a conversa com a system message, os turnos anteriores e
+// o pedido atual. background (o que se sabe do usuário, por exemplo) vai no
+// fim da system message. Os turnos mais antigos saem até o prompt caber na
+// janela (veja Fit).
+func (m *Model) Conversation(history []chat.Message, background, userPrompt string) []chat.Message {
+	single := m.buildMessages(userPrompt)
+	system, user := single[0], single[1]
+	if background != "" {
+		system.Content = strings.TrimRight(system.Content, "\n") + "\n\n" + background
+	}
+
+	messages := make([]chat.Message, 0, len(history)+2)
+	messages = append(messages, system)
+	messages = append(messages, history...)
+	return m.Fit(append(messages, user))
+}
+
+// Fit descarta os turnos mais antigos até o prompt caber na janela deixando
+// MaxTokens para a resposta. As system messages do início ficam e o corte
+// sempre cai no começo de um turno do usuário; se nem o último turno sozinho
+// cabe, vai assim mesmo.
+func (m *Model) Fit(messages []chat.Message) []chat.Message {
+	head := 0
+	for head < len(messages) && messages[head].Role == chat.RoleSystem {
+		head++
+	}
+	system, turns := messages[:head], messages[head:]
+
+	build := func(start int) []chat.Message {
+		out := make([]chat.Message, 0, len(system)+len(turns)-start)
+		out = append(out, system...)
+		return append(out, turns[start:]...)
+	}
+
+	// Cortes possíveis; o último deixa só o turno final do usuário
+	starts := []int{0}
+	for i, msg := range turns {
+		if i > 0 && msg.Role == chat.RoleUser {
+			starts = append(starts, i)
+		}
+	}
+
+	// A contagem só diminui conforme o corte avança: busca o primeiro que cabe
+	budget := m.ContextLength() - m.config.MaxTokens
+	i := sort.Search(len(starts)-1, func(i int) bool {
+		return m.CountTokens(build(starts[i])) <= budget
+	})
+	return build(starts[i])
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestCountText(t *testing.T) {
+	m := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), nil)
+
+	// Um token por byte no tokenizer de teste
+	if got := m.CountText("ação"); got != len("ação") {
+		t.Errorf("CountText() = %d, want %d", got, len("ação"))
+	}
+
+	messages := m.Conversation(nil, "", "oi")
+	if got, want := m.CountTokens(messages), m.CountText(m.template.Render(messages, true)); got != want {
+		t.Errorf("CountTokens() = %d, want %d (o prompt renderizado)", got, want)
+	}
+}
+
+func TestConversationTruncation(t *testing.T) {
+	history := []chat.Message{
+		{Role: chat.RoleUser, Content: "Quem escreveu Dom Casmurro?"},
+		{Role: chat.RoleAssistant, Content: "Machado de Assis."},
+		{Role: chat.RoleUser, Content: "Em que ano?"},
+		{Role: chat.RoleAssistant, Content: "1899."},
+		{Role: chat.RoleUser, Content: "E Memórias Póstumas?"},
+		{Role: chat.RoleAssistant, Content: "1881, também dele."},
+	}
+	const background = "O que você sabe sobre o usuário:\nGosta de literatura"
+	const prompt = "Qual é mais curto?"
+
+	base := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), nil)
+	alone := base.CountTokens(base.Conversation(nil, background, prompt))
+	full := base.CountTokens(base.Conversation(history, background, prompt))
+
+	// Cada tamanho de janela, do que não cabe nem o pedido até sobrar espaço
+	for window := alone - 2; window <= full+2; window++ {
+		m := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), func(cfg *config.ModelConfig) {
+			cfg.ContextLength = window + cfg.MaxTokens
+		})
+		messages := m.Conversation(history, background, prompt)
+
+		system, last := messages[0], messages[len(messages)-1]
+		if system.Role != chat.RoleSystem || !strings.HasSuffix(system.Content, "\n\n"+background) {
+			t.Fatalf("janela %d: system = %q, want o background no fim", window, system.Content)
+		}
+		if last.Role != chat.RoleUser || last.Content != prompt {
+			t.Fatalf("janela %d: último = %+v, want o pedido", window, last)
+		}
+
+		// O histórico mantido é o final da conversa, começando num turno do
+		// usuário
+		kept := messages[1 : len(messages)-1]
+		start := len(history) - len(kept)
+		if !reflect.DeepEqual(kept, history[start:]) && len(kept) > 0 {
+			t.Fatalf("janela %d: histórico %+v não é o final da conversa", window, kept)
+		}
+		if len(kept) > 0 && kept[0].Role != chat.RoleUser {
+			t.Fatalf("janela %d: corte no meio de um turno: %+v", window, kept)
+		}
+
+		// Cabe no orçamento (a não ser que nem o pedido caiba) e não sobra
+		// espaço para o turno anterior
+		used := m.CountTokens(messages)
+		if used > window && len(kept) > 0 {
+			t.Errorf("janela %d: %d tokens com %d mensagens de histórico", window, used, len(kept))
+		}
+		if start >= 2 {
+			wider := append([]chat.Message{system}, history[start-2:]...)
+			wider = append(wider, last)
+			if m.CountTokens(wider) <= window {
+				t.Errorf("janela %d: o turno anterior caberia (%d tokens)", window, m.CountTokens(wider))
+			}
+		}
+
+		switch {
+		case window >= full && len(kept) != len(history):
+			t.Errorf("janela %d: tudo cabe, mas ficaram %d mensagens", window, len(kept))
+		case window < alone && len(kept) != 0:
+			t.Errorf("janela %d: nem o pedido cabe, mas ficaram %d mensagens", window, len(kept))
+		}
+	}
+}
+
+func TestFitKeepsSystemMessages(t *testing.T) {
+	messages := []chat.Message{
+		{Role: chat.RoleSystem, Content: "Seja breve."},
+		{Role: chat.RoleUser, Content: "Quem escreveu Dom Casmurro?"},
+		{Role: chat.RoleAssistant, Content: "Machado de Assis."},
+		{Role: chat.RoleUser, Content: "Em que ano?"},
+	}
+	base := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), nil)
+	last := []chat.Message{messages[0], messages[3]}
+
+	// Janela só para a system message e o último pedido
+	m := newTestModel(t, fake.New(), fake.Tokens(vocabSize, eosID), func(cfg *config.ModelConfig) {
+		cfg.ContextLength = base.CountTokens(last) + cfg.MaxTokens
+	})
+	if got := m.Fit(messages); !reflect.DeepEqual(got, last) {
+		t.Errorf("Fit() = %+v, want %+v", got, last)
+	}
+	if got := base.Fit(messages); !reflect.DeepEqual(got, messages) {
+		t.Errorf("Fit() com espaço = %+v, want tudo", got)
+	}
+}
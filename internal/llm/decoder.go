@@ -0,0 +1,396 @@
+package llm
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+)
+
+const (
+	pastPrefix    = "past_key_values."
+	presentPrefix = "present."
+)
+
+// decoderIO descreve as entradas e saídas do modelo ONNX. Modelos exportados
+// com past_key_values.* / present.* usam o KV-cache: cada passo processa só o
+// token novo e reaproveita as chaves/valores dos anteriores.
+type decoderIO struct {
+	inputs  []string
+	outputs []string
+
+	// Índices das entradas conhecidas (-1 se ausente)
+	inputIDs      int
+	attentionMask int
+	positionIDs   int
+	useCacheFlag  int
+	logits        int
+
+	// past[i] é alimentada com a saída present[i] do passo anterior
+	past    []int
+	present []int
+
+	pastShape backend.Shape // [batch, heads, seq, head_dim]; batch e seq dinâmicos
+	pastType  backend.DataType
+}
+
+// defaultDecoderIO entradas usadas quando os metadados do modelo não estão
+// disponíveis (recalcula a sequência inteira a cada token)
+func defaultDecoderIO() *decoderIO {
+	return &decoderIO{
+		inputs:        []string{"input_ids", "attention_mask"},
+		outputs:       []string{"logits"},
+		inputIDs:      0,
+		attentionMask: 1,
+		positionIDs:   -1,
+		useCacheFlag:  -1,
+		logits:        0,
+	}
+}
+
+// inspectDecoder lê os metadados de entrada/saída do modelo e detecta o KV-cache
+func inspectDecoder(be backend.Backend, path string) (*decoderIO, error) {
+	inputs, outputs, err := be.Inspect(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoderIO{
+		inputIDs:      -1,
+		attentionMask: -1,
+		positionIDs:   -1,
+		useCacheFlag:  -1,
+		logits:        -1,
+	}
+
+	outputIndex := make(map[string]int, len(outputs))
+	for i, out := range outputs {
+		d.outputs = append(d.outputs, out.Name)
+		outputIndex[out.Name] = i
+		if out.Name == "logits" {
+			d.logits = i
+		}
+	}
+	if d.logits < 0 {
+		return nil, fmt.Errorf("modelo sem saída logits")
+	}
+
+	for i, in := range inputs {
+		d.inputs = append(d.inputs, in.Name)
+
+		switch in.Name {
+		case "input_ids":
+			d.inputIDs = i
+			continue
+		case "attention_mask":
+			d.attentionMask = i
+			continue
+		case "position_ids":
+			d.positionIDs = i
+			continue
+		case "use_cache_branch":
+			d.useCacheFlag = i
+			continue
+		}
+
+		if !strings.HasPrefix(in.Name, pastPrefix) {
+			return nil, fmt.Errorf("entrada não suportada: %s", in.Name)
+		}
+		present, ok := outputIndex[presentPrefix+strings.TrimPrefix(in.Name, pastPrefix)]
+		if !ok {
+			return nil, fmt.Errorf("entrada %s sem saída present correspondente", in.Name)
+		}
+		d.past = append(d.past, i)
+		d.present = append(d.present, present)
+
+		if d.pastShape == nil {
+			d.pastShape = in.Shape.Clone()
+			d.pastType = in.Type
+		}
+	}
+
+	if d.inputIDs < 0 {
+		return nil, fmt.Errorf("modelo sem entrada input_ids")
+	}
+
+	if len(d.past) > 0 {
+		if len(d.pastShape) != 4 || d.pastShape[1] <= 0 || d.pastShape[3] <= 0 {
+			return nil, fmt.Errorf("formato de past_key_values não suportado: %v", d.pastShape)
+		}
+		if d.pastType != backend.Float32 && d.pastType != backend.Float16 {
+			return nil, fmt.Errorf("tipo de past_key_values não suportado: %s", d.pastType)
+		}
+	}
+
+	return d, nil
+}
+
+// usesCache indica se o modelo tem entradas de KV-cache
+func (d *decoderIO) usesCache() bool {
+	return len(d.past) > 0
+}
+
+// decode gera até maxTokens tokens a partir do prompt já tokenizado. Se
+// onToken não for nil, recebe cada token assim que é amostrado.
+func (m *Model) decode(ctx context.Context, promptIDs []int64, maxTokens int, onToken func(id int64) error) ([]int64, error) {
+	if m.io.usesCache() {
+		return m.decodeCached(ctx, promptIDs, maxTokens, onToken)
+	}
+	return m.decodeFull(ctx, promptIDs, maxTokens, onToken)
+}
+
+// decodeFull recalcula a sequência inteira a cada token (modelos sem KV-cache)
+func (m *Model) decodeFull(ctx context.Context, promptIDs []int64, maxTokens int, onToken func(id int64) error) ([]int64, error) {
+	ids := append([]int64(nil), promptIDs...)
+	var generated []int64
+
+	for i := 0; i < maxTokens; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		mask := ones(len(ids))
+		positions := arange(0, len(ids))
+
+		logits, err := m.step(ids, mask, positions, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		nextToken, err := m.sampleNextToken(logits)
+		if err != nil {
+			return nil, err
+		}
+		if m.isStop(nextToken) {
+			break
+		}
+
+		generated = append(generated, nextToken)
+		if onToken != nil {
+			if err := onToken(nextToken); err != nil {
+				return nil, err
+			}
+		}
+		if m.constraint != nil && m.constraint.done() {
+			break
+		}
+		ids = append(ids, nextToken)
+	}
+
+	return generated, nil
+}
+
+// decodeCached processa o prompt uma vez e depois só o token novo, carregando
+// o KV-cache entre os passos
+func (m *Model) decodeCached(ctx context.Context, promptIDs []int64, maxTokens int, onToken func(id int64) error) ([]int64, error) {
+	// O onnxruntime_go não aceita tensores com dimensão 0, então o cache
+	// começa com uma posição fictícia que fica mascarada na attention_mask
+	past, err := m.emptyCache()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { backend.DestroyAll(past) }()
+
+	mask := []int64{0}
+	ids := promptIDs
+	position := 0
+	var generated []int64
+
+	for i := 0; i < maxTokens; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		mask = append(mask, ones(len(ids))...)
+		positions := arange(position, len(ids))
+
+		logits, err := m.step(ids, mask, positions, past)
+		if err != nil {
+			return nil, err
+		}
+		position += len(ids)
+
+		nextToken, err := m.sampleNextToken(logits)
+		if err != nil {
+			return nil, err
+		}
+		if m.isStop(nextToken) {
+			break
+		}
+
+		generated = append(generated, nextToken)
+		if onToken != nil {
+			if err := onToken(nextToken); err != nil {
+				return nil, err
+			}
+		}
+		if m.constraint != nil && m.constraint.done() {
+			break
+		}
+		ids = []int64{nextToken}
+	}
+
+	return generated, nil
+}
+
+// step executa uma inferência e retorna os logits da última posição. Com
+// KV-cache, past é substituído no lugar pelas saídas present.
+func (m *Model) step(ids, mask, positions []int64, past []backend.Tensor) ([]float32, error) {
+	inputs := make([]backend.Tensor, len(m.io.inputs))
+
+	var owned []backend.Tensor
+	defer func() { backend.DestroyAll(owned) }()
+
+	newInput := func(idx int, data []int64) error {
+		if idx < 0 {
+			return nil
+		}
+		t, err := m.backend.NewTensor(backend.Int64, backend.Shape{1, int64(len(data))}, data)
+		if err != nil {
+			return err
+		}
+		owned = append(owned, t)
+		inputs[idx] = t
+		return nil
+	}
+
+	if err := newInput(m.io.inputIDs, ids); err != nil {
+		return nil, err
+	}
+	if err := newInput(m.io.attentionMask, mask); err != nil {
+		return nil, err
+	}
+	if err := newInput(m.io.positionIDs, positions); err != nil {
+		return nil, err
+	}
+	if m.io.useCacheFlag >= 0 {
+		flag, err := m.backend.NewTensor(backend.Bool, backend.Shape{1}, []byte{1})
+		if err != nil {
+			return nil, err
+		}
+		owned = append(owned, flag)
+		inputs[m.io.useCacheFlag] = flag
+	}
+	for i, idx := range m.io.past {
+		inputs[idx] = past[i]
+	}
+
+	outputs, err := m.session.Run(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("erro na inferência: %w", err)
+	}
+
+	// Troca o cache antigo pelo novo; o restante das saídas é liberado
+	for i, idx := range m.io.present {
+		past[i].Destroy()
+		past[i] = outputs[idx]
+		outputs[idx] = nil
+	}
+	for _, out := range outputs {
+		if out != nil {
+			owned = append(owned, out)
+		}
+	}
+
+	return lastLogits(outputs[m.io.logits])
+}
+
+// emptyCache cria o cache inicial com uma única posição zerada
+func (m *Model) emptyCache() ([]backend.Tensor, error) {
+	shape := m.io.pastShape.Clone()
+	shape[0], shape[2] = 1, 1
+
+	past := make([]backend.Tensor, 0, len(m.io.past))
+	for range m.io.past {
+		var (
+			t   backend.Tensor
+			err error
+		)
+		if m.io.pastType == backend.Float16 {
+			t, err = m.backend.NewTensor(backend.Float16, shape, make([]byte, 2*shape.Size()))
+		} else {
+			t, err = m.backend.NewTensor(backend.Float32, shape, make([]float32, shape.Size()))
+		}
+		if err != nil {
+			backend.DestroyAll(past)
+			return nil, fmt.Errorf("erro ao criar KV-cache: %w", err)
+		}
+		past = append(past, t)
+	}
+	return past, nil
+}
+
+// lastLogits extrai os logits da última posição de [batch, seq, vocab]
+func lastLogits(t backend.Tensor) ([]float32, error) {
+	shape := t.Shape()
+	vocab := int(shape[len(shape)-1])
+
+	var data []float32
+	switch t.Type() {
+	case backend.Float32:
+		data = t.Data().([]float32)
+	case backend.Float16:
+		raw := t.Data().([]byte)
+		if len(raw) < 2*vocab {
+			return nil, fmt.Errorf("logits vazios")
+		}
+		raw = raw[len(raw)-2*vocab:]
+		data = make([]float32, vocab)
+		for i := range data {
+			data[i] = float16ToFloat32(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("tipo de logits não suportado")
+	}
+
+	if len(data) < vocab {
+		return nil, fmt.Errorf("logits vazios")
+	}
+	out := make([]float32, vocab)
+	copy(out, data[len(data)-vocab:])
+	return out, nil
+}
+
+// float16ToFloat32 converte meia precisão IEEE 754
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+
+	switch {
+	case exp == 0:
+		// Zero ou subnormal
+		v := float32(math.Ldexp(float64(frac), -24))
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
+
+func ones(n int) []int64 {
+	out := make([]int64, n)
+	for i := range out {
+		out[i] = 1
+	}
+	return out
+}
+
+func arange(start, n int) []int64 {
+	out := make([]int64, n)
+	for i := range out {
+		out[i] = int64(start + i)
+	}
+	return out
+}
@@ -0,0 +1,294 @@
+package llm
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// nextLetter "modelo de linguagem" de teste: o próximo token depende da
+// sequência inteira, então um cache errado muda a saída
+func nextLetter(seq []int64) int64 {
+	var sum int64
+	for _, id := range seq {
+		sum += id
+	}
+	return 'a' + sum%26
+}
+
+// fullDecoder decoder sem KV-cache: recebe a sequência toda a cada passo
+func fullDecoder(inputs []backend.TensorInfo) *fake.Model {
+	return &fake.Model{
+		Inputs: inputs,
+		Outputs: []backend.TensorInfo{
+			{Name: "logits", Type: backend.Float32, Shape: backend.Shape{-1, -1, vocabSize}},
+		},
+		Run: func(in []backend.Tensor) ([]backend.Tensor, error) {
+			if len(in) != 2 {
+				return nil, fmt.Errorf("%d entradas, esperadas input_ids e attention_mask", len(in))
+			}
+			ids := in[0].Data().([]int64)
+			logits, err := logitsTensor(backend.Float32, len(ids), nextLetter(ids))
+			if err != nil {
+				return nil, err
+			}
+			return []backend.Tensor{logits}, nil
+		},
+	}
+}
+
+// cachedDecoder decoder com uma camada de KV-cache em dt. O "cache" guarda o
+// próprio ID de cada posição, e a sequência vista pelo modelo é a das
+// posições válidas do cache mais os IDs novos. steps registra quantos IDs
+// chegaram em cada passo.
+func cachedDecoder(dt backend.DataType, steps *[]int) *fake.Model {
+	cacheShape := backend.Shape{-1, 1, -1, 1}
+	return &fake.Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "attention_mask", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "position_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+			{Name: "past_key_values.0.key", Type: dt, Shape: cacheShape},
+			{Name: "past_key_values.0.value", Type: dt, Shape: cacheShape},
+		},
+		Outputs: []backend.TensorInfo{
+			{Name: "logits", Type: dt, Shape: backend.Shape{-1, -1, vocabSize}},
+			{Name: "present.0.key", Type: dt, Shape: cacheShape},
+			{Name: "present.0.value", Type: dt, Shape: cacheShape},
+		},
+		Run: func(in []backend.Tensor) ([]backend.Tensor, error) {
+			ids := in[0].Data().([]int64)
+			mask := in[1].Data().([]int64)
+			positions := in[2].Data().([]int64)
+			past := cacheValues(in[3])
+			*steps = append(*steps, len(ids))
+
+			if len(mask) != len(past)+len(ids) {
+				return nil, fmt.Errorf("attention_mask com %d posições, esperadas %d", len(mask), len(past)+len(ids))
+			}
+			var seq []int64
+			for i, id := range past {
+				if mask[i] == 1 {
+					seq = append(seq, id)
+				}
+			}
+			for i := range ids {
+				if positions[i] != int64(len(seq)+i) {
+					return nil, fmt.Errorf("position_ids = %v com %d posições no cache", positions, len(seq))
+				}
+			}
+			seq = append(seq, ids...)
+
+			present := append(past, ids...)
+			logits, err := logitsTensor(dt, len(ids), nextLetter(seq))
+			if err != nil {
+				return nil, err
+			}
+			key, err := cacheTensor(dt, present)
+			if err != nil {
+				return nil, err
+			}
+			value, err := cacheTensor(dt, present)
+			if err != nil {
+				return nil, err
+			}
+			return []backend.Tensor{logits, key, value}, nil
+		},
+	}
+}
+
+func TestDecodeCachedMatchesFull(t *testing.T) {
+	const maxTokens = 12
+
+	full := newTestModel(t, fake.New(), fullDecoder([]backend.TensorInfo{
+		{Name: "input_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+		{Name: "attention_mask", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+	}), func(cfg *config.ModelConfig) { cfg.MaxTokens = maxTokens })
+	if full.io.usesCache() {
+		t.Fatal("modelo sem past_key_values não deveria usar o cache")
+	}
+	want, err := full.Generate(context.Background(), "Conta uma história")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != maxTokens {
+		t.Fatalf("Generate() sem cache = %q, want %d letras", want, maxTokens)
+	}
+
+	for _, dt := range []backend.DataType{backend.Float32, backend.Float16} {
+		t.Run(dt.String(), func(t *testing.T) {
+			var steps []int
+			cached := newTestModel(t, fake.New(), cachedDecoder(dt, &steps), func(cfg *config.ModelConfig) {
+				cfg.MaxTokens = maxTokens
+			})
+			if !cached.io.usesCache() {
+				t.Fatal("modelo com past_key_values/present deveria usar o cache")
+			}
+
+			got, err := cached.Generate(context.Background(), "Conta uma história")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("Generate() com cache = %q, want %q (sem cache)", got, want)
+			}
+
+			// O prompt entra uma vez; depois, um token por passo
+			if len(steps) != maxTokens || steps[0] < 2 {
+				t.Fatalf("passos = %v", steps)
+			}
+			for i, n := range steps[1:] {
+				if n != 1 {
+					t.Errorf("passo %d recebeu %d tokens, want 1", i+1, n)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFallbackWithoutPresent(t *testing.T) {
+	// Exportado com past_key_values mas sem as saídas present: o cache não
+	// tem como avançar, então recalcula a sequência inteira
+	be := fake.New()
+	m := newTestModel(t, be, fullDecoder([]backend.TensorInfo{
+		{Name: "input_ids", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+		{Name: "attention_mask", Type: backend.Int64, Shape: backend.Shape{-1, -1}},
+		{Name: "past_key_values.0.key", Type: backend.Float32, Shape: backend.Shape{-1, 1, -1, 1}},
+	}), func(cfg *config.ModelConfig) { cfg.MaxTokens = 4 })
+
+	if m.io.usesCache() {
+		t.Fatal("sem present.* o modelo não deveria usar o cache")
+	}
+	got, err := m.Generate(context.Background(), "oi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || be.Runs("model.onnx") != 4 {
+		t.Errorf("Generate() = %q em %d execuções, want 4 letras em 4", got, be.Runs("model.onnx"))
+	}
+}
+
+func TestFloat16ToFloat32(t *testing.T) {
+	tests := []struct {
+		bits uint16
+		want float32
+	}{
+		{0x0000, 0},
+		{0x3C00, 1},
+		{0xC000, -2},
+		{0x3555, 0.333251953125},
+		{0x7BFF, 65504},                           // maior normal
+		{0x0400, float32(math.Ldexp(1, -14))},     // menor normal
+		{0x0001, float32(math.Ldexp(1, -24))},     // menor subnormal
+		{0x83FF, -float32(math.Ldexp(1023, -24))}, // subnormal negativo
+		{0x7C00, float32(math.Inf(1))},
+		{0xFC00, float32(math.Inf(-1))},
+	}
+	for _, tt := range tests {
+		if got := float16ToFloat32(tt.bits); got != tt.want {
+			t.Errorf("float16ToFloat32(%#04x) = %g, want %g", tt.bits, got, tt.want)
+		}
+	}
+
+	if got := float16ToFloat32(0x8000); got != 0 || !math.Signbit(float64(got)) {
+		t.Errorf("float16ToFloat32(0x8000) = %g, want -0", got)
+	}
+	if got := float16ToFloat32(0x7E00); !math.IsNaN(float64(got)) {
+		t.Errorf("float16ToFloat32(0x7E00) = %g, want NaN", got)
+	}
+
+	// Todo float16 finito sobrevive à ida e volta
+	for h := 0; h < 0x10000; h++ {
+		if h&0x7C00 == 0x7C00 {
+			continue
+		}
+		if back := toFloat16(float16ToFloat32(uint16(h))); back != uint16(h) {
+			t.Fatalf("%#04x → %g → %#04x", h, float16ToFloat32(uint16(h)), back)
+		}
+	}
+}
+
+func TestLastLogitsFloat16(t *testing.T) {
+	logits, err := logitsTensor(backend.Float16, 3, 'z')
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, err := lastLogits(logits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(row) != vocabSize || row['z'] != fake.Peak || row['a'] != 0 {
+		t.Errorf("lastLogits() = %d logits, [z] = %g, [a] = %g", len(row), row['z'], row['a'])
+	}
+}
+
+// logitsTensor logits [1, seq, vocab] com o pico em id na última posição
+func logitsTensor(dt backend.DataType, seq int, id int64) (backend.Tensor, error) {
+	row := fake.OneHot(vocabSize, id)
+	data := make([]float32, seq*vocabSize)
+	copy(data[(seq-1)*vocabSize:], row)
+	return floatTensor(dt, backend.Shape{1, int64(seq), vocabSize}, data)
+}
+
+// cacheTensor cache [1, 1, seq, 1] com um valor por posição
+func cacheTensor(dt backend.DataType, ids []int64) (backend.Tensor, error) {
+	data := make([]float32, len(ids))
+	for i, id := range ids {
+		data[i] = float32(id)
+	}
+	return floatTensor(dt, backend.Shape{1, 1, int64(len(ids)), 1}, data)
+}
+
+// cacheValues lê de volta os IDs guardados no cache
+func cacheValues(t backend.Tensor) []int64 {
+	var values []float32
+	if t.Type() == backend.Float16 {
+		raw := t.Data().([]byte)
+		for i := 0; i < len(raw); i += 2 {
+			values = append(values, float16ToFloat32(binary.LittleEndian.Uint16(raw[i:])))
+		}
+	} else {
+		values = t.Data().([]float32)
+	}
+
+	ids := make([]int64, len(values))
+	for i, v := range values {
+		ids[i] = int64(v)
+	}
+	return ids
+}
+
+func floatTensor(dt backend.DataType, shape backend.Shape, data []float32) (backend.Tensor, error) {
+	if dt != backend.Float16 {
+		return backend.NewHostTensor(dt, shape, data)
+	}
+	raw := make([]byte, 2*len(data))
+	for i, v := range data {
+		binary.LittleEndian.PutUint16(raw[2*i:], toFloat16(v))
+	}
+	return backend.NewHostTensor(dt, shape, raw)
+}
+
+// toFloat16 converte para meia precisão valores representáveis exatamente
+// (inteiros pequenos, o próprio float16 de volta)
+func toFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	frac := bits & 0x7fffff
+
+	switch {
+	case f == 0:
+		return sign
+	case exp < -14:
+		// Subnormal: mantissa com o 1 implícito, deslocada
+		return sign | uint16((frac|0x800000)>>uint(-exp-14+13))
+	}
+	return sign | uint16(exp+15)<<10 | uint16(frac>>13)
+}
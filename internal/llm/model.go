@@ -7,9 +7,12 @@ import (
 	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	ort "github.com/yalue/onnxruntime_go"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/grammar"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
@@ -27,35 +30,39 @@ type SamplingParams struct {
 
 // Model representa um modelo de linguagem
 type Model struct {
-	session       *ort.DynamicAdvancedSession
+	backend       backend.Backend
+	session       backend.Session
+	io            *decoderIO
 	config        config.ModelConfig
 	tokenizer     *Tokenizer
+	template      *chat.Template
+	stopIDs       map[int64]bool // EOS e tokens de parada do template
 	systemPrompt  string
 	sampling      SamplingParams
 	generatedIDs  []int64 // Para aplicar repetition penalty
-}
 
-// New cria um novo modelo LLM
-func New(cfg config.ModelConfig) (*Model, error) {
-	// Configura opções ONNX
-	options, err := ort.NewSessionOptions()
-	if err != nil {
-		return nil, err
-	}
+	// Uma geração por vez: sessão, sampling e restrição são estado do modelo
+	genMu sync.Mutex
 
-	// Usa DirectML para NPU AMD
-	err = options.AppendExecutionProviderDirectML(0)
+	// Decodificação restrita (GenerateWithGrammar)
+	constraint *constraint
+	vocab      *vocabulary
+	vocabOnce  sync.Once
+	grammars   map[string]*grammar.Grammar
+	grammarMu  sync.Mutex
+}
+
+// New cria um novo modelo LLM executado pelo backend
+func New(be backend.Backend, cfg config.ModelConfig) (*Model, error) {
+	// Detecta entradas/saídas (KV-cache se o modelo exporta past_key_values)
+	io, err := inspectDecoder(be, cfg.Path)
 	if err != nil {
-		fmt.Printf("DirectML não disponível para %s, usando CPU\n", cfg.Name)
+		fmt.Printf("%s: KV-cache indisponível (%v), recalculando sequência completa\n", cfg.Name, err)
+		io = defaultDecoderIO()
 	}
 
 	// Carrega modelo
-	session, err := ort.NewDynamicAdvancedSession(
-		cfg.Path,
-		[]string{"input_ids", "attention_mask"},
-		[]string{"logits"},
-		options,
-	)
+	session, err := be.Open(cfg.Path, io.inputs, io.outputs)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao carregar %s: %w", cfg.Name, err)
 	}
@@ -63,9 +70,24 @@ func New(cfg config.ModelConfig) (*Model, error) {
 	// Carrega tokenizer
 	tokenizer, err := NewTokenizer(cfg.TokenizerPath)
 	if err != nil {
+		session.Close()
 		return nil, fmt.Errorf("erro ao carregar tokenizer: %w", err)
 	}
 
+	// Formato de prompt da família do modelo
+	template, err := chat.Resolve(cfg)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	stopIDs := map[int64]bool{tokenizer.EOSToken(): true}
+	for _, tok := range template.StopTokens {
+		if id := tokenizer.lookup(tok); id >= 0 {
+			stopIDs[id] = true
+		}
+	}
+
 	// Configura sampling baseado na temperatura do config
 	sampling := SamplingParams{
 		Temperature:       cfg.Temperature,
@@ -85,75 +107,55 @@ func New(cfg config.ModelConfig) (*Model, error) {
 		sampling.TopP = 0.98
 	}
 
+	systemPrompt := cfg.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = "Você é um assistente IA útil que responde em português brasileiro de forma concisa."
+	}
+
 	return &Model{
+		backend:      be,
 		session:      session,
+		io:           io,
 		config:       cfg,
 		tokenizer:    tokenizer,
-		systemPrompt: cfg.SystemPrompt,
+		template:     template,
+		stopIDs:      stopIDs,
+		systemPrompt: systemPrompt,
 		sampling:     sampling,
 	}, nil
 }
 
 // Generate gera texto a partir do prompt
 func (m *Model) Generate(ctx context.Context, prompt string) (string, error) {
-	// Limpa histórico de geração anterior
-	m.generatedIDs = nil
+	return m.GenerateChat(ctx, m.buildMessages(prompt), nil)
+}
 
-	// Monta prompt completo
-	fullPrompt := m.buildPrompt(prompt)
+// GenerateChat gera a próxima resposta do assistente para uma conversa de
+// vários turnos. Se onToken não for nil, recebe o texto conforme é gerado.
+// Chamadas concorrentes esperam a vez.
+func (m *Model) GenerateChat(ctx context.Context, messages []chat.Message, onToken TokenCallback) (string, error) {
+	m.genMu.Lock()
+	defer m.genMu.Unlock()
+	return m.generate(ctx, messages, onToken)
+}
 
-	// Tokeniza
-	inputIDs, attentionMask := m.tokenizer.Encode(fullPrompt)
+// generate gera a resposta; quem chama segura genMu
+func (m *Model) generate(ctx context.Context, messages []chat.Message, onToken TokenCallback) (string, error) {
+	// Limpa histórico de geração anterior
+	m.generatedIDs = nil
 
-	// Prepara tensores
-	inputShape := ort.NewShape(1, int64(len(inputIDs)))
+	// Monta prompt no formato do modelo e tokeniza
+	inputIDs, _ := m.tokenizer.Encode(m.template.Render(messages, true))
 
-	inputTensor, err := ort.NewTensor(inputShape, inputIDs)
-	if err != nil {
-		return "", err
+	// Geração autoregressiva
+	var push func(id int64) error
+	if onToken != nil {
+		push = (&textStream{tokenizer: m.tokenizer, onText: onToken}).push
 	}
-	defer inputTensor.Destroy()
-
-	maskTensor, err := ort.NewTensor(inputShape, attentionMask)
+	generatedIDs, err := m.decode(ctx, inputIDs, m.config.MaxTokens, push)
 	if err != nil {
 		return "", err
 	}
-	defer maskTensor.Destroy()
-
-	// Geração autoregressiva
-	var generatedIDs []int64
-	maxTokens := m.config.MaxTokens
-
-	for i := 0; i < maxTokens; i++ {
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		default:
-		}
-
-		// Executa inferência
-		outputs, err := m.session.Run(map[string]*ort.Tensor[int64]{
-			"input_ids":      inputTensor,
-			"attention_mask": maskTensor,
-		})
-		if err != nil {
-			return "", err
-		}
-
-		// Pega próximo token (sampling)
-		nextToken := m.sampleNextToken(outputs["logits"])
-
-		// Verifica EOS
-		if nextToken == m.tokenizer.EOSToken() {
-			break
-		}
-
-		generatedIDs = append(generatedIDs, nextToken)
-
-		// Atualiza input para próxima iteração
-		inputIDs = append(inputIDs, nextToken)
-		attentionMask = append(attentionMask, 1)
-	}
 
 	// Decodifica
 	result := m.tokenizer.Decode(generatedIDs)
@@ -161,56 +163,53 @@ func (m *Model) Generate(ctx context.Context, prompt string) (string, error) {
 	return strings.TrimSpace(result), nil
 }
 
-// GenerateAction gera uma ação estruturada
-func (m *Model) GenerateAction(ctx context.Context, prompt string) (string, error) {
-	actionPrompt := fmt.Sprintf(`Você é um assistente que executa ações no computador.
-Dado o comando do usuário, retorne APENAS o JSON da ação, sem explicações.
-
-Formato:
-{"action": "tipo_acao", "params": {"param1": "valor1"}}
-
-Ações disponíveis:
-- open_app: abre aplicativo {"app": "nome"}
-- open_url: abre URL {"url": "endereco"}
-- type_text: digita texto {"text": "texto"}
-- read_email: lê emails {}
-- send_email: envia email {"to": "email", "subject": "assunto", "body": "corpo"}
-- volume: ajusta volume {"level": 50}
-- screenshot: captura tela {}
-
-Comando: %s
-
-JSON:`, prompt)
+// buildMessages monta a conversa de um turno com a system message
+func (m *Model) buildMessages(userPrompt string) []chat.Message {
+	return []chat.Message{
+		{Role: chat.RoleSystem, Content: m.systemPrompt},
+		{Role: chat.RoleUser, Content: userPrompt},
+	}
+}
 
-	return m.Generate(ctx, actionPrompt)
+// isStop indica se o token encerra a resposta
+func (m *Model) isStop(id int64) bool {
+	return m.stopIDs[id]
 }
 
-// buildPrompt monta o prompt completo com system message
-func (m *Model) buildPrompt(userPrompt string) string {
-	if m.systemPrompt == "" {
-		m.systemPrompt = "Você é um assistente IA útil que responde em português brasileiro de forma concisa."
+// sampleNextToken faz sampling do próximo token com temperature, top-k e top-p.
+// Com gramática ativa, se o token amostrado a violar, refaz o sampling só
+// entre os tokens permitidos; sem nenhum permitido, é erro.
+func (m *Model) sampleNextToken(logits []float32) (int64, error) {
+	if len(logits) == 0 {
+		return m.tokenizer.EOSToken(), nil
+	}
+	if m.constraint == nil {
+		return m.sample(logits), nil
 	}
 
-	return fmt.Sprintf(`<|system|>
-%s
-<|end|>
-<|user|>
-%s
-<|end|>
-<|assistant|>
-`, m.systemPrompt, userPrompt)
-}
+	original := make([]float32, len(logits))
+	copy(original, logits)
+	history := len(m.generatedIDs)
 
-// sampleNextToken faz sampling do próximo token com temperature, top-k e top-p
-func (m *Model) sampleNextToken(logits *ort.Tensor[float32]) int64 {
-	data := logits.GetData()
-	if len(data) == 0 {
-		return m.tokenizer.EOSToken()
+	token := m.sample(logits)
+	if !m.constraint.accepts(token) {
+		m.generatedIDs = m.generatedIDs[:history]
+		if !m.constraint.mask(original) {
+			return 0, fmt.Errorf("nenhum token do vocabulário continua a saída na gramática")
+		}
+		token = m.sample(original)
 	}
+	if m.constraint.scored {
+		m.constraint.mask(original)
+		m.constraint.logProb += math.Log(float64(m.softmax(original)[token]))
+	}
+	m.constraint.advance(token)
+
+	return token, nil
+}
 
-	// Copia para não modificar original
-	logitsCopy := make([]float32, len(data))
-	copy(logitsCopy, data)
+// sample aplica penalidade, temperature, top-k e top-p e amostra um token
+func (m *Model) sample(logitsCopy []float32) int64 {
 
 	// Aplica repetition penalty
 	if m.sampling.RepetitionPenalty != 1.0 && len(m.generatedIDs) > 0 {
@@ -437,7 +436,7 @@ func (m *Model) SetSamplingParams(params SamplingParams) {
 // Close libera recursos
 func (m *Model) Close() error {
 	if m.session != nil {
-		return m.session.Destroy()
+		return m.session.Close()
 	}
 	return nil
 }
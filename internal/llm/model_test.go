@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestGenerateChatStops(t *testing.T) {
+	// "<|end|>" fecha o turno do Phi-3 antes do EOS do tokenizer
+	script := []int64{'o', 'k', endID, 'x', eosID}
+
+	be := fake.New()
+	m := newTestModel(t, be, fake.Tokens(vocabSize, script...), nil)
+
+	got, err := m.GenerateChat(context.Background(), m.buildMessages("oi"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "ok" {
+		t.Errorf("GenerateChat() = %q, want \"ok\"", got)
+	}
+}
+
+// IDs do tokenizer de teste: 0-255 são os bytes; depois vêm os especiais
+const (
+	eosID     = 256 // <|endoftext|>
+	endID     = 257 // <|end|> (Phi-3)
+	vocabSize = 258
+)
+
+// newTestModel abre um modelo Phi-3 sobre o roteiro, com o tokenizer
+// byte-level de teste e sampling guloso. edit ajusta o config.
+func newTestModel(t *testing.T, be *fake.Backend, script *fake.Model, edit func(*config.ModelConfig)) *Model {
+	t.Helper()
+	be.Add("model.onnx", script)
+
+	cfg := config.ModelConfig{
+		Name:          "phi-teste",
+		Path:          "model.onnx",
+		TokenizerPath: fake.ByteLevelTokenizer(t, "<|endoftext|>", "<|end|>"),
+		ChatTemplate:  "phi3",
+		MaxTokens:     64,
+	}
+	if edit != nil {
+		edit(&cfg)
+	}
+
+	m, err := New(be, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
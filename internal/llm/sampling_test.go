@@ -0,0 +1,237 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+var negInf = float32(math.Inf(-1))
+
+func TestGreedySample(t *testing.T) {
+	tests := []struct {
+		name   string
+		logits []float32
+		want   int64
+	}{
+		{"primeiro", []float32{3, 1, 2}, 0},
+		{"meio", []float32{-1, 5, 2}, 1},
+		{"último", []float32{0, 0, 0.5}, 2},
+		{"negativos", []float32{-3, -2, -9}, 1},
+		{"empate fica com o primeiro", []float32{1, 4, 4}, 1},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.greedySample(tt.logits); got != tt.want {
+				t.Errorf("greedySample(%v) = %d, want %d", tt.logits, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTemperature(t *testing.T) {
+	tests := []struct {
+		name        string
+		logits      []float32
+		temperature float32
+		want        []float32
+	}{
+		{"neutra", []float32{1, -2, 4}, 1, []float32{1, -2, 4}},
+		{"fria aguça", []float32{1, -2, 4}, 0.5, []float32{2, -4, 8}},
+		{"quente achata", []float32{1, -2, 4}, 2, []float32{0.5, -1, 2}},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logits := append([]float32(nil), tt.logits...)
+			m.applyTemperature(logits, tt.temperature)
+			assertLogits(t, logits, tt.want)
+		})
+	}
+}
+
+func TestApplyRepetitionPenalty(t *testing.T) {
+	tests := []struct {
+		name      string
+		logits    []float32
+		generated []int64
+		penalty   float32
+		want      []float32
+	}{
+		{"positivo é dividido", []float32{4, 1}, []int64{0}, 2, []float32{2, 1}},
+		{"negativo é multiplicado", []float32{-4, 1}, []int64{0}, 2, []float32{-8, 1}},
+		{"repetido penaliza uma vez", []float32{4, 1}, []int64{0, 0, 0}, 2, []float32{2, 1}},
+		{"id fora do vocabulário", []float32{4, 1}, []int64{7}, 2, []float32{4, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Model{
+				sampling:     SamplingParams{RepetitionPenalty: tt.penalty},
+				generatedIDs: tt.generated,
+			}
+			logits := append([]float32(nil), tt.logits...)
+			m.applyRepetitionPenalty(logits)
+			assertLogits(t, logits, tt.want)
+		})
+	}
+}
+
+func TestApplyTopK(t *testing.T) {
+	tests := []struct {
+		name   string
+		logits []float32
+		k      int
+		want   []float32
+	}{
+		{"mantém os k maiores", []float32{1, 5, 3, 4}, 2, []float32{negInf, 5, negInf, 4}},
+		{"k = 1", []float32{1, 5, 3, 4}, 1, []float32{negInf, 5, negInf, negInf}},
+		{"k maior que o vocabulário", []float32{1, 5, 3}, 10, []float32{1, 5, 3}},
+		{"empates no limite ficam", []float32{2, 2, 1}, 1, []float32{2, 2, negInf}},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logits := append([]float32(nil), tt.logits...)
+			m.applyTopK(logits, tt.k)
+			assertLogits(t, logits, tt.want)
+		})
+	}
+}
+
+func TestApplyTopP(t *testing.T) {
+	// ln das probabilidades 0.6, 0.3, 0.1
+	probs := []float32{
+		float32(math.Log(0.6)),
+		float32(math.Log(0.3)),
+		float32(math.Log(0.1)),
+	}
+
+	tests := []struct {
+		name   string
+		logits []float32
+		p      float32
+		want   []bool // posições mantidas
+	}{
+		{"só o mais provável", probs, 0.5, []bool{true, false, false}},
+		{"dois primeiros", probs, 0.8, []bool{true, true, false}},
+		{"todos", probs, 0.99, []bool{true, true, true}},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logits := append([]float32(nil), tt.logits...)
+			m.applyTopP(logits, tt.p)
+			for i, keep := range tt.want {
+				if kept := !math.IsInf(float64(logits[i]), -1); kept != keep {
+					t.Errorf("posição %d mantida = %v, want %v (logits %v)", i, kept, keep, logits)
+				}
+			}
+		})
+	}
+}
+
+func TestSoftmax(t *testing.T) {
+	tests := []struct {
+		name   string
+		logits []float32
+		want   []float32
+	}{
+		{"uniforme", []float32{1, 1, 1, 1}, []float32{0.25, 0.25, 0.25, 0.25}},
+		{"ln 3 e 1", []float32{float32(math.Log(3)), 0}, []float32{0.75, 0.25}},
+		{"-inf vira zero", []float32{0, negInf, 0}, []float32{0.5, 0, 0.5}},
+		{"valores grandes estáveis", []float32{1000, 1000}, []float32{0.5, 0.5}},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertLogits(t, m.softmax(tt.logits), tt.want)
+		})
+	}
+}
+
+func TestSampleFromProbs(t *testing.T) {
+	tests := []struct {
+		name  string
+		probs []float32
+		want  int64
+	}{
+		{"certeza", []float32{0, 1, 0}, 1},
+		{"certeza no último", []float32{0, 0, 1}, 2},
+		{"sem probabilidade retorna 0", []float32{0, 0, 0}, 0},
+	}
+
+	m := &Model{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := m.sampleFromProbs(tt.probs); got != tt.want {
+					t.Fatalf("sampleFromProbs(%v) = %d, want %d", tt.probs, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling SamplingParams
+		logits   []float32
+		want     int64
+	}{
+		{"greedy com temperatura zero", SamplingParams{Temperature: 0, RepetitionPenalty: 1}, []float32{1, 3, 2}, 1},
+		{"top-k 1 é determinístico", SamplingParams{Temperature: 1, TopK: 1, RepetitionPenalty: 1}, []float32{1, 3, 2}, 1},
+		{"top-p estreito é determinístico", SamplingParams{Temperature: 0.7, TopP: 0.1, RepetitionPenalty: 1}, []float32{1, 9, 2}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				m := &Model{sampling: tt.sampling}
+				logits := append([]float32(nil), tt.logits...)
+				if got := m.sample(logits); got != tt.want {
+					t.Fatalf("sample(%v) = %d, want %d", tt.logits, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleRecordsHistory(t *testing.T) {
+	m := &Model{sampling: SamplingParams{Temperature: 1, TopK: 1, RepetitionPenalty: 1.1}}
+	m.sample([]float32{0, 5})
+	m.sample([]float32{5, 0})
+
+	if len(m.generatedIDs) != 2 || m.generatedIDs[0] != 1 || m.generatedIDs[1] != 0 {
+		t.Errorf("generatedIDs = %v, want [1 0]", m.generatedIDs)
+	}
+
+	m.ResetGeneration()
+	if len(m.generatedIDs) != 0 {
+		t.Errorf("ResetGeneration não limpou o histórico: %v", m.generatedIDs)
+	}
+}
+
+func assertLogits(t *testing.T, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if math.IsInf(float64(want[i]), -1) {
+			if !math.IsInf(float64(got[i]), -1) {
+				t.Errorf("[%d] = %v, want -inf", i, got[i])
+			}
+			continue
+		}
+		if math.Abs(float64(got[i]-want[i])) > 1e-5 {
+			t.Errorf("[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
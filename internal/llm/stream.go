@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenCallback recebe cada trecho de texto gerado. Retornar erro interrompe
+// a geração.
+type TokenCallback func(token string) error
+
+// GenerateStream gera texto como Generate, mas entrega cada trecho ao callback
+// assim que é decodificado. Retorna o texto completo ao final.
+func (m *Model) GenerateStream(ctx context.Context, prompt string, onToken TokenCallback) (string, error) {
+	return m.GenerateChat(ctx, m.buildMessages(prompt), onToken)
+}
+
+// textStream converte IDs em trechos de texto. Tokens byte-level podem
+// carregar só parte de um caractere UTF-8, então o texto é redecodificado e
+// só o sufixo novo e completo é emitido.
+type textStream struct {
+	tokenizer *Tokenizer
+	onText    TokenCallback
+	ids       []int64
+	emitted   string
+}
+
+func (s *textStream) push(id int64) error {
+	s.ids = append(s.ids, id)
+	text := s.tokenizer.Decode(s.ids)
+
+	// Caractere incompleto: espera o próximo token
+	if r, _ := utf8.DecodeLastRuneInString(text); r == utf8.RuneError {
+		return nil
+	}
+	if !strings.HasPrefix(text, s.emitted) {
+		return nil
+	}
+
+	delta := text[len(s.emitted):]
+	if s.emitted == "" {
+		delta = strings.TrimLeft(delta, " \n")
+	}
+	if delta == "" {
+		return nil
+	}
+	s.emitted = text
+	return s.onText(delta)
+}
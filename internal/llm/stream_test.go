@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+)
+
+func TestTextStream(t *testing.T) {
+	tk, err := NewTokenizer(fake.ByteLevelTokenizer(t, "<|endoftext|>", "<|end|>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []string
+	s := &textStream{tokenizer: tk, onText: func(text string) error {
+		chunks = append(chunks, text)
+		return nil
+	}}
+
+	// Espaço inicial some; "ã" chega em dois tokens e só sai inteiro
+	for _, b := range []byte(" \nSão 3.5") {
+		if err := s.push(int64(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"S", "ã", "o", " ", "3", ".", "5"}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("trechos = %q, want %q", chunks, want)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	errStop := errors.New("parou")
+
+	tests := []struct {
+		name     string
+		stopAt   int // trecho em que o callback interrompe (0 = nunca)
+		cancel   bool
+		wantErr  error
+		wantText []string
+		wantRuns int
+	}{
+		{"completo", 0, false, nil, []string{"O", "l", "á", "!"}, 6}, // "á" ocupa dois tokens, mais o EOS
+		{"callback interrompe", 2, false, errStop, []string{"O", "l"}, 2},
+		{"contexto cancelado", 1, true, context.Canceled, []string{"O"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var script []int64
+			for _, b := range []byte("Olá!") {
+				script = append(script, int64(b))
+			}
+			script = append(script, eosID)
+
+			be := fake.New()
+			m := newTestModel(t, be, fake.Tokens(vocabSize, script...), nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var chunks []string
+			result, err := m.GenerateStream(ctx, "oi", func(text string) error {
+				chunks = append(chunks, text)
+				if len(chunks) == tt.stopAt {
+					if tt.cancel {
+						cancel()
+						return nil
+					}
+					return errStop
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateStream() erro = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && result != "Olá!" {
+				t.Errorf("GenerateStream() = %q, want \"Olá!\"", result)
+			}
+			if !reflect.DeepEqual(chunks, tt.wantText) {
+				t.Errorf("trechos = %q, want %q", chunks, tt.wantText)
+			}
+			if runs := be.Runs("model.onnx"); runs != tt.wantRuns {
+				t.Errorf("%d passos de decodificação, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
@@ -1,83 +1,108 @@
 package llm
 
 import (
-	"encoding/json"
-	"os"
+	"fmt"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tokenizer"
 )
 
+// eosCandidates nomes usuais do token de fim de sequência, usados quando o
+// tokenizer_config.json não está disponível
+var eosCandidates = []string{"<|endoftext|>", "</s>", "<|eot_id|>", "<|im_end|>", "<|end|>", "<eos>"}
+
 // Tokenizer gerencia tokenização de texto
 type Tokenizer struct {
-	vocab     map[string]int64
-	vocabRev  map[int64]string
-	eosToken  int64
-	padToken  int64
-	bosToken  int64
+	tk       *tokenizer.Tokenizer
+	eosToken int64
+	padToken int64
+	bosToken int64
 }
 
-// NewTokenizer carrega um tokenizer
+// NewTokenizer carrega o tokenizer.json (formato HuggingFace) do modelo
 func NewTokenizer(path string) (*Tokenizer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("tokenizer_path não configurado")
+	}
+
+	tk, err := tokenizer.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
 	t := &Tokenizer{
-		vocab:    make(map[string]int64),
-		vocabRev: make(map[int64]string),
-		eosToken: 2,  // Default
-		padToken: 0,
-		bosToken: 1,
+		tk:       tk,
+		eosToken: -1,
+		padToken: -1,
+		bosToken: -1,
 	}
 
-	// Carrega vocabulário se existir
-	if path != "" {
-		data, err := os.ReadFile(path)
-		if err == nil {
-			var vocab map[string]int64
-			if err := json.Unmarshal(data, &vocab); err == nil {
-				t.vocab = vocab
-				for k, v := range vocab {
-					t.vocabRev[v] = k
-				}
+	// Tokens especiais do tokenizer_config.json, se existir
+	if cfg, err := tokenizer.LoadConfig(path); err == nil {
+		t.eosToken = t.lookup(cfg.EOSToken)
+		t.padToken = t.lookup(cfg.PADToken)
+		t.bosToken = t.lookup(cfg.BOSToken)
+	}
+
+	if t.eosToken < 0 {
+		for _, name := range eosCandidates {
+			if id := t.lookup(name); id >= 0 {
+				t.eosToken = id
+				break
 			}
 		}
 	}
+	if t.eosToken < 0 {
+		return nil, fmt.Errorf("token de fim de sequência não encontrado em %s", path)
+	}
+	if t.padToken < 0 {
+		t.padToken = t.eosToken
+	}
 
 	return t, nil
 }
 
-// Encode converte texto em tokens
+// lookup retorna o ID do token ou -1
+func (t *Tokenizer) lookup(token string) int64 {
+	if token == "" {
+		return -1
+	}
+	if id, ok := t.tk.TokenToID(token); ok {
+		return id
+	}
+	return -1
+}
+
+// Encode converte texto em tokens e máscara de atenção. Os tokens especiais
+// do modelo (BOS etc.) são adicionados pelo pós-processador do tokenizer.
 func (t *Tokenizer) Encode(text string) ([]int64, []int64) {
-	// TODO: Implementar tokenização real (BPE/SentencePiece)
-	// Por enquanto, tokenização simples por caractere
-
-	tokens := make([]int64, 0, len(text))
-	mask := make([]int64, 0, len(text))
-
-	// Adiciona BOS
-	tokens = append(tokens, t.bosToken)
-	mask = append(mask, 1)
-
-	for _, char := range text {
-		if id, ok := t.vocab[string(char)]; ok {
-			tokens = append(tokens, id)
-		} else {
-			// Token desconhecido
-			tokens = append(tokens, 3) // UNK
-		}
-		mask = append(mask, 1)
+	tokens := t.tk.Encode(text, true)
+
+	mask := make([]int64, len(tokens))
+	for i := range mask {
+		mask[i] = 1
 	}
 
 	return tokens, mask
 }
 
-// Decode converte tokens em texto
+// Decode converte tokens em texto, omitindo tokens especiais
 func (t *Tokenizer) Decode(tokens []int64) string {
-	result := ""
-	for _, token := range tokens {
-		if token == t.eosToken || token == t.padToken || token == t.bosToken {
-			continue
-		}
-		if str, ok := t.vocabRev[token]; ok {
-			result += str
-		}
+	return t.tk.Decode(tokens, true)
+}
+
+// Piece retorna os bytes do token no meio de um texto; false para tokens
+// especiais, que não fazem parte do texto gerado
+func (t *Tokenizer) Piece(id int64) ([]byte, bool) {
+	if t.tk.IsSpecial(id) {
+		return nil, false
 	}
-	return result
+	piece := t.tk.TokenBytes(id)
+	return piece, len(piece) > 0
+}
+
+// VocabSize retorna o tamanho do vocabulário
+func (t *Tokenizer) VocabSize() int {
+	return t.tk.VocabSize()
 }
 
 // EOSToken retorna o token de fim de sequência
@@ -89,3 +114,8 @@ func (t *Tokenizer) EOSToken() int64 {
 func (t *Tokenizer) PADToken() int64 {
 	return t.padToken
 }
+
+// BOSToken retorna o token de início de sequência (-1 se o modelo não usa)
+func (t *Tokenizer) BOSToken() int64 {
+	return t.bosToken
+}
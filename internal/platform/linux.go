@@ -0,0 +1,372 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// appAliases nomes falados em português para os termos dos arquivos .desktop
+var appAliases = map[string][]string{
+	"navegador":   {"browser", "web browser"},
+	"calculadora": {"calculator"},
+	"arquivos":    {"files", "file manager"},
+	"explorer":    {"files", "file manager"},
+	"bloco":       {"text editor"},
+	"notepad":     {"text editor"},
+	"vscode":      {"code"},
+	"edge":        {"microsoft-edge"},
+}
+
+// linux implementação com xdg-open, PipeWire/PulseAudio/ALSA e as ferramentas
+// de captura de Wayland e X11; a ferramenta de cada operação é a primeira
+// instalada
+type linux struct {
+	lookPath func(string) (string, error)
+	getenv   func(string) string
+	tools    map[string]string
+
+	// start inicia sem esperar; run espera e devolve a saída (stdin opcional)
+	start func(name string, args ...string) error
+	run   func(stdin []byte, name string, args ...string) ([]byte, error)
+}
+
+func newLinux(lookPath func(string) (string, error), getenv func(string) string) *linux {
+	l := &linux{lookPath: lookPath, getenv: getenv, tools: make(map[string]string), start: start, run: run}
+	first := func(op string, names ...string) {
+		for _, name := range names {
+			if _, err := lookPath(name); err == nil {
+				l.tools[op] = name
+				return
+			}
+		}
+	}
+
+	first("url", "xdg-open")
+	first("apps", "gtk-launch")
+	if l.tools["apps"] == "" {
+		l.tools["apps"] = "exec" // Linha Exec do .desktop
+	}
+	first("volume", "wpctl", "pactl", "amixer")
+	first("audio", "paplay", "pw-play", "aplay")
+
+	if getenv("WAYLAND_DISPLAY") != "" {
+		first("screenshot", "grim")
+	}
+	if l.tools["screenshot"] == "" && getenv("DISPLAY") != "" {
+		first("screenshot", "scrot")
+		if l.tools["screenshot"] == "" {
+			// xwd gera XWD; o convert do ImageMagick passa para PNG
+			if _, err := lookPath("convert"); err == nil {
+				first("screenshot", "xwd")
+			}
+		}
+	}
+	return l
+}
+
+func (l *linux) Name() string {
+	return "linux"
+}
+
+func (l *linux) Tools() map[string]string {
+	tools := make(map[string]string, len(l.tools))
+	for op, tool := range l.tools {
+		tools[op] = tool
+	}
+	return tools
+}
+
+// missing erro de operação sem ferramenta instalada
+func (l *linux) missing(op string, options string) error {
+	return fmt.Errorf("%w: %s precisa de %s", ErrUnsupported, op, options)
+}
+
+func (l *linux) OpenURL(url string) error {
+	if l.tools["url"] == "" {
+		return l.missing("abrir URL", "xdg-open")
+	}
+	return l.start("xdg-open", url)
+}
+
+// OpenApp procura o app nos arquivos .desktop (ID, nome, nome genérico e
+// palavras-chave). Sem entrada não procura no PATH: open_app roda sem
+// confirmação e abriria poweroff ou reboot como se fossem apps.
+func (l *linux) OpenApp(name string) error {
+	entry := findDesktopEntry(l.applicationDirs(), name)
+	if entry == nil {
+		return fmt.Errorf("app não encontrado: %s", name)
+	}
+
+	if l.tools["apps"] == "gtk-launch" {
+		return l.start("gtk-launch", entry.id)
+	}
+	argv, err := entry.command()
+	if err != nil {
+		return err
+	}
+	return l.start(argv[0], argv[1:]...)
+}
+
+// applicationDirs diretórios applications na ordem de prioridade do XDG
+func (l *linux) applicationDirs() []string {
+	home := l.getenv("HOME")
+	dataHome := l.getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dataDirs := l.getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	var dirs []string
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "applications"))
+		dirs = append(dirs, filepath.Join(dataHome, "flatpak", "exports", "share", "applications"))
+	}
+	for _, dir := range strings.Split(dataDirs, ":") {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "applications"))
+		}
+	}
+	return append(dirs, "/var/lib/flatpak/exports/share/applications")
+}
+
+func (l *linux) SetVolume(percent int) error {
+	percent = max(0, min(100, percent))
+	var err error
+	switch l.tools["volume"] {
+	case "wpctl":
+		_, err = l.run(nil, "wpctl", "set-volume", "@DEFAULT_AUDIO_SINK@", fmt.Sprintf("%.2f", float64(percent)/100))
+	case "pactl":
+		_, err = l.run(nil, "pactl", "set-sink-volume", "@DEFAULT_SINK@", fmt.Sprintf("%d%%", percent))
+	case "amixer":
+		_, err = l.run(nil, "amixer", "-q", "sset", "Master", fmt.Sprintf("%d%%", percent))
+	default:
+		return l.missing("volume", "wpctl, pactl ou amixer")
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao ajustar volume: %w", err)
+	}
+	return nil
+}
+
+func (l *linux) Screenshot() ([]byte, error) {
+	switch l.tools["screenshot"] {
+	case "grim":
+		return l.run(nil, "grim", "-t", "png", "-")
+	case "scrot":
+		// scrot só escreve em arquivo
+		dir, err := os.MkdirTemp("", "npu-ia-screen-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "tela.png")
+		if _, err := l.run(nil, "scrot", path); err != nil {
+			return nil, fmt.Errorf("erro ao capturar tela: %w", err)
+		}
+		return os.ReadFile(path)
+	case "xwd":
+		dump, err := l.run(nil, "xwd", "-root", "-silent")
+		if err != nil {
+			return nil, fmt.Errorf("erro ao capturar tela: %w", err)
+		}
+		return l.run(dump, "convert", "xwd:-", "png:-")
+	}
+	return nil, l.missing("captura de tela", "grim (Wayland), scrot ou xwd com convert (X11)")
+}
+
+func (l *linux) PlayCommand(path string) (*exec.Cmd, error) {
+	switch tool := l.tools["audio"]; tool {
+	case "paplay", "pw-play":
+		return exec.Command(tool, path), nil
+	case "aplay":
+		return exec.Command("aplay", "-q", path), nil
+	}
+	return nil, l.missing("áudio", "paplay, pw-play ou aplay")
+}
+
+// run executa e devolve a saída padrão; o stderr vai no erro
+func run(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// desktopEntry campos de [Desktop Entry] usados para achar e abrir o app
+type desktopEntry struct {
+	id       string // Nome do arquivo sem .desktop
+	names    []string
+	keywords []string
+	exec     string
+}
+
+// findDesktopEntry acha o app pelo nome: primeiro por ID ou nome (inclusive o
+// genérico) exato, depois por palavra-chave ou trecho do nome. Arquivos dos primeiros
+// diretórios escondem os de mesmo ID nos seguintes.
+func findDesktopEntry(dirs []string, name string) *desktopEntry {
+	query := strings.ToLower(strings.TrimSpace(name))
+	if query == "" {
+		return nil
+	}
+	terms := append([]string{query}, appAliases[query]...)
+
+	var entries []*desktopEntry
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.desktop"))
+		sort.Strings(files)
+		for _, file := range files {
+			id := strings.TrimSuffix(filepath.Base(file), ".desktop")
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if entry := parseDesktopEntry(file); entry != nil {
+				entry.id = id
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	for _, term := range terms {
+		for _, entry := range entries {
+			if strings.ToLower(entry.id) == term || strings.HasSuffix(strings.ToLower(entry.id), "."+term) {
+				return entry
+			}
+			for _, n := range entry.names {
+				if n == term {
+					return entry
+				}
+			}
+		}
+	}
+	for _, term := range terms {
+		for _, entry := range entries {
+			for _, keyword := range entry.keywords {
+				if keyword == term {
+					return entry
+				}
+			}
+			for _, n := range entry.names {
+				if strings.Contains(n, term) {
+					return entry
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseDesktopEntry lê um .desktop; nil se não é app visível
+func parseDesktopEntry(path string) *desktopEntry {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	entry := &desktopEntry{}
+	inEntry := false
+	application := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inEntry || !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "Type":
+			application = value == "Application"
+		case "NoDisplay", "Hidden":
+			if value == "true" {
+				return nil
+			}
+		case "Exec":
+			entry.exec = value
+		case "Name", "Name[pt]", "Name[pt_BR]", "GenericName", "GenericName[pt]", "GenericName[pt_BR]":
+			entry.names = append(entry.names, strings.ToLower(value))
+		case "Keywords", "Keywords[pt]", "Keywords[pt_BR]":
+			for _, keyword := range strings.Split(value, ";") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					entry.keywords = append(entry.keywords, strings.ToLower(keyword))
+				}
+			}
+		}
+	}
+	if !application || entry.exec == "" {
+		return nil
+	}
+	return entry
+}
+
+// command argumentos da linha Exec, sem os códigos de campo (%u, %F...) e sem
+// passar por shell
+func (e *desktopEntry) command() ([]string, error) {
+	var (
+		argv    []string
+		current strings.Builder
+		quoted  bool
+		escaped bool
+		inWord  bool
+	)
+	for _, c := range e.exec {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted, inWord = !quoted, true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inWord {
+				argv = append(argv, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("linha Exec inválida em %s.desktop", e.id)
+	}
+	if inWord {
+		argv = append(argv, current.String())
+	}
+
+	args := argv[:0]
+	for _, arg := range argv {
+		if len(arg) == 2 && arg[0] == '%' && arg[1] != '%' {
+			continue // %f, %U, %i... viram nada: abrimos sem arquivo
+		}
+		args = append(args, strings.ReplaceAll(arg, "%%", "%"))
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("linha Exec vazia em %s.desktop", e.id)
+	}
+	return args, nil
+}
@@ -0,0 +1,165 @@
+package platform
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestLinux implementação com as ferramentas e variáveis dadas, gravando os
+// comandos em vez de executá-los
+func newTestLinux(t *testing.T, tools []string, env map[string]string) (*linux, *[]string) {
+	t.Helper()
+	installed := make(map[string]bool)
+	for _, tool := range tools {
+		installed[tool] = true
+	}
+	lookPath := func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	l := newLinux(lookPath, func(key string) string { return env[key] })
+
+	var commands []string
+	l.start = func(name string, args ...string) error {
+		commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+	l.run = func(stdin []byte, name string, args ...string) ([]byte, error) {
+		commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		return []byte("png"), nil
+	}
+	return l, &commands
+}
+
+func TestLinuxTools(t *testing.T) {
+	tests := []struct {
+		tools []string
+		env   map[string]string
+		want  string
+	}{
+		{nil, nil, "linux (apps: exec)"},
+		{
+			[]string{"xdg-open", "gtk-launch", "wpctl", "pactl", "paplay", "aplay", "grim", "scrot"},
+			map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			"linux (apps: gtk-launch, audio: paplay, screenshot: grim, url: xdg-open, volume: wpctl)",
+		},
+		{
+			// X11 sem scrot cai no xwd, que precisa do convert
+			[]string{"pactl", "aplay", "grim", "xwd", "convert"},
+			map[string]string{"DISPLAY": ":0"},
+			"linux (apps: exec, audio: aplay, screenshot: xwd, volume: pactl)",
+		},
+		{[]string{"amixer", "xwd"}, map[string]string{"DISPLAY": ":0"}, "linux (apps: exec, volume: amixer)"},
+	}
+	for _, tt := range tests {
+		l, _ := newTestLinux(t, tt.tools, tt.env)
+		if got := Describe(l); got != tt.want {
+			t.Errorf("Describe(%v) = %q, want %q", tt.tools, got, tt.want)
+		}
+	}
+}
+
+func TestLinuxCommands(t *testing.T) {
+	l, commands := newTestLinux(t, []string{"xdg-open", "pactl", "xwd", "convert"}, map[string]string{"DISPLAY": ":0"})
+
+	if err := l.OpenURL("https://example.com/?a=1&b=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetVolume(140); err != nil {
+		t.Fatal(err)
+	}
+	if image, err := l.Screenshot(); err != nil || string(image) != "png" {
+		t.Fatalf("Screenshot = %q, %v", image, err)
+	}
+	want := []string{
+		"xdg-open https://example.com/?a=1&b=2",
+		"pactl set-sink-volume @DEFAULT_SINK@ 100%",
+		"xwd -root -silent",
+		"convert xwd:- png:-",
+	}
+	if got := strings.Join(*commands, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("comandos:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// Sem ferramenta a operação falha com ErrUnsupported
+	bare, _ := newTestLinux(t, nil, nil)
+	if _, err := bare.PlayCommand("a.wav"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PlayCommand sem player: erro = %v", err)
+	}
+	if _, err := bare.Screenshot(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Screenshot sem display: erro = %v", err)
+	}
+}
+
+func TestLinuxOpenApp(t *testing.T) {
+	home := t.TempDir()
+	system := t.TempDir()
+	write := func(dir, name, content string) {
+		apps := filepath.Join(dir, "applications")
+		if err := os.MkdirAll(apps, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(apps, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(system, "firefox.desktop", `[Desktop Entry]
+Type=Application
+Name=Firefox
+GenericName=Web Browser
+GenericName[pt_BR]=Navegador web
+Keywords=Internet;WWW;Browser;
+Exec=/usr/lib/firefox/firefox %u
+
+[Desktop Action new-window]
+Name=Nova janela
+Exec=/usr/lib/firefox/firefox --new-window %u
+`)
+	write(system, "org.gnome.Calculator.desktop", "[Desktop Entry]\nType=Application\nName=Calculator\nExec=gnome-calculator\n")
+	write(system, "code.desktop", "[Desktop Entry]\nType=Application\nName=Visual Studio Code\nExec=/usr/share/code/code --unity-launch %F\n")
+	write(system, "oculto.desktop", "[Desktop Entry]\nType=Application\nName=Oculto\nExec=oculto\n")
+	// A entrada do usuário esconde a do sistema
+	write(filepath.Join(home, ".local", "share"), "oculto.desktop", "[Desktop Entry]\nType=Application\nName=Oculto\nHidden=true\nExec=oculto\n")
+	write(system, "editor.desktop", "[Desktop Entry]\nType=Application\nName=Editor\nGenericName=Text Editor\nExec=sh -c \"editor \\\"$1\\\"\" 100%% %f\n")
+
+	l, commands := newTestLinux(t, []string{"xterm", "poweroff"}, map[string]string{"HOME": home, "XDG_DATA_DIRS": system})
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"firefox", "/usr/lib/firefox/firefox", false},
+		{"navegador", "/usr/lib/firefox/firefox", false},
+		{"Calculadora", "gnome-calculator", false},
+		{"vscode", "/usr/share/code/code --unity-launch", false},
+		{"bloco", `sh -c editor "$1" 100%`, false},
+		// Sem .desktop não abre, mesmo no PATH
+		{"xterm", "", true},
+		{"poweroff", "", true},
+		{"oculto", "", true},
+		{"/bin/sh", "", true},
+	}
+	for _, tt := range tests {
+		*commands = nil
+		err := l.OpenApp(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("OpenApp(%q) erro = %v", tt.name, err)
+			continue
+		}
+		if got := strings.Join(*commands, ""); got != tt.want {
+			t.Errorf("OpenApp(%q) executou %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Com gtk-launch o app abre pelo ID
+	l, commands = newTestLinux(t, []string{"gtk-launch"}, map[string]string{"HOME": home, "XDG_DATA_DIRS": system})
+	if err := l.OpenApp("calculadora"); err != nil || strings.Join(*commands, "") != "gtk-launch org.gnome.Calculator" {
+		t.Errorf("gtk-launch: %q, %v", *commands, err)
+	}
+}
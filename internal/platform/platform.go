@@ -0,0 +1,102 @@
+// Package platform isola o que depende do sistema operacional: abrir apps e
+// URLs, volume, captura de tela e reprodução de áudio. A implementação
+// (Windows ou Linux) e as ferramentas usadas são escolhidas em tempo de
+// execução.
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupported a operação não tem ferramenta nesta máquina
+var ErrUnsupported = errors.New("não suportado nesta plataforma")
+
+// Platform operações do sistema usadas pelas ações, pelo TTS, pelo player e
+// pela visão
+type Platform interface {
+	// Name sistema da implementação (windows, linux)
+	Name() string
+	// Tools ferramenta escolhida para cada operação ("volume": "wpctl");
+	// operações sem ferramenta ficam de fora
+	Tools() map[string]string
+
+	OpenApp(name string) error
+	OpenURL(url string) error
+	SetVolume(percent int) error
+	// Screenshot PNG da tela inteira
+	Screenshot() ([]byte, error)
+	// PlayCommand comando que toca o WAV até o fim; Process.Kill interrompe
+	PlayCommand(path string) (*exec.Cmd, error)
+}
+
+var (
+	current     Platform
+	currentOnce sync.Once
+)
+
+// Current plataforma desta máquina, detectada no primeiro uso
+func Current() Platform {
+	currentOnce.Do(func() {
+		current = Detect()
+	})
+	return current
+}
+
+// Detect escolhe a implementação pelo sistema e pelas ferramentas instaladas
+func Detect() Platform {
+	switch runtime.GOOS {
+	case "windows":
+		return newWindows()
+	case "linux":
+		return newLinux(exec.LookPath, os.Getenv)
+	}
+	return unsupported{runtime.GOOS}
+}
+
+// Describe nome e ferramentas em uma linha, para o log de inicialização
+func Describe(p Platform) string {
+	tools := p.Tools()
+	names := make([]string, 0, len(tools))
+	for op := range tools {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, op := range names {
+		parts[i] = op + ": " + tools[op]
+	}
+	return fmt.Sprintf("%s (%s)", p.Name(), strings.Join(parts, ", "))
+}
+
+// start inicia o programa sem esperar (apps, navegador)
+func start(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Não deixa processo zumbi enquanto o app roda
+	go cmd.Wait()
+	return nil
+}
+
+// unsupported sistemas sem implementação
+type unsupported struct{ goos string }
+
+func (u unsupported) Name() string                          { return u.goos }
+func (u unsupported) Tools() map[string]string              { return map[string]string{} }
+func (u unsupported) OpenApp(string) error                  { return u.err() }
+func (u unsupported) OpenURL(string) error                  { return u.err() }
+func (u unsupported) SetVolume(int) error                   { return u.err() }
+func (u unsupported) Screenshot() ([]byte, error)           { return nil, u.err() }
+func (u unsupported) PlayCommand(string) (*exec.Cmd, error) { return nil, u.err() }
+
+func (u unsupported) err() error {
+	return fmt.Errorf("%w (%s)", ErrUnsupported, u.goos)
+}
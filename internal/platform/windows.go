@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// windowsApps nomes falados para os executáveis do Windows
+var windowsApps = map[string]string{
+	"chrome":      "chrome",
+	"navegador":   "chrome",
+	"firefox":     "firefox",
+	"edge":        "msedge",
+	"notepad":     "notepad",
+	"bloco":       "notepad",
+	"calculadora": "calc",
+	"calc":        "calc",
+	"explorer":    "explorer",
+	"arquivos":    "explorer",
+	"terminal":    "wt",
+	"cmd":         "cmd",
+	"vscode":      "code",
+	"code":        "code",
+	"spotify":     "spotify",
+	"discord":     "discord",
+	"outlook":     "outlook",
+	"word":        "winword",
+	"excel":       "excel",
+	"powerpoint":  "powerpnt",
+}
+
+// windows implementação com cmd, rundll32 e PowerShell
+type windows struct{}
+
+func newWindows() *windows {
+	return &windows{}
+}
+
+func (w *windows) Name() string {
+	return "windows"
+}
+
+func (w *windows) Tools() map[string]string {
+	return map[string]string{
+		"apps":       "start",
+		"url":        "rundll32",
+		"volume":     "powershell",
+		"screenshot": "powershell",
+		"audio":      "powershell",
+	}
+}
+
+// OpenApp abre pelo start do cmd, que acha apps registrados fora do PATH
+func (w *windows) OpenApp(name string) error {
+	executable := name
+	if mapped, ok := windowsApps[strings.ToLower(name)]; ok {
+		executable = mapped
+	}
+	// O nome passa pelo cmd: nada que ele interprete
+	if strings.ContainsAny(executable, "&|<>^%\"") {
+		return fmt.Errorf("nome de app inválido: %q", name)
+	}
+	return start("cmd", "/c", "start", "", executable)
+}
+
+// OpenURL abre no navegador padrão sem passar pelo cmd (& na URL quebraria o
+// start)
+func (w *windows) OpenURL(url string) error {
+	return start("rundll32", "url.dll,FileProtocolHandler", url)
+}
+
+// SetVolume zera o volume com a tecla de abaixar e sobe em passos de 2%
+func (w *windows) SetVolume(percent int) error {
+	script := fmt.Sprintf(`
+		$wshShell = New-Object -ComObject WScript.Shell
+		1..50 | ForEach-Object { $wshShell.SendKeys([char]174) }
+		1..%d | ForEach-Object { $wshShell.SendKeys([char]175) }
+	`, percent/2)
+	return exec.Command("powershell", "-Command", script).Run()
+}
+
+// Screenshot copia a área de trabalho virtual (todos os monitores) para PNG
+func (w *windows) Screenshot() ([]byte, error) {
+	file, err := os.CreateTemp("", "npu-ia-screen-*.png")
+	if err != nil {
+		return nil, err
+	}
+	path := file.Name()
+	file.Close()
+	defer os.Remove(path)
+
+	script := fmt.Sprintf(`
+		Add-Type -AssemblyName System.Windows.Forms,System.Drawing
+		$b = [System.Windows.Forms.SystemInformation]::VirtualScreen
+		$bmp = New-Object System.Drawing.Bitmap $b.Width, $b.Height
+		$g = [System.Drawing.Graphics]::FromImage($bmp)
+		$g.CopyFromScreen($b.Left, $b.Top, 0, 0, $bmp.Size)
+		$bmp.Save('%s', [System.Drawing.Imaging.ImageFormat]::Png)
+	`, quotePS(path))
+	if output, err := exec.Command("powershell", "-Command", script).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("erro ao capturar tela: %w: %s", err, output)
+	}
+	return os.ReadFile(path)
+}
+
+func (w *windows) PlayCommand(path string) (*exec.Cmd, error) {
+	return exec.Command("powershell", "-c",
+		fmt.Sprintf(`(New-Object Media.SoundPlayer '%s').PlaySync()`, quotePS(path))), nil
+}
+
+// quotePS escapa o texto para uma string entre aspas simples do PowerShell
+func quotePS(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
 )
 
 // AudioPlayer reproduz áudios
@@ -52,16 +54,24 @@ func (ap *AudioPlayer) Play(filename string) error {
 		return fmt.Errorf("arquivo não encontrado: %s", path)
 	}
 
-	// Usa PowerShell para reproduzir no Windows
-	ap.current = exec.Command("powershell", "-c",
-		fmt.Sprintf(`(New-Object Media.SoundPlayer '%s').PlaySync()`, path))
-
+	// Player da plataforma (PowerShell, paplay, aplay...)
+	cmd, err := platform.Current().PlayCommand(path)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("erro ao reproduzir %s: %w", filename, err)
+	}
+	ap.current = cmd
 	ap.isPlaying = true
 
 	go func() {
-		ap.current.Run()
+		cmd.Wait()
 		ap.mu.Lock()
-		ap.isPlaying = false
+		// Outro Play pode ter trocado o áudio atual
+		if ap.current == cmd {
+			ap.isPlaying = false
+		}
 		ap.mu.Unlock()
 	}()
 
@@ -147,17 +157,9 @@ func (ap *AudioPlayer) FadeOut(duration time.Duration) {
 	ap.Stop()
 }
 
-// SetVolume ajusta volume do sistema (Windows)
+// SetVolume ajusta volume do sistema
 func (ap *AudioPlayer) SetVolume(percent int) error {
-	// Usa nircmd ou PowerShell
-	script := fmt.Sprintf(`
-		$wshShell = New-Object -ComObject WScript.Shell
-		1..50 | ForEach-Object { $wshShell.SendKeys([char]174) }
-		1..%d | ForEach-Object { $wshShell.SendKeys([char]175) }
-	`, percent/2)
-
-	cmd := exec.Command("powershell", "-c", script)
-	return cmd.Run()
+	return platform.Current().SetVolume(percent)
 }
 
 // ListAudioFiles lista arquivos de áudio disponíveis
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -11,15 +12,18 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
 )
 
 // Zettelkasten sistema de notas Zettelkasten
 type Zettelkasten struct {
-	basePath string
-	notes    map[string]*Note
-	index    *NoteIndex
-	llm      LLMInterface
-	mu       sync.RWMutex
+	basePath  string
+	notes     map[string]*Note
+	index     *NoteIndex
+	llm       LLMInterface
+	retriever embeddings.Retriever // Busca semântica (nil = substring)
+	mu        sync.RWMutex
 }
 
 // LLMInterface interface para o modelo de linguagem
@@ -358,8 +362,46 @@ Crie o MOC em Markdown com:
 
 // ==================== BUSCA ====================
 
-// Search busca notas
+// searchLimit notas retornadas pela busca semântica
+const searchLimit = 10
+
+// searchMinScore similaridade mínima de uma nota encontrada. Na escala do E5
+// textos sem relação ficam perto de 0.75.
+const searchMinScore = 0.8
+
+// SetRetriever ativa a busca semântica e indexa as notas existentes
+func (z *Zettelkasten) SetRetriever(ctx context.Context, r embeddings.Retriever) error {
+	z.mu.Lock()
+	z.retriever = r
+	docs := make([]embeddings.Document, 0, len(z.notes))
+	for _, note := range z.notes {
+		docs = append(docs, noteDocument(note))
+	}
+	z.mu.Unlock()
+
+	return r.Upsert(ctx, docs...)
+}
+
+// noteDocument texto indexado de uma nota
+func noteDocument(note *Note) embeddings.Document {
+	text := note.Title + "\n" + note.Content
+	if len(note.Tags) > 0 {
+		text += "\n" + strings.Join(note.Tags, ", ")
+	}
+	return embeddings.Document{
+		ID:       note.ID,
+		Text:     text,
+		Metadata: map[string]string{"type": string(note.Type)},
+	}
+}
+
+// Search busca notas. Com retriever retorna as mais similares à consulta;
+// sem ele (ou se falhar), as que contêm o texto.
 func (z *Zettelkasten) Search(query string) []*Note {
+	if notes, ok := z.searchSemantic(query); ok {
+		return notes
+	}
+
 	z.mu.RLock()
 	defer z.mu.RUnlock()
 
@@ -394,6 +436,36 @@ func (z *Zettelkasten) Search(query string) []*Note {
 	return results
 }
 
+// searchSemantic busca notas pelo índice vetorial, na ordem de similaridade,
+// descartando as pouco similares
+func (z *Zettelkasten) searchSemantic(query string) ([]*Note, bool) {
+	z.mu.RLock()
+	retriever := z.retriever
+	z.mu.RUnlock()
+	if retriever == nil {
+		return nil, false
+	}
+
+	results, err := retriever.SearchWith(context.Background(), query, embeddings.SearchOptions{
+		K:        searchLimit,
+		MinScore: searchMinScore,
+	})
+	if err != nil {
+		return nil, false // Cai para a busca por substring
+	}
+
+	z.mu.RLock()
+	defer z.mu.RUnlock()
+
+	notes := make([]*Note, 0, len(results))
+	for _, r := range results {
+		if note, ok := z.notes[r.ID]; ok {
+			notes = append(notes, note)
+		}
+	}
+	return notes, true
+}
+
 // SearchByContent busca por conteúdo
 func (z *Zettelkasten) SearchByContent(query string) []*Note {
 	return z.Search(query)
@@ -502,7 +574,6 @@ func (z *Zettelkasten) extractLinks(content string) []string {
 // saveNote salva nota
 func (z *Zettelkasten) saveNote(note *Note) (*Note, error) {
 	z.mu.Lock()
-	defer z.mu.Unlock()
 
 	z.notes[note.ID] = note
 
@@ -524,7 +595,20 @@ func (z *Zettelkasten) saveNote(note *Note) (*Note, error) {
 	}
 
 	// Salva em arquivo
-	return note, z.saveToFile(note)
+	err := z.saveToFile(note)
+	retriever := z.retriever
+	z.mu.Unlock()
+	if err != nil {
+		return note, err
+	}
+
+	// Indexa fora do lock; o embedding roda na NPU
+	if retriever != nil {
+		if err := retriever.Upsert(context.Background(), noteDocument(note)); err != nil {
+			log.Printf("Aviso: erro ao indexar nota %s: %v", note.ID, err)
+		}
+	}
+	return note, nil
 }
 
 // saveToFile salva nota em arquivo
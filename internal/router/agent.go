@@ -0,0 +1,129 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+)
+
+// AgentStep um passo do agente: a ferramenta chamada e o que ela devolveu
+type AgentStep struct {
+	Step        int                    `json:"step"`
+	Action      string                 `json:"action"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Observation string                 `json:"observation,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	DurationMs  int64                  `json:"duration_ms"`
+}
+
+// agentTrace registro de um pedido atendido pelo agente
+type agentTrace struct {
+	Time   time.Time   `json:"time"`
+	Text   string      `json:"text"`
+	Steps  []AgentStep `json:"steps"`
+	Answer string      `json:"answer"`
+	End    string      `json:"end"` // answer, confirmation, max_steps ou timeout
+}
+
+// runAgent atende o pedido em passos: a cada um o modelo escolhe uma
+// ferramenta do executor (ou responde), a ferramenta roda e o resultado
+// volta como observação. Termina na resposta do modelo, no limite de passos
+// (a resposta é a última observação) ou no tempo máximo do pedido.
+func (r *Router) runAgent(ctx context.Context, model *llm.Model, text string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Agent.Timeout)
+	defer cancel()
+
+	tools := append(actions.Catalog(r.executor.Specs()), actions.ReplySpec)
+	messages := model.Conversation(r.history(), r.background(model, text), text)
+	trace := agentTrace{Time: time.Now(), Text: text, Steps: []AgentStep{}}
+	response := &Response{}
+
+	finish := func(end string) (*Response, error) {
+		trace.End, trace.Answer = end, response.Text
+		response.Steps = trace.Steps
+		r.recordTrace(trace)
+		return response, nil
+	}
+
+	for step := 1; step <= r.cfg.Agent.MaxSteps; step++ {
+		started := time.Now()
+		result, err := model.GenerateToolCall(ctx, messages, tools)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				response.Text = "Desculpe, o pedido demorou demais e foi interrompido."
+				return finish("timeout")
+			}
+			return nil, err
+		}
+
+		var call actions.Action
+		if err := json.Unmarshal([]byte(result), &call); err != nil {
+			return nil, fmt.Errorf("ação inválida do modelo: %w", err)
+		}
+		if call.Type == actions.ReplyAction {
+			response.Text, _ = call.Params["texto"].(string)
+			response.Success = true
+			return finish("answer")
+		}
+
+		// Executa a ferramenta; o erro também é observação, para o modelo
+		// tentar outro caminho
+		action, err := r.executor.Execute(result)
+		current := AgentStep{Step: step, Action: call.Type, Params: call.Params}
+
+		// Ação segurada pela política: o pedido para até o usuário responder
+		if errors.Is(err, actions.ErrConfirmationRequired) {
+			current.Observation = action.Response
+			current.DurationMs = time.Since(started).Milliseconds()
+			trace.Steps = append(trace.Steps, current)
+			log.Printf("🔧 Passo %d: %s aguarda confirmação %s", step, call.Type, action.Pending)
+			response.Text, response.Action, response.Success = action.Response, action, false
+			return finish("confirmation")
+		}
+		switch {
+		case action == nil:
+			current.Error = err.Error()
+			current.Observation = "Erro: " + err.Error()
+		case err != nil:
+			current.Error = err.Error()
+			current.Observation = action.Response
+		default:
+			current.Observation = action.Response
+		}
+		current.DurationMs = time.Since(started).Milliseconds()
+		trace.Steps = append(trace.Steps, current)
+		log.Printf("🔧 Passo %d: %s → %s", step, call.Type, current.Observation)
+
+		response.Text, response.Action, response.Success = current.Observation, action, err == nil
+		if err != nil {
+			response.Text = "Desculpe, não consegui executar: " + err.Error()
+		}
+
+		messages = append(messages,
+			chat.Message{Role: chat.RoleAssistant, Content: result},
+			chat.Message{Role: chat.RoleTool, Name: call.Type, Content: current.Observation},
+		)
+
+		if ctx.Err() != nil {
+			return finish("timeout")
+		}
+	}
+	return finish("max_steps")
+}
+
+// recordTrace grava os passos do pedido, se houver arquivo de trace
+func (r *Router) recordTrace(trace agentTrace) {
+	if r.traces == nil {
+		return
+	}
+	if err := r.traces.record(trace); err != nil {
+		log.Printf("Aviso: erro ao gravar passos do agente: %v", err)
+	}
+}
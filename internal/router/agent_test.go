@@ -0,0 +1,143 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestAgentLoop(t *testing.T) {
+	var prompts []string
+	qwen := recordPrompts(scriptText(
+		`{"action":"horario","params":{"lugar":"padaria"}}`,
+		`{"action":"lembrete","params":{"texto":"pão às 7h"}}`,
+		`{"action":"responder","params":{"texto":"A padaria abre às 7h; deixei um lembrete."}}`,
+	), &prompts)
+	r, cfg := newAgentRouter(t, qwen, nil)
+	defer r.Close()
+
+	resp, err := r.ProcessText(context.Background(), "Quando abre a padaria? Me lembra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "A padaria abre às 7h; deixei um lembrete." || !resp.Success {
+		t.Errorf("resposta = %+v", resp)
+	}
+	if len(resp.Steps) != 2 || resp.Steps[0].Observation != "Abre às 7h." || resp.Steps[1].Action != "lembrete" {
+		t.Fatalf("passos = %+v", resp.Steps)
+	}
+
+	// Cada passo vê as observações anteriores
+	last := prompts[len(prompts)-1]
+	if !strings.Contains(last, "Abre às 7h.") || !strings.Contains(last, "Lembrete criado.") {
+		t.Errorf("último prompt não tem as observações: %q", last)
+	}
+
+	traces := readTraces(t, cfg.Agent.TracePath)
+	if len(traces) != 1 || traces[0].End != "answer" || len(traces[0].Steps) != 2 {
+		t.Errorf("trace = %+v", traces)
+	}
+}
+
+func TestAgentLimits(t *testing.T) {
+	loop := `{"action":"horario","params":{"lugar":"padaria"}}`
+
+	t.Run("limite de passos", func(t *testing.T) {
+		r, cfg := newAgentRouter(t, scriptText(loop, loop, loop), func(cfg *config.Config) {
+			cfg.Agent.MaxSteps = 2
+		})
+		defer r.Close()
+
+		resp, err := r.ProcessText(context.Background(), "Quando abre a padaria?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Sem resposta do modelo, fica a última observação
+		if len(resp.Steps) != 2 || resp.Text != "Abre às 7h." {
+			t.Errorf("resposta = %+v", resp)
+		}
+		if traces := readTraces(t, cfg.Agent.TracePath); len(traces) != 1 || traces[0].End != "max_steps" {
+			t.Errorf("trace = %+v", traces)
+		}
+	})
+
+	t.Run("tempo esgotado", func(t *testing.T) {
+		r, cfg := newAgentRouter(t, scriptText(loop), func(cfg *config.Config) {
+			cfg.Agent.Timeout = time.Nanosecond
+		})
+		defer r.Close()
+
+		resp, err := r.ProcessText(context.Background(), "Quando abre a padaria?")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Success || len(resp.Steps) != 0 || !strings.Contains(resp.Text, "demorou demais") {
+			t.Errorf("resposta = %+v", resp)
+		}
+		if traces := readTraces(t, cfg.Agent.TracePath); len(traces) != 1 || traces[0].End != "timeout" {
+			t.Errorf("trace = %+v", traces)
+		}
+	})
+}
+
+// newAgentRouter router com o Qwen do roteiro, as ferramentas horario e
+// lembrete e o trace num diretório temporário
+func newAgentRouter(t *testing.T, qwen *fake.Model, edit func(cfg *config.Config)) (*Router, *config.Config) {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Agent.TracePath = filepath.Join(t.TempDir(), "agent.jsonl")
+	if edit != nil {
+		edit(cfg)
+	}
+
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText(""))
+	be.Add("qwen.onnx", qwen)
+
+	r, err := New(context.Background(), cfg, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentAction, Confidence: 1}})
+
+	r.executor.RegisterAction(actions.ActionSpec{
+		Name:   "horario",
+		Params: []actions.ParamSpec{{Name: "lugar", Type: "string", Required: true}},
+	}, func(params map[string]interface{}) (string, error) {
+		return "Abre às 7h.", nil
+	})
+	r.executor.RegisterAction(actions.ActionSpec{
+		Name:   "lembrete",
+		Params: []actions.ParamSpec{{Name: "texto", Type: "string", Required: true}},
+	}, func(params map[string]interface{}) (string, error) {
+		return "Lembrete criado.", nil
+	})
+	return r, cfg
+}
+
+// readTraces lê os registros do trace do agente
+func readTraces(t *testing.T, path string) []agentTrace {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var traces []agentTrace
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var trace agentTrace
+		if err := json.Unmarshal([]byte(line), &trace); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, trace)
+	}
+	return traces
+}
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// ErrUnknownModel o nome não é de um modelo de chat do registro
+var ErrUnknownModel = errors.New("modelo desconhecido")
+
+// ModelInfo entrada do registro de modelos
+type ModelInfo struct {
+	Name   string
+	Role   string
+	Family string
+}
+
+// Models entradas do registro, em ordem alfabética
+func (r *Router) Models() []ModelInfo {
+	models := make([]ModelInfo, 0, len(r.cfg.Models.Entries))
+	for name, cfg := range r.cfg.Models.Entries {
+		models = append(models, ModelInfo{Name: name, Role: cfg.Role, Family: cfg.Family})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
+
+// Complete gera a resposta de um modelo do registro para uma conversa já
+// montada (API /v1): sem classificação, sem sessão e sem memória pessoal. Os
+// turnos mais antigos saem se a conversa não couber na janela.
+func (r *Router) Complete(ctx context.Context, name string, messages []chat.Message, onToken llm.TokenCallback) (string, error) {
+	model, release, err := r.acquireChat(name)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return model.llm.GenerateChat(ctx, model.llm.Fit(messages), onToken)
+}
+
+// CompleteToolCall como Complete, mas a resposta é uma ação do set (veja
+// llm.Model.GenerateToolCall)
+func (r *Router) CompleteToolCall(ctx context.Context, name string, messages []chat.Message, set llm.ActionSet) (string, error) {
+	model, release, err := r.acquireChat(name)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return model.llm.GenerateToolCall(ctx, messages, set)
+}
+
+// acquireChat reserva um modelo de texto do registro pelo nome
+func (r *Router) acquireChat(name string) (*instance, func(), error) {
+	cfg, ok := r.cfg.Models.Entries[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownModel, name)
+	}
+	switch cfg.Role {
+	case config.RoleChat, config.RoleContext, config.RoleAction:
+	default:
+		return nil, nil, fmt.Errorf("%w: %s tem role %s, não gera chat", ErrUnknownModel, name, cfg.Role)
+	}
+	return r.acquire(name)
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+)
+
+func TestComplete(t *testing.T) {
+	var prompts []string
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText(""))
+	be.Add("llama.onnx", recordPrompts(scriptText("Machado de Assis."), &prompts))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	messages := []chat.Message{
+		{Role: chat.RoleSystem, Content: "Responda com o nome do autor."},
+		{Role: chat.RoleUser, Content: "Quem escreveu Dom Casmurro?"},
+	}
+	var streamed strings.Builder
+	got, err := r.Complete(context.Background(), "llama", messages, func(token string) error {
+		streamed.WriteString(token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Machado de Assis." || streamed.String() != got {
+		t.Errorf("Complete() = %q, streamed %q", got, streamed.String())
+	}
+
+	// A system message do cliente substitui a do config, e nada vai para a
+	// sessão
+	if len(prompts) == 0 || !strings.Contains(prompts[0], "Responda com o nome do autor.") {
+		t.Errorf("prompt = %q, want a system message do pedido", prompts)
+	}
+	if history := r.Session().History(); len(history) != 0 {
+		t.Errorf("History() = %+v, want vazio", history)
+	}
+
+	for _, name := range []string{"coder", "whisper", "gpt-4"} {
+		if _, err := r.Complete(context.Background(), name, messages, nil); !errors.Is(err, ErrUnknownModel) {
+			t.Errorf("Complete(%q) erro = %v, want ErrUnknownModel", name, err)
+		}
+	}
+}
+
+func TestCompleteToolCall(t *testing.T) {
+	want := `{"action": "luz", "params": {"cor": "fria"}}`
+
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText(""))
+	be.Add("qwen.onnx", fake.Steer(eosID+1, eosID, `{"action": "som"`, want))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Só a luz foi oferecida: o "som" que o modelo queria não passa
+	catalog := actions.Catalog{{
+		Name:   "luz",
+		Params: []actions.ParamSpec{{Name: "cor", Type: "string", Required: true, Enum: []string{"quente", "fria"}}},
+	}}
+	got, err := r.CompleteToolCall(context.Background(), "qwen",
+		[]chat.Message{{Role: chat.RoleUser, Content: "Deixa a luz fria"}}, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("CompleteToolCall() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package router
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+)
+
+// Respostas à pergunta da política de ações. Só a frase inteira conta: "sim,
+// e o clima?" segue para os modelos.
+var (
+	confirmAnswers = []string{"confirmar", "confirmo", "confirma", "sim", "pode", "pode sim", "ok"}
+	rejectAnswers  = []string{"cancelar", "cancela", "cancelo", "não", "nao", "não pode"}
+)
+
+// confirm aprova ou recusa a confirmação mais recente da política quando o
+// texto é uma resposta a ela; ok falso se não há confirmação em aberto ou o
+// texto é outro pedido
+func (r *Router) confirm(text string) (*Response, bool) {
+	policy := r.executor.Policy()
+	if policy == nil {
+		return nil, false
+	}
+
+	answer := strings.ToLower(strings.Trim(text, " .,!?"))
+	approve := containsAnswer(confirmAnswers, answer)
+	if !approve && !containsAnswer(rejectAnswers, answer) {
+		return nil, false
+	}
+	pending := policy.Pending()
+	if len(pending) == 0 {
+		return nil, false
+	}
+	last := pending[len(pending)-1]
+
+	if !approve {
+		if err := policy.Reject(last.ID); err != nil {
+			return expiredConfirmation(err), true
+		}
+		return &Response{Text: "Cancelado.", Intent: IntentAction, Success: true}, true
+	}
+
+	action, err := policy.Approve(last.ID)
+	switch {
+	case action == nil:
+		return expiredConfirmation(err), true
+	case err != nil:
+		return &Response{Text: "Desculpe, não consegui executar: " + err.Error(), Intent: IntentAction, Action: action}, true
+	}
+	return &Response{Text: action.Response, Intent: IntentAction, Action: action, Success: true}, true
+}
+
+// expiredConfirmation resposta quando a confirmação saiu da fila (expirou ou
+// foi respondida pela API) entre a listagem e a resposta
+func expiredConfirmation(err error) *Response {
+	text := "Desculpe, não consegui executar: " + err.Error()
+	if errors.Is(err, actions.ErrNoPending) {
+		text = "Essa confirmação expirou; peça de novo."
+	}
+	return &Response{Text: text, Intent: IntentAction}
+}
+
+// containsAnswer indica se answer está na lista
+func containsAnswer(answers []string, answer string) bool {
+	for _, a := range answers {
+		if a == answer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestConfirmation(t *testing.T) {
+	send := `{"action":"enviar","params":{"para":"ana"}}`
+
+	for _, tt := range []struct {
+		answer   string
+		wantSent bool
+		wantText string
+	}{
+		{"Confirmar.", true, "Enviado para ana."},
+		{"cancelar", false, "Cancelado."},
+	} {
+		t.Run(tt.answer, func(t *testing.T) {
+			audit := filepath.Join(t.TempDir(), "audit.jsonl")
+			r, _ := newAgentRouter(t, scriptText(send), func(cfg *config.Config) {
+				cfg.Policy.AuditPath = audit
+			})
+			defer r.Close()
+
+			sent := false
+			r.executor.RegisterAction(actions.ActionSpec{
+				Name:   "enviar",
+				Params: []actions.ParamSpec{{Name: "para", Type: "string", Required: true}},
+				Risk:   actions.RiskExternal,
+			}, func(params map[string]interface{}) (string, error) {
+				sent = true
+				return "Enviado para ana.", nil
+			})
+
+			// A ação externa fica esperando; o agente para e pergunta
+			resp, err := r.ProcessText(context.Background(), "Manda para a Ana")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent || resp.Action == nil || resp.Action.Pending == "" || !strings.Contains(resp.Text, "confirmar") {
+				t.Fatalf("resposta = %+v", resp)
+			}
+
+			resp, err = r.ProcessText(context.Background(), tt.answer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent != tt.wantSent || resp.Text != tt.wantText {
+				t.Errorf("enviado = %v, resposta = %+v", sent, resp)
+			}
+			if pending := r.executor.Policy().Pending(); len(pending) != 0 {
+				t.Errorf("fila = %+v", pending)
+			}
+
+			// Sem confirmação em aberto, "sim" é um pedido como outro
+			if resp, ok := r.confirm("sim"); ok {
+				t.Errorf("confirm sem fila = %+v", resp)
+			}
+
+			data, err := os.ReadFile(audit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lines := strings.Count(string(data), "\n"); lines != 2 {
+				t.Errorf("auditoria com %d linhas, want 2:\n%s", lines, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+)
+
+// intents todas as intenções, na ordem usada em prompts e desempates
+var intents = []Intent{IntentSimple, IntentAction, IntentContext, IntentVision, IntentCode}
+
+// intentDescriptions o que cada intenção faz, do ponto de vista do usuário
+var intentDescriptions = map[Intent]string{
+	IntentSimple:  "responda rapidamente",
+	IntentAction:  "execute uma ação no computador",
+	IntentContext: "explique com mais detalhes",
+	IntentVision:  "olhe a sua tela",
+	IntentCode:    "escreva ou corrija código",
+}
+
+// Classification resultado da classificação de intenção
+type Classification struct {
+	Intent     Intent
+	Confidence float32            // 0-1
+	Scores     map[Intent]float32 // Pontuação de cada intenção avaliada
+}
+
+// Classifier decide a intenção de uma frase
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) (*Classification, error)
+}
+
+// ==================== EMBEDDINGS ====================
+
+// similarityTemperature escala as similaridades de cosseno antes do softmax;
+// entre frases as diferenças ficam em centésimos
+const similarityTemperature = 0.05
+
+// nearestExamples quantos exemplos mais próximos de cada intenção entram na média
+const nearestExamples = 3
+
+// EmbeddingClassifier compara a frase com exemplos rotulados por similaridade
+// de embeddings
+type EmbeddingClassifier struct {
+	embedder embeddings.Embedder
+	examples map[Intent][][]float32
+}
+
+// NewEmbeddingClassifier gera os embeddings dos exemplos do arquivo YAML
+// (intenção → lista de frases)
+func NewEmbeddingClassifier(ctx context.Context, embedder embeddings.Embedder, examplesPath string) (*EmbeddingClassifier, error) {
+	examples, err := LoadExamples(examplesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &EmbeddingClassifier{
+		embedder: embedder,
+		examples: make(map[Intent][][]float32, len(examples)),
+	}
+	for intent, texts := range examples {
+		// Frases contra frases: as duas pontas usam o modo consulta
+		vectors, err := embedder.Embed(ctx, texts, embeddings.Query)
+		if err != nil {
+			return nil, fmt.Errorf("erro nos exemplos de %s: %w", intent, err)
+		}
+		c.examples[intent] = vectors
+	}
+	return c, nil
+}
+
+// LoadExamples lê os exemplos rotulados de intenção
+func LoadExamples(path string) (map[Intent][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	examples := make(map[Intent][]string, len(raw))
+	for name, texts := range raw {
+		intent := Intent(name)
+		if _, ok := intentDescriptions[intent]; !ok {
+			return nil, fmt.Errorf("%s: intenção desconhecida %q", path, name)
+		}
+		if len(texts) > 0 {
+			examples[intent] = texts
+		}
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("%s: nenhum exemplo", path)
+	}
+	return examples, nil
+}
+
+func (c *EmbeddingClassifier) Name() string { return "embedding" }
+
+// Classify pontua cada intenção pela média dos exemplos mais próximos; a
+// confiança é o softmax dessas pontuações
+func (c *EmbeddingClassifier) Classify(ctx context.Context, text string) (*Classification, error) {
+	vectors, err := c.embedder.Embed(ctx, []string{text}, embeddings.Query)
+	if err != nil {
+		return nil, err
+	}
+	query := vectors[0]
+
+	scores := make(map[Intent]float32, len(c.examples))
+	for intent, examples := range c.examples {
+		sims := make([]float32, len(examples))
+		for i, v := range examples {
+			sims[i] = embeddings.Dot(query, v)
+		}
+		sort.Slice(sims, func(i, j int) bool { return sims[i] > sims[j] })
+
+		n := len(sims)
+		if n > nearestExamples {
+			n = nearestExamples
+		}
+		var sum float32
+		for _, s := range sims[:n] {
+			sum += s
+		}
+		scores[intent] = sum / float32(n)
+	}
+
+	return fromScores(scores), nil
+}
+
+// fromScores escolhe a intenção de maior pontuação e calcula a confiança
+func fromScores(scores map[Intent]float32) *Classification {
+	best := Intent("")
+	for _, intent := range intents {
+		s, ok := scores[intent]
+		if ok && (best == "" || s > scores[best]) {
+			best = intent
+		}
+	}
+
+	var sum float64
+	for _, s := range scores {
+		sum += math.Exp(float64(s-scores[best]) / similarityTemperature)
+	}
+
+	return &Classification{
+		Intent:     best,
+		Confidence: float32(1 / sum),
+		Scores:     scores,
+	}
+}
+
+// ==================== LLM ====================
+
+// LLMClassifier pede ao modelo rápido que escolha a intenção, com a saída
+// restrita aos nomes das intenções
+type LLMClassifier struct {
+	model *llm.Model
+}
+
+// NewLLMClassifier cria o classificador com o modelo dado (normalmente o Phi)
+func NewLLMClassifier(model *llm.Model) *LLMClassifier {
+	return &LLMClassifier{model: model}
+}
+
+func (c *LLMClassifier) Name() string { return "llm" }
+
+// Classify a confiança é a probabilidade que o modelo deu à intenção escolhida
+func (c *LLMClassifier) Classify(ctx context.Context, text string) (*Classification, error) {
+	var options strings.Builder
+	names := make([]string, len(intents))
+	for i, intent := range intents {
+		names[i] = string(intent)
+		options.WriteString(fmt.Sprintf("- %s: o usuário quer que eu %s\n", intent, intentDescriptions[intent]))
+	}
+
+	prompt := fmt.Sprintf(`Classifique o pedido do usuário em uma das categorias:
+%s
+Pedido: %s
+
+Categoria:`, options.String(), text)
+
+	choice, confidence, err := c.model.Choose(ctx, prompt, names)
+	if err != nil {
+		return nil, err
+	}
+
+	intent := Intent(choice)
+	return &Classification{
+		Intent:     intent,
+		Confidence: confidence,
+		Scores:     map[Intent]float32{intent: confidence},
+	}, nil
+}
+
+// ==================== DECISÕES ====================
+
+// intentDecision registro de uma classificação, para ajustar exemplos e limiar
+type intentDecision struct {
+	Time       time.Time          `json:"time"`
+	Text       string             `json:"text"`
+	Classifier string             `json:"classifier"`
+	Intent     Intent             `json:"intent"`
+	Confidence float32            `json:"confidence"`
+	Scores     map[Intent]float32 `json:"scores"`
+	Asked      bool               `json:"asked"` // Confiança baixa: perguntou ao usuário
+}
+
+// clarification pergunta ao usuário entre as intenções mais prováveis
+func clarification(c *Classification) string {
+	ranked := make([]Intent, 0, len(c.Scores))
+	for _, intent := range intents {
+		if _, ok := c.Scores[intent]; ok {
+			ranked = append(ranked, intent)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return c.Scores[ranked[i]] > c.Scores[ranked[j]]
+	})
+
+	if len(ranked) < 2 {
+		return fmt.Sprintf("Não entendi bem. Você quer que eu %s?", intentDescriptions[c.Intent])
+	}
+	return fmt.Sprintf("Não entendi bem. Você quer que eu %s ou que eu %s?",
+		intentDescriptions[ranked[0]], intentDescriptions[ranked[1]])
+}
@@ -0,0 +1,187 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+)
+
+// bagEmbedder bag-of-words sobre um vocabulário fixo
+type bagEmbedder []string
+
+func (vocab bagEmbedder) Embed(ctx context.Context, texts []string, mode embeddings.Mode) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(vocab))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for j, w := range vocab {
+				if w == word {
+					v[j]++
+				}
+			}
+		}
+		embeddings.Normalize(v)
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestEmbeddingClassifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intents.yaml")
+	os.WriteFile(path, []byte(`
+simple:
+  - "bom dia"
+  - "boa noite"
+code:
+  - "função em python"
+  - "bug no código"
+action:
+  - "abre o chrome"
+`), 0644)
+
+	embedder := bagEmbedder{"bom", "boa", "dia", "noite", "função", "python", "bug", "código", "abre", "chrome"}
+	c, err := NewEmbeddingClassifier(context.Background(), embedder, path)
+	if err != nil {
+		t.Fatalf("NewEmbeddingClassifier() erro: %v", err)
+	}
+
+	tests := []struct {
+		text          string
+		want          Intent
+		minConfidence float32
+		maxConfidence float32
+	}{
+		{"corrige o bug no código", IntentCode, 0.9, 1},
+		{"bom dia", IntentSimple, 0.9, 1},
+		{"abre o chrome por favor", IntentAction, 0.9, 1},
+		// Sem palavra conhecida todas as intenções empatam
+		{"xyz", IntentSimple, 0.33, 0.34},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := c.Classify(context.Background(), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Intent != tt.want {
+				t.Errorf("Intent = %s, want %s (scores %v)", got.Intent, tt.want, got.Scores)
+			}
+			if got.Confidence < tt.minConfidence || got.Confidence > tt.maxConfidence {
+				t.Errorf("Confidence = %v, want entre %v e %v", got.Confidence, tt.minConfidence, tt.maxConfidence)
+			}
+			if len(got.Scores) != 3 {
+				t.Errorf("Scores = %v, want as 3 intenções do arquivo", got.Scores)
+			}
+		})
+	}
+}
+
+func TestLoadExamplesUnknownIntent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "intents.yaml")
+	os.WriteFile(path, []byte("musica:\n  - \"toca algo\"\n"), 0644)
+
+	if _, err := LoadExamples(path); err == nil || !strings.Contains(err.Error(), "musica") {
+		t.Errorf("LoadExamples() erro = %v, want intenção desconhecida", err)
+	}
+}
+
+func TestLLMClassifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		want       Intent
+		confidence float64
+	}{
+		{"modelo convicto", "vision", IntentVision, 1},
+		// Fora das opções: os logits permitidos empatam entre a, c, s e v
+		// ("code" e "context" dividem o c) e o guloso fica com o primeiro
+		{"modelo indeciso", "x", IntentAction, 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := fake.New()
+			be.Add("phi.onnx", scriptText(tt.script))
+
+			cfg := testConfig(t)
+			model, err := llm.New(be, cfg.Models.Entries["phi"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.Close()
+
+			got, err := NewLLMClassifier(model).Classify(context.Background(), "olha minha tela")
+			if err != nil {
+				t.Fatalf("Classify() erro: %v", err)
+			}
+			if got.Intent != tt.want {
+				t.Errorf("Intent = %s, want %s", got.Intent, tt.want)
+			}
+			if math.Abs(float64(got.Confidence)-tt.confidence) > 1e-3 {
+				t.Errorf("Confidence = %v, want %v", got.Confidence, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestDecisionLog(t *testing.T) {
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts("Bom dia", "Hmm"))
+	be.Add("phi.onnx", scriptText("Oi!"))
+
+	cfg := testConfig(t)
+	cfg.Intent.LogPath = filepath.Join(t.TempDir(), "intents.jsonl")
+
+	r, err := New(context.Background(), cfg, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, confidence := range []float32{0.9, 0.2} {
+		r.SetClassifier(fixedClassifier{&Classification{
+			Intent:     IntentSimple,
+			Confidence: confidence,
+			Scores:     map[Intent]float32{IntentSimple: confidence},
+		}})
+		if _, err := r.Process(context.Background(), make([]float32, 1600)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	file, err := os.Open(cfg.Intent.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var decisions []intentDecision
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var d intentDecision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, d)
+	}
+
+	if len(decisions) != 2 {
+		t.Fatalf("%d decisões gravadas, want 2", len(decisions))
+	}
+	if d := decisions[0]; d.Text != "Bom dia" || d.Intent != IntentSimple || d.Asked || d.Classifier != "fixo" {
+		t.Errorf("primeira decisão = %+v", d)
+	}
+	if d := decisions[1]; !d.Asked || d.Scores[IntentSimple] != 0.2 {
+		t.Errorf("segunda decisão = %+v, want Asked", d)
+	}
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonLog arquivo de registros em JSON Lines (decisões de intenção, fatos
+// aprendidos)
+type jsonLog struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// openJSONLog abre o arquivo para acrescentar linhas. Os registros trazem
+// frases do usuário, então só o dono lê.
+func openJSONLog(path string) (*jsonLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLog{file: file}, nil
+}
+
+// record grava v numa linha
+func (l *jsonLog) record(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+func (l *jsonLog) Close() error {
+	return l.file.Close()
+}
@@ -2,34 +2,47 @@ package router
 
 import (
 	"log"
+	"os"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
-// MemoryManager gerencia modelos na memória de forma inteligente
+// MemoryManager gerencia modelos na memória de forma inteligente: descarrega
+// os inativos depois do TTL e, com orçamento, os menos usados antes de
+// carregar outro. Modelo em uso (Router.acquire) nunca é descarregado. O
+// orçamento vale para a soma das estimativas de cada modelo (veja modelSize),
+// não para a memória residente do processo.
 type MemoryManager struct {
-	router    *Router
-	lastUsed  map[string]time.Time
-	mu        sync.RWMutex
-	ttl       time.Duration // Tempo para descarregar modelo inativo
-	ticker    *time.Ticker
-	stopChan  chan struct{}
+	router   *Router
+	lastUsed map[string]time.Time
+	mu       sync.RWMutex
+	ttl      time.Duration // Tempo para descarregar modelo inativo (0 = nunca)
+	budget   int64         // Soma das estimativas dos modelos carregados, em bytes (0 = sem limite)
+	ticker   *time.Ticker
+	stopChan chan struct{}
+	done     chan struct{}
 
 	// Modelos que NUNCA descarrega
 	persistent map[string]bool
 }
 
-// NewMemoryManager cria um gerenciador de memória
-func NewMemoryManager(router *Router, ttl time.Duration) *MemoryManager {
+// NewMemoryManager cria um gerenciador de memória com o TTL, os modelos
+// persistentes e o orçamento do config
+func NewMemoryManager(router *Router, cfg config.MemoryConfig) *MemoryManager {
 	mm := &MemoryManager{
 		router:     router,
 		lastUsed:   make(map[string]time.Time),
-		ttl:        ttl,
+		ttl:        cfg.UnloadAfter,
+		budget:     cfg.EstimatedBudgetMB << 20,
 		stopChan:   make(chan struct{}),
-		persistent: map[string]bool{
-			"whisper": true, // STT sempre ativo
-			"phi":     true, // Modelo rápido sempre ativo
-		},
+		done:       make(chan struct{}),
+		persistent: make(map[string]bool),
+	}
+	for _, name := range cfg.Persistent {
+		mm.persistent[name] = true
 	}
 
 	// Inicia goroutine de limpeza
@@ -48,6 +61,7 @@ func (mm *MemoryManager) Touch(name string) {
 
 // cleanupLoop verifica periodicamente modelos inativos
 func (mm *MemoryManager) cleanupLoop() {
+	defer close(mm.done)
 	for {
 		select {
 		case <-mm.ticker.C:
@@ -60,78 +74,97 @@ func (mm *MemoryManager) cleanupLoop() {
 
 // cleanup descarrega modelos inativos
 func (mm *MemoryManager) cleanup() {
-	mm.mu.Lock()
-	defer mm.mu.Unlock()
-
+	mm.mu.RLock()
+	ttl := mm.ttl
+	var expired []string
 	now := time.Now()
-
 	for name, lastUsed := range mm.lastUsed {
 		// Pula modelos persistentes
-		if mm.persistent[name] {
-			continue
+		if ttl > 0 && !mm.persistent[name] && now.Sub(lastUsed) > ttl {
+			expired = append(expired, name)
 		}
+	}
+	mm.mu.RUnlock()
 
-		// Verifica se passou do TTL
-		if now.Sub(lastUsed) > mm.ttl {
-			mm.unloadModel(name)
+	// O router trava depois: quem carrega modelo trava o router e depois o
+	// gerenciador (evict)
+	for _, name := range expired {
+		if mm.router.unload(name, "inativo por "+ttl.String()) {
+			mm.mu.Lock()
 			delete(mm.lastUsed, name)
+			mm.mu.Unlock()
 		}
 	}
 }
 
-// unloadModel descarrega um modelo da memória
-func (mm *MemoryManager) unloadModel(name string) {
-	log.Printf("💤 Descarregando %s (inativo por %v)", name, mm.ttl)
-
-	mm.router.mu.Lock()
-	defer mm.router.mu.Unlock()
+// evictLocked descarrega os modelos menos usados até need bytes caberem no
+// orçamento. Chamado com router.mu travado, antes de carregar name.
+func (mm *MemoryManager) evictLocked(name string, need int64) {
+	if mm.budget <= 0 {
+		return
+	}
 
-	switch name {
-	case "llama":
-		if mm.router.llama != nil {
-			mm.router.llama.Close()
-			mm.router.llama = nil
-			mm.router.loaded["llama"] = false
-		}
-	case "qwen":
-		if mm.router.qwen != nil {
-			mm.router.qwen.Close()
-			mm.router.qwen = nil
-			mm.router.loaded["qwen"] = false
+	for _, victim := range mm.leastRecent() {
+		if mm.router.usedLocked()+need <= mm.budget {
+			return
 		}
-	case "vision":
-		if mm.router.vision != nil {
-			mm.router.vision.Close()
-			mm.router.vision = nil
-			mm.router.loaded["vision"] = false
+		if victim == name {
+			continue
 		}
-	case "coder":
-		if mm.router.coder != nil {
-			mm.router.coder.Close()
-			mm.router.coder = nil
-			mm.router.loaded["coder"] = false
+		if mm.router.unloadLocked(victim, "orçamento de memória") {
+			mm.mu.Lock()
+			delete(mm.lastUsed, victim)
+			mm.mu.Unlock()
 		}
 	}
 
-	// Força garbage collection
-	// runtime.GC()
+	if used := mm.router.usedLocked(); used+need > mm.budget {
+		log.Printf("Aviso: %s passa do orçamento de memória (%d MB em uso + %d MB, limite %d MB)",
+			name, used>>20, need>>20, mm.budget>>20)
+	}
 }
 
-// GetStats retorna estatísticas de memória
-func (mm *MemoryManager) GetStats() map[string]interface{} {
+// leastRecent modelos descarregáveis, do usado há mais tempo ao mais recente
+func (mm *MemoryManager) leastRecent() []string {
 	mm.mu.RLock()
 	defer mm.mu.RUnlock()
 
+	var names []string
+	for name := range mm.lastUsed {
+		if !mm.persistent[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return mm.lastUsed[names[i]].Before(mm.lastUsed[names[j]])
+	})
+	return names
+}
+
+// GetStats retorna estatísticas de memória
+func (mm *MemoryManager) GetStats() map[string]interface{} {
 	stats := make(map[string]interface{})
 
+	mm.router.mu.RLock()
 	loaded := []string{}
-	for name, isLoaded := range mm.router.loaded {
-		if isLoaded {
-			loaded = append(loaded, name)
-		}
+	if mm.router.transcriber != nil {
+		loaded = append(loaded, "whisper")
+	}
+	for name := range mm.router.instances {
+		loaded = append(loaded, name)
 	}
+	used := mm.router.usedLocked()
+	mm.router.mu.RUnlock()
+	sort.Strings(loaded)
+
 	stats["loaded_models"] = loaded
 	stats["total_loaded"] = len(loaded)
+	stats["estimated_mb"] = used >> 20
+
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	stats["estimated_budget_mb"] = mm.budget >> 20
 
 	// Tempo desde último uso
 	lastUsed := make(map[string]string)
@@ -143,10 +176,11 @@ func (mm *MemoryManager) GetStats() map[string]interface{} {
 	return stats
 }
 
-// Stop para o gerenciador
+// Stop para o gerenciador e espera a limpeza em andamento
 func (mm *MemoryManager) Stop() {
 	mm.ticker.Stop()
 	close(mm.stopChan)
+	<-mm.done
 }
 
 // SetPersistent define se um modelo deve ficar sempre na memória
@@ -162,3 +196,18 @@ func (mm *MemoryManager) SetTTL(ttl time.Duration) {
 	defer mm.mu.Unlock()
 	mm.ttl = ttl
 }
+
+// modelSize estimativa da memória de um modelo: memory_mb do registro ou o
+// tamanho do .onnx mais o dos pesos externos (.onnx.data), quando existem
+func modelSize(cfg config.ModelConfig) int64 {
+	if cfg.MemoryMB > 0 {
+		return cfg.MemoryMB << 20
+	}
+	var size int64
+	for _, path := range []string{cfg.Path, cfg.Path + ".data"} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,208 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestMemoryBudgetEvictsLeastRecent(t *testing.T) {
+	be, r := newBudgetRouter(t, 2)
+	defer r.Close()
+
+	for _, name := range []string{"llama", "qwen", "coder"} {
+		_, release, err := r.acquire(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// 1 MB cada, 2 MB de orçamento: o Llama, usado há mais tempo, sai
+	if got, want := loadedModels(r), []string{"coder", "phi", "qwen"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("carregados = %v, want %v", got, want)
+	}
+	if stats := r.MemoryStats(); stats["estimated_mb"] != int64(2) {
+		t.Errorf("estimated_mb = %v, want 2", stats["estimated_mb"])
+	}
+
+	// Usar de novo recarrega, agora tirando o Qwen
+	_, release, err := r.acquire("llama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if got, want := loadedModels(r), []string{"coder", "llama", "phi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("carregados = %v, want %v", got, want)
+	}
+	if opened := be.Opened(r.cfg.Models.Entries["llama"].Path); opened != 2 {
+		t.Errorf("Llama aberto %d vezes, want 2", opened)
+	}
+}
+
+func TestMemoryKeepsModelsInUse(t *testing.T) {
+	_, r := newBudgetRouter(t, 1)
+	defer r.Close()
+
+	_, inUse, err := r.acquire("qwen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sem espaço, mas o Qwen está gerando: carrega por cima do orçamento
+	_, release, err := r.acquire("coder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if got, want := loadedModels(r), []string{"coder", "phi", "qwen"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("carregados = %v, want %v", got, want)
+	}
+
+	// Inativos há mais que o TTL: só o que não está em uso sai, e o Phi
+	// (persistente) fica
+	r.models.SetTTL(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	r.models.cleanup()
+	if got, want := loadedModels(r), []string{"phi", "qwen"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("carregados = %v, want %v", got, want)
+	}
+
+	inUse()
+	time.Sleep(time.Millisecond)
+	r.models.cleanup()
+	if got, want := loadedModels(r), []string{"phi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("carregados = %v, want %v", got, want)
+	}
+}
+
+func TestAcquireLoadsOutsideLock(t *testing.T) {
+	cfg, be := budgetConfig(t, 0)
+	llama := cfg.Models.Entries["llama"].Path
+	slow := &slowBackend{Backend: be, path: llama, opening: make(chan struct{}), open: make(chan struct{})}
+	r, err := New(context.Background(), cfg, slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Dois pedidos ao Llama enquanto ele carrega
+	loaded := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, release, err := r.acquire("llama")
+			if err == nil {
+				release()
+			}
+			loaded <- err
+		}()
+	}
+	<-slow.opening
+
+	// O Phi, já carregado, atende sem esperar o Llama
+	acquired := make(chan error, 1)
+	go func() {
+		_, release, err := r.acquire("phi")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire do Phi esperou o carregamento do Llama")
+	}
+
+	close(slow.open)
+	for i := 0; i < 2; i++ {
+		if err := <-loaded; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opened := be.Opened(llama); opened != 1 {
+		t.Errorf("Llama aberto %d vezes, want 1", opened)
+	}
+}
+
+// slowBackend backend cujo Open de path espera open fechar; opening fecha
+// quando o carregamento começa
+type slowBackend struct {
+	*fake.Backend
+	path    string
+	once    sync.Once
+	opening chan struct{}
+	open    chan struct{}
+}
+
+func (b *slowBackend) Open(path string, inputs, outputs []string) (backend.Session, error) {
+	if path == b.path {
+		b.once.Do(func() { close(b.opening) })
+		<-b.open
+	}
+	return b.Backend.Open(path, inputs, outputs)
+}
+
+// newBudgetRouter router com llama, qwen e coder de 1 MB cada e orçamento de
+// budgetMB
+func newBudgetRouter(t *testing.T, budgetMB int64) (*fake.Backend, *Router) {
+	t.Helper()
+	cfg, be := budgetConfig(t, budgetMB)
+	r, err := New(context.Background(), cfg, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return be, r
+}
+
+// budgetConfig config e backend de newBudgetRouter
+func budgetConfig(t *testing.T, budgetMB int64) (*config.Config, *fake.Backend) {
+	t.Helper()
+	cfg := testConfig(t)
+	cfg.Memory.EstimatedBudgetMB = budgetMB
+	cfg.Memory.Persistent = []string{"whisper", "phi"}
+
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText(""))
+
+	dir := t.TempDir()
+	for _, name := range []string{"llama", "qwen", "coder"} {
+		m := cfg.Models.Entries[name]
+		m.Path = filepath.Join(dir, m.Path)
+		if err := os.WriteFile(m.Path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(m.Path, 1<<20); err != nil {
+			t.Fatal(err)
+		}
+		cfg.Models.Entries[name] = m
+		be.Add(m.Path, scriptText(""))
+	}
+	return cfg, be
+}
+
+// loadedModels modelos do registro carregados, em ordem alfabética
+func loadedModels(r *Router) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for name := range r.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,187 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+)
+
+// Memory memória de longo prazo do usuário (assistant.Memory, adaptada em
+// main)
+type Memory interface {
+	// Context o que se sabe do usuário relevante para a frase, do mais para o
+	// menos importante
+	Context(query string) string
+
+	// Learn guarda um fato aprendido na conversa e devolve seu ID
+	Learn(fact LearnedFact) string
+}
+
+// LearnedFact fato sobre o usuário extraído de um turno
+type LearnedFact struct {
+	Category   string  `json:"category"`
+	Subject    string  `json:"subject"`
+	Content    string  `json:"content"`
+	Confidence float64 `json:"confidence"` // 0-1, estimada pelo modelo
+}
+
+// factSchema saída da extração de fatos
+const factSchema = `{
+  "type": "object",
+  "properties": {
+    "facts": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "category": {"enum": ["personal", "work", "preference", "relationship", "health", "routine"]},
+          "subject": {"type": "string"},
+          "content": {"type": "string"},
+          "confidence": {"type": "number"}
+        },
+        "required": ["category", "subject", "content", "confidence"]
+      }
+    }
+  },
+  "required": ["facts"]
+}`
+
+// learnedRecord registro de auditoria: o fato e o turno de onde veio
+type learnedRecord struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Reply  string    `json:"reply"`
+	FactID string    `json:"fact_id"`
+	LearnedFact
+}
+
+// SetMemory liga a memória pessoal: o que se sabe do usuário entra nos
+// prompts e, com personal.learn_facts, cada turno pode ensinar fatos novos
+func (r *Router) SetMemory(m Memory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memory = m
+}
+
+// background bloco de memória para o prompt, cortado por linhas até caber no
+// orçamento de tokens
+func (r *Router) background(model *llm.Model, text string) string {
+	r.mu.RLock()
+	memory := r.memory
+	r.mu.RUnlock()
+	budget := r.cfg.Personal.Budget()
+	if memory == nil || budget <= 0 {
+		return ""
+	}
+
+	known := strings.TrimSpace(memory.Context(text))
+	if known == "" {
+		return ""
+	}
+
+	lines := strings.Split(known, "\n")
+	for len(lines) > 0 && model.CountText(strings.Join(lines, "\n")) > budget {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "O que você sabe sobre o usuário:\n" + strings.Join(lines, "\n")
+}
+
+// learnAsync extrai fatos do turno em segundo plano. A extração divide o Phi
+// com os pedidos do usuário, então o próximo pedido a cancela (stopLearning);
+// Close espera terminar.
+func (r *Router) learnAsync(text, reply string) {
+	if !r.cfg.Personal.LearnFacts {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r.mu.Lock()
+	memory, model := r.memory, r.instances[r.fast].llm
+	if memory == nil || model == nil {
+		r.mu.Unlock()
+		cancel()
+		return
+	}
+	if r.cancelLearning != nil {
+		r.cancelLearning()
+	}
+	r.cancelLearning = cancel
+	r.mu.Unlock()
+
+	r.learning.Add(1)
+	go func() {
+		defer r.learning.Done()
+		defer cancel()
+		err := r.learn(ctx, model, memory, text, reply)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Aviso: erro ao extrair fatos: %v", err)
+		}
+	}()
+}
+
+// stopLearning cancela a extração em andamento, liberando o Phi
+func (r *Router) stopLearning() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancelLearning != nil {
+		r.cancelLearning()
+		r.cancelLearning = nil
+	}
+}
+
+// learn pede ao modelo os fatos novos que o usuário revelou no turno
+func (r *Router) learn(ctx context.Context, model *llm.Model, memory Memory, text, reply string) error {
+	prompt := fmt.Sprintf(`Extraia fatos duradouros sobre o usuário que aparecem na frase dele:
+nome, família, trabalho, preferências, saúde, rotina. Ignore pedidos pontuais
+e o que não for sobre o usuário. Para cada fato, dê a confiança de 0 a 1.
+Se não houver nenhum, retorne a lista vazia.
+
+Usuário: %s
+Assistente: %s
+
+JSON:`, text, reply)
+
+	result, err := model.GenerateJSON(ctx, prompt, []byte(factSchema))
+	if err != nil {
+		return err
+	}
+
+	var extracted struct {
+		Facts []LearnedFact `json:"facts"`
+	}
+	if err := json.Unmarshal([]byte(result), &extracted); err != nil {
+		return fmt.Errorf("resposta inválida: %w", err)
+	}
+
+	for _, fact := range extracted.Facts {
+		if fact.Confidence < r.cfg.Personal.MinConfidence ||
+			strings.TrimSpace(fact.Subject) == "" || strings.TrimSpace(fact.Content) == "" {
+			continue
+		}
+
+		id := memory.Learn(fact)
+		log.Printf("🧠 Aprendido: %s: %s (%.0f%%)", fact.Subject, fact.Content, 100*fact.Confidence)
+
+		if r.learned != nil {
+			err := r.learned.record(learnedRecord{
+				Time:        time.Now(),
+				User:        text,
+				Reply:       reply,
+				FactID:      id,
+				LearnedFact: fact,
+			})
+			if err != nil {
+				log.Printf("Aviso: erro ao gravar auditoria de fatos: %v", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+)
+
+// fakeMemory memória com contexto fixo que guarda o que aprende
+type fakeMemory struct {
+	context string
+
+	mu      sync.Mutex
+	learned []LearnedFact
+}
+
+func (m *fakeMemory) Context(string) string { return m.context }
+
+func (m *fakeMemory) Learn(fact LearnedFact) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.learned = append(m.learned, fact)
+	return fmt.Sprintf("fato-%d", len(m.learned))
+}
+
+func TestProcessWithMemory(t *testing.T) {
+	about := "## Sobre o usuário:\n- Nome: Ana"
+	memory := &fakeMemory{context: about + "\n\n## Fatos relevantes:\n- João: irmão da Ana\n"}
+
+	tokens := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		budget   *int
+		want     []string
+		unwanted []string
+	}{
+		{"cabe tudo", tokens(1000), []string{"Nome: Ana", "João: irmão da Ana"}, nil},
+		// Tokenizer byte-level: um token por byte
+		{"corta pelo fim", tokens(len(about)), []string{"Nome: Ana"}, []string{"João"}},
+		{"nada cabe", tokens(5), nil, []string{"Ana", "O que você sabe"}},
+		// Config montada à mão, sem context_tokens: vale o padrão
+		{"sem orçamento", nil, []string{"Nome: Ana", "João: irmão da Ana"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			be := fake.New()
+			be.Add("whisper.onnx", fake.Transcripts("Liga pro meu irmão"))
+			be.Add("phi.onnx", recordPrompts(scriptText("Ligando."), &prompts))
+
+			cfg := testConfig(t)
+			cfg.Personal.ContextTokens = tt.budget
+
+			r, err := New(context.Background(), cfg, be)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			r.SetClassifier(fixedClassifier{&Classification{Intent: IntentSimple, Confidence: 1}})
+			r.SetMemory(memory)
+
+			if _, err := r.Process(context.Background(), make([]float32, 1600)); err != nil {
+				t.Fatal(err)
+			}
+
+			prompt := prompts[0]
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt sem %q:\n%s", want, prompt)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(prompt, unwanted) {
+					t.Errorf("prompt não deveria ter %q:\n%s", unwanted, prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestLearnFacts(t *testing.T) {
+	extraction := `{"facts":[` +
+		`{"category":"relationship","subject":"João","content":"irmão do usuário","confidence":0.9},` +
+		`{"category":"preference","subject":"café","content":"talvez goste","confidence":0.2}]}`
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    int
+	}{
+		{"aprende com confiança suficiente", true, 1},
+		{"privacidade desligada não aprende", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := fake.New()
+			be.Add("whisper.onnx", fake.Transcripts("Meu irmão João chega amanhã"))
+			be.Add("phi.onnx", scriptText("Que bom!", extraction))
+
+			cfg := testConfig(t)
+			cfg.Personal.LearnFacts = tt.enabled
+			cfg.Personal.AuditPath = filepath.Join(t.TempDir(), "learned.jsonl")
+
+			r, err := New(context.Background(), cfg, be)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.SetClassifier(fixedClassifier{&Classification{Intent: IntentSimple, Confidence: 1}})
+			memory := &fakeMemory{}
+			r.SetMemory(memory)
+
+			if _, err := r.Process(context.Background(), make([]float32, 1600)); err != nil {
+				t.Fatal(err)
+			}
+			r.Close() // Espera a extração
+
+			if len(memory.learned) != tt.want {
+				t.Fatalf("aprendeu %+v, want %d fato(s)", memory.learned, tt.want)
+			}
+			if tt.want == 0 {
+				if _, err := os.Stat(cfg.Personal.AuditPath); !os.IsNotExist(err) {
+					t.Errorf("auditoria criada com o aprendizado desligado")
+				}
+				return
+			}
+			if f := memory.learned[0]; f.Subject != "João" || f.Category != "relationship" {
+				t.Errorf("fato = %+v", f)
+			}
+
+			data, err := os.ReadFile(cfg.Personal.AuditPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var record learnedRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("auditoria inválida: %v\n%s", err, data)
+			}
+			if record.User != "Meu irmão João chega amanhã" || record.Reply != "Que bom!" ||
+				record.FactID != "fato-1" || record.Subject != "João" {
+				t.Errorf("auditoria = %+v", record)
+			}
+		})
+	}
+}
@@ -2,11 +2,16 @@ package router
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/coder"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
@@ -36,158 +41,220 @@ type Input struct {
 // Response representa a resposta do sistema
 type Response struct {
 	Text    string
+	Intent  Intent
 	Action  *actions.Action
+	Steps   []AgentStep // Ferramentas usadas pelo agente, em ordem
 	Success bool
 }
 
 // Router gerencia os modelos e roteia requisições
 type Router struct {
-	// STT
-	whisper *stt.Whisper
+	// STT (Whisper em ONNX ou whisper.cpp, conforme stt.backend)
+	transcriber stt.Transcriber
 
-	// LLMs
-	phi   *llm.Model // Rápido
-	llama *llm.Model // Contexto
-	qwen  *llm.Model // Ações
-
-	// Especialistas
-	vision *vision.Model // Ver tela
-	coder  *coder.Model  // Código
+	// Modelo de chat carregado em New: classificação, memória e resumos
+	fast string
 
 	// Executor de ações
 	executor *actions.Executor
 
+	// Comandos locais, atendidos antes da classificação
+	commands CommandHandler
+
+	// Classificação de intenção
+	classifier Classifier
+	decisions  *jsonLog
+
+	// Passos do agente de ações
+	traces *jsonLog
+
+	// Conversa em andamento
+	session *Session
+
+	// Memória pessoal
+	memory         Memory
+	learned        *jsonLog // Auditoria dos fatos aprendidos
+	learning       sync.WaitGroup
+	cancelLearning context.CancelFunc // Extração em andamento
+
+	// Runtime de inferência de todos os modelos
+	backend backend.Backend
+
 	// Config
 	cfg *config.Config
 	mu  sync.RWMutex
 
-	// Pool de modelos carregados
-	loaded map[string]bool
+	// Pool de modelos carregados, pelo nome da entrada no registro
+	instances map[string]*instance
+	refs      map[string]int   // Pedidos em andamento por modelo
+	sizes     map[string]int64 // Memória estimada de cada modelo carregado
+	loading   map[string]*loading
+	models    *MemoryManager
+}
+
+// loading carregamento em andamento, fora de mu: quem pede o mesmo modelo
+// espera done em vez de carregar de novo
+type loading struct {
+	done chan struct{}
+	size int64 // Já conta no orçamento enquanto carrega
+	err  error
+}
+
+// instance modelo carregado; o campo preenchido depende do papel
+type instance struct {
+	llm    *llm.Model    // chat, context e action
+	vision *vision.Model // vision
+	coder  *coder.Model  // code
+}
+
+// Close libera o modelo
+func (i *instance) Close() error {
+	switch {
+	case i.llm != nil:
+		return i.llm.Close()
+	case i.vision != nil:
+		return i.vision.Close()
+	case i.coder != nil:
+		return i.coder.Close()
+	}
+	return nil
 }
 
-// New cria um novo Router
-func New(ctx context.Context, cfg *config.Config) (*Router, error) {
+// New cria um novo Router com os modelos executados pelo backend
+func New(ctx context.Context, cfg *config.Config, be backend.Backend) (*Router, error) {
 	r := &Router{
-		cfg:    cfg,
-		loaded: make(map[string]bool),
+		backend:   be,
+		cfg:       cfg,
+		instances: make(map[string]*instance),
+		refs:      make(map[string]int),
+		sizes:     make(map[string]int64),
+		loading:   make(map[string]*loading),
 	}
+	fast, ok := cfg.Models.ForRole(config.RoleChat)
+	if !ok {
+		return nil, fmt.Errorf("nenhum modelo com role %s no config", config.RoleChat)
+	}
+	r.fast = fast
+	r.models = NewMemoryManager(r, cfg.Memory)
 
 	// Whisper sempre carrega (STT principal)
-	log.Println("  → Carregando Whisper (STT)...")
-	whisper, err := stt.NewWhisper(cfg.STT)
+	log.Printf("  → Carregando Whisper (STT, %s)...", cfg.STT.Backend)
+	transcriber, err := stt.New(be, cfg.STT)
 	if err != nil {
+		r.models.Stop()
 		return nil, err
 	}
-	r.whisper = whisper
-	r.loaded["whisper"] = true
+	r.transcriber = transcriber
 
 	// Carrega modelos conforme configuração
 	if cfg.Models.LoadAll {
-		if err := r.loadAllModels(ctx); err != nil {
-			return nil, err
-		}
+		err = r.loadAllModels(ctx)
 	} else {
-		// Lazy loading - carrega só o Phi (rápido) por padrão
-		log.Println("  → Carregando Phi-3.5 (LLM rápido)...")
-		phi, err := llm.New(cfg.Models.Phi)
-		if err != nil {
-			return nil, err
-		}
-		r.phi = phi
-		r.loaded["phi"] = true
+		// Lazy loading - carrega o de chat (rápido) e os marcados com preload
+		err = r.preload()
+	}
+	if err != nil {
+		r.models.Stop()
+		return nil, err
 	}
 
-	// Executor de ações
+	// Executor de ações, com a política de confirmação
 	r.executor = actions.NewExecutor()
+	policy, err := actions.NewPolicy(cfg.Policy)
+	if err != nil {
+		r.models.Stop()
+		return nil, err
+	}
+	r.executor.SetPolicy(policy)
+	commands, err := actions.NewCommandRunner(cfg.Actions)
+	if err != nil {
+		r.models.Stop()
+		return nil, err
+	}
+	r.executor.SetCommandRunner(commands)
 
-	log.Println("  ✓ Router inicializado!")
-	return r, nil
-}
-
-// loadAllModels carrega todos os modelos na memória
-func (r *Router) loadAllModels(ctx context.Context) error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, 5)
-
-	// Phi
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Println("  → Carregando Phi-3.5...")
-		phi, err := llm.New(r.cfg.Models.Phi)
+	// Classificador padrão: o modelo de chat escolhendo entre as intenções. O
+	// classificador por embeddings entra via SetClassifier.
+	r.classifier = NewLLMClassifier(r.FastModel())
+	if cfg.Intent.LogPath != "" {
+		decisions, err := openJSONLog(cfg.Intent.LogPath)
 		if err != nil {
-			errChan <- err
-			return
+			log.Printf("Aviso: decisões de intenção não serão gravadas: %v", err)
+		} else {
+			r.decisions = decisions
 		}
-		r.mu.Lock()
-		r.phi = phi
-		r.loaded["phi"] = true
-		r.mu.Unlock()
-	}()
-
-	// Llama
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Println("  → Carregando Llama 3.2...")
-		llama, err := llm.New(r.cfg.Models.Llama)
+	}
+
+	if cfg.Agent.TracePath != "" {
+		traces, err := openJSONLog(cfg.Agent.TracePath)
 		if err != nil {
-			errChan <- err
-			return
+			log.Printf("Aviso: passos do agente não serão gravados: %v", err)
+		} else {
+			r.traces = traces
 		}
-		r.mu.Lock()
-		r.llama = llama
-		r.loaded["llama"] = true
-		r.mu.Unlock()
-	}()
-
-	// Qwen
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Println("  → Carregando Qwen 2.5...")
-		qwen, err := llm.New(r.cfg.Models.Qwen)
+	}
+
+	if cfg.Personal.LearnFacts && cfg.Personal.AuditPath != "" {
+		learned, err := openJSONLog(cfg.Personal.AuditPath)
 		if err != nil {
-			errChan <- err
-			return
+			log.Printf("Aviso: fatos aprendidos não serão auditados: %v", err)
+		} else {
+			r.learned = learned
 		}
-		r.mu.Lock()
-		r.qwen = qwen
-		r.loaded["qwen"] = true
-		r.mu.Unlock()
-	}()
-
-	// Vision
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Println("  → Carregando MiniCPM-V (visão)...")
-		v, err := vision.New(r.cfg.Models.Vision)
-		if err != nil {
-			errChan <- err
-			return
+	}
+
+	session, err := OpenSession(cfg.Session)
+	if err != nil {
+		log.Printf("Aviso: conversa anterior não pôde ser retomada: %v", err)
+		session, _ = OpenSession(config.SessionConfig{
+			IdleTimeout: cfg.Session.IdleTimeout,
+			MaxTurns:    cfg.Session.MaxTurns,
+		})
+	}
+	r.session = session
+
+	log.Println("  ✓ Router inicializado!")
+	return r, nil
+}
+
+// preload carrega o modelo de chat e as entradas marcadas com preload
+func (r *Router) preload() error {
+	for _, name := range r.loadable() {
+		if name != r.fast && !r.cfg.Models.Entries[name].Preload {
+			continue
 		}
-		r.mu.Lock()
-		r.vision = v
-		r.loaded["vision"] = true
-		r.mu.Unlock()
-	}()
-
-	// Coder
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Println("  → Carregando Qwen-Coder...")
-		c, err := coder.New(r.cfg.Models.Coder)
-		if err != nil {
-			errChan <- err
-			return
+		if err := r.load(name); err != nil {
+			return err
 		}
-		r.mu.Lock()
-		r.coder = c
-		r.loaded["coder"] = true
-		r.mu.Unlock()
-	}()
+		r.models.Touch(name)
+	}
+	return nil
+}
+
+// loadAllModels carrega todos os modelos na memória
+func (r *Router) loadAllModels(ctx context.Context) error {
+	names := r.loadable()
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(names))
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			cfg := r.cfg.Models.Entries[name]
+			log.Printf("  → Carregando %s (%s)...", cfg.Name, cfg.Role)
+			inst, err := r.newInstance(cfg)
+			if err != nil {
+				errChan <- fmt.Errorf("erro ao carregar %s: %w", name, err)
+				return
+			}
+			r.mu.Lock()
+			r.instances[name] = inst
+			r.sizes[name] = modelSize(cfg)
+			r.mu.Unlock()
+			r.models.Touch(name)
+		}(name)
+	}
 
 	wg.Wait()
 	close(errChan)
@@ -202,10 +269,65 @@ func (r *Router) loadAllModels(ctx context.Context) error {
 	return nil
 }
 
+// loadable entradas do registro que o router carrega (stt e embed ficam com
+// as próprias seções), em ordem alfabética
+func (r *Router) loadable() []string {
+	var names []string
+	for name, model := range r.cfg.Models.Entries {
+		switch model.Role {
+		case config.RoleChat, config.RoleContext, config.RoleAction, config.RoleCode, config.RoleVision:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newInstance carrega o modelo com a implementação do seu papel
+func (r *Router) newInstance(cfg config.ModelConfig) (*instance, error) {
+	if cfg.Backend != "" && !strings.EqualFold(cfg.Backend, r.cfg.Backend.Name) {
+		return nil, fmt.Errorf("%s pede o backend %s, mas o ativo é %s", cfg.Name, cfg.Backend, r.cfg.Backend.Name)
+	}
+
+	switch cfg.Role {
+	case config.RoleCode:
+		c, err := coder.New(r.backend, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &instance{coder: c}, nil
+	case config.RoleVision:
+		v, err := vision.New(r.backend, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &instance{vision: v}, nil
+	case config.RoleChat, config.RoleContext, config.RoleAction:
+		m, err := llm.New(r.backend, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &instance{llm: m}, nil
+	}
+	return nil, fmt.Errorf("%s tem role %s, que o router não carrega", cfg.Name, cfg.Role)
+}
+
 // Process processa a entrada e retorna resposta
 func (r *Router) Process(ctx context.Context, audioData []float32) (*Response, error) {
+	return r.ProcessStream(ctx, audioData, nil)
+}
+
+// ProcessStream processa a entrada como Process, entregando o texto da
+// resposta ao callback conforme é gerado. Respostas que não vêm de geração
+// livre (ações, visão) não passam pelo callback; use Response.Text.
+func (r *Router) ProcessStream(ctx context.Context, audioData []float32, onToken llm.TokenCallback) (*Response, error) {
+	// O pedido do usuário tem prioridade sobre a extração de fatos do turno
+	// anterior
+	r.stopLearning()
+	asked := time.Now()
+
 	// 1. Transcreve áudio
-	text, err := r.whisper.Transcribe(audioData)
+	text, err := r.transcriber.Transcribe(audioData)
 	if err != nil {
 		return nil, err
 	}
@@ -215,10 +337,93 @@ func (r *Router) Process(ctx context.Context, audioData []float32) (*Response, e
 	}
 
 	log.Printf("🎤 Você: %s", text)
+	return r.respond(ctx, asked, text, nil, onToken)
+}
+
+// ProcessText processa um pedido já em texto (REPL, API), sem passar pelo
+// Whisper
+func (r *Router) ProcessText(ctx context.Context, text string) (*Response, error) {
+	return r.ProcessTextStream(ctx, text, nil)
+}
+
+// ProcessTextStream processa o texto como ProcessText, entregando a resposta
+// ao callback conforme é gerada (veja ProcessStream)
+func (r *Router) ProcessTextStream(ctx context.Context, text string, onToken llm.TokenCallback) (*Response, error) {
+	r.stopLearning()
+	asked := time.Now()
 
-	// 2. Detecta intenção
-	intent := r.detectIntent(text)
-	log.Printf("🎯 Intenção: %s", intent)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return &Response{}, nil
+	}
+
+	log.Printf("⌨️ Você: %s", text)
+	return r.respond(ctx, asked, text, nil, onToken)
+}
+
+// Transcribe só transcreve o áudio, sem responder
+func (r *Router) Transcribe(audioData []float32) (string, error) {
+	return r.transcriber.Transcribe(audioData)
+}
+
+// Segments transcreve o áudio em trechos com início e fim, sem responder
+func (r *Router) Segments(audioData []float32) ([]stt.Segment, error) {
+	return r.transcriber.Segments(audioData)
+}
+
+// TranscribeStream transcreve o áudio em passos de stt.stream, como se ele
+// chegasse do microfone, entregando as hipóteses a onHypothesis; sem responder
+func (r *Router) TranscribeStream(audioData []float32, onHypothesis stt.HypothesisCallback) (string, error) {
+	stream := stt.NewStream(r.transcriber, r.cfg.STT.Stream, onHypothesis)
+	step := r.cfg.STT.Stream.StepMs * stt.SampleRate / 1000
+	for start := 0; start < len(audioData); start += step {
+		if err := stream.Write(audioData[start:min(start+step, len(audioData))]); err != nil {
+			return "", err
+		}
+	}
+	return stream.Finish()
+}
+
+// respond atende o pedido transcrito ou digitado: comandos locais,
+// classificação e o modelo da intenção. asked é quando o pedido chegou;
+// guess (pode ser nil) é a classificação adiantada sobre o texto parcial.
+func (r *Router) respond(ctx context.Context, asked time.Time, text string, guess *intentGuess, onToken llm.TokenCallback) (*Response, error) {
+	// "confirmar"/"cancelar" respondem à ação que a política segurou
+	if response, ok := r.confirm(text); ok {
+		log.Printf("🤖 NPU-IA: %s", response.Text)
+		r.remember(asked, text, response)
+		return response, nil
+	}
+
+	// Comandos locais (foco, música, notas) não passam pelos modelos
+	r.mu.RLock()
+	commands := r.commands
+	r.mu.RUnlock()
+	if commands != nil {
+		if reply, ok := commands(text); ok {
+			response := &Response{Text: reply, Intent: IntentAction, Success: true}
+			log.Printf("🤖 NPU-IA: %s", response.Text)
+			r.remember(asked, text, response)
+			return response, nil
+		}
+	}
+
+	// 2. Classifica a intenção (se a fala não mudou, a adiantada vale); na
+	// dúvida, pergunta
+	class := guess.result(text)
+	var err error
+	if class == nil {
+		if class, err = r.classify(ctx, text); err != nil {
+			return nil, err
+		}
+	}
+	if class.Confidence < r.cfg.Intent.MinConfidence {
+		response := &Response{Text: clarification(class), Intent: class.Intent}
+		log.Printf("🤖 NPU-IA: %s", response.Text)
+		r.remember(asked, text, response)
+		return response, nil
+	}
+	intent := class.Intent
 
 	// 3. Roteia para modelo apropriado
 	var response *Response
@@ -231,116 +436,171 @@ func (r *Router) Process(ctx context.Context, audioData []float32) (*Response, e
 	case IntentAction:
 		response, err = r.handleAction(ctx, text)
 	case IntentContext:
-		response, err = r.handleContext(ctx, text)
+		response, err = r.handleContext(ctx, text, onToken)
 	default:
-		response, err = r.handleSimple(ctx, text)
+		response, err = r.handleSimple(ctx, text, onToken)
 	}
 
 	if err != nil {
 		return nil, err
 	}
+	response.Intent = intent
 
 	log.Printf("🤖 NPU-IA: %s", response.Text)
+	r.remember(asked, text, response)
+	r.learnAsync(text, response.Text)
 	return response, nil
 }
 
-// detectIntent detecta a intenção do usuário
-func (r *Router) detectIntent(text string) Intent {
-	lower := strings.ToLower(text)
+// FastModel modelo rápido (role chat), sempre carregado (New falha sem ele);
+// serve a quem precisa de um LLM de uso geral (resumos, memória)
+func (r *Router) FastModel() *llm.Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.instances[r.fast].llm
+}
 
-	// Visão
-	visionKeywords := []string{"tela", "vendo", "olha", "mostra", "screenshot", "imagem", "o que tem"}
-	for _, kw := range visionKeywords {
-		if strings.Contains(lower, kw) {
-			return IntentVision
-		}
-	}
+// Executor executor das ações geradas pelo modelo de ações
+func (r *Router) Executor() *actions.Executor {
+	return r.executor
+}
 
-	// Código
-	codeKeywords := []string{"código", "codigo", "função", "funcao", "bug", "erro", "programa", "script", "python", "go ", "javascript"}
-	for _, kw := range codeKeywords {
-		if strings.Contains(lower, kw) {
-			return IntentCode
-		}
+// Session conversa em andamento
+func (r *Router) Session() *Session {
+	return r.session
+}
+
+// remember registra o turno na sessão; asked é quando o pedido chegou
+func (r *Router) remember(asked time.Time, text string, response *Response) {
+	if err := r.session.Append(response.Intent, asked, text, response.Text); err != nil {
+		log.Printf("Aviso: erro ao gravar conversa: %v", err)
 	}
+}
 
-	// Ações
-	actionKeywords := []string{"abre", "abra", "fecha", "feche", "envia", "manda", "lê ", "ler ", "email", "chrome", "navegador", "volume", "brilho"}
-	for _, kw := range actionKeywords {
-		if strings.Contains(lower, kw) {
-			return IntentAction
-		}
+// CommandHandler atende um comando local a partir da frase do usuário; ok
+// falso segue para a classificação e os modelos
+type CommandHandler func(text string) (reply string, ok bool)
+
+// SetCommandHandler liga os comandos locais, verificados antes de qualquer
+// modelo: a resposta deles substitui a do LLM em vez de somar-se a ela
+func (r *Router) SetCommandHandler(h CommandHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = h
+}
+
+// SetClassifier troca o classificador de intenção
+func (r *Router) SetClassifier(c Classifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.classifier = c
+}
+
+// classify classifica a intenção e registra a decisão com as pontuações
+func (r *Router) classify(ctx context.Context, text string) (*Classification, error) {
+	classifier := r.currentClassifier()
+	class, err := classifier.Classify(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao classificar intenção: %w", err)
 	}
+	r.recordDecision(classifier, text, class)
+	return class, nil
+}
+
+// currentClassifier classificador em uso
+func (r *Router) currentClassifier() Classifier {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.classifier
+}
 
-	// Contexto longo
-	contextKeywords := []string{"explica", "conte", "história", "como funciona", "por que", "porque"}
-	for _, kw := range contextKeywords {
-		if strings.Contains(lower, kw) {
-			return IntentContext
+// recordDecision registra no log e em intent.log_path a intenção da frase
+func (r *Router) recordDecision(classifier Classifier, text string, class *Classification) {
+	asked := class.Confidence < r.cfg.Intent.MinConfidence
+	log.Printf("🎯 Intenção: %s (%.0f%%, %s) %v", class.Intent, 100*class.Confidence, classifier.Name(), class.Scores)
+
+	if r.decisions != nil {
+		err := r.decisions.record(intentDecision{
+			Time:       time.Now(),
+			Text:       text,
+			Classifier: classifier.Name(),
+			Intent:     class.Intent,
+			Confidence: class.Confidence,
+			Scores:     class.Scores,
+			Asked:      asked,
+		})
+		if err != nil {
+			log.Printf("Aviso: erro ao gravar decisão de intenção: %v", err)
 		}
 	}
-
-	return IntentSimple
 }
 
-// handleSimple usa Phi para respostas rápidas
-func (r *Router) handleSimple(ctx context.Context, text string) (*Response, error) {
-	r.ensureLoaded("phi")
-	result, err := r.phi.Generate(ctx, text)
+// handleSimple usa o modelo de chat para respostas rápidas
+func (r *Router) handleSimple(ctx context.Context, text string, onToken llm.TokenCallback) (*Response, error) {
+	model, release, err := r.acquireRole(config.RoleChat)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	result, err := r.converse(ctx, model.llm, text, onToken)
 	if err != nil {
 		return nil, err
 	}
 	return &Response{Text: result, Success: true}, nil
 }
 
-// handleAction usa Qwen para ações
-func (r *Router) handleAction(ctx context.Context, text string) (*Response, error) {
-	r.ensureLoaded("qwen")
+// converse gera a resposta com os turnos anteriores da conversa e o que se
+// sabe do usuário, no limite da janela de contexto do modelo
+func (r *Router) converse(ctx context.Context, model *llm.Model, text string, onToken llm.TokenCallback) (string, error) {
+	return model.GenerateChat(ctx, model.Conversation(r.history(), r.background(model, text), text), onToken)
+}
 
-	// Gera o comando de ação
-	result, err := r.qwen.GenerateAction(ctx, text)
+// history turnos anteriores da conversa atual
+func (r *Router) history() []chat.Message {
+	return r.session.History()
+}
+
+// handleAction usa o modelo de ações, em passos de ferramenta até a
+// resposta (veja runAgent)
+func (r *Router) handleAction(ctx context.Context, text string) (*Response, error) {
+	model, release, err := r.acquireRole(config.RoleAction)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
+	return r.runAgent(ctx, model.llm, text)
+}
 
-	// Executa a ação
-	action, err := r.executor.Execute(result)
+// handleContext usa o modelo de contexto para conversas longas
+func (r *Router) handleContext(ctx context.Context, text string, onToken llm.TokenCallback) (*Response, error) {
+	model, release, err := r.acquireRole(config.RoleContext)
 	if err != nil {
-		return &Response{
-			Text:    "Desculpe, não consegui executar: " + err.Error(),
-			Success: false,
-		}, nil
+		return nil, err
 	}
-
-	return &Response{
-		Text:    action.Response,
-		Action:  action,
-		Success: true,
-	}, nil
-}
-
-// handleContext usa Llama para conversas longas
-func (r *Router) handleContext(ctx context.Context, text string) (*Response, error) {
-	r.ensureLoaded("llama")
-	result, err := r.llama.Generate(ctx, text)
+	defer release()
+	result, err := r.converse(ctx, model.llm, text, onToken)
 	if err != nil {
 		return nil, err
 	}
 	return &Response{Text: result, Success: true}, nil
 }
 
-// handleVision usa MiniCPM-V para ver a tela
+// handleVision usa o modelo de visão para ver a tela
 func (r *Router) handleVision(ctx context.Context, text string) (*Response, error) {
-	r.ensureLoaded("vision")
+	model, release, err := r.acquireRole(config.RoleVision)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	// Captura screenshot
-	screenshot, err := r.vision.CaptureScreen()
+	screenshot, err := model.vision.CaptureScreen()
 	if err != nil {
 		return nil, err
 	}
 
 	// Analisa com visão
-	result, err := r.vision.Analyze(ctx, screenshot, text)
+	result, err := model.vision.Analyze(ctx, screenshot, r.history(), text)
 	if err != nil {
 		return nil, err
 	}
@@ -348,76 +608,168 @@ func (r *Router) handleVision(ctx context.Context, text string) (*Response, erro
 	return &Response{Text: result, Success: true}, nil
 }
 
-// handleCode usa Qwen-Coder para código
+// handleCode usa o modelo de código
 func (r *Router) handleCode(ctx context.Context, text string) (*Response, error) {
-	r.ensureLoaded("coder")
-	result, err := r.coder.Generate(ctx, text)
+	model, release, err := r.acquireRole(config.RoleCode)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	result, err := model.coder.Generate(ctx, r.history(), text)
 	if err != nil {
 		return nil, err
 	}
 	return &Response{Text: result, Success: true}, nil
 }
 
-// ensureLoaded carrega modelo se necessário (lazy loading)
-func (r *Router) ensureLoaded(name string) {
-	r.mu.RLock()
-	if r.loaded[name] {
-		r.mu.RUnlock()
-		return
+// acquireRole reserva o modelo que atende role; sem modelo de contexto, o
+// de chat atende as conversas longas
+func (r *Router) acquireRole(role string) (*instance, func(), error) {
+	name, ok := r.cfg.Models.ForRole(role)
+	if !ok && role == config.RoleContext {
+		name, ok = r.fast, true
 	}
-	r.mu.RUnlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("nenhum modelo com role %s no config", role)
+	}
+	return r.acquire(name)
+}
 
+// acquire carrega o modelo se necessário (lazy loading) e o reserva até
+// release: o MemoryManager não descarrega modelo com pedido em andamento
+func (r *Router) acquire(name string) (*instance, func(), error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Double check
-	if r.loaded[name] {
-		return
+	// Outro carregamento pode descarregá-lo antes de travar de novo
+	for r.instances[name] == nil {
+		r.mu.Unlock()
+		err := r.load(name)
+		r.mu.Lock()
+		if err != nil {
+			return nil, nil, err
+		}
 	}
+	r.refs[name]++
+	r.models.Touch(name)
 
-	log.Printf("  → Carregando %s sob demanda...", name)
+	release := func() {
+		r.mu.Lock()
+		r.refs[name]--
+		r.mu.Unlock()
+		r.models.Touch(name)
+	}
+	return r.instances[name], release, nil
+}
 
-	var err error
-	switch name {
-	case "phi":
-		r.phi, err = llm.New(r.cfg.Models.Phi)
-	case "llama":
-		r.llama, err = llm.New(r.cfg.Models.Llama)
-	case "qwen":
-		r.qwen, err = llm.New(r.cfg.Models.Qwen)
-	case "vision":
-		r.vision, err = vision.New(r.cfg.Models.Vision)
-	case "coder":
-		r.coder, err = coder.New(r.cfg.Models.Coder)
+// load carrega um modelo, abrindo espaço no orçamento de memória antes. A
+// leitura dos pesos é fora de r.mu, para não travar os pedidos aos modelos já
+// carregados; quem pede um modelo que está carregando espera o mesmo
+// carregamento.
+func (r *Router) load(name string) error {
+	r.mu.Lock()
+	if r.instances[name] != nil {
+		r.mu.Unlock()
+		return nil
+	}
+	if l := r.loading[name]; l != nil {
+		r.mu.Unlock()
+		<-l.done
+		return l.err
+	}
+	cfg, ok := r.cfg.Models.Entries[name]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("erro ao carregar %s: modelo desconhecido", name)
 	}
+	l := &loading{done: make(chan struct{}), size: modelSize(cfg)}
+	r.models.evictLocked(name, l.size)
+	r.loading[name] = l
+	r.mu.Unlock()
 
+	log.Printf("  → Carregando %s (%s) sob demanda...", cfg.Name, cfg.Role)
+	inst, err := r.newInstance(cfg)
+
+	r.mu.Lock()
+	delete(r.loading, name)
 	if err != nil {
-		log.Printf("Erro ao carregar %s: %v", name, err)
-		return
+		l.err = fmt.Errorf("erro ao carregar %s: %w", cfg.Name, err)
+	} else {
+		r.instances[name] = inst
+		r.sizes[name] = l.size
 	}
+	r.mu.Unlock()
+	close(l.done)
+	return l.err
+}
 
-	r.loaded[name] = true
+// unload descarrega um modelo se ninguém o estiver usando
+func (r *Router) unload(name, reason string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unloadLocked(name, reason)
+}
+
+// unloadLocked descarrega um modelo sem pedidos em andamento; chamado com
+// r.mu travado. O de chat fica: FastModel o entrega a quem guarda a
+// referência.
+func (r *Router) unloadLocked(name, reason string) bool {
+	inst := r.instances[name]
+	if inst == nil {
+		return true
+	}
+	if r.refs[name] > 0 || name == r.fast {
+		return false
+	}
+
+	log.Printf("💤 Descarregando %s (%s)", name, reason)
+	inst.Close()
+	delete(r.instances, name)
+	delete(r.sizes, name)
+	return true
+}
+
+// usedLocked memória estimada dos modelos carregados e dos que estão
+// carregando; chamado com r.mu travado
+func (r *Router) usedLocked() int64 {
+	var used int64
+	for name := range r.instances {
+		used += r.sizes[name]
+	}
+	for _, l := range r.loading {
+		used += l.size
+	}
+	return used
+}
+
+// MemoryStats modelos carregados, memória estimada e último uso de cada um
+func (r *Router) MemoryStats() map[string]interface{} {
+	return r.models.GetStats()
 }
 
 // Close libera recursos
 func (r *Router) Close() error {
-	if r.whisper != nil {
-		r.whisper.Close()
+	// Extrações de fatos em andamento ainda usam o Phi
+	r.learning.Wait()
+	r.models.Stop()
+
+	if r.transcriber != nil {
+		r.transcriber.Close()
 	}
-	if r.phi != nil {
-		r.phi.Close()
+	for _, inst := range r.instances {
+		inst.Close()
 	}
-	if r.llama != nil {
-		r.llama.Close()
+	if r.decisions != nil {
+		r.decisions.Close()
 	}
-	if r.qwen != nil {
-		r.qwen.Close()
+	if r.traces != nil {
+		r.traces.Close()
 	}
-	if r.vision != nil {
-		r.vision.Close()
+	if r.learned != nil {
+		r.learned.Close()
 	}
-	if r.coder != nil {
-		r.coder.Close()
+	if policy := r.executor.Policy(); policy != nil {
+		policy.Close()
 	}
 	return nil
 }
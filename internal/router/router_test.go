@@ -0,0 +1,448 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		intent     Intent
+		confidence float32
+		models     map[string]*fake.Model // roteiros além do Whisper
+		wantText   string
+		wantAction string
+		wantRuns   string // modelo que deve ter sido executado
+		wantErr    bool
+	}{
+		{
+			name:       "sem fala",
+			transcript: "",
+		},
+		{
+			name:       "pergunta simples usa o phi",
+			transcript: "Bom dia",
+			intent:     IntentSimple,
+			models:     map[string]*fake.Model{"phi.onnx": scriptText("Bom dia!")},
+			wantText:   "Bom dia!",
+			wantRuns:   "phi.onnx",
+		},
+		{
+			name:       "explicação usa o llama",
+			transcript: "Explica como funciona um motor",
+			intent:     IntentContext,
+			models:     map[string]*fake.Model{"llama.onnx": scriptText("Combustão interna.")},
+			wantText:   "Combustão interna.",
+			wantRuns:   "llama.onnx",
+		},
+		{
+			name:       "código usa o coder",
+			transcript: "Cria uma função em Go",
+			intent:     IntentCode,
+			models:     map[string]*fake.Model{"coder.onnx": scriptText("func soma(a, b int) int { return a + b }")},
+			wantText:   "func soma(a, b int) int { return a + b }",
+			wantRuns:   "coder.onnx",
+		},
+		{
+			name:       "modelo que não carrega vira erro",
+			transcript: "Explica como funciona um motor",
+			intent:     IntentContext,
+			wantErr:    true,
+		},
+		{
+			name:       "ação usa o qwen e o executor",
+			transcript: "Abre o lembrete comprar pão",
+			intent:     IntentAction,
+			models: map[string]*fake.Model{
+				"qwen.onnx": scriptText(
+					`{"action":"lembrete","params":{"texto":"comprar pão"}}`,
+					`{"action":"responder","params":{"texto":"Pronto, anotei."}}`,
+				),
+			},
+			wantText:   "Pronto, anotei.",
+			wantAction: "lembrete",
+			wantRuns:   "qwen.onnx",
+		},
+		{
+			name:       "confiança baixa pergunta ao usuário",
+			transcript: "Go",
+			intent:     IntentCode,
+			confidence: 0.3,
+			wantText:   "Não entendi bem. Você quer que eu escreva ou corrija código?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := fake.New()
+			be.Add("whisper.onnx", fake.Transcripts(tt.transcript))
+			be.Add("phi.onnx", scriptText(""))
+			for path, m := range tt.models {
+				be.Add(path, m)
+			}
+
+			r, err := New(context.Background(), testConfig(t), be)
+			if err != nil {
+				t.Fatalf("New() erro: %v", err)
+			}
+			defer r.Close()
+
+			confidence := tt.confidence
+			if confidence == 0 {
+				confidence = 0.9
+			}
+			r.SetClassifier(fixedClassifier{&Classification{
+				Intent:     tt.intent,
+				Confidence: confidence,
+				Scores:     map[Intent]float32{tt.intent: confidence},
+			}})
+
+			r.executor.RegisterAction(actions.ActionSpec{
+				Name:   "lembrete",
+				Params: []actions.ParamSpec{{Name: "texto", Type: "string", Required: true}},
+			}, func(params map[string]interface{}) (string, error) {
+				return "Lembrete: " + params["texto"].(string), nil
+			})
+
+			resp, err := r.Process(context.Background(), make([]float32, 1600))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Process() = %+v, want erro", resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() erro: %v", err)
+			}
+
+			if resp.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", resp.Text, tt.wantText)
+			}
+
+			if tt.wantAction != "" {
+				if resp.Action == nil || resp.Action.Type != tt.wantAction || !resp.Success {
+					t.Errorf("Action = %+v, Success = %v, want %s executada", resp.Action, resp.Success, tt.wantAction)
+				}
+			}
+
+			if tt.wantRuns != "" && be.Runs(tt.wantRuns) == 0 {
+				t.Errorf("%s não foi executado", tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestProcessCommandHandler(t *testing.T) {
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts("Iniciar foco", "Bom dia"))
+	be.Add("phi.onnx", scriptText("Bom dia!"))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentSimple, Confidence: 1}})
+	r.SetCommandHandler(func(text string) (string, bool) {
+		if text == "Iniciar foco" {
+			return "Foco iniciado.", true
+		}
+		return "", false
+	})
+
+	var streamed string
+	onToken := func(text string) error {
+		streamed += text
+		return nil
+	}
+
+	// O comando responde sozinho: nada é gerado nem transmitido
+	resp, err := r.ProcessStream(context.Background(), make([]float32, 1600), onToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "Foco iniciado." || streamed != "" || be.Runs("phi.onnx") != 0 {
+		t.Errorf("comando: Text = %q, stream = %q, %d passos do phi", resp.Text, streamed, be.Runs("phi.onnx"))
+	}
+
+	// Frases que não são comando seguem para o modelo
+	resp, err = r.ProcessStream(context.Background(), make([]float32, 1600), onToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "Bom dia!" || streamed != "Bom dia!" {
+		t.Errorf("conversa: Text = %q, stream = %q", resp.Text, streamed)
+	}
+
+	if history := r.Session().History(); len(history) != 4 || history[1].Content != "Foco iniciado." {
+		t.Errorf("History() = %+v, want o comando registrado na conversa", history)
+	}
+}
+
+func TestProcessText(t *testing.T) {
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText("Olá!"))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentSimple, Confidence: 1}})
+	r.SetCommandHandler(func(text string) (string, bool) {
+		return "São 10:00.", text == "que horas são"
+	})
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"  ", ""},
+		{"que horas são", "São 10:00."},
+		{"  oi\n", "Olá!"},
+	}
+	for _, tt := range tests {
+		resp, err := r.ProcessText(context.Background(), tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Text != tt.want {
+			t.Errorf("ProcessText(%q) = %q, want %q", tt.text, resp.Text, tt.want)
+		}
+	}
+
+	if runs := be.Runs("whisper.onnx"); runs != 0 {
+		t.Errorf("Whisper executado %d vezes para texto", runs)
+	}
+	if history := r.Session().History(); len(history) != 4 || history[2].Content != "oi" {
+		t.Errorf("History() = %+v, want os dois pedidos registrados", history)
+	}
+}
+
+func TestProcessRoutesByRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(models map[string]config.ModelConfig)
+		intent   Intent
+		wantRuns string
+		wantErr  string
+	}{
+		{"gemma no lugar do llama", func(models map[string]config.ModelConfig) {
+			gemma := models["llama"]
+			gemma.Name, gemma.Path = "gemma-2-2b", "gemma.onnx"
+			delete(models, "llama")
+			models["gemma"] = gemma
+		}, IntentContext, "gemma.onnx", ""},
+		{"segundo coder como default", func(models map[string]config.ModelConfig) {
+			deepseek := models["coder"]
+			deepseek.Path, deepseek.Default = "deepseek.onnx", true
+			models["deepseek"] = deepseek
+		}, IntentCode, "deepseek.onnx", ""},
+		{"sem modelo de contexto, o de chat atende", func(models map[string]config.ModelConfig) {
+			delete(models, "llama")
+		}, IntentContext, "phi.onnx", ""},
+		{"sem modelo de visão", func(models map[string]config.ModelConfig) {
+			delete(models, "vision")
+		}, IntentVision, "", "nenhum modelo com role vision"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			tt.edit(cfg.Models.Entries)
+
+			be := fake.New()
+			be.Add("whisper.onnx", fake.Transcripts("pedido"))
+			be.Add("phi.onnx", scriptText("ok"))
+			if tt.wantRuns != "phi.onnx" && tt.wantRuns != "" {
+				be.Add(tt.wantRuns, scriptText("ok"))
+			}
+
+			r, err := New(context.Background(), cfg, be)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			r.SetClassifier(fixedClassifier{&Classification{Intent: tt.intent, Confidence: 1}})
+
+			resp, err := r.Process(context.Background(), make([]float32, 1600))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Process() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text != "ok" || be.Runs(tt.wantRuns) == 0 {
+				t.Errorf("Text = %q, %d passos em %s", resp.Text, be.Runs(tt.wantRuns), tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestProcessActionFollowsSchema(t *testing.T) {
+	want := `{"action": "luz", "params": {"cor": "fria", "brilho": 40}}`
+
+	// O Qwen quer abrir com texto solto, usar uma cor fora do enum e mandar o
+	// brilho como string; a decodificação restrita corrige cada desvio
+	wish := "Claro!" + want[len("Claro!"):]
+	wish = strings.Replace(wish, `"fria"`, `"frio"`, 1)
+	wish = strings.Replace(wish, `: 40`, `: "0`, 1)
+
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts("Deixa a luz fria em 40"))
+	be.Add("phi.onnx", scriptText(""))
+	be.Add("qwen.onnx", fake.Steer(eosID+1, eosID, wish, want))
+
+	// Um passo só: a resposta é a da ação
+	cfg := testConfig(t)
+	cfg.Agent.MaxSteps = 1
+	r, err := New(context.Background(), cfg, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentAction, Confidence: 1}})
+
+	r.executor.RegisterAction(actions.ActionSpec{
+		Name: "luz",
+		Params: []actions.ParamSpec{
+			{Name: "cor", Type: "string", Required: true, Enum: []string{"quente", "fria"}},
+			{Name: "brilho", Type: "integer", Required: true},
+			{Name: "sala", Type: "string"},
+		},
+	}, func(params map[string]interface{}) (string, error) {
+		return "Luz ajustada.", nil
+	})
+
+	resp, err := r.Process(context.Background(), make([]float32, 1600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.Action == nil || resp.Action.Type != "luz" {
+		t.Fatalf("Process() = %+v, want a ação luz executada", resp)
+	}
+	validateAction(t, r.executor.Schema(), resp.Action)
+	if resp.Action.Params["cor"] != "fria" || resp.Action.Params["brilho"] != float64(40) {
+		t.Errorf("Params = %v, want cor fria e brilho 40", resp.Action.Params)
+	}
+}
+
+// validateAction confere a ação contra o JSON schema do executor: ação
+// registrada, parâmetros obrigatórios, tipos e enums
+func validateAction(t *testing.T, schema []byte, action *actions.Action) {
+	t.Helper()
+
+	type node struct {
+		Type       string          `json:"type"`
+		Const      string          `json:"const"`
+		Enum       []string        `json:"enum"`
+		Properties map[string]node `json:"properties"`
+		Required   []string        `json:"required"`
+		OneOf      []node          `json:"oneOf"`
+	}
+	var root node
+	if err := json.Unmarshal(schema, &root); err != nil {
+		t.Fatalf("schema inválido: %v", err)
+	}
+
+	var params *node
+	for _, option := range root.OneOf {
+		if option.Properties["action"].Const == action.Type {
+			p := option.Properties["params"]
+			params = &p
+		}
+	}
+	if params == nil {
+		t.Fatalf("ação %q fora do schema", action.Type)
+	}
+
+	for _, name := range params.Required {
+		if _, ok := action.Params[name]; !ok {
+			t.Errorf("parâmetro obrigatório %q ausente", name)
+		}
+	}
+	for name, value := range action.Params {
+		spec, ok := params.Properties[name]
+		if !ok {
+			t.Errorf("parâmetro %q fora do schema", name)
+			continue
+		}
+
+		var typeOK bool
+		switch v := value.(type) {
+		case string:
+			typeOK = spec.Type == "string"
+		case float64:
+			typeOK = spec.Type == "number" || (spec.Type == "integer" && v == math.Trunc(v))
+		case bool:
+			typeOK = spec.Type == "boolean"
+		}
+		if !typeOK {
+			t.Errorf("parâmetro %q = %v (%T), want %s", name, value, value, spec.Type)
+		}
+
+		if len(spec.Enum) > 0 {
+			found := false
+			for _, option := range spec.Enum {
+				found = found || value == option
+			}
+			if !found {
+				t.Errorf("parâmetro %q = %v fora do enum %v", name, value, spec.Enum)
+			}
+		}
+	}
+}
+
+// fixedClassifier classifica toda frase do mesmo jeito
+type fixedClassifier struct{ class *Classification }
+
+func (c fixedClassifier) Name() string { return "fixo" }
+
+func (c fixedClassifier) Classify(context.Context, string) (*Classification, error) {
+	return c.class, nil
+}
+
+// eosID token de fim de sequência do tokenizer de teste
+const eosID = 256
+
+// testConfig configuração com os modelos do backend fake e um tokenizer
+// byte-level em que o ID de cada token é o próprio byte
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	tokenizerPath := fake.ByteLevelTokenizer(t, "<|endoftext|>")
+
+	cfg := config.Default()
+	cfg.STT.ModelPath = "whisper.onnx"
+	for name, m := range cfg.Models.Entries {
+		m.Path = name + ".onnx"
+		m.TokenizerPath = tokenizerPath
+		cfg.Models.Entries[name] = m
+	}
+	return cfg
+}
+
+// scriptText decoder que gera cada texto byte a byte, um por geração,
+// terminando cada um com EOS
+func scriptText(texts ...string) *fake.Model {
+	var ids []int64
+	for _, text := range texts {
+		for _, b := range []byte(text) {
+			ids = append(ids, int64(b))
+		}
+		ids = append(ids, eosID)
+	}
+	return fake.Tokens(eosID+1, ids...)
+}
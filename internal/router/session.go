@@ -0,0 +1,150 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Turn uma mensagem da conversa
+type Turn struct {
+	Role    chat.Role `json:"role"` // user ou assistant
+	Content string    `json:"content"`
+	Intent  Intent    `json:"intent,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Session conversa em andamento. Os turnos são gravados a cada resposta, então
+// um reinício retoma de onde parou.
+type Session struct {
+	path        string
+	idleTimeout time.Duration
+	maxTurns    int
+
+	turns []Turn
+	mu    sync.Mutex
+}
+
+// OpenSession abre a sessão gravada em cfg.Path (sem caminho, fica só em
+// memória)
+func OpenSession(cfg config.SessionConfig) (*Session, error) {
+	s := &Session{
+		path:        cfg.Path,
+		idleTimeout: cfg.IdleTimeout,
+		maxTurns:    cfg.MaxTurns,
+	}
+	if s.path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.turns); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return s, nil
+}
+
+// Append registra um pedido do usuário, feito em asked, e a resposta dada
+// agora
+func (s *Session) Append(intent Intent, asked time.Time, user, assistant string) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.turns = append(s.turns,
+		Turn{Role: chat.RoleUser, Content: user, Intent: intent, Time: asked},
+		Turn{Role: chat.RoleAssistant, Content: assistant, Intent: intent, Time: now},
+	)
+	if s.maxTurns > 0 && len(s.turns) > s.maxTurns {
+		// Descarta em pares para a conversa continuar começando pelo usuário
+		drop := len(s.turns) - s.maxTurns
+		drop += drop % 2
+		s.turns = append([]Turn(nil), s.turns[drop:]...)
+	}
+	return s.save()
+}
+
+// History mensagens da conversa atual, para o template de chat. Uma pausa
+// maior que o idle timeout encerra a conversa: o que veio antes dela não entra.
+func (s *Session) History() []chat.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := s.threadStart(time.Now())
+	messages := make([]chat.Message, 0, len(s.turns)-start)
+	for _, turn := range s.turns[start:] {
+		messages = append(messages, chat.Message{Role: turn.Role, Content: turn.Content})
+	}
+	return messages
+}
+
+// Since turnos registrados a partir de t
+func (s *Session) Since(t time.Time) []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var turns []Turn
+	for _, turn := range s.turns {
+		if !turn.Time.Before(t) {
+			turns = append(turns, turn)
+		}
+	}
+	return turns
+}
+
+// Reset começa outra conversa, apagando os turnos gravados
+func (s *Session) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.turns = nil
+	return s.save()
+}
+
+// threadStart índice do primeiro turno da conversa atual
+func (s *Session) threadStart(now time.Time) int {
+	if s.idleTimeout <= 0 {
+		return 0
+	}
+	for i := len(s.turns); i > 0; i-- {
+		if now.Sub(s.turns[i-1].Time) > s.idleTimeout {
+			return i
+		}
+		now = s.turns[i-1].Time
+	}
+	return 0
+}
+
+// save grava os turnos (arquivo temporário + rename, para não corromper).
+// A conversa é pessoal: só o dono do arquivo lê.
+func (s *Session) save() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s.turns, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,268 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestSessionPersistence(t *testing.T) {
+	cfg := config.SessionConfig{Path: filepath.Join(t.TempDir(), "session.json")}
+
+	s, err := OpenSession(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	s.Append(IntentSimple, time.Now(), "Qual a previsão pra hoje?", "Sol o dia todo.")
+	s.Append(IntentSimple, time.Now(), "E amanhã?", "Chuva à tarde.")
+
+	s, err = OpenSession(cfg)
+	if err != nil {
+		t.Fatalf("OpenSession() ao retomar: %v", err)
+	}
+	history := s.History()
+	want := []chat.Message{
+		{Role: chat.RoleUser, Content: "Qual a previsão pra hoje?"},
+		{Role: chat.RoleAssistant, Content: "Sol o dia todo."},
+		{Role: chat.RoleUser, Content: "E amanhã?"},
+		{Role: chat.RoleAssistant, Content: "Chuva à tarde."},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("History() = %+v, want %+v", history, want)
+	}
+	for i := range want {
+		if history[i] != want[i] {
+			t.Errorf("History()[%d] = %+v, want %+v", i, history[i], want[i])
+		}
+	}
+
+	if turns := s.Since(start); len(turns) != 4 || turns[0].Intent != IntentSimple {
+		t.Errorf("Since() = %+v", turns)
+	}
+	if turns := s.Since(time.Now().Add(time.Hour)); len(turns) != 0 {
+		t.Errorf("Since(futuro) = %+v, want vazio", turns)
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ = OpenSession(cfg); len(s.History()) != 0 {
+		t.Error("Reset() deveria apagar a conversa gravada")
+	}
+}
+
+func TestSessionTurnTimes(t *testing.T) {
+	cfg := config.SessionConfig{Path: filepath.Join(t.TempDir(), "npu-ia", "session.json")}
+	s, err := OpenSession(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// O pedido fica com a hora em que chegou, não a da resposta
+	asked := time.Now().Add(-5 * time.Second)
+	if err := s.Append(IntentContext, asked, "Explica a relatividade", "É longa..."); err != nil {
+		t.Fatal(err)
+	}
+	turns := s.Since(asked)
+	if len(turns) != 2 || !turns[0].Time.Equal(asked) || !turns[1].Time.After(asked.Add(4*time.Second)) {
+		t.Errorf("turnos = %+v, want o pedido em %v e a resposta agora", turns, asked)
+	}
+
+	if runtime.GOOS == "windows" {
+		return // Permissões POSIX não se aplicam
+	}
+	for path, want := range map[string]os.FileMode{cfg.Path: 0600, filepath.Dir(cfg.Path): 0700} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s com permissão %v, want %v", filepath.Base(path), info.Mode().Perm(), want)
+		}
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	s, _ := OpenSession(config.SessionConfig{IdleTimeout: 30 * time.Minute})
+	s.Append(IntentSimple, time.Now(), "ontem", "ok")
+	s.Append(IntentSimple, time.Now(), "hoje cedo", "ok")
+	s.Append(IntentSimple, time.Now(), "agora", "ok")
+
+	now := time.Now()
+	s.turns[0].Time, s.turns[1].Time = now.Add(-24*time.Hour), now.Add(-24*time.Hour)
+	s.turns[2].Time, s.turns[3].Time = now.Add(-20*time.Minute), now.Add(-20*time.Minute)
+
+	history := s.History()
+	if len(history) != 4 || history[0].Content != "hoje cedo" {
+		t.Errorf("History() = %+v, want a partir de \"hoje cedo\"", history)
+	}
+
+	for i := range s.turns {
+		s.turns[i].Time = now.Add(-time.Hour)
+	}
+	if history := s.History(); len(history) != 0 {
+		t.Errorf("History() após pausa = %+v, want vazio", history)
+	}
+}
+
+func TestSessionMaxTurns(t *testing.T) {
+	s, _ := OpenSession(config.SessionConfig{MaxTurns: 3})
+	s.Append(IntentSimple, time.Now(), "um", "1")
+	s.Append(IntentSimple, time.Now(), "dois", "2")
+
+	history := s.History()
+	if len(history) != 2 || history[0].Role != chat.RoleUser || history[0].Content != "dois" {
+		t.Errorf("History() = %+v, want só o último par", history)
+	}
+}
+
+func TestProcessFollowUp(t *testing.T) {
+	var prompts []string
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts("Qual a previsão pra hoje?", "E amanhã?"))
+	be.Add("phi.onnx", recordPrompts(scriptText("Sol."), &prompts))
+
+	cfg := testConfig(t)
+	cfg.Session.Path = filepath.Join(t.TempDir(), "session.json")
+
+	r, err := New(context.Background(), cfg, be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentSimple, Confidence: 1}})
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Process(context.Background(), make([]float32, 1600)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"Qual a previsão pra hoje?", "Sol.", "E amanhã?"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("prompt do segundo turno sem %q:\n%s", want, last)
+		}
+	}
+
+	// Outro processo retoma a mesma conversa
+	resumed, err := OpenSession(cfg.Session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history := resumed.History(); len(history) != 4 || history[2].Content != "E amanhã?" {
+		t.Errorf("History() retomado = %+v", history)
+	}
+}
+
+func TestProcessCodeFollowUp(t *testing.T) {
+	var prompts []string
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts("Escreve um fatorial em Go", "Agora recursivo"))
+	be.Add("phi.onnx", scriptText(""))
+	be.Add("coder.onnx", recordPrompts(scriptText("func fat(n int) int", "return n * fat(n-1)"), &prompts))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.SetClassifier(fixedClassifier{&Classification{Intent: IntentCode, Confidence: 1}})
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Process(context.Background(), make([]float32, 1600)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// O pedido de código vê o turno anterior
+	last := prompts[len(prompts)-1]
+	for _, want := range []string{"Escreve um fatorial em Go", "func fat(n int) int", "Agora recursivo"} {
+		if !strings.Contains(last, want) {
+			t.Errorf("prompt do segundo turno sem %q:\n%s", want, last)
+		}
+	}
+}
+
+func TestConversationWindow(t *testing.T) {
+	be := fake.New()
+	be.Add("phi.onnx", scriptText(""))
+
+	history := []chat.Message{
+		{Role: chat.RoleUser, Content: "primeira pergunta"},
+		{Role: chat.RoleAssistant, Content: "primeira resposta"},
+		{Role: chat.RoleUser, Content: "segunda pergunta"},
+		{Role: chat.RoleAssistant, Content: "segunda resposta"},
+	}
+
+	cfg := testConfig(t).Models.Entries["phi"]
+	model, err := llm.New(be, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := model.CountTokens(model.Conversation(history, "", "terceira"))
+	alone := model.CountTokens(model.Conversation(nil, "", "terceira"))
+	model.Close()
+
+	tests := []struct {
+		name   string
+		window int // tokens de prompt disponíveis
+		want   []string
+	}{
+		{"cabe tudo", full, []string{"primeira pergunta", "primeira resposta", "segunda pergunta", "segunda resposta"}},
+		{"sai o turno mais antigo", full - 1, []string{"segunda pergunta", "segunda resposta"}},
+		{"só o pedido", alone, nil},
+		{"nem o pedido cabe", alone - 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.ContextLength = tt.window + cfg.MaxTokens
+			model, err := llm.New(be, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.Close()
+
+			messages := model.Conversation(history, "", "terceira")
+			if messages[0].Role != chat.RoleSystem || messages[len(messages)-1].Content != "terceira" {
+				t.Fatalf("Conversation() = %+v, want system ... terceira", messages)
+			}
+			var got []string
+			for _, msg := range messages[1 : len(messages)-1] {
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("histórico = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordPrompts guarda o texto de entrada de cada execução do decoder
+func recordPrompts(m *fake.Model, prompts *[]string) *fake.Model {
+	run := m.Run
+	m.Run = func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+		var text []byte
+		for _, id := range inputs[0].Data().([]int64) {
+			if id < eosID {
+				text = append(text, byte(id))
+			}
+		}
+		*prompts = append(*prompts, string(text))
+		return run(inputs)
+	}
+	return m
+}
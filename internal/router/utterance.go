@@ -0,0 +1,210 @@
+package router
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// intentRoles papel do modelo que atende cada intenção
+var intentRoles = map[Intent]string{
+	IntentSimple:  config.RoleChat,
+	IntentAction:  config.RoleAction,
+	IntentContext: config.RoleContext,
+	IntentVision:  config.RoleVision,
+	IntentCode:    config.RoleCode,
+}
+
+// Utterance fala em andamento (stt.stream): o áudio chega enquanto o usuário
+// fala, as hipóteses vão para onPartial e o texto estável já é classificado,
+// carregando o modelo da intenção antes do fim da frase
+type Utterance struct {
+	router    *Router
+	ctx       context.Context
+	asked     time.Time
+	stream    *stt.Stream
+	onPartial stt.HypothesisCallback
+
+	mu      sync.Mutex
+	pending []float32     // Áudio ainda não entregue ao stream
+	closed  bool          // Finish ou Cancel chamados
+	ready   chan struct{} // Há áudio em pending
+	done    chan struct{} // Transcrição parcial encerrada
+
+	// Classificação do último texto estável; só run mexe até done
+	guess *intentGuess
+}
+
+// StartUtterance começa uma fala transcrita enquanto chega; onPartial (pode
+// ser nil) recebe as hipóteses. Termine com Finish ou Cancel.
+func (r *Router) StartUtterance(ctx context.Context, onPartial stt.HypothesisCallback) *Utterance {
+	r.stopLearning()
+	u := &Utterance{
+		router:    r,
+		ctx:       ctx,
+		asked:     time.Now(),
+		onPartial: onPartial,
+		ready:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	u.stream = stt.NewStream(r.transcriber, r.cfg.STT.Stream, u.hypothesis)
+	go u.run()
+	return u
+}
+
+// Write entrega áudio capturado; não bloqueia (a transcrição é em segundo
+// plano e junta o que chegou enquanto a anterior rodava)
+func (u *Utterance) Write(samples []float32) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return
+	}
+	u.pending = append(u.pending, samples...)
+	select {
+	case u.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Finish encerra a fala e responde ao texto final, como ProcessStream
+func (u *Utterance) Finish(onToken llm.TokenCallback) (*Response, error) {
+	u.close()
+	defer u.guess.stop()
+	u.stream.Append(u.take())
+	text, err := u.stream.Finish()
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return &Response{}, nil
+	}
+	// Palpite sobre um texto que não é o final só ocuparia o modelo
+	if u.guess != nil && u.guess.text != text {
+		u.guess.stop()
+	}
+
+	log.Printf("🎤 Você: %s", text)
+	return u.router.respond(u.ctx, u.asked, text, u.guess, onToken)
+}
+
+// Cancel descarta a fala sem responder
+func (u *Utterance) Cancel() {
+	u.close()
+	u.guess.stop()
+}
+
+// close para de aceitar áudio e espera a transcrição parcial em andamento
+func (u *Utterance) close() {
+	u.mu.Lock()
+	if !u.closed {
+		u.closed = true
+		close(u.ready)
+	}
+	u.mu.Unlock()
+	<-u.done
+}
+
+// take retira o áudio pendente
+func (u *Utterance) take() []float32 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	samples := u.pending
+	u.pending = nil
+	return samples
+}
+
+// run transcreve o áudio conforme chega
+func (u *Utterance) run() {
+	defer close(u.done)
+	for range u.ready {
+		if err := u.stream.Write(u.take()); err != nil {
+			log.Printf("Aviso: erro na transcrição parcial: %v", err)
+		}
+	}
+}
+
+// hypothesis repassa a hipótese e adianta a classificação do texto estável
+func (u *Utterance) hypothesis(h stt.Hypothesis) {
+	if u.onPartial != nil {
+		u.onPartial(h)
+	}
+	if h.Final || h.Stable == "" || (u.guess != nil && u.guess.text == h.Stable) {
+		return
+	}
+	u.guess.stop()
+	u.guess = u.router.guessIntent(u.ctx, h.Stable)
+}
+
+// intentGuess classificação adiantada do texto estável de uma fala
+type intentGuess struct {
+	router     *Router
+	text       string
+	classifier Classifier
+	cancel     context.CancelFunc
+	done       chan struct{}
+	class      *Classification // nil se falhou ou foi cancelada
+}
+
+// guessIntent classifica text em segundo plano e, com confiança suficiente,
+// já carrega o modelo da intenção
+func (r *Router) guessIntent(ctx context.Context, text string) *intentGuess {
+	ctx, cancel := context.WithCancel(ctx)
+	g := &intentGuess{
+		router:     r,
+		text:       text,
+		classifier: r.currentClassifier(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(g.done)
+		// Erros aparecem na classificação do texto final
+		class, err := g.classifier.Classify(ctx, text)
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+		g.class = class
+		if class.Confidence >= r.cfg.Intent.MinConfidence {
+			r.warm(class.Intent)
+		}
+	}()
+	return g
+}
+
+// result classificação de text, se for o texto adiantado (espera terminar);
+// nil pede uma classificação nova
+func (g *intentGuess) result(text string) *Classification {
+	if g == nil || g.text != text {
+		return nil
+	}
+	<-g.done
+	g.cancel()
+	if g.class != nil {
+		g.router.recordDecision(g.classifier, text, g.class)
+	}
+	return g.class
+}
+
+// stop cancela a classificação sem esperar
+func (g *intentGuess) stop() {
+	if g != nil {
+		g.cancel()
+	}
+}
+
+// warm carrega o modelo que atende intent, sem reservá-lo
+func (r *Router) warm(intent Intent) {
+	role, ok := intentRoles[intent]
+	if !ok {
+		return
+	}
+	if _, release, err := r.acquireRole(role); err == nil {
+		release()
+	}
+}
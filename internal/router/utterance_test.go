@@ -0,0 +1,110 @@
+package router
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
+)
+
+// secondsTranscriber ouve uma palavra da frase por segundo de áudio
+type secondsTranscriber struct{ words []string }
+
+func (s secondsTranscriber) Transcribe(audio []float32) (string, error) {
+	n := min(len(audio)/stt.SampleRate, len(s.words))
+	return strings.Join(s.words[:n], " "), nil
+}
+
+func (s secondsTranscriber) Segments(audio []float32) ([]stt.Segment, error) { return nil, nil }
+func (s secondsTranscriber) SetLanguage(string)                              {}
+func (s secondsTranscriber) Close() error                                    { return nil }
+
+// countingClassifier classifica tudo como simple e guarda os textos
+type countingClassifier struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (c *countingClassifier) Name() string { return "contador" }
+
+func (c *countingClassifier) Classify(ctx context.Context, text string) (*Classification, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.texts = append(c.texts, text)
+	return &Classification{Intent: IntentSimple, Confidence: 1}, nil
+}
+
+func TestUtterance(t *testing.T) {
+	be := fake.New()
+	be.Add("whisper.onnx", fake.Transcripts())
+	be.Add("phi.onnx", scriptText("Abrindo."))
+
+	r, err := New(context.Background(), testConfig(t), be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.transcriber = secondsTranscriber{strings.Fields("abre o github")}
+	classifier := &countingClassifier{}
+	r.SetClassifier(classifier)
+
+	hypotheses := make(chan stt.Hypothesis, 10)
+	u := r.StartUtterance(context.Background(), func(h stt.Hypothesis) { hypotheses <- h })
+
+	// Um segundo por vez, esperando cada hipótese; o último é silêncio
+	var texts []string
+	for i := 0; i < 4; i++ {
+		u.Write(make([]float32, stt.SampleRate))
+		select {
+		case h := <-hypotheses:
+			texts = append(texts, h.Stable+"|"+h.Partial)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sem hipótese após %d s de áudio", i+1)
+		}
+	}
+	want := []string{"|abre", "abre|o", "abre o|github", "abre o github|"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("hipóteses = %q, want %q", texts, want)
+	}
+
+	resp, err := u.Finish(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "Abrindo." || resp.Intent != IntentSimple {
+		t.Errorf("resposta = %+v", resp)
+	}
+	if h := <-hypotheses; !h.Final || h.Stable != "abre o github" {
+		t.Errorf("hipótese final = %+v", h)
+	}
+
+	// O mesmo stream sobre um áudio inteiro (API)
+	var partials int
+	text, err := r.TranscribeStream(make([]float32, 3*stt.SampleRate), func(h stt.Hypothesis) {
+		if !h.Final {
+			partials++
+		}
+	})
+	if err != nil || text != "abre o github" || partials != 3 {
+		t.Errorf("TranscribeStream = %q, %v com %d parciais", text, err, partials)
+	}
+
+	// O texto final já tinha sido classificado quando ficou estável; os
+	// palpites antigos rodam em paralelo e podem nem ter terminado
+	classifier.mu.Lock()
+	defer classifier.mu.Unlock()
+	final := 0
+	for _, text := range classifier.texts {
+		if text == "abre o github" {
+			final++
+		}
+	}
+	if final != 1 {
+		t.Errorf("texto final classificado %d vezes (%q), want 1", final, classifier.texts)
+	}
+}
@@ -0,0 +1,589 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/router"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Completer modelos do registro servidos em /v1 (implementado pelo router)
+type Completer interface {
+	Models() []router.ModelInfo
+	Complete(ctx context.Context, name string, messages []chat.Message, onToken llm.TokenCallback) (string, error)
+	CompleteToolCall(ctx context.Context, name string, messages []chat.Message, set llm.ActionSet) (string, error)
+}
+
+// SetCompleter liga /v1/models e /v1/chat/completions aos modelos do registro
+func (s *Server) SetCompleter(c Completer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completer = c
+}
+
+// SetEmbedder liga /v1/embeddings ao modelo de embeddings, anunciado com o
+// nome name
+func (s *Server) SetEmbedder(name string, e embeddings.Embedder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embedName, s.embedder = name, e
+}
+
+// openAIError erro no formato da API da OpenAI, que os clientes sabem ler
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, code string, err error) {
+	kind := "invalid_request_error"
+	if status >= 500 {
+		kind = "server_error"
+	}
+	writeJSON(w, status, map[string]openAIError{"error": {Message: err.Error(), Type: kind, Code: code}})
+}
+
+// handleModels lista os modelos de chat do registro e o de embeddings
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	completer, embedName, embedder := s.completer, s.embedName, s.embedder
+	s.mu.RUnlock()
+
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+		Role    string `json:"role"`
+	}
+	data := []model{}
+	if completer != nil {
+		for _, m := range completer.Models() {
+			switch m.Role {
+			case config.RoleChat, config.RoleContext, config.RoleAction:
+				data = append(data, model{m.Name, "model", s.started.Unix(), "npu-ia", m.Role})
+			}
+		}
+	}
+	if embedder != nil {
+		data = append(data, model{embedName, "model", s.started.Unix(), "npu-ia", config.RoleEmbed})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": data})
+}
+
+// openAIMessage mensagem de /v1/chat/completions, com os campos de
+// ferramentas atuais (tool_calls) e os legados (function_call)
+type openAIMessage struct {
+	Role         string              `json:"role"`
+	Content      messageContent      `json:"content"`
+	Name         string              `json:"name,omitempty"`
+	ToolCalls    []openAIToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID   string              `json:"tool_call_id,omitempty"`
+	FunctionCall *openAIFunctionCall `json:"function_call,omitempty"`
+}
+
+// messageContent texto da mensagem: string ou lista de partes {"type":
+// "text"}; partes de imagem não são aceitas
+type messageContent string
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*c = ""
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = messageContent(text)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content deve ser texto ou lista de partes")
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return fmt.Errorf("parte %q não suportada: só texto", p.Type)
+		}
+		b.WriteString(p.Text)
+	}
+	*c = messageContent(b.String())
+	return nil
+}
+
+type openAIToolCall struct {
+	Index    *int               `json:"index,omitempty"` // Só nos chunks de streaming
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON em texto, como na API
+}
+
+// openAIFunction definição de ferramenta enviada pelo cliente; só o nome
+// importa: descrição e parâmetros vêm da ação registrada no executor
+type openAIFunction struct {
+	Name string `json:"name"`
+}
+
+type chatCompletionRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Tools    []struct {
+		Type     string         `json:"type"`
+		Function openAIFunction `json:"function"`
+	} `json:"tools"`
+	ToolChoice json.RawMessage `json:"tool_choice"`
+
+	// Campos legados da API de functions
+	Functions    []openAIFunction `json:"functions"`
+	FunctionCall json.RawMessage  `json:"function_call"`
+}
+
+// legacy pedido no formato antigo: a resposta usa function_call
+func (req *chatCompletionRequest) legacy() bool {
+	return len(req.Tools) == 0 && len(req.Functions) > 0
+}
+
+// conversation converte as mensagens do pedido. Chamadas de ferramenta do
+// assistente voltam ao formato de ação que o modelo gera, e o resultado de
+// cada uma vira uma mensagem de ferramenta com o nome da ação.
+func (req *chatCompletionRequest) conversation() ([]chat.Message, error) {
+	calls := make(map[string]string) // tool_call_id → ação
+	messages := make([]chat.Message, 0, len(req.Messages))
+	for i, msg := range req.Messages {
+		content := string(msg.Content)
+		switch msg.Role {
+		case "system", "developer":
+			messages = append(messages, chat.Message{Role: chat.RoleSystem, Content: content})
+		case "user":
+			messages = append(messages, chat.Message{Role: chat.RoleUser, Content: content})
+		case "assistant":
+			parts := []string{}
+			if content != "" {
+				parts = append(parts, content)
+			}
+			invoked := msg.ToolCalls
+			if msg.FunctionCall != nil {
+				invoked = append(invoked, openAIToolCall{Function: *msg.FunctionCall})
+			}
+			for _, call := range invoked {
+				action, err := actionJSON(call.Function)
+				if err != nil {
+					return nil, fmt.Errorf("messages[%d]: %w", i, err)
+				}
+				parts = append(parts, action)
+				calls[call.ID] = call.Function.Name
+			}
+			messages = append(messages, chat.Message{Role: chat.RoleAssistant, Content: strings.Join(parts, "\n")})
+		case "tool", "function":
+			name := msg.Name
+			if name == "" {
+				name = calls[msg.ToolCallID]
+			}
+			messages = append(messages, chat.Message{Role: chat.RoleTool, Content: content, Name: name})
+		default:
+			return nil, fmt.Errorf("messages[%d]: role desconhecido %q", i, msg.Role)
+		}
+	}
+	return messages, nil
+}
+
+// actionJSON chamada de ferramenta no formato de ação do executor
+func actionJSON(call openAIFunctionCall) (string, error) {
+	params := json.RawMessage("{}")
+	if strings.TrimSpace(call.Arguments) != "" {
+		params = json.RawMessage(call.Arguments)
+	}
+	data, err := json.Marshal(struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}{call.Name, params})
+	if err != nil {
+		return "", fmt.Errorf("arguments de %s não é JSON: %w", call.Name, err)
+	}
+	return string(data), nil
+}
+
+// catalog ações oferecidas ao modelo: as ferramentas pedidas, que precisam
+// ser ações do executor, filtradas pelo tool_choice. nil responde em texto.
+func (s *Server) catalog(req *chatCompletionRequest) (actions.Catalog, error) {
+	var names []string
+	for _, tool := range req.Tools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("ferramenta do tipo %q não suportada", tool.Type)
+		}
+		names = append(names, tool.Function.Name)
+	}
+	choice := req.ToolChoice
+	if req.legacy() {
+		for _, fn := range req.Functions {
+			names = append(names, fn.Name)
+		}
+		choice = req.FunctionCall
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	// "none", "auto", "required" ou {"type": "function", "function": {"name": ...}}
+	// (no legado, {"name": ...})
+	mode, forced := "auto", ""
+	if len(choice) > 0 && !bytes.Equal(choice, []byte("null")) {
+		if err := json.Unmarshal(choice, &mode); err != nil {
+			var named struct {
+				Name     string         `json:"name"`
+				Function openAIFunction `json:"function"`
+			}
+			if err := json.Unmarshal(choice, &named); err != nil {
+				return nil, fmt.Errorf("tool_choice inválido: %w", err)
+			}
+			mode, forced = "required", named.Function.Name
+			if forced == "" {
+				forced = named.Name
+			}
+		}
+	}
+	switch mode {
+	case "none":
+		return nil, nil
+	case "auto", "required":
+	default:
+		return nil, fmt.Errorf("tool_choice desconhecido %q", mode)
+	}
+
+	if s.executor == nil {
+		return nil, errors.New("executor de ações indisponível")
+	}
+	var catalog actions.Catalog
+	for _, name := range names {
+		spec, ok := s.executor.Spec(name)
+		if !ok {
+			return nil, fmt.Errorf("ferramenta %s não é uma ação registrada (veja GET /api/actions)", name)
+		}
+		if forced == "" || forced == name {
+			catalog = append(catalog, spec)
+		}
+	}
+	if len(catalog) == 0 {
+		return nil, fmt.Errorf("tool_choice pede %s, que não está em tools", forced)
+	}
+	if mode == "auto" {
+		catalog = append(catalog, actions.ReplySpec)
+	}
+	return catalog, nil
+}
+
+// completion resultado da geração: texto ou uma chamada de ferramenta
+type completion struct {
+	text string
+	call *openAIToolCall
+}
+
+// finishReason motivo de parada no formato da API
+func (c completion) finishReason(legacy bool) string {
+	switch {
+	case c.call == nil:
+		return "stop"
+	case legacy:
+		return "function_call"
+	}
+	return "tool_calls"
+}
+
+// message mensagem do assistente com o resultado
+func (c completion) message(legacy bool) map[string]interface{} {
+	msg := map[string]interface{}{"role": "assistant", "content": nil}
+	switch {
+	case c.call == nil:
+		msg["content"] = c.text
+	case legacy:
+		msg["function_call"] = c.call.Function
+	default:
+		msg["tool_calls"] = []openAIToolCall{*c.call}
+	}
+	return msg
+}
+
+// callTool gera a ação com as ferramentas do catálogo; a pseudo-ação
+// responder vira texto
+func (s *Server) callTool(ctx context.Context, completer Completer, model string, messages []chat.Message, catalog actions.Catalog) (completion, error) {
+	out, err := completer.CompleteToolCall(ctx, model, messages, catalog)
+	if err != nil {
+		return completion{}, err
+	}
+	var action struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(out), &action); err != nil {
+		return completion{}, fmt.Errorf("ação inválida do modelo: %w", err)
+	}
+
+	if action.Action == actions.ReplyAction {
+		var reply struct {
+			Texto string `json:"texto"`
+		}
+		json.Unmarshal(action.Params, &reply)
+		return completion{text: reply.Texto}, nil
+	}
+	return completion{call: &openAIToolCall{
+		ID:       "call_" + randomID(),
+		Type:     "function",
+		Function: openAIFunctionCall{Name: action.Action, Arguments: string(action.Params)},
+	}}, nil
+}
+
+// handleChatCompletions POST /v1/chat/completions. Com ferramentas, a resposta
+// é uma ação do executor devolvida como tool_calls (o cliente executa, por
+// exemplo em POST /api/actions, e manda o resultado numa mensagem "tool").
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	completer := s.completer
+	s.mu.RUnlock()
+	if completer == nil {
+		writeOpenAIError(w, http.StatusServiceUnavailable, "", errors.New("modelos indisponíveis"))
+		return
+	}
+
+	var req chatCompletionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	if req.Model == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "", errors.New("model vazio (veja GET /v1/models)"))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "", errors.New("messages vazio"))
+		return
+	}
+	messages, err := req.conversation()
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	catalog, err := s.catalog(&req)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "", err)
+		return
+	}
+
+	id, created, legacy := "chatcmpl-"+randomID(), time.Now().Unix(), req.legacy()
+	if !req.Stream {
+		var result completion
+		if catalog != nil {
+			result, err = s.callTool(r.Context(), completer, req.Model, messages, catalog)
+		} else {
+			result.text, err = completer.Complete(r.Context(), req.Model, messages, nil)
+		}
+		if err != nil {
+			writeCompletionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       result.message(legacy),
+				"finish_reason": result.finishReason(legacy),
+			}},
+		})
+		return
+	}
+
+	// Streaming: chunks "data: {...}" e "data: [DONE]" no fim. O primeiro
+	// espera o modelo, para que um nome errado ainda vire 404.
+	var events *eventStream
+	chunk := func(delta map[string]interface{}, finish interface{}) error {
+		return events.send("", map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+	}
+	open := func() error {
+		if events != nil {
+			return nil
+		}
+		stream, err := newEventStream(w)
+		if err != nil {
+			return err
+		}
+		events = stream
+		return chunk(map[string]interface{}{"role": "assistant", "content": ""}, nil)
+	}
+
+	var result completion
+	if catalog != nil {
+		result, err = s.callTool(r.Context(), completer, req.Model, messages, catalog)
+		if err == nil {
+			err = open()
+		}
+		if err == nil {
+			delta := map[string]interface{}{"content": result.text}
+			if result.call != nil {
+				delta = result.message(legacy)
+				if !legacy {
+					index := 0
+					result.call.Index = &index
+					delta["tool_calls"] = []openAIToolCall{*result.call}
+				}
+			}
+			err = chunk(delta, nil)
+		}
+	} else {
+		_, err = completer.Complete(r.Context(), req.Model, messages, func(token string) error {
+			if err := open(); err != nil {
+				return err
+			}
+			return chunk(map[string]interface{}{"content": token}, nil)
+		})
+	}
+	if err != nil {
+		if events == nil {
+			writeCompletionError(w, err)
+			return
+		}
+		events.send("", map[string]openAIError{"error": {Message: err.Error(), Type: "server_error"}})
+		return
+	}
+	if err := open(); err != nil { // Resposta vazia
+		return
+	}
+	chunk(map[string]interface{}{}, result.finishReason(legacy))
+	events.done()
+}
+
+// writeCompletionError 404 para modelo fora do registro, 500 para o resto
+func writeCompletionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, router.ErrUnknownModel) {
+		writeOpenAIError(w, http.StatusNotFound, "model_not_found", err)
+		return
+	}
+	writeOpenAIError(w, http.StatusInternalServerError, "", err)
+}
+
+// handleEmbeddings POST /v1/embeddings. input é um texto ou uma lista; os
+// vetores saem normalizados, em float ou base64 (float32 little-endian, o
+// padrão do cliente oficial). input_type "query" usa o prefixo de consulta dos
+// modelos E5; o padrão é "passage".
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	embedName, embedder := s.embedName, s.embedder
+	s.mu.RUnlock()
+	if embedder == nil {
+		writeOpenAIError(w, http.StatusServiceUnavailable, "", errors.New("modelo de embeddings indisponível"))
+		return
+	}
+
+	var req struct {
+		Model          string          `json:"model"`
+		Input          json.RawMessage `json:"input"`
+		EncodingFormat string          `json:"encoding_format"`
+		InputType      string          `json:"input_type"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	if req.Model != "" && req.Model != embedName {
+		writeOpenAIError(w, http.StatusNotFound, "model_not_found", fmt.Errorf("%v: %s (use %s)", router.ErrUnknownModel, req.Model, embedName))
+		return
+	}
+
+	var texts []string
+	if err := json.Unmarshal(req.Input, &texts); err != nil {
+		var text string
+		if err := json.Unmarshal(req.Input, &text); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "", errors.New("input deve ser texto ou lista de textos"))
+			return
+		}
+		texts = []string{text}
+	}
+	if len(texts) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "", errors.New("input vazio"))
+		return
+	}
+
+	mode := embeddings.Passage
+	switch req.InputType {
+	case "", "passage", "document":
+	case "query":
+		mode = embeddings.Query
+	default:
+		writeOpenAIError(w, http.StatusBadRequest, "", fmt.Errorf("input_type desconhecido %q (use query ou passage)", req.InputType))
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeOpenAIError(w, http.StatusBadRequest, "", fmt.Errorf("encoding_format desconhecido %q", req.EncodingFormat))
+		return
+	}
+
+	vectors, err := embedder.Embed(r.Context(), texts, mode)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	data := make([]map[string]interface{}, len(vectors))
+	for i, v := range vectors {
+		var embedding interface{} = v
+		if req.EncodingFormat == "base64" {
+			embedding = encodeFloats(v)
+		}
+		data[i] = map[string]interface{}{"object": "embedding", "index": i, "embedding": embedding}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  embedName,
+		"usage":  map[string]int{"prompt_tokens": 0, "total_tokens": 0},
+	})
+}
+
+// encodeFloats vetor em base64 de float32 little-endian
+func encodeFloats(v []float32) string {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// randomID identificador aleatório das respostas e chamadas
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/router"
+)
+
+// stubCompleter responde com tokens fixos (ou a ação fixa, com ferramentas)
+// e guarda o último pedido
+type stubCompleter struct {
+	tokens   []string
+	action   string
+	messages []chat.Message
+	set      llm.ActionSet
+}
+
+func (c *stubCompleter) Models() []router.ModelInfo {
+	return []router.ModelInfo{{Name: "coder", Role: "code"}, {Name: "phi", Role: "chat"}, {Name: "qwen", Role: "action"}}
+}
+
+func (c *stubCompleter) Complete(ctx context.Context, name string, messages []chat.Message, onToken llm.TokenCallback) (string, error) {
+	if name != "phi" {
+		return "", fmt.Errorf("%w: %s", router.ErrUnknownModel, name)
+	}
+	c.messages = messages
+	for _, token := range c.tokens {
+		if onToken != nil {
+			if err := onToken(token); err != nil {
+				return "", err
+			}
+		}
+	}
+	return strings.Join(c.tokens, ""), nil
+}
+
+func (c *stubCompleter) CompleteToolCall(ctx context.Context, name string, messages []chat.Message, set llm.ActionSet) (string, error) {
+	c.messages, c.set = messages, set
+	return c.action, nil
+}
+
+// stubEmbedder vetor [índice, tamanho do texto] por texto
+type stubEmbedder struct{ mode embeddings.Mode }
+
+func (e *stubEmbedder) Embed(ctx context.Context, texts []string, mode embeddings.Mode) ([][]float32, error) {
+	e.mode = mode
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{float32(i), float32(len(text))}
+	}
+	return out, nil
+}
+
+func TestModels(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	s.SetCompleter(&stubCompleter{})
+	s.SetEmbedder("e5", &stubEmbedder{})
+
+	var got struct {
+		Object string `json:"object"`
+		Data   []struct {
+			ID     string `json:"id"`
+			Object string `json:"object"`
+		} `json:"data"`
+	}
+	decode(t, call(t, srv, "GET", "/v1/models", nil), http.StatusOK, &got)
+
+	var ids []string
+	for _, m := range got.Data {
+		ids = append(ids, m.ID)
+	}
+	// O de código não gera chat
+	if want := []string{"phi", "qwen", "e5"}; got.Object != "list" || !reflect.DeepEqual(ids, want) {
+		t.Errorf("modelos = %v, want %v", ids, want)
+	}
+}
+
+func TestChatCompletions(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	completer := &stubCompleter{tokens: []string{"Bom ", "dia!"}}
+	s.SetCompleter(completer)
+
+	body := `{"model": "phi", "messages": [
+		{"role": "system", "content": "Seja breve."},
+		{"role": "user", "content": [{"type": "text", "text": "oi"}]}
+	]}`
+	var got struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	decode(t, call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body)), http.StatusOK, &got)
+	if got.Object != "chat.completion" || got.Choices[0].Message.Content != "Bom dia!" || got.Choices[0].FinishReason != "stop" {
+		t.Errorf("resposta = %+v", got)
+	}
+	want := []chat.Message{{Role: chat.RoleSystem, Content: "Seja breve."}, {Role: chat.RoleUser, Content: "oi"}}
+	if !reflect.DeepEqual(completer.messages, want) {
+		t.Errorf("mensagens = %+v, want %+v", completer.messages, want)
+	}
+
+	// Streaming: chunks com o delta de cada token e [DONE] no fim
+	body = `{"model": "phi", "stream": true, "messages": [{"role": "user", "content": "oi"}]}`
+	resp := call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body))
+	defer resp.Body.Close()
+	var deltas []string
+	var finish string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if line == "[DONE]" {
+			deltas = append(deltas, line)
+			continue
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta        map[string]string `json:"delta"`
+				FinishReason *string           `json:"finish_reason"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil || chunk.Object != "chat.completion.chunk" {
+			t.Fatalf("chunk %q: %v", line, err)
+		}
+		if choice := chunk.Choices[0]; choice.FinishReason != nil {
+			finish = *choice.FinishReason
+		} else {
+			deltas = append(deltas, choice.Delta["content"])
+		}
+	}
+	if want := []string{"", "Bom ", "dia!", "[DONE]"}; !reflect.DeepEqual(deltas, want) || finish != "stop" {
+		t.Errorf("deltas = %q (%s), want %q", deltas, finish, want)
+	}
+
+	// Modelo fora do registro: 404, também com streaming
+	for _, stream := range []string{"false", "true"} {
+		body = `{"model": "gpt-4", "stream": ` + stream + `, "messages": [{"role": "user", "content": "oi"}]}`
+		var failed struct {
+			Error openAIError `json:"error"`
+		}
+		decode(t, call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body)), http.StatusNotFound, &failed)
+		if failed.Error.Code != "model_not_found" {
+			t.Errorf("stream %s: erro = %+v", stream, failed.Error)
+		}
+	}
+}
+
+func TestChatCompletionsTools(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	completer := &stubCompleter{action: `{"action": "luz", "params": {"cor": "fria"}}`}
+	s.SetCompleter(completer)
+	s.executor.RegisterAction(actions.ActionSpec{Name: "luz", Description: "ajusta a luz"},
+		func(params map[string]interface{}) (string, error) { return "ok", nil })
+
+	// O resultado de uma chamada anterior volta como mensagem de ferramenta
+	body := `{"model": "qwen", "tools": [{"type": "function", "function": {"name": "luz"}}], "messages": [
+		{"role": "user", "content": "luz quente"},
+		{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "luz", "arguments": "{\"cor\":\"quente\"}"}}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": "ok"},
+		{"role": "user", "content": "agora fria"}
+	]}`
+	var got struct {
+		Choices []struct {
+			Message struct {
+				Content   *string          `json:"content"`
+				ToolCalls []openAIToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	decode(t, call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body)), http.StatusOK, &got)
+	choice := got.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 || choice.Message.Content != nil {
+		t.Fatalf("resposta = %+v", got)
+	}
+	if fn := choice.Message.ToolCalls[0].Function; fn.Name != "luz" || fn.Arguments != `{"cor": "fria"}` {
+		t.Errorf("chamada = %+v", fn)
+	}
+
+	wantMessages := []chat.Message{
+		{Role: chat.RoleUser, Content: "luz quente"},
+		{Role: chat.RoleAssistant, Content: `{"action":"luz","params":{"cor":"quente"}}`},
+		{Role: chat.RoleTool, Content: "ok", Name: "luz"},
+		{Role: chat.RoleUser, Content: "agora fria"},
+	}
+	if !reflect.DeepEqual(completer.messages, wantMessages) {
+		t.Errorf("mensagens = %+v, want %+v", completer.messages, wantMessages)
+	}
+	// "auto": o modelo também pode responder em texto
+	if catalog := completer.set.(actions.Catalog); len(catalog) != 2 || catalog[0].Name != "luz" || catalog[1].Name != actions.ReplyAction {
+		t.Errorf("catálogo = %+v", catalog)
+	}
+
+	// Resposta em texto pela pseudo-ação, no formato legado de functions
+	completer.action = `{"action": "responder", "params": {"texto": "Já está fria."}}`
+	body = `{"model": "qwen", "functions": [{"name": "luz"}], "messages": [{"role": "user", "content": "e a luz?"}]}`
+	decode(t, call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body)), http.StatusOK, &got)
+	if choice := got.Choices[0]; choice.Message.Content == nil || *choice.Message.Content != "Já está fria." || choice.FinishReason != "stop" {
+		t.Errorf("resposta = %+v", got)
+	}
+
+	// Só ações do executor viram ferramentas
+	body = `{"model": "qwen", "tools": [{"type": "function", "function": {"name": "formatar_disco"}}], "messages": [{"role": "user", "content": "x"}]}`
+	resp := call(t, srv, "POST", "/v1/chat/completions", strings.NewReader(body))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("ferramenta desconhecida: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	embedder := &stubEmbedder{}
+	s.SetEmbedder("e5", embedder)
+
+	var got struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Model string `json:"model"`
+	}
+	body := `{"model": "e5", "input": ["oi", "bom dia"], "input_type": "query"}`
+	decode(t, call(t, srv, "POST", "/v1/embeddings", strings.NewReader(body)), http.StatusOK, &got)
+	if len(got.Data) != 2 || !reflect.DeepEqual(got.Data[1].Embedding, []float32{1, 7}) || got.Model != "e5" {
+		t.Errorf("embeddings = %+v", got)
+	}
+	if embedder.mode != embeddings.Query {
+		t.Errorf("modo = %v, want Query", embedder.mode)
+	}
+
+	// base64, o padrão do cliente oficial
+	var encoded struct {
+		Data []struct {
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+	}
+	body = `{"input": "oi", "encoding_format": "base64"}`
+	decode(t, call(t, srv, "POST", "/v1/embeddings", strings.NewReader(body)), http.StatusOK, &encoded)
+	raw, err := base64.StdEncoding.DecodeString(encoded.Data[0].Embedding)
+	if err != nil || len(raw) != 8 {
+		t.Fatalf("base64 = %q: %v", encoded.Data[0].Embedding, err)
+	}
+	if v := math.Float32frombits(binary.LittleEndian.Uint32(raw[4:])); v != 2 || embedder.mode != embeddings.Passage {
+		t.Errorf("vetor[1] = %v, modo %v", v, embedder.mode)
+	}
+
+	resp := call(t, srv, "POST", "/v1/embeddings", strings.NewReader(`{"model": "ada", "input": "oi"}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("modelo errado: status %d, want 404", resp.StatusCode)
+	}
+}
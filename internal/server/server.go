@@ -0,0 +1,481 @@
+// Package server expõe o assistente numa API HTTP local: chat com streaming
+// (SSE), transcrição de áudio, ações, status e leitura de hábitos, notas e
+// memória, além de rotas /v1 compatíveis com a API da OpenAI. Toda chamada
+// exige o bearer token do config.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/embeddings"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/router"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// maxRequestBytes limite dos corpos JSON (o áudio usa max_upload_mb)
+const maxRequestBytes = 1 << 20
+
+// Assistant o que o servidor usa do router
+type Assistant interface {
+	ProcessTextStream(ctx context.Context, text string, onToken llm.TokenCallback) (*router.Response, error)
+	Segments(audioData []float32) ([]stt.Segment, error)
+	TranscribeStream(audioData []float32, onHypothesis stt.HypothesisCallback) (string, error)
+	MemoryStats() map[string]interface{}
+}
+
+// Reader consulta de leitura de um módulo (hábitos, notas, memória); query
+// vem do parâmetro q e pode ser vazia
+type Reader func(query string) (interface{}, error)
+
+// Server API HTTP local do assistente
+type Server struct {
+	cfg       config.ServerConfig
+	token     string
+	assistant Assistant
+	executor  *actions.Executor
+	mux       *http.ServeMux
+	started   time.Time
+
+	mu        sync.RWMutex
+	readers   map[string]Reader
+	listen    func()
+	completer Completer
+	embedName string
+	embedder  embeddings.Embedder
+	http      *http.Server
+}
+
+// New cria o servidor. O token vem do config ou de NPU_IA_TOKEN; sem ele a
+// API não sobe.
+func New(cfg config.ServerConfig, assistant Assistant, executor *actions.Executor) (*Server, error) {
+	token := cfg.Token
+	if token == "" {
+		token = os.Getenv("NPU_IA_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("server.token vazio: defina no config ou em NPU_IA_TOKEN")
+	}
+
+	s := &Server{
+		cfg:       cfg,
+		token:     token,
+		assistant: assistant,
+		executor:  executor,
+		mux:       http.NewServeMux(),
+		started:   time.Now(),
+		readers:   make(map[string]Reader),
+	}
+	s.mux.HandleFunc("POST /api/chat", s.handleChat)
+	s.mux.HandleFunc("POST /api/transcribe", s.handleTranscribe)
+	s.mux.HandleFunc("GET /api/actions", s.handleListActions)
+	s.mux.HandleFunc("POST /api/actions", s.handleAction)
+	s.mux.HandleFunc("GET /api/confirmations", s.handleListConfirmations)
+	s.mux.HandleFunc("POST /api/confirmations/{id}/{answer}", s.handleConfirmation)
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.HandleFunc("POST /api/listen", s.handleListen)
+	s.mux.HandleFunc("GET /api/{name}", s.handleRead)
+
+	// Compatível com a API da OpenAI (editores, scripts, LangChain)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("POST /v1/embeddings", s.handleEmbeddings)
+	return s, nil
+}
+
+// SetReader expõe a consulta em GET /api/{name}
+func (s *Server) SetReader(name string, read Reader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readers[name] = read
+}
+
+// SetPushToTalk liga POST /api/listen: listen faz o assistente ouvir sem a
+// palavra de ativação (atalho de teclado, botão)
+func (s *Server) SetPushToTalk(listen func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listen = listen
+}
+
+// Handler rotas da API com a verificação do token
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("token ausente ou inválido"))
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+// Start escuta em cfg.Addr e atende em segundo plano
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir %s: %w", s.cfg.Addr, err)
+	}
+
+	srv := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	s.mu.Lock()
+	s.http = srv
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Erro na API local: %v", err)
+		}
+	}()
+	log.Printf("✓ API local em http://%s", ln.Addr())
+	return nil
+}
+
+// Close encerra o servidor, esperando as respostas em andamento por alguns
+// segundos
+func (s *Server) Close() error {
+	s.mu.RLock()
+	srv := s.http
+	s.mu.RUnlock()
+	if srv == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// chatResponse resposta do assistente na API
+type chatResponse struct {
+	Text    string             `json:"text"`
+	Intent  router.Intent      `json:"intent,omitempty"`
+	Success bool               `json:"success"`
+	Action  *actions.Action    `json:"action,omitempty"`
+	Steps   []router.AgentStep `json:"steps,omitempty"`
+}
+
+func newChatResponse(resp *router.Response) chatResponse {
+	return chatResponse{Text: resp.Text, Intent: resp.Intent, Success: resp.Success, Action: resp.Action, Steps: resp.Steps}
+}
+
+// handleChat responde a um pedido em texto; com "stream": true (ou Accept:
+// text/event-stream) o texto chega em eventos SSE "token" e a resposta
+// completa no evento "done"
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text   string `json:"text"`
+		Stream bool   `json:"stream"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text vazio"))
+		return
+	}
+
+	if !req.Stream && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		resp, err := s.assistant.ProcessTextStream(r.Context(), req.Text, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newChatResponse(resp))
+		return
+	}
+
+	events, err := newEventStream(w)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp, err := s.assistant.ProcessTextStream(r.Context(), req.Text, func(text string) error {
+		return events.send("token", map[string]string{"text": text})
+	})
+	if err != nil {
+		events.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	events.send("done", newChatResponse(resp))
+}
+
+// handleTranscribe transcreve um WAV de 16 kHz enviado no corpo; com
+// ?stream=true (ou Accept: text/event-stream) as hipóteses parciais chegam
+// em eventos SSE "partial" e o texto final no evento "done"
+func (s *Server) handleTranscribe(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadMB<<20))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("áudio maior que %d MB", s.cfg.MaxUploadMB))
+		return
+	}
+
+	samples, rate, err := decodeWAV(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if rate != sampleRate {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("áudio em %d Hz; envie %d Hz", rate, sampleRate))
+		return
+	}
+
+	if r.URL.Query().Get("stream") != "true" && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		segments, err := s.assistant.Segments(samples)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, transcription{Text: stt.JoinSegments(segments), Segments: toSegmentJSON(segments)})
+		return
+	}
+
+	events, err := newEventStream(w)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	text, err := s.assistant.TranscribeStream(samples, func(h stt.Hypothesis) {
+		if !h.Final {
+			events.send("partial", map[string]string{"stable": h.Stable, "partial": h.Partial})
+		}
+	})
+	if err != nil {
+		events.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	events.send("done", map[string]string{"text": text})
+}
+
+// transcription resposta de /api/transcribe
+type transcription struct {
+	Text     string        `json:"text"`
+	Segments []segmentJSON `json:"segments"`
+}
+
+// segmentJSON trecho com início e fim em segundos
+type segmentJSON struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// toSegmentJSON converte os trechos para a resposta
+func toSegmentJSON(segments []stt.Segment) []segmentJSON {
+	out := make([]segmentJSON, len(segments))
+	for i, seg := range segments {
+		out[i] = segmentJSON{Start: seg.Start.Seconds(), End: seg.End.Seconds(), Text: seg.Text}
+	}
+	return out
+}
+
+// handleListActions ações registradas e seus parâmetros
+func (s *Server) handleListActions(w http.ResponseWriter, r *http.Request) {
+	if s.executor == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("executor de ações indisponível"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.executor.Specs())
+}
+
+// handleAction executa {"action": ..., "params": {...}} pelo executor
+func (s *Server) handleAction(w http.ResponseWriter, r *http.Request) {
+	if s.executor == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("executor de ações indisponível"))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	action, err := s.executor.Execute(string(body))
+	switch {
+	case action == nil:
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, actions.ErrConfirmationRequired):
+		// Aprovar em POST /api/confirmations/{pending}/approve
+		writeJSON(w, http.StatusAccepted, action)
+	case errors.Is(err, actions.ErrDenied):
+		writeJSON(w, http.StatusForbidden, action)
+	case err != nil:
+		writeJSON(w, http.StatusUnprocessableEntity, action)
+	default:
+		writeJSON(w, http.StatusOK, action)
+	}
+}
+
+// handleListConfirmations ações esperando confirmação
+func (s *Server) handleListConfirmations(w http.ResponseWriter, r *http.Request) {
+	policy := s.policy()
+	if policy == nil {
+		writeJSON(w, http.StatusOK, []actions.Pending{})
+		return
+	}
+	writeJSON(w, http.StatusOK, policy.Pending())
+}
+
+// handleConfirmation aprova (approve) ou recusa (reject) a ação pendente
+func (s *Server) handleConfirmation(w http.ResponseWriter, r *http.Request) {
+	policy := s.policy()
+	if policy == nil {
+		writeError(w, http.StatusNotFound, actions.ErrNoPending)
+		return
+	}
+	id := r.PathValue("id")
+
+	switch r.PathValue("answer") {
+	case "approve":
+		action, err := policy.Approve(id)
+		switch {
+		case action == nil:
+			writeError(w, http.StatusNotFound, err)
+		case err != nil:
+			writeJSON(w, http.StatusUnprocessableEntity, action)
+		default:
+			writeJSON(w, http.StatusOK, action)
+		}
+	case "reject":
+		if err := policy.Reject(id); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "rejected"})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("resposta desconhecida %q (use approve ou reject)", r.PathValue("answer")))
+	}
+}
+
+// policy política de confirmação do executor, se houver
+func (s *Server) policy() *actions.Policy {
+	if s.executor == nil {
+		return nil
+	}
+	return s.executor.Policy()
+}
+
+// handleStatus saúde da API e modelos carregados
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "ok",
+		"uptime_s": int64(time.Since(s.started).Seconds()),
+		"models":   s.assistant.MemoryStats(),
+		"platform": map[string]interface{}{
+			"name":  platform.Current().Name(),
+			"tools": platform.Current().Tools(),
+		},
+	})
+}
+
+// handleListen push-to-talk pela API
+func (s *Server) handleListen(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	listen := s.listen
+	s.mu.RUnlock()
+	if listen == nil {
+		writeError(w, http.StatusNotFound, errors.New("push-to-talk desativado (wake_word.push_to_talk)"))
+		return
+	}
+
+	listen()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "listening"})
+}
+
+// handleRead consulta um módulo registrado em SetReader
+func (s *Server) handleRead(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.mu.RLock()
+	read, ok := s.readers[name]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s não encontrado", name))
+		return
+	}
+
+	result, err := read(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// eventStream resposta em Server-Sent Events
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming não suportado pela conexão")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return &eventStream{w: w, flusher: flusher}, nil
+}
+
+// send envia um evento com data em JSON; sem event, só a linha data (o
+// formato da OpenAI)
+func (e *eventStream) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(e.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(e.w, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// done encerra o stream no formato da OpenAI
+func (e *eventStream) done() {
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	e.flusher.Flush()
+}
+
+// decodeJSON lê o corpo JSON da requisição
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(v); err != nil {
+		return fmt.Errorf("JSON inválido: %w", err)
+	}
+	return nil
+}
+
+// writeJSON responde v em JSON com o status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responde {"error": ...}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
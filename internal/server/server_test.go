@@ -0,0 +1,341 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/llm"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/router"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/stt"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+const testToken = "segredo"
+
+// stubAssistant responde com tokens fixos e guarda o áudio recebido
+type stubAssistant struct {
+	tokens []string
+	audio  []float32
+}
+
+func (a *stubAssistant) ProcessTextStream(ctx context.Context, text string, onToken llm.TokenCallback) (*router.Response, error) {
+	for _, token := range a.tokens {
+		if onToken != nil {
+			if err := onToken(token); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &router.Response{Text: strings.Join(a.tokens, ""), Intent: router.IntentSimple, Success: true}, nil
+}
+
+func (a *stubAssistant) Segments(audioData []float32) ([]stt.Segment, error) {
+	a.audio = audioData
+	return []stt.Segment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "olá"},
+		{Start: 1500 * time.Millisecond, End: 2 * time.Second, Text: "mundo"},
+	}, nil
+}
+
+func (a *stubAssistant) TranscribeStream(audioData []float32, onHypothesis stt.HypothesisCallback) (string, error) {
+	onHypothesis(stt.Hypothesis{Partial: "olá"})
+	onHypothesis(stt.Hypothesis{Stable: "olá", Partial: "mun"})
+	onHypothesis(stt.Hypothesis{Stable: "olá mundo", Final: true})
+	return "olá mundo", nil
+}
+
+func (a *stubAssistant) MemoryStats() map[string]interface{} {
+	return map[string]interface{}{"loaded_models": []string{"phi"}}
+}
+
+func TestAuth(t *testing.T) {
+	if _, err := New(config.ServerConfig{}, &stubAssistant{}, nil); err == nil {
+		t.Error("New() sem token deveria falhar")
+	}
+
+	_, srv := newTestServer(t, &stubAssistant{})
+	for _, header := range []string{"", "Bearer errado", testToken} {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	resp := call(t, srv, "GET", "/api/status", nil)
+	var status map[string]interface{}
+	decode(t, resp, http.StatusOK, &status)
+	if status["status"] != "ok" || status["models"] == nil {
+		t.Errorf("status = %v", status)
+	}
+	if p, _ := status["platform"].(map[string]interface{}); p["name"] != runtime.GOOS {
+		t.Errorf("plataforma = %v, want %s", status["platform"], runtime.GOOS)
+	}
+}
+
+func TestChat(t *testing.T) {
+	_, srv := newTestServer(t, &stubAssistant{tokens: []string{"Bom ", "dia!"}})
+
+	var got chatResponse
+	decode(t, call(t, srv, "POST", "/api/chat", strings.NewReader(`{"text": "oi"}`)), http.StatusOK, &got)
+	if got.Text != "Bom dia!" || got.Intent != router.IntentSimple || !got.Success {
+		t.Errorf("chat = %+v", got)
+	}
+
+	resp := call(t, srv, "POST", "/api/chat", strings.NewReader(`{"text": ""}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("texto vazio: status %d, want 400", resp.StatusCode)
+	}
+
+	// Streaming: um evento por token e a resposta completa no fim
+	resp = call(t, srv, "POST", "/api/chat", strings.NewReader(`{"text": "oi", "stream": true}`))
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			events = append(events, line)
+		}
+	}
+	want := []string{
+		"event: token", `data: {"text":"Bom "}`,
+		"event: token", `data: {"text":"dia!"}`,
+		"event: done", `data: {"text":"Bom dia!","intent":"simple","success":true}`,
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("eventos =\n%q\nwant\n%q", events, want)
+	}
+}
+
+func TestTranscribe(t *testing.T) {
+	assistant := &stubAssistant{}
+	_, srv := newTestServer(t, assistant)
+
+	// Estéreo: cada amostra vira a média dos dois canais
+	var got transcription
+	decode(t, call(t, srv, "POST", "/api/transcribe", bytes.NewReader(wav(16000, 2, 16384, 0, -16384, -16384))), http.StatusOK, &got)
+	want := transcription{Text: "olá mundo", Segments: []segmentJSON{{0, 1.5, "olá"}, {1.5, 2, "mundo"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transcrição = %+v, want %+v", got, want)
+	}
+	if want := []float32{0.25, -0.5}; !reflect.DeepEqual(assistant.audio, want) {
+		t.Errorf("amostras = %v, want %v", assistant.audio, want)
+	}
+
+	// Streaming: hipóteses parciais e o texto final
+	resp := call(t, srv, "POST", "/api/transcribe?stream=true", bytes.NewReader(wav(16000, 1, 0)))
+	defer resp.Body.Close()
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			events = append(events, line)
+		}
+	}
+	wantEvents := []string{
+		"event: partial", `data: {"partial":"olá","stable":""}`,
+		"event: partial", `data: {"partial":"mun","stable":"olá"}`,
+		"event: done", `data: {"text":"olá mundo"}`,
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("eventos =\n%q\nwant\n%q", events, wantEvents)
+	}
+
+	for name, body := range map[string][]byte{
+		"taxa errada": wav(8000, 1, 0),
+		"não é WAV":   []byte("ID3 mp3..."),
+	} {
+		resp := call(t, srv, "POST", "/api/transcribe", bytes.NewReader(body))
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestActions(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	s.executor.RegisterAction(actions.ActionSpec{Name: "ping", Description: "responde pong"},
+		func(params map[string]interface{}) (string, error) { return "pong", nil })
+
+	var specs []actions.ActionSpec
+	decode(t, call(t, srv, "GET", "/api/actions", nil), http.StatusOK, &specs)
+	if len(specs) == 0 {
+		t.Error("nenhuma ação listada")
+	}
+
+	var action actions.Action
+	decode(t, call(t, srv, "POST", "/api/actions", strings.NewReader(`{"action": "ping", "params": {}}`)), http.StatusOK, &action)
+	if action.Response != "pong" || !action.Success {
+		t.Errorf("ação = %+v", action)
+	}
+
+	resp := call(t, srv, "POST", "/api/actions", strings.NewReader(`{"action": "formatar_disco"}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("ação desconhecida: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestConfirmations(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	policy, err := actions.NewPolicy(config.Default().Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer policy.Close()
+	s.executor.SetPolicy(policy)
+
+	sent := 0
+	s.executor.RegisterAction(actions.ActionSpec{Name: "enviar", Risk: actions.RiskExternal},
+		func(params map[string]interface{}) (string, error) {
+			sent++
+			return "enviado", nil
+		})
+
+	// A ação externa espera a confirmação: 202 com o id
+	var action actions.Action
+	for i := 0; i < 2; i++ {
+		decode(t, call(t, srv, "POST", "/api/actions", strings.NewReader(`{"action": "enviar", "params": {}}`)), http.StatusAccepted, &action)
+	}
+	var pending []actions.Pending
+	decode(t, call(t, srv, "GET", "/api/confirmations", nil), http.StatusOK, &pending)
+	if sent != 0 || len(pending) != 2 || pending[1].ID != action.Pending {
+		t.Fatalf("enviados %d, fila %+v", sent, pending)
+	}
+
+	decode(t, call(t, srv, "POST", "/api/confirmations/"+pending[0].ID+"/approve", nil), http.StatusOK, &action)
+	if sent != 1 || action.Response != "enviado" {
+		t.Errorf("aprovada: enviados %d, ação %+v", sent, action)
+	}
+	var status map[string]string
+	decode(t, call(t, srv, "POST", "/api/confirmations/"+pending[1].ID+"/reject", nil), http.StatusOK, &status)
+	if sent != 1 {
+		t.Errorf("recusada foi enviada")
+	}
+
+	// Já respondida
+	resp := call(t, srv, "POST", "/api/confirmations/"+pending[0].ID+"/approve", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("segunda resposta: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestReaders(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	s.SetReader("notes", func(query string) (interface{}, error) {
+		return []string{"nota sobre " + query}, nil
+	})
+
+	var notes []string
+	decode(t, call(t, srv, "GET", "/api/notes?q=go", nil), http.StatusOK, &notes)
+	if !reflect.DeepEqual(notes, []string{"nota sobre go"}) {
+		t.Errorf("notas = %v", notes)
+	}
+
+	resp := call(t, srv, "GET", "/api/habits", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("módulo não registrado: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestListen(t *testing.T) {
+	s, srv := newTestServer(t, &stubAssistant{})
+	resp := call(t, srv, "POST", "/api/listen", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("sem push-to-talk: status %d, want 404", resp.StatusCode)
+	}
+
+	pushes := 0
+	s.SetPushToTalk(func() { pushes++ })
+	var body map[string]string
+	decode(t, call(t, srv, "POST", "/api/listen", nil), http.StatusAccepted, &body)
+	if pushes != 1 || body["status"] != "listening" {
+		t.Errorf("push-to-talk: %d chamadas, resposta %v", pushes, body)
+	}
+}
+
+// newTestServer servidor com o token de teste e um executor padrão
+func newTestServer(t *testing.T, assistant Assistant) (*Server, *httptest.Server) {
+	t.Helper()
+	s, err := New(config.ServerConfig{Token: testToken, MaxUploadMB: 1}, assistant, actions.NewExecutor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+// call faz a requisição autenticada
+func call(t *testing.T, srv *httptest.Server, method, path string, body io.Reader) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// decode confere o status e lê o corpo JSON em v
+func decode(t *testing.T, resp *http.Response, status int, v interface{}) {
+	t.Helper()
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status %d, want %d: %s", resp.StatusCode, status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wav monta um WAV PCM de 16 bits com as amostras intercaladas por canal
+func wav(rate, channels int, samples ...int16) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+data.Len()))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{wavePCM, uint16(channels)})
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(rate), uint32(rate * channels * 2)})
+	binary.Write(&b, binary.LittleEndian, []uint16{uint16(channels * 2), 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(data.Len()))
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
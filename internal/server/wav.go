@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// sampleRate taxa esperada pelo Whisper
+const sampleRate = 16000
+
+// WAVE_FORMAT_* usados pelos gravadores comuns
+const (
+	wavePCM        = 1
+	waveFloat      = 3
+	waveExtensible = 0xFFFE
+)
+
+// decodeWAV lê um WAV PCM de 16 bits ou float de 32 bits e devolve as
+// amostras em mono (média dos canais) com a taxa do arquivo
+func decodeWAV(data []byte) ([]float32, int, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, errors.New("áudio não é WAV")
+	}
+
+	var format, channels, bits int
+	var rate int
+	var samples []byte
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			size = len(body) // Gravadores que não corrigem o tamanho no fim
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, errors.New("WAV com bloco fmt inválido")
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == waveExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(body[24:26]))
+			}
+		case "data":
+			samples = body
+		}
+		pos += 8 + size + size%2 // Blocos alinhados em 2 bytes
+	}
+
+	if channels == 0 {
+		return nil, 0, errors.New("WAV sem bloco fmt")
+	}
+	var sample func(b []byte) float32
+	switch {
+	case format == wavePCM && bits == 16:
+		sample = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / 32768 }
+	case format == waveFloat && bits == 32:
+		sample = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	default:
+		return nil, 0, fmt.Errorf("WAV não suportado (formato %d, %d bits): use PCM 16 bits ou float 32", format, bits)
+	}
+
+	width := bits / 8
+	frame := width * channels
+	out := make([]float32, len(samples)/frame)
+	for i := range out {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += sample(samples[i*frame+c*width:])
+		}
+		out[i] = sum / float32(channels)
+	}
+	return out, rate, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+)
+
+// maxResultBytes limite do resultado devolvido ao modelo (listas de emails,
+// arquivos e tweets passariam da janela de contexto)
+const maxResultBytes = 4000
+
+// Operation operação do Hub.Execute com o schema exposto ao modelo
+type Operation struct {
+	Service string // Primeiro argumento de Execute
+	Action  string // Segundo argumento de Execute
+	Spec    actions.ActionSpec
+}
+
+// param parâmetro obrigatório de texto
+func param(name, description string) actions.ParamSpec {
+	return actions.ParamSpec{Name: name, Type: "string", Required: true, Description: description}
+}
+
+// operation monta a operação; a ação no executor se chama servico_acao
+func operation(service, action, description string, risk actions.Risk, params ...actions.ParamSpec) Operation {
+	if params == nil {
+		params = []actions.ParamSpec{}
+	}
+	return Operation{
+		Service: service,
+		Action:  action,
+		Spec: actions.ActionSpec{
+			Name:        service + "_" + action,
+			Description: description,
+			Params:      params,
+			SideEffects: risk != actions.RiskReadOnly,
+			Risk:        risk,
+		},
+	}
+}
+
+// Operations todas as operações de Hub.Execute, na ordem do switch
+func Operations() []Operation {
+	return []Operation{
+		operation("gmail", "list", "lista emails do Gmail", actions.RiskReadOnly, param("query", "busca no formato do Gmail, ex.: is:unread")),
+		operation("gmail", "send", "envia email pelo Gmail", actions.RiskExternal,
+			param("to", "destinatário"), param("subject", "assunto"), param("body", "texto do email")),
+		operation("drive", "list", "lista arquivos do Google Drive", actions.RiskReadOnly, param("query", "filtro de busca do Drive")),
+		operation("drive", "search", "procura arquivo no Google Drive pelo nome", actions.RiskReadOnly, param("name", "nome do arquivo")),
+		operation("calendar", "today", "eventos de hoje no Google Calendar", actions.RiskReadOnly),
+
+		operation("github", "repos", "lista seus repositórios no GitHub", actions.RiskReadOnly),
+		operation("github", "issues", "lista issues abertas de um repositório", actions.RiskReadOnly,
+			param("owner", "dono do repositório"), param("repo", "nome do repositório")),
+		operation("github", "prs", "lista pull requests abertos de um repositório", actions.RiskReadOnly,
+			param("owner", "dono do repositório"), param("repo", "nome do repositório")),
+		operation("github", "notifications", "notificações não lidas do GitHub", actions.RiskReadOnly),
+
+		operation("spotify", "playing", "música tocando no Spotify", actions.RiskReadOnly),
+		operation("spotify", "play", "retoma a música no Spotify", actions.RiskReversible),
+		operation("spotify", "pause", "pausa o Spotify", actions.RiskReversible),
+		operation("spotify", "next", "próxima música no Spotify", actions.RiskReversible),
+		operation("spotify", "previous", "música anterior no Spotify", actions.RiskReversible),
+		operation("spotify", "search", "procura músicas no Spotify", actions.RiskReadOnly, param("query", "música, artista ou álbum")),
+
+		operation("todoist", "list", "lista tarefas do Todoist", actions.RiskReadOnly),
+		operation("todoist", "create", "cria tarefa no Todoist", actions.RiskReversible, param("content", "texto da tarefa")),
+		operation("todoist", "complete", "conclui tarefa do Todoist", actions.RiskReversible, param("id", "id da tarefa (veja todoist_list)")),
+
+		operation("notion", "search", "procura páginas no Notion", actions.RiskReadOnly, param("query", "texto buscado")),
+
+		operation("discord", "send", "envia mensagem num canal do Discord", actions.RiskExternal,
+			param("channel", "id do canal"), param("message", "texto da mensagem")),
+		operation("discord", "guilds", "lista os servidores do Discord", actions.RiskReadOnly),
+
+		operation("slack", "send", "envia mensagem num canal do Slack", actions.RiskExternal,
+			param("channel", "canal, ex.: #geral"), param("message", "texto da mensagem")),
+		operation("slack", "channels", "lista os canais do Slack", actions.RiskReadOnly),
+
+		operation("telegram", "send", "envia mensagem no Telegram", actions.RiskExternal,
+			param("chat", "id do chat"), param("message", "texto da mensagem")),
+
+		operation("x", "post", "publica no X (Twitter)", actions.RiskExternal, param("text", "texto do post")),
+		operation("x", "search", "procura posts no X (Twitter)", actions.RiskReadOnly, param("query", "texto buscado")),
+
+		operation("linkedin", "profile", "seu perfil no LinkedIn", actions.RiskReadOnly),
+		operation("linkedin", "post", "publica no LinkedIn", actions.RiskExternal, param("text", "texto do post")),
+	}
+}
+
+// RegisterActions registra no executor as operações dos serviços conectados
+// e retorna quantas foram registradas
+func (h *Hub) RegisterActions(e *actions.Executor) int {
+	count := 0
+	for _, op := range Operations() {
+		if !h.connected(op.Service) {
+			continue
+		}
+		op := op
+		e.RegisterAction(op.Spec, func(params map[string]interface{}) (string, error) {
+			result, err := h.Execute(op.Service, op.Action, params)
+			if err != nil {
+				return "", err
+			}
+			return formatResult(result), nil
+		})
+		count++
+	}
+	return count
+}
+
+// connected se o serviço da operação está configurado
+func (h *Hub) connected(service string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	switch service {
+	case "gmail", "drive", "calendar":
+		return h.Google != nil
+	case "github":
+		return h.GitHub != nil
+	case "spotify":
+		return h.Spotify != nil
+	case "todoist":
+		return h.Todoist != nil
+	case "notion":
+		return h.Notion != nil
+	case "discord":
+		return h.Discord != nil
+	case "slack":
+		return h.Slack != nil
+	case "telegram":
+		return h.Telegram != nil
+	case "x":
+		return h.X != nil
+	case "linkedin":
+		return h.LinkedIn != nil
+	}
+	return false
+}
+
+// formatResult resultado de Execute como texto para o modelo: JSON compacto,
+// cortado em maxResultBytes
+func formatResult(result interface{}) string {
+	switch v := result.(type) {
+	case nil:
+		return "Feito."
+	case string:
+		return truncate(v)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprint(result)
+	}
+	return truncate(string(data))
+}
+
+// truncate corta o texto em maxResultBytes sem partir um caractere
+func truncate(s string) string {
+	if len(s) <= maxResultBytes {
+		return s
+	}
+	cut := maxResultBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "… (cortado)"
+}
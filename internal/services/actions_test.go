@@ -0,0 +1,240 @@
+package services
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/actions"
+)
+
+func TestOperations(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      []string
+		sideEffects bool
+	}{
+		{"gmail_list", []string{"query"}, false},
+		{"gmail_send", []string{"to", "subject", "body"}, true},
+		{"drive_list", []string{"query"}, false},
+		{"drive_search", []string{"name"}, false},
+		{"calendar_today", nil, false},
+		{"github_repos", nil, false},
+		{"github_issues", []string{"owner", "repo"}, false},
+		{"github_prs", []string{"owner", "repo"}, false},
+		{"github_notifications", nil, false},
+		{"spotify_playing", nil, false},
+		{"spotify_play", nil, true},
+		{"spotify_pause", nil, true},
+		{"spotify_next", nil, true},
+		{"spotify_previous", nil, true},
+		{"spotify_search", []string{"query"}, false},
+		{"todoist_list", nil, false},
+		{"todoist_create", []string{"content"}, true},
+		{"todoist_complete", []string{"id"}, true},
+		{"notion_search", []string{"query"}, false},
+		{"discord_send", []string{"channel", "message"}, true},
+		{"discord_guilds", nil, false},
+		{"slack_send", []string{"channel", "message"}, true},
+		{"slack_channels", nil, false},
+		{"telegram_send", []string{"chat", "message"}, true},
+		{"x_post", []string{"text"}, true},
+		{"x_search", []string{"query"}, false},
+		{"linkedin_profile", nil, false},
+		{"linkedin_post", []string{"text"}, true},
+	}
+
+	order, cases := executeCases(t)
+	ops := Operations()
+	if len(ops) != len(tests) {
+		t.Fatalf("Operations() tem %d entradas, want %d", len(ops), len(tests))
+	}
+	var names []string
+	for i, tt := range tests {
+		op := ops[i]
+		names = append(names, op.Spec.Name)
+		t.Run(tt.name, func(t *testing.T) {
+			if op.Spec.Name != tt.name || op.Service+"_"+op.Action != tt.name {
+				t.Fatalf("operação %d = %s (%s/%s), want %s", i, op.Spec.Name, op.Service, op.Action, tt.name)
+			}
+
+			var params []string
+			for _, p := range op.Spec.Params {
+				params = append(params, p.Name)
+				if !p.Required || p.Type != "string" {
+					t.Errorf("parâmetro %s: %+v, want string obrigatório", p.Name, p)
+				}
+			}
+			if !reflect.DeepEqual(params, tt.params) {
+				t.Errorf("parâmetros = %v, want %v", params, tt.params)
+			}
+			if op.Spec.SideEffects != tt.sideEffects || (op.Spec.Risk != actions.RiskReadOnly) != tt.sideEffects {
+				t.Errorf("SideEffects = %v, Risk = %v, want efeito colateral %v", op.Spec.SideEffects, op.Spec.Risk, tt.sideEffects)
+			}
+
+			// O case de Hub.Execute lê os mesmos parâmetros
+			read, ok := cases[tt.name]
+			if !ok {
+				t.Fatalf("Hub.Execute não tem case para %s/%s", op.Service, op.Action)
+			}
+			if !reflect.DeepEqual(read, tt.params) {
+				t.Errorf("Hub.Execute lê %v, want %v", read, tt.params)
+			}
+		})
+	}
+
+	// E todo case de Hub.Execute tem operação, na mesma ordem
+	if !reflect.DeepEqual(order, names) {
+		t.Errorf("cases de Hub.Execute = %v, want %v", order, names)
+	}
+}
+
+// executeCases servico_acao de cada case de Hub.Execute, na ordem do switch,
+// e os parâmetros que cada um lê. Com vários nomes num case ("x", "twitter"),
+// vale o primeiro.
+func executeCases(t *testing.T) ([]string, map[string][]string) {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "hub.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var execute *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && fn.Name.Name == "Execute" {
+			execute = fn
+		}
+	}
+	if execute == nil {
+		t.Fatal("Hub.Execute não encontrado em hub.go")
+	}
+
+	var order []string
+	cases := make(map[string][]string)
+	for _, service := range switchCases(execute.Body, "service") {
+		for _, action := range switchCases(service.body, "action") {
+			name := service.name + "_" + action.name
+			var params []string
+			ast.Inspect(action.body, func(n ast.Node) bool {
+				index, ok := n.(*ast.IndexExpr)
+				if !ok {
+					return true
+				}
+				if id, ok := index.X.(*ast.Ident); ok && id.Name == "params" {
+					params = append(params, stringLit(t, index.Index))
+				}
+				return true
+			})
+			order = append(order, name)
+			cases[name] = params
+		}
+	}
+	return order, cases
+}
+
+// switchCase case de um switch sobre string: o primeiro nome e o corpo
+type switchCase struct {
+	name string
+	body *ast.BlockStmt
+}
+
+// switchCases cases do primeiro switch sobre a variável tag dentro de body
+func switchCases(body *ast.BlockStmt, tag string) []switchCase {
+	var cases []switchCase
+	ast.Inspect(body, func(n ast.Node) bool {
+		sw, ok := n.(*ast.SwitchStmt)
+		if !ok || cases != nil {
+			return cases == nil
+		}
+		if id, ok := sw.Tag.(*ast.Ident); !ok || id.Name != tag {
+			return true
+		}
+		for _, stmt := range sw.Body.List {
+			clause := stmt.(*ast.CaseClause)
+			if len(clause.List) == 0 {
+				continue
+			}
+			lit, ok := clause.List[0].(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			name, _ := strconv.Unquote(lit.Value)
+			cases = append(cases, switchCase{name, &ast.BlockStmt{List: clause.Body}})
+		}
+		return false
+	})
+	return cases
+}
+
+// stringLit valor de um literal de string
+func stringLit(t *testing.T, expr ast.Expr) string {
+	t.Helper()
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		t.Fatalf("índice de params não é literal: %T", expr)
+	}
+	s, _ := strconv.Unquote(lit.Value)
+	return s
+}
+
+func TestRegisterActions(t *testing.T) {
+	h := &Hub{GitHub: &GitHubServices{}, Slack: &SlackServices{}}
+	e := actions.NewExecutor()
+
+	var want []string
+	for _, op := range Operations() {
+		if op.Service == "github" || op.Service == "slack" {
+			want = append(want, op.Spec.Name)
+		}
+	}
+	if n := h.RegisterActions(e); n != len(want) {
+		t.Errorf("RegisterActions() = %d, want %d", n, len(want))
+	}
+
+	for _, op := range Operations() {
+		spec, ok := e.Spec(op.Spec.Name)
+		connected := op.Service == "github" || op.Service == "slack"
+		if ok != connected {
+			t.Errorf("Spec(%q) encontrado = %v, want %v", op.Spec.Name, ok, connected)
+			continue
+		}
+		if ok && !reflect.DeepEqual(spec, op.Spec) {
+			t.Errorf("Spec(%q) = %+v, want %+v", op.Spec.Name, spec, op.Spec)
+		}
+	}
+	if schema := string(e.Schema()); !strings.Contains(schema, `"slack_send"`) || strings.Contains(schema, `"gmail_send"`) {
+		t.Errorf("Schema() sem slack_send ou com gmail_send:\n%s", schema)
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	long := strings.Repeat("a", maxResultBytes-1) + "ção"
+	tests := []struct {
+		name   string
+		result interface{}
+		want   string
+	}{
+		{"nada", nil, "Feito."},
+		{"texto", "ok", "ok"},
+		{"JSON", map[string]int{"total": 2}, `{"total":2}`},
+		{"no limite", strings.Repeat("a", maxResultBytes), strings.Repeat("a", maxResultBytes)},
+		// O "ç" (2 bytes) começa no último byte permitido: sai inteiro
+		{"sem partir caractere", long, strings.Repeat("a", maxResultBytes-1) + "… (cortado)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatResult(tt.result)
+			if got != tt.want {
+				t.Errorf("formatResult() = %q (%d bytes), want %q", got, len(got), tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("formatResult() = %q não é UTF-8 válido", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package stt
+
+import (
+	"math"
+)
+
+// Parâmetros de áudio fixos do Whisper
+const (
+	SampleRate   = 16000
+	nFFT         = 400 // Janela de 25 ms
+	hopLength    = 160 // Passo de 10 ms
+	chunkSeconds = 30
+	chunkSamples = chunkSeconds * SampleRate
+	chunkFrames  = chunkSamples / hopLength // 3000 quadros por trecho
+	nBins        = nFFT/2 + 1
+)
+
+// melFrontend espectrograma log-mel como o do Whisper (torch.stft com janela
+// Hann, filtros mel do librosa na escala Slaney)
+type melFrontend struct {
+	nMels   int
+	filters []melFilter
+	window  []float64
+	fft     *fftPlan
+}
+
+func newMelFrontend(nMels int) *melFrontend {
+	window := make([]float64, nFFT)
+	for i := range window {
+		// Hann periódica
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/nFFT)
+	}
+	return &melFrontend{
+		nMels:   nMels,
+		filters: compactFilters(melFilters(SampleRate, nFFT, nMels)),
+		window:  window,
+		fft:     newFFTPlan(nFFT),
+	}
+}
+
+// logMel espectrograma [nMels][chunkFrames] em ordem de linha de até 30 s de
+// áudio; trechos mais curtos são completados com silêncio
+func (f *melFrontend) logMel(samples []float32) []float32 {
+	audio := make([]float64, chunkSamples)
+	for i := 0; i < len(samples) && i < chunkSamples; i++ {
+		audio[i] = float64(samples[i])
+	}
+
+	// center=True: reflexão de nFFT/2 amostras em cada ponta
+	half := nFFT / 2
+	padded := make([]float64, chunkSamples+nFFT)
+	copy(padded[half:], audio)
+	for i := 1; i <= half; i++ {
+		padded[half-i] = audio[i]
+		padded[half+chunkSamples-1+i] = audio[chunkSamples-1-i]
+	}
+
+	mel := make([]float64, f.nMels*chunkFrames)
+	frame := make([]complex128, nFFT)
+	power := make([]float64, nBins)
+	// O torch gera chunkFrames+1 quadros; o Whisper descarta o último. Quadros
+	// só com o silêncio do complemento ficam com energia zero.
+	for t := 0; t < chunkFrames; t++ {
+		offset := t * hopLength
+		if offset-half >= len(samples) {
+			break
+		}
+		for i := range frame {
+			frame[i] = complex(padded[offset+i]*f.window[i], 0)
+		}
+		spectrum := f.fft.transform(frame)
+		for k := range power {
+			re, im := real(spectrum[k]), imag(spectrum[k])
+			power[k] = re*re + im*im
+		}
+		for m, filter := range f.filters {
+			var sum float64
+			for k := filter.first; k < filter.first+len(filter.weights); k++ {
+				sum += filter.weights[k-filter.first] * power[k]
+			}
+			mel[m*chunkFrames+t] = sum
+		}
+	}
+
+	// log10 com piso, faixa dinâmica de 80 dB e escala para perto de [-1, 1]
+	peak := math.Inf(-1)
+	for i, v := range mel {
+		mel[i] = math.Log10(max(v, 1e-10))
+		peak = max(peak, mel[i])
+	}
+	features := make([]float32, len(mel))
+	for i, v := range mel {
+		features[i] = float32((max(v, peak-8) + 4) / 4)
+	}
+	return features
+}
+
+// melFilters banco de filtros triangulares na escala mel Slaney com
+// normalização de área (librosa.filters.mel com htk=False, norm="slaney")
+func melFilters(sampleRate, n, nMels int) [][]float64 {
+	bins := n/2 + 1
+	fftFreqs := make([]float64, bins)
+	for k := range fftFreqs {
+		fftFreqs[k] = float64(k) * float64(sampleRate) / float64(n)
+	}
+
+	// nMels+2 pontos igualmente espaçados em mel entre 0 e Nyquist
+	minMel, maxMel := hzToMel(0), hzToMel(float64(sampleRate)/2)
+	points := make([]float64, nMels+2)
+	for i := range points {
+		points[i] = melToHz(minMel + (maxMel-minMel)*float64(i)/float64(nMels+1))
+	}
+
+	filters := make([][]float64, nMels)
+	for m := range filters {
+		lower, center, upper := points[m], points[m+1], points[m+2]
+		norm := 2 / (upper - lower)
+		filters[m] = make([]float64, bins)
+		for k, freq := range fftFreqs {
+			rising := (freq - lower) / (center - lower)
+			falling := (upper - freq) / (upper - center)
+			filters[m][k] = max(0, min(rising, falling)) * norm
+		}
+	}
+	return filters
+}
+
+// melFilter filtro guardado só na faixa de bins com peso
+type melFilter struct {
+	first   int
+	weights []float64
+}
+
+// compactFilters corta os zeros das pontas de cada filtro
+func compactFilters(filters [][]float64) []melFilter {
+	compact := make([]melFilter, len(filters))
+	for m, filter := range filters {
+		first, last := 0, len(filter)-1
+		for first < last && filter[first] == 0 {
+			first++
+		}
+		for last > first && filter[last] == 0 {
+			last--
+		}
+		compact[m] = melFilter{first: first, weights: filter[first : last+1]}
+	}
+	return compact
+}
+
+// Escala mel Slaney: linear até 1 kHz, logarítmica acima
+const (
+	melLinearStep = 200.0 / 3
+	melLogStartHz = 1000.0
+	melLogStart   = melLogStartHz / melLinearStep
+)
+
+var melLogStep = math.Log(6.4) / 27
+
+func hzToMel(hz float64) float64 {
+	if hz < melLogStartHz {
+		return hz / melLinearStep
+	}
+	return melLogStart + math.Log(hz/melLogStartHz)/melLogStep
+}
+
+func melToHz(mel float64) float64 {
+	if mel < melLogStart {
+		return mel * melLinearStep
+	}
+	return melLogStartHz * math.Exp(melLogStep*(mel-melLogStart))
+}
+
+// fftPlan FFT de Cooley-Tukey de base mista (como o kissfft), com fatores e
+// rotações pré-calculados (400 = 2·2·2·2·5·5)
+type fftPlan struct {
+	n       int
+	factors []int
+	twiddle []complex128 // twiddle[j] = e^(-2πij/n)
+}
+
+func newFFTPlan(n int) *fftPlan {
+	p := &fftPlan{n: n, twiddle: make([]complex128, n)}
+	for j := range p.twiddle {
+		angle := -2 * math.Pi * float64(j) / float64(n)
+		p.twiddle[j] = complex(math.Cos(angle), math.Sin(angle))
+	}
+	for rest := n; rest > 1; {
+		f := smallestFactor(rest)
+		p.factors = append(p.factors, f)
+		rest /= f
+	}
+	return p
+}
+
+// transform transformada de x (len(x) deve ser p.n)
+func (p *fftPlan) transform(x []complex128) []complex128 {
+	out := make([]complex128, p.n)
+	if p.n == 1 {
+		out[0] = x[0]
+		return out
+	}
+	p.work(out, x, 1, p.factors)
+	return out
+}
+
+// work escreve em out a transformada de in[0], in[stride], in[2·stride]...
+// (len(out) elementos): primeiro as radix subsequências intercaladas, cada uma
+// no seu bloco de out, depois as borboletas
+func (p *fftPlan) work(out, in []complex128, stride int, factors []int) {
+	radix := factors[0]
+	m := len(out) / radix
+	if m == 1 {
+		for r := 0; r < radix; r++ {
+			out[r] = in[r*stride]
+		}
+	} else {
+		for r := 0; r < radix; r++ {
+			p.work(out[r*m:(r+1)*m], in[r*stride:], stride*radix, factors[1:])
+		}
+	}
+
+	// X[q·m+j] = Σr W_L^(rj) · W_radix^(rq) · Sub_r[j], com L = n/stride
+	var small [8]complex128
+	scratch := small[:]
+	if radix > len(small) {
+		scratch = make([]complex128, radix)
+	}
+	for j := 0; j < m; j++ {
+		for r := 0; r < radix; r++ {
+			scratch[r] = out[r*m+j] * p.twiddle[(r*j*stride)%p.n]
+		}
+		for q := 0; q < radix; q++ {
+			var sum complex128
+			for r := 0; r < radix; r++ {
+				sum += scratch[r] * p.twiddle[(r*q*m*stride)%p.n]
+			}
+			out[q*m+j] = sum
+		}
+	}
+}
+
+// smallestFactor menor fator primo de n
+func smallestFactor(n int) int {
+	for f := 2; f*f <= n; f++ {
+		if n%f == 0 {
+			return f
+		}
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+package stt
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestFFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{nFFT, 12, 7} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(rng.Float64()-0.5, 0)
+		}
+
+		got := newFFTPlan(n).transform(x)
+		for k := 0; k < n; k++ {
+			// DFT direta
+			var want complex128
+			for j, v := range x {
+				want += v * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+			}
+			if cmplx.Abs(got[k]-want) > 1e-9 {
+				t.Fatalf("n=%d: X[%d] = %v, want %v", n, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestMelFilters(t *testing.T) {
+	for _, nMels := range []int{80, 128} {
+		filters := melFilters(SampleRate, nFFT, nMels)
+		if len(filters) != nMels || len(filters[0]) != nBins {
+			t.Fatalf("%d mels: banco %dx%d", nMels, len(filters), len(filters[0]))
+		}
+		// Cada filtro cobre alguma frequência e os picos sobem com o índice
+		last := -1
+		for m, filter := range filters {
+			peak := 0
+			for k, w := range filter {
+				if w < 0 {
+					t.Fatalf("%d mels: peso negativo no filtro %d", nMels, m)
+				}
+				if w > filter[peak] {
+					peak = k
+				}
+			}
+			if filter[peak] == 0 || peak < last {
+				t.Fatalf("%d mels: filtro %d com pico no bin %d (anterior %d)", nMels, m, peak, last)
+			}
+			last = peak
+		}
+	}
+}
+
+func TestLogMel(t *testing.T) {
+	f := newMelFrontend(80)
+
+	// Silêncio fica no piso: (log10(1e-10) + 4) / 4
+	features := f.logMel(nil)
+	if len(features) != 80*chunkFrames {
+		t.Fatalf("%d valores, want %d", len(features), 80*chunkFrames)
+	}
+	for _, v := range features {
+		if v != -1.5 {
+			t.Fatalf("silêncio = %v, want -1.5", v)
+		}
+	}
+
+	// Tom de 1 kHz por 1 s: energia no filtro centrado perto de 1 kHz e só
+	// no começo do trecho
+	tone := make([]float32, SampleRate)
+	for i := range tone {
+		tone[i] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/SampleRate))
+	}
+	features = f.logMel(tone)
+
+	frame := 50
+	best := 0
+	for m := 0; m < 80; m++ {
+		if features[m*chunkFrames+frame] > features[best*chunkFrames+frame] {
+			best = m
+		}
+	}
+	maxMel := hzToMel(SampleRate / 2)
+	center := melToHz(maxMel * float64(best+1) / 81)
+	if math.Abs(center-1000) > 60 {
+		t.Errorf("pico no filtro %d (%.0f Hz), want perto de 1000 Hz", best, center)
+	}
+
+	// Faixa de 80 dB (2 após a escala) abaixo do pico, que o fim em silêncio
+	// atinge
+	hi, lo := features[0], features[0]
+	for _, v := range features {
+		hi, lo = max(hi, v), min(lo, v)
+	}
+	if math.Abs(float64(hi-lo)-2) > 1e-5 || features[best*chunkFrames+2000] != lo {
+		t.Errorf("faixa [%.3f, %.3f], fim %.3f", lo, hi, features[best*chunkFrames+2000])
+	}
+}
@@ -0,0 +1,178 @@
+package stt
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// maxOverlapWords maior repetição procurada entre o texto já fechado e o
+// começo de uma janela nova
+const maxOverlapWords = 12
+
+// Hypothesis transcrição da fala em andamento
+type Hypothesis struct {
+	Stable  string // Não muda mais até o fim da fala
+	Partial string // Resto da hipótese atual; pode mudar no próximo passo
+	Final   bool   // Texto final, no fim da fala
+}
+
+// Text hipótese completa
+func (h Hypothesis) Text() string {
+	return strings.TrimSpace(h.Stable + " " + h.Partial)
+}
+
+// HypothesisCallback recebe cada hipótese nova
+type HypothesisCallback func(h Hypothesis)
+
+// Stream transcreve a fala enquanto ela chega: a cada passo de áudio novo a
+// janela atual é transcrita de novo, e as palavras em que duas hipóteses
+// seguidas concordam ficam estáveis. Quando a janela enche, o texto estável
+// é fechado e a próxima começa com as últimas overlap amostras, descartando
+// as palavras repetidas na emenda. Não é seguro para uso concorrente.
+type Stream struct {
+	transcriber  Transcriber
+	onHypothesis HypothesisCallback
+
+	// Em amostras
+	step    int
+	window  int
+	overlap int
+
+	audio   []float32 // Janela atual
+	pending int       // Amostras ainda não transcritas
+
+	committed []string // Palavras de janelas anteriores
+	stable    []string // Palavras estáveis da janela atual
+	previous  []string // Hipótese anterior da janela atual
+	last      Hypothesis
+}
+
+// NewStream cria o stream de uma fala; onHypothesis (pode ser nil) recebe as
+// hipóteses que mudaram
+func NewStream(t Transcriber, cfg config.STTStreamConfig, onHypothesis HypothesisCallback) *Stream {
+	return &Stream{
+		transcriber:  t,
+		onHypothesis: onHypothesis,
+		step:         cfg.StepMs * SampleRate / 1000,
+		window:       cfg.WindowMs * SampleRate / 1000,
+		overlap:      cfg.OverlapMs * SampleRate / 1000,
+	}
+}
+
+// Append guarda áudio sem transcrever
+func (s *Stream) Append(samples []float32) {
+	s.audio = append(s.audio, samples...)
+	s.pending += len(samples)
+}
+
+// Write guarda o áudio e, se já houver um passo inteiro sem transcrição,
+// transcreve a janela. Blocos grandes viram uma só transcrição.
+func (s *Stream) Write(samples []float32) error {
+	s.Append(samples)
+	if s.pending < s.step {
+		return nil
+	}
+	return s.advance()
+}
+
+// advance transcreve a janela atual e emite a hipótese
+func (s *Stream) advance() error {
+	words, err := s.transcribe()
+	if err != nil {
+		return err
+	}
+
+	// Local agreement: o prefixo comum com a hipótese anterior não muda mais
+	if agreed := commonPrefix(s.previous, words); agreed > len(s.stable) {
+		s.stable = append(s.stable[:0], words[:agreed]...)
+	}
+	s.previous = words
+	s.emit(Hypothesis{
+		Stable:  joinWords(s.committed, s.stable),
+		Partial: strings.Join(words[min(len(s.stable), len(words)):], " "),
+	})
+
+	if len(s.audio) >= s.window {
+		s.committed = append(s.committed, s.stable...)
+		s.audio = append([]float32(nil), s.audio[len(s.audio)-s.overlap:]...)
+		s.stable, s.previous = nil, nil
+	}
+	return nil
+}
+
+// Finish fecha a fala e devolve o texto final. Sem áudio novo desde o último
+// passo, a última hipótese já é o texto final.
+func (s *Stream) Finish() (string, error) {
+	var words []string
+	if s.pending > 0 || s.previous == nil {
+		var err error
+		if words, err = s.transcribe(); err != nil {
+			return "", err
+		}
+	} else {
+		words = s.previous
+	}
+
+	text := joinWords(s.committed, words)
+	s.emit(Hypothesis{Stable: text, Final: true})
+	return text, nil
+}
+
+// transcribe palavras da janela atual, sem as que repetem o fim do texto fechado
+func (s *Stream) transcribe() ([]string, error) {
+	s.pending = 0
+	if len(s.audio) == 0 {
+		return nil, nil
+	}
+	text, err := s.transcriber.Transcribe(s.audio)
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(text)
+	return words[overlapWords(s.committed, words):], nil
+}
+
+// emit entrega a hipótese se ela mudou
+func (s *Stream) emit(h Hypothesis) {
+	if h == s.last {
+		return
+	}
+	s.last = h
+	if s.onHypothesis != nil {
+		s.onHypothesis(h)
+	}
+}
+
+// overlapWords quantas palavras do começo de words repetem o fim de committed
+func overlapWords(committed, words []string) int {
+	for n := min(len(committed), len(words), maxOverlapWords); n > 0; n-- {
+		if commonPrefix(committed[len(committed)-n:], words[:n]) == n {
+			return n
+		}
+	}
+	return 0
+}
+
+// commonPrefix quantas palavras iniciais a e b têm em comum, ignorando
+// maiúsculas e pontuação
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && normalizeWord(a[n]) == normalizeWord(b[n]) {
+		n++
+	}
+	return n
+}
+
+// normalizeWord palavra em minúsculas e sem pontuação nas pontas
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// joinWords junta as duas listas numa frase
+func joinWords(a, b []string) string {
+	return strings.TrimSpace(strings.Join(a, " ") + " " + strings.Join(b, " "))
+}
@@ -0,0 +1,117 @@
+package stt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// spokenWords Transcriber de teste: a amostra de valor v é a palavra v-1
+// (0 é silêncio), e a palavra ainda sendo dita no fim do áudio sai cortada
+type spokenWords struct {
+	words []string
+	calls int
+}
+
+func (s *spokenWords) Transcribe(audio []float32) (string, error) {
+	s.calls++
+	var out []string
+	prev := float32(0)
+	for _, v := range audio {
+		if v != prev && v != 0 {
+			out = append(out, s.words[int(v)-1])
+		}
+		prev = v
+	}
+	if prev != 0 {
+		last := out[len(out)-1]
+		out[len(out)-1] = last[:len(last)-1]
+	}
+	return strings.Join(out, " "), nil
+}
+
+func (s *spokenWords) Segments(audio []float32) ([]Segment, error) { return nil, nil }
+func (s *spokenWords) SetLanguage(string)                          {}
+func (s *spokenWords) Close() error                                { return nil }
+
+// second um segundo da palavra n (1 em diante; 0 é silêncio)
+func second(n int) []float32 {
+	samples := make([]float32, SampleRate)
+	for i := range samples {
+		samples[i] = float32(n)
+	}
+	return samples
+}
+
+func TestStream(t *testing.T) {
+	spoken := &spokenWords{words: strings.Fields("abre meu navegador no site do github agora")}
+	cfg := config.STTStreamConfig{StepMs: 1000, WindowMs: 4000, OverlapMs: 2000}
+
+	var hypotheses []Hypothesis
+	s := NewStream(spoken, cfg, func(h Hypothesis) { hypotheses = append(hypotheses, h) })
+	for n := 1; n <= len(spoken.words); n++ {
+		if err := s.Write(second(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Meio segundo de silêncio encerra a fala sem completar um passo
+	if err := s.Write(second(0)[:SampleRate/2]); err != nil {
+		t.Fatal(err)
+	}
+	text, err := s.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "abre meu navegador no site do github agora"; text != want {
+		t.Errorf("Finish() = %q, want %q", text, want)
+	}
+	want := []Hypothesis{
+		{Partial: "abr"},
+		{Partial: "abre me"},
+		{Stable: "abre", Partial: "meu navegado"},
+		{Stable: "abre meu", Partial: "navegador n"},
+		// Janela nova a partir do segundo 3
+		{Stable: "abre meu", Partial: "navegador no sit"},
+		{Stable: "abre meu navegador no", Partial: "site d"},
+		{Stable: "abre meu navegador no", Partial: "site do githu"},
+		{Stable: "abre meu navegador no site do", Partial: "github agor"},
+		{Stable: "abre meu navegador no site do github agora", Final: true},
+	}
+	if !reflect.DeepEqual(hypotheses, want) {
+		t.Errorf("hipóteses =\n%+v\nwant\n%+v", hypotheses, want)
+	}
+	if spoken.calls != 9 {
+		t.Errorf("%d transcrições, want 9 (8 passos e o final)", spoken.calls)
+	}
+
+	// Um bloco grande é uma transcrição só, e sem áudio novo o final reaproveita
+	// a última hipótese
+	spoken.calls = 0
+	s = NewStream(spoken, cfg, nil)
+	if err := s.Write(append(second(1), second(0)...)); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := s.Finish(); err != nil || text != "abre" || spoken.calls != 1 {
+		t.Errorf("Finish() = %q, %v após %d transcrições", text, err, spoken.calls)
+	}
+}
+
+func TestOverlapWords(t *testing.T) {
+	tests := []struct {
+		committed, words string
+		want             int
+	}{
+		{"abre o site do", "site do github", 2},
+		{"abre o site do", "Site, do GitHub", 2},
+		{"abre o site", "github", 0},
+		{"", "abre", 0},
+	}
+	for _, tt := range tests {
+		if got := overlapWords(strings.Fields(tt.committed), strings.Fields(tt.words)); got != tt.want {
+			t.Errorf("overlapWords(%q, %q) = %d, want %d", tt.committed, tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package stt transcreve fala em texto. O router só conhece Transcriber; a
+// implementação (Whisper em ONNX ou whisper.cpp) vem de stt.backend.
+package stt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Transcriber reconhecimento de fala sobre PCM mono de 16 kHz
+type Transcriber interface {
+	Transcribe(audio []float32) (string, error)
+	// Segments trechos transcritos com início e fim no áudio
+	Segments(audio []float32) ([]Segment, error)
+	// SetLanguage idioma da transcrição ("auto" detecta)
+	SetLanguage(lang string)
+	Close() error
+}
+
+// Segment trecho transcrito
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Backends de STT aceitos em stt.backend
+const (
+	BackendONNX       = "onnx"
+	BackendWhisperCpp = "whispercpp"
+)
+
+// New cria o Transcriber de cfg.Backend; be é o runtime do Whisper em ONNX
+func New(be backend.Backend, cfg config.STTConfig) (Transcriber, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendONNX:
+		return NewWhisper(be, cfg)
+	case BackendWhisperCpp, "whisper.cpp":
+		return newWhisperCpp(cfg)
+	}
+	return nil, fmt.Errorf("stt.backend desconhecido: %s", cfg.Backend)
+}
+
+// JoinSegments texto dos trechos separado por espaço
+func JoinSegments(segments []Segment) string {
+	texts := make([]string, 0, len(segments))
+	for _, s := range segments {
+		if s.Text != "" {
+			texts = append(texts, s.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,45 @@
+package stt
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+func TestNew(t *testing.T) {
+	dir := filepath.Dir(fake.ByteLevelTokenizer(t, specials...))
+	be := fake.New()
+	be.Add(filepath.Join(dir, "whisper.onnx"), fake.Transcripts("oi"))
+
+	cfg := config.Default().STT
+	cfg.ModelPath = filepath.Join(dir, "whisper.onnx")
+	transcriber, err := New(be, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transcriber.Close()
+	if _, ok := transcriber.(*Whisper); !ok {
+		t.Errorf("backend onnx criou %T", transcriber)
+	}
+
+	// Sem a build tag o whisper.cpp explica como habilitar
+	cfg.Backend = BackendWhisperCpp
+	if _, err := New(be, cfg); err == nil || !strings.Contains(err.Error(), "-tags whispercpp") {
+		t.Errorf("whispercpp sem a tag: erro = %v", err)
+	}
+
+	cfg.Backend = "vosk"
+	if _, err := New(be, cfg); err == nil {
+		t.Error("backend desconhecido deveria falhar")
+	}
+}
+
+func TestJoinSegments(t *testing.T) {
+	got := JoinSegments([]Segment{{Text: "bom"}, {Text: ""}, {Text: "dia"}})
+	if got != "bom dia" {
+		t.Errorf("JoinSegments = %q", got)
+	}
+}
@@ -1,264 +1,621 @@
 package stt
 
 import (
-	"encoding/json"
+	"bytes"
+	"compress/zlib"
 	"fmt"
-	"os"
+	"math"
+	"math/rand"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
-	ort "github.com/yalue/onnxruntime_go"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tokenizer"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
-// Whisper implementa Speech-to-Text usando Faster-Whisper ONNX
+// Whisper implementa Speech-to-Text com Whisper em ONNX. Aceita a exportação
+// separada (encoder do log-mel + decoder autorregressivo) e a ponta a ponta,
+// com beam search no grafo (audio_pcm → texto).
 type Whisper struct {
-	session   *ort.DynamicAdvancedSession
+	backend   backend.Backend
+	session   backend.Session // Encoder, ou o modelo inteiro no ponta a ponta
+	decoder   backend.Session // Nil no ponta a ponta
+	endToEnd  bool            // Modelo devolve o texto pronto (saída "str")
+	forced    bool            // Ponta a ponta com entrada decoder_input_ids
+	mel       *melFrontend
 	config    config.STTConfig
-	language  string
 	tokenizer *WhisperTokenizer
+
+	mu       sync.Mutex // Uma transcrição por vez
+	language string
+	rand     *rand.Rand
 }
 
+// maxSampleTokens limite de tokens gerados por trecho (metade do contexto de
+// texto de 448, como no Whisper original)
+const maxSampleTokens = 224
+
 // WhisperTokenizer tokenizer específico para Whisper
 type WhisperTokenizer struct {
-	vocab       map[int64]string
-	specialIDs  map[string]int64
-	timestamps  bool
+	tk         *tokenizer.Tokenizer
+	specialIDs map[string]int64
+	languages  map[string]int64 // Código do idioma ("pt") → token <|pt|>
+	timestamps bool
 }
 
-// NewWhisperTokenizer cria tokenizer para Whisper
+// NewWhisperTokenizer cria tokenizer para Whisper a partir do tokenizer.json
+// que acompanha o modelo
 func NewWhisperTokenizer(modelPath string) (*WhisperTokenizer, error) {
 	t := &WhisperTokenizer{
-		vocab:      make(map[int64]string),
-		specialIDs: make(map[string]int64),
 		timestamps: true,
 	}
 
-	// Tenta carregar vocab.json do diretório do modelo
-	vocabPath := filepath.Join(filepath.Dir(modelPath), "vocab.json")
-	if data, err := os.ReadFile(vocabPath); err == nil {
-		var vocab map[string]int64
-		if err := json.Unmarshal(data, &vocab); err == nil {
-			for token, id := range vocab {
-				t.vocab[id] = token
-			}
+	// IDs especiais padrão do Whisper multilíngue
+	t.specialIDs = map[string]int64{
+		"<|endoftext|>":         50257,
+		"<|startoftranscript|>": 50258,
+		"<|translate|>":         50358,
+		"<|transcribe|>":        50359,
+		"<|startoflm|>":         50360,
+		"<|startofprev|>":       50361,
+		"<|nospeech|>":          50362,
+		"<|notimestamps|>":      50363,
+	}
+	t.languages = map[string]int64{"en": 50259, "pt": 50267}
+
+	tokenizerPath := filepath.Join(filepath.Dir(modelPath), "tokenizer.json")
+	tk, err := tokenizer.Load(tokenizerPath)
+	if err != nil {
+		return t, err
+	}
+	t.tk = tk
+
+	// Usa os IDs reais do vocabulário (variam entre tamanhos de modelo)
+	for name := range t.specialIDs {
+		if id, ok := tk.TokenToID(name); ok {
+			t.specialIDs[name] = id
 		}
 	}
+	// Vocabulários anteriores ao large-v3 chamam o token de <|nocaptions|>
+	if id, ok := tk.TokenToID("<|nocaptions|>"); ok {
+		t.specialIDs["<|nospeech|>"] = id
+	}
 
-	// IDs especiais do Whisper
-	t.specialIDs = map[string]int64{
-		"<|endoftext|>":      50257,
-		"<|startoftranscript|>": 50258,
-		"<|translate|>":      50358,
-		"<|transcribe|>":     50359,
-		"<|startoflm|>":      50360,
-		"<|startofprev|>":    50361,
-		"<|nospeech|>":       50362,
-		"<|notimestamps|>":   50363,
-		"<|pt|>":             50316, // Português
-		"<|en|>":             50259, // Inglês
+	// Tokens de idioma ficam entre <|startoftranscript|> e <|translate|>
+	sot, translate := t.specialIDs["<|startoftranscript|>"], t.specialIDs["<|translate|>"]
+	t.languages = make(map[string]int64)
+	for _, added := range tk.AddedTokens() {
+		if added.ID <= sot || added.ID >= translate {
+			continue
+		}
+		if code, ok := strings.CutPrefix(added.Content, "<|"); ok && strings.HasSuffix(code, "|>") {
+			t.languages[strings.TrimSuffix(code, "|>")] = added.ID
+		}
 	}
 
 	return t, nil
 }
 
-// NewWhisper cria uma nova instância do Whisper
-func NewWhisper(cfg config.STTConfig) (*Whisper, error) {
-	// Inicializa ONNX Runtime com DirectML (NPU AMD)
-	err := ort.InitializeEnvironment()
+// Decode converte tokens em texto, omitindo tokens especiais
+func (t *WhisperTokenizer) Decode(tokens []int64) string {
+	if t.tk == nil {
+		return ""
+	}
+	return t.tk.Decode(tokens, true)
+}
+
+// prompt prefixo <|startoftranscript|><|idioma|><|transcribe|><|notimestamps|>
+func (t *WhisperTokenizer) prompt(language int64) []int64 {
+	return []int64{
+		t.specialIDs["<|startoftranscript|>"],
+		language,
+		t.specialIDs["<|transcribe|>"],
+		t.specialIDs["<|notimestamps|>"],
+	}
+}
+
+// endToEndInputs entradas do Whisper exportado com beam search e
+// pós-processamento no grafo (saída "str" já é o texto)
+var endToEndInputs = []string{"audio_pcm", "min_length", "max_length", "num_beams", "num_return_sequences", "length_penalty", "repetition_penalty"}
+
+// NewWhisper cria uma nova instância do Whisper executada pelo backend
+func NewWhisper(be backend.Backend, cfg config.STTConfig) (*Whisper, error) {
+	// Nomes de entrada/saída variam por versão do modelo
+	inputs, outputs, err := be.Inspect(cfg.ModelPath)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao inicializar ONNX: %w", err)
+		return nil, fmt.Errorf("erro ao carregar modelo Whisper: %w", err)
+	}
+
+	defaults := config.Default().STT
+	if len(cfg.Temperatures) == 0 {
+		cfg.Temperatures = defaults.Temperatures
+	}
+	if cfg.CompressionRatioThreshold == 0 {
+		cfg.CompressionRatioThreshold = defaults.CompressionRatioThreshold
+	}
+	if cfg.LogProbThreshold == 0 {
+		cfg.LogProbThreshold = defaults.LogProbThreshold
+	}
+	if cfg.NoSpeechThreshold == 0 {
+		cfg.NoSpeechThreshold = defaults.NoSpeechThreshold
+	}
+
+	w := &Whisper{
+		backend:  be,
+		config:   cfg,
+		language: cfg.Language,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 
-	// Configura para usar DirectML (NPU)
-	options, err := ort.NewSessionOptions()
+	switch {
+	case hasTensor(outputs, "str"):
+		err = w.openEndToEnd(inputs)
+	case len(inputs) == 1 && len(outputs) > 0:
+		err = w.openSplit(inputs[0], outputs[0])
+	default:
+		err = fmt.Errorf("entradas/saídas não reconhecidas")
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("erro ao carregar modelo Whisper: %w", err)
+	}
+	return w, nil
+}
+
+// openEndToEnd abre o modelo ponta a ponta; com decoder_input_ids o prefixo de
+// idioma e tarefa também vale para ele
+func (w *Whisper) openEndToEnd(inputs []backend.TensorInfo) error {
+	w.endToEnd = true
+	names := endToEndInputs
+	if hasTensor(inputs, "decoder_input_ids") {
+		w.forced = true
+		names = append(append([]string(nil), endToEndInputs...), "decoder_input_ids")
 	}
 
-	// Adiciona DirectML como provider (usa NPU AMD)
-	err = options.AppendExecutionProviderDirectML(0)
+	session, err := w.backend.Open(w.config.ModelPath, names, []string{"str"})
 	if err != nil {
-		// Fallback para CPU se DirectML não disponível
-		fmt.Println("DirectML não disponível, usando CPU")
+		return err
 	}
+	w.session = session
 
-	// Carrega o modelo Whisper ONNX
-	// Nomes de entrada/saída variam por versão do modelo
-	inputNames := []string{"audio_pcm", "min_length", "max_length", "num_beams", "num_return_sequences", "length_penalty", "repetition_penalty"}
-	outputNames := []string{"str"}
-
-	// Tenta carregar com nomes padrão do whisper ONNX
-	session, err := ort.NewDynamicAdvancedSession(
-		cfg.ModelPath,
-		inputNames,
-		outputNames,
-		options,
-	)
+	// O modelo já devolve texto; o tokenizer, se houver, só dá os IDs do
+	// prefixo (sem ele valem os do multilíngue)
+	w.tokenizer, _ = NewWhisperTokenizer(w.config.ModelPath)
+	return nil
+}
+
+// openSplit abre o encoder (log-mel [1, mels, 3000] → estados) e o decoder
+// (input_ids + encoder_hidden_states → logits). O decoder recalcula a
+// sequência a cada token: exportações com KV-cache (decoder_with_past,
+// merged) não são usadas.
+func (w *Whisper) openSplit(input, output backend.TensorInfo) error {
+	if input.Type != backend.Float32 {
+		return fmt.Errorf("encoder com entrada %s, esperado float32", input.Type)
+	}
+	nMels := 80
+	if len(input.Shape) == 3 && input.Shape[1] > 0 {
+		nMels = int(input.Shape[1])
+	} else if strings.Contains(w.config.ModelSize, "v3") {
+		nMels = 128
+	}
+	w.mel = newMelFrontend(nMels)
+
+	decoderPath := w.config.DecoderPath
+	if decoderPath == "" {
+		decoderPath = filepath.Join(filepath.Dir(w.config.ModelPath), "decoder_model.onnx")
+	}
+	decoderInputs, decoderOutputs, err := w.backend.Inspect(decoderPath)
 	if err != nil {
-		// Tenta com nomes alternativos
-		session, err = ort.NewDynamicAdvancedSession(
-			cfg.ModelPath,
-			[]string{"audio_input"},
-			[]string{"logits"},
-			options,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("erro ao carregar modelo Whisper: %w", err)
+		return fmt.Errorf("decoder: %w", err)
+	}
+	for _, in := range decoderInputs {
+		if in.Name != "input_ids" && in.Name != "encoder_hidden_states" {
+			return fmt.Errorf("entrada %s do decoder não suportada (use o decoder_model.onnx sem KV-cache)", in.Name)
 		}
 	}
+	if !hasTensor(decoderInputs, "input_ids") || !hasTensor(decoderInputs, "encoder_hidden_states") || !hasTensor(decoderOutputs, "logits") {
+		return fmt.Errorf("decoder sem input_ids, encoder_hidden_states ou logits")
+	}
 
-	// Carrega tokenizer
-	tokenizer, err := NewWhisperTokenizer(cfg.ModelPath)
-	if err != nil {
-		fmt.Printf("Aviso: não foi possível carregar tokenizer: %v\n", err)
+	if w.tokenizer, err = NewWhisperTokenizer(w.config.ModelPath); err != nil {
+		return fmt.Errorf("erro ao carregar tokenizer do Whisper: %w", err)
 	}
 
-	return &Whisper{
-		session:   session,
-		config:    cfg,
-		language:  cfg.Language,
-		tokenizer: tokenizer,
-	}, nil
+	if w.session, err = w.backend.Open(w.config.ModelPath, []string{input.Name}, []string{output.Name}); err != nil {
+		return err
+	}
+	if w.decoder, err = w.backend.Open(decoderPath, []string{"input_ids", "encoder_hidden_states"}, []string{"logits"}); err != nil {
+		w.session.Close()
+		return err
+	}
+	return nil
 }
 
-// Transcribe converte áudio em texto
+// hasTensor indica se há uma entrada/saída com o nome
+func hasTensor(infos []backend.TensorInfo, name string) bool {
+	for _, info := range infos {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Transcribe converte áudio PCM mono de 16 kHz em texto
 func (w *Whisper) Transcribe(audioData []float32) (string, error) {
-	if len(audioData) == 0 {
-		return "", nil
+	segments, err := w.Segments(audioData)
+	if err != nil {
+		return "", err
 	}
+	return JoinSegments(segments), nil
+}
+
+// Segments transcreve em trechos de 30 s; sem timestamps no prefixo, cada
+// trecho vai do início ao fim da sua janela
+func (w *Whisper) Segments(audioData []float32) ([]Segment, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
+	var segments []Segment
+	for start := 0; start < len(audioData); start += chunkSamples {
+		end := min(start+chunkSamples, len(audioData))
+		chunk := audioData[start:end]
+
+		var (
+			text string
+			err  error
+		)
+		if w.endToEnd {
+			text, err = w.transcribeEndToEnd(chunk)
+		} else {
+			text, err = w.transcribeSplit(chunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if text != "" {
+			segments = append(segments, Segment{Start: samplesDuration(start), End: samplesDuration(end), Text: text})
+		}
+	}
+	return segments, nil
+}
+
+// samplesDuration duração de n amostras a 16 kHz
+func samplesDuration(n int) time.Duration {
+	return time.Duration(n) * time.Second / SampleRate
+}
+
+// transcribeEndToEnd executa o modelo ponta a ponta num trecho
+func (w *Whisper) transcribeEndToEnd(chunk []float32) (string, error) {
 	// Prepara input tensor
-	inputShape := ort.NewShape(1, int64(len(audioData)))
-	inputTensor, err := ort.NewTensor(inputShape, audioData)
+	audio, err := w.backend.NewTensor(backend.Float32, backend.Shape{1, int64(len(chunk))}, chunk)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar tensor: %w", err)
+	}
+	defer audio.Destroy()
+
+	params, err := w.searchParams()
 	if err != nil {
 		return "", fmt.Errorf("erro ao criar tensor: %w", err)
 	}
-	defer inputTensor.Destroy()
+	defer backend.DestroyAll(params)
+	inputs := append([]backend.Tensor{audio}, params...)
+
+	if w.forced {
+		forced, err := w.forcedIDs()
+		if err != nil {
+			return "", err
+		}
+		defer forced.Destroy()
+		inputs = append(inputs, forced)
+	}
 
 	// Executa inferência
-	outputs, err := w.session.Run(map[string]*ort.Tensor[float32]{
-		"audio_input": inputTensor,
-	})
+	outputs, err := w.session.Run(inputs)
 	if err != nil {
 		return "", fmt.Errorf("erro na inferência: %w", err)
 	}
+	defer backend.DestroyAll(outputs)
 
-	// Processa output
-	transcription := w.decodeOutput(outputs)
+	texts, err := backend.Strings(outputs[0])
+	if err != nil {
+		return "", err
+	}
+	if len(texts) == 0 {
+		return "", nil
+	}
+	return cleanText(texts[0]), nil
+}
+
+// searchParams parâmetros do beam search do Whisper ponta a ponta, na ordem
+// de endToEndInputs
+func (w *Whisper) searchParams() ([]backend.Tensor, error) {
+	scalar := backend.Shape{1}
+	ints := []int32{1, 448, 1, 1} // min_length, max_length, num_beams, num_return_sequences
+	floats := []float32{1.0, 1.0} // length_penalty, repetition_penalty
 
-	return transcription, nil
+	var params []backend.Tensor
+	for _, v := range ints {
+		t, err := w.backend.NewTensor(backend.Int32, scalar, []int32{v})
+		if err != nil {
+			backend.DestroyAll(params)
+			return nil, err
+		}
+		params = append(params, t)
+	}
+	for _, v := range floats {
+		t, err := w.backend.NewTensor(backend.Float32, scalar, []float32{v})
+		if err != nil {
+			backend.DestroyAll(params)
+			return nil, err
+		}
+		params = append(params, t)
+	}
+	return params, nil
 }
 
-// decodeOutput decodifica o output do modelo
-func (w *Whisper) decodeOutput(outputs map[string]*ort.Tensor[float32]) string {
-	// Obtém tensor de logits
-	logits, ok := outputs["logits"]
-	if !ok {
-		// Tenta nome alternativo
-		for name, tensor := range outputs {
-			if strings.Contains(name, "logit") || strings.Contains(name, "output") {
-				logits = tensor
-				break
-			}
+// forcedIDs prefixo do ponta a ponta em decoder_input_ids; em "auto" só o
+// <|startoftranscript|>, e o modelo escolhe o idioma
+func (w *Whisper) forcedIDs() (backend.Tensor, error) {
+	ids := []int32{int32(w.tokenizer.specialIDs["<|startoftranscript|>"])}
+	if !autoLanguage(w.language) {
+		language, ok := w.tokenizer.languages[w.language]
+		if !ok {
+			return nil, fmt.Errorf("idioma não suportado pelo Whisper: %s", w.language)
+		}
+		ids = nil
+		for _, id := range w.tokenizer.prompt(language) {
+			ids = append(ids, int32(id))
 		}
 	}
+	t, err := w.backend.NewTensor(backend.Int32, backend.Shape{1, int64(len(ids))}, ids)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar tensor: %w", err)
+	}
+	return t, nil
+}
 
-	if logits == nil {
-		return ""
+// transcribeSplit log-mel, encoder e decodificação com fallback de
+// temperatura num trecho
+func (w *Whisper) transcribeSplit(chunk []float32) (string, error) {
+	features := w.mel.logMel(chunk)
+	input, err := w.backend.NewTensor(backend.Float32, backend.Shape{1, int64(w.mel.nMels), chunkFrames}, features)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar tensor: %w", err)
 	}
+	defer input.Destroy()
 
-	data := logits.GetData()
-	if len(data) == 0 {
-		return ""
+	outputs, err := w.session.Run([]backend.Tensor{input})
+	if err != nil {
+		return "", fmt.Errorf("erro no encoder: %w", err)
 	}
+	defer backend.DestroyAll(outputs)
+	states := outputs[0]
 
-	// Decodifica tokens usando greedy search
-	tokens := w.greedyDecode(data)
+	prompt, err := w.prompt(states)
+	if err != nil {
+		return "", err
+	}
 
-	// Converte tokens em texto
-	return w.tokensToText(tokens)
+	var result *decodeResult
+	for _, temperature := range w.config.Temperatures {
+		if result, err = w.decode(states, prompt, temperature); err != nil {
+			return "", err
+		}
+		if !result.needsFallback(w.config) {
+			break
+		}
+	}
+
+	if result.silent(w.config) {
+		return "", nil
+	}
+	return result.text, nil
 }
 
-// greedyDecode faz decodificação greedy dos logits
-func (w *Whisper) greedyDecode(logits []float32) []int64 {
-	// Whisper vocab size é ~51865
-	vocabSize := 51865
+// prompt prefixo do decoder, com o idioma detectado quando é "auto"
+func (w *Whisper) prompt(states backend.Tensor) ([]int64, error) {
+	if !autoLanguage(w.language) {
+		language, ok := w.tokenizer.languages[w.language]
+		if !ok {
+			return nil, fmt.Errorf("idioma não suportado pelo Whisper: %s", w.language)
+		}
+		return w.tokenizer.prompt(language), nil
+	}
 
-	if len(logits) < vocabSize {
-		vocabSize = len(logits)
+	// Idioma mais provável logo depois de <|startoftranscript|>
+	rows, err := w.decoderRows([]int64{w.tokenizer.specialIDs["<|startoftranscript|>"]}, states, 0)
+	if err != nil {
+		return nil, err
+	}
+	best := int64(-1)
+	for _, id := range w.tokenizer.languages {
+		if int(id) < len(rows[0]) && (best < 0 || rows[0][id] > rows[0][best]) {
+			best = id
+		}
 	}
+	if best < 0 {
+		return nil, fmt.Errorf("modelo sem tokens de idioma")
+	}
+	return w.tokenizer.prompt(best), nil
+}
 
-	var tokens []int64
-	endOfText := int64(50257) // <|endoftext|>
+// autoLanguage idioma a detectar pelo modelo
+func autoLanguage(language string) bool {
+	return language == "" || language == "auto"
+}
 
-	// Processa em blocos de vocabSize
-	for i := 0; i < len(logits); i += vocabSize {
-		end := i + vocabSize
-		if end > len(logits) {
-			break
-		}
+// decodeResult uma tentativa de decodificação de um trecho
+type decodeResult struct {
+	tokens           []int64
+	text             string
+	avgLogProb       float64
+	noSpeechProb     float64 // Probabilidade de <|nospeech|> logo após <|startoftranscript|>
+	compressionRatio float64 // Texto repetitivo comprime muito
+}
 
-		// Encontra argmax
-		maxIdx := 0
-		maxVal := logits[i]
-		for j := 1; j < vocabSize && i+j < len(logits); j++ {
-			if logits[i+j] > maxVal {
-				maxVal = logits[i + j]
-				maxIdx = j
-			}
-		}
+// needsFallback a tentativa repetiu demais ou teve pouca confiança. Silêncio
+// não é refeito: outra temperatura só inventaria texto.
+func (r *decodeResult) needsFallback(cfg config.STTConfig) bool {
+	if r.noSpeechProb > float64(cfg.NoSpeechThreshold) {
+		return false
+	}
+	return r.compressionRatio > float64(cfg.CompressionRatioThreshold) || r.avgLogProb < float64(cfg.LogProbThreshold)
+}
 
-		token := int64(maxIdx)
+// silent o trecho é silêncio: o modelo aposta em <|nospeech|> e o texto
+// gerado tem pouca confiança
+func (r *decodeResult) silent(cfg config.STTConfig) bool {
+	return r.noSpeechProb > float64(cfg.NoSpeechThreshold) && r.avgLogProb < float64(cfg.LogProbThreshold)
+}
 
-		// Para se encontrar end of text
-		if token == endOfText {
-			break
+// decode gera o texto do trecho a partir do prefixo. Temperatura 0 é greedy;
+// acima disso amostra da distribuição suavizada.
+func (w *Whisper) decode(states backend.Tensor, prompt []int64, temperature float32) (*decodeResult, error) {
+	eot := w.tokenizer.specialIDs["<|endoftext|>"]
+	noSpeech := w.tokenizer.specialIDs["<|nospeech|>"]
+
+	ids := append([]int64(nil), prompt...)
+	result := &decodeResult{}
+	var sumLogProb float64
+	for len(result.tokens) < maxSampleTokens {
+		first := len(result.tokens) == 0
+		positions := []int{len(ids) - 1}
+		if first {
+			// A posição de <|startoftranscript|> dá a chance de não haver fala
+			positions = []int{0, len(ids) - 1}
 		}
-
-		// Ignora tokens especiais de timestamp (<|0.00|> a <|30.00|>)
-		if token >= 50364 && token <= 50864 {
-			continue
+		rows, err := w.decoderRows(ids, states, positions...)
+		if err != nil {
+			return nil, err
+		}
+		if first && int(noSpeech) < len(rows[0]) {
+			result.noSpeechProb = math.Exp(logSoftmax(rows[0])[noSpeech])
 		}
 
-		// Ignora outros tokens especiais
-		if token >= 50257 && token < 50364 {
-			continue
+		logits := rows[len(rows)-1]
+		suppressTokens(logits, eot, first)
+		logProbs := logSoftmax(logits)
+		next := w.sample(logits, temperature)
+		sumLogProb += logProbs[next]
+		if next == eot {
+			break
 		}
+		result.tokens = append(result.tokens, next)
+		ids = append(ids, next)
+	}
+
+	result.avgLogProb = sumLogProb / float64(len(result.tokens)+1)
+	result.text = cleanText(w.tokenizer.Decode(result.tokens))
+	result.compressionRatio = compressionRatio(result.text)
+	return result, nil
+}
+
+// decoderRows executa o decoder na sequência e copia os logits das posições
+// pedidas
+func (w *Whisper) decoderRows(ids []int64, states backend.Tensor, positions ...int) ([][]float32, error) {
+	input, err := w.backend.NewTensor(backend.Int64, backend.Shape{1, int64(len(ids))}, ids)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	outputs, err := w.decoder.Run([]backend.Tensor{input, states})
+	if err != nil {
+		return nil, fmt.Errorf("erro no decoder: %w", err)
+	}
+	defer backend.DestroyAll(outputs)
+
+	data, err := backend.Float32s(outputs[0])
+	if err != nil {
+		return nil, err
+	}
+	shape := outputs[0].Shape()
+	vocab := int(shape[len(shape)-1])
+	if len(data) < len(ids)*vocab {
+		return nil, fmt.Errorf("logits do decoder com %d valores para %d tokens", len(data), len(ids))
+	}
 
-		tokens = append(tokens, token)
+	rows := make([][]float32, len(positions))
+	for i, pos := range positions {
+		rows[i] = append([]float32(nil), data[pos*vocab:(pos+1)*vocab]...)
 	}
+	return rows, nil
+}
 
-	return tokens
+// suppressTokens proíbe os tokens especiais (idiomas, tarefas, timestamps),
+// menos o fim de texto, que só é proibido no primeiro token
+func suppressTokens(logits []float32, eot int64, first bool) {
+	for id := int(eot); id < len(logits); id++ {
+		if id != int(eot) || first {
+			logits[id] = float32(math.Inf(-1))
+		}
+	}
 }
 
-// tokensToText converte tokens em texto
-func (w *Whisper) tokensToText(tokens []int64) string {
-	var result strings.Builder
-
-	for _, token := range tokens {
-		// Tenta decodificar do vocabulário
-		if w.tokenizer != nil && w.tokenizer.vocab != nil {
-			if text, ok := w.tokenizer.vocab[token]; ok {
-				// Whisper usa Ġ para espaço
-				text = strings.ReplaceAll(text, "Ġ", " ")
-				// Remove caracteres especiais de byte
-				if !strings.HasPrefix(text, "<|") {
-					result.WriteString(text)
-				}
-				continue
-			}
+// sample escolhe o próximo token: maior logit em temperatura 0, sorteio
+// proporcional a exp(logit/temperatura) acima disso
+func (w *Whisper) sample(logits []float32, temperature float32) int64 {
+	best := 0
+	for i, v := range logits {
+		if v > logits[best] {
+			best = i
 		}
+	}
+	if temperature <= 0 {
+		return int64(best)
+	}
 
-		// Fallback: decodifica usando byte-level BPE básico
-		if token < 256 {
-			result.WriteByte(byte(token))
+	probs := make([]float64, len(logits))
+	var total float64
+	for i, v := range logits {
+		probs[i] = math.Exp(float64(v-logits[best]) / float64(temperature))
+		total += probs[i]
+	}
+	r := w.rand.Float64() * total
+	for i, p := range probs {
+		if r -= p; r <= 0 {
+			return int64(i)
 		}
 	}
+	return int64(best)
+}
+
+// logSoftmax log das probabilidades dos logits
+func logSoftmax(logits []float32) []float64 {
+	peak := math.Inf(-1)
+	for _, v := range logits {
+		peak = max(peak, float64(v))
+	}
+	var total float64
+	for _, v := range logits {
+		total += math.Exp(float64(v) - peak)
+	}
+	logNorm := peak + math.Log(total)
+
+	out := make([]float64, len(logits))
+	for i, v := range logits {
+		out[i] = float64(v) - logNorm
+	}
+	return out
+}
 
-	text := result.String()
+// compressionRatio tamanho do texto sobre o tamanho comprimido com zlib
+func compressionRatio(text string) float64 {
+	if text == "" {
+		return 0
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(text))
+	zw.Close()
+	return float64(len(text)) / float64(buf.Len())
+}
 
-	// Limpa o texto
+// cleanText remove espaços das pontas e espaços repetidos
+func cleanText(text string) string {
 	text = strings.TrimSpace(text)
 
 	// Remove múltiplos espaços
@@ -271,13 +628,18 @@ func (w *Whisper) tokensToText(tokens []int64) string {
 
 // Close libera recursos
 func (w *Whisper) Close() error {
+	if w.decoder != nil {
+		w.decoder.Close()
+	}
 	if w.session != nil {
-		return w.session.Destroy()
+		return w.session.Close()
 	}
 	return nil
 }
 
-// SetLanguage define o idioma para transcrição
+// SetLanguage define o idioma para transcrição ("auto" detecta)
 func (w *Whisper) SetLanguage(lang string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	w.language = lang
 }
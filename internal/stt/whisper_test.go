@@ -0,0 +1,249 @@
+package stt
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// IDs do tokenizer de teste: bytes 0-255 e os especiais logo depois
+const (
+	eotID = 256 + iota
+	sotID
+	enID
+	ptID
+	translateID
+	transcribeID
+	startOfLMID
+	startOfPrevID
+	noCaptionsID
+	noTimestampsID
+	timestampID
+	vocabSize
+)
+
+// specials especiais do tokenizer de teste, na ordem dos IDs acima
+var specials = []string{"<|endoftext|>", "<|startoftranscript|>", "<|en|>", "<|pt|>", "<|translate|>", "<|transcribe|>",
+	"<|startoflm|>", "<|startofprev|>", "<|nocaptions|>", "<|notimestamps|>", "<|0.00|>"}
+
+// decoderScript logits do decoder para a sequência: a linha da posição de
+// <|startoftranscript|> (nil = zeros) e a da última posição
+type decoderScript func(ids []int64) (first, last []float32)
+
+// newTestWhisper Whisper com encoder e decoder roteirizados; devolve também o
+// backend para contar execuções
+func newTestWhisper(t *testing.T, language string, script decoderScript) (*Whisper, *fake.Backend) {
+	t.Helper()
+	dir := filepath.Dir(fake.ByteLevelTokenizer(t, specials...))
+	encoderPath := filepath.Join(dir, "encoder_model.onnx")
+	decoderPath := filepath.Join(dir, "decoder_model.onnx")
+
+	be := fake.New()
+	be.Add(encoderPath, &fake.Model{
+		Inputs:  []backend.TensorInfo{{Name: "input_features", Type: backend.Float32, Shape: backend.Shape{-1, 80, 3000}}},
+		Outputs: []backend.TensorInfo{{Name: "last_hidden_state", Type: backend.Float32}},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			if shape := inputs[0].Shape(); !reflect.DeepEqual(shape, backend.Shape{1, 80, 3000}) {
+				t.Errorf("encoder recebeu %v", shape)
+			}
+			states, err := backend.NewHostTensor(backend.Float32, backend.Shape{1, 1, 2}, []float32{1, 2})
+			return []backend.Tensor{states}, err
+		},
+	})
+	be.Add(decoderPath, &fake.Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input_ids", Type: backend.Int64},
+			{Name: "encoder_hidden_states", Type: backend.Float32},
+		},
+		Outputs: []backend.TensorInfo{{Name: "logits", Type: backend.Float32}},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			ids := inputs[0].Data().([]int64)
+			first, last := script(ids)
+			logits := make([]float32, len(ids)*vocabSize)
+			copy(logits, first)
+			copy(logits[(len(ids)-1)*vocabSize:], last)
+			out, err := backend.NewHostTensor(backend.Float32, backend.Shape{1, int64(len(ids)), vocabSize}, logits)
+			return []backend.Tensor{out}, err
+		},
+	})
+
+	cfg := config.Default().STT
+	cfg.ModelPath = encoderPath
+	cfg.Language = language
+	w, err := NewWhisper(be, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, be
+}
+
+// spell roteiro que escreve text byte a byte depois do prefixo de 4 tokens e
+// termina com <|endoftext|>
+func spell(text string) decoderScript {
+	return func(ids []int64) ([]float32, []float32) {
+		if n := len(ids) - 4; n < len(text) {
+			return nil, fake.OneHot(vocabSize, int64(text[n]))
+		}
+		return nil, fake.OneHot(vocabSize, eotID)
+	}
+}
+
+func TestWhisperTranscribe(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		prompts [][]int64
+	)
+	script := func(ids []int64) ([]float32, []float32) {
+		if len(ids) == 4 {
+			mu.Lock()
+			prompts = append(prompts, append([]int64(nil), ids...))
+			mu.Unlock()
+		}
+		return spell("bom dia")(ids)
+	}
+	w, be := newTestWhisper(t, "pt", script)
+
+	text, err := w.Transcribe(make([]float32, SampleRate))
+	if err != nil || text != "bom dia" {
+		t.Fatalf("Transcribe = %q, %v", text, err)
+	}
+
+	// O idioma entra no prefixo
+	w.SetLanguage("en")
+	if _, err := w.Transcribe(make([]float32, SampleRate)); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int64{
+		{sotID, ptID, transcribeID, noTimestampsID},
+		{sotID, enID, transcribeID, noTimestampsID},
+	}
+	if !reflect.DeepEqual(prompts, want) {
+		t.Errorf("prefixos = %v, want %v", prompts, want)
+	}
+
+	// 45 s viram dois trechos de até 30 s
+	encoder := filepath.Join(filepath.Dir(w.config.ModelPath), "encoder_model.onnx")
+	before := be.Runs(encoder)
+	text, err = w.Transcribe(make([]float32, 45*SampleRate))
+	if err != nil || text != "bom dia bom dia" {
+		t.Errorf("45 s = %q, %v", text, err)
+	}
+	if runs := be.Runs(encoder) - before; runs != 2 {
+		t.Errorf("encoder executado %d vezes, want 2", runs)
+	}
+
+	segments, err := w.Segments(make([]float32, 45*SampleRate))
+	wantSegments := []Segment{{0, 30 * time.Second, "bom dia"}, {30 * time.Second, 45 * time.Second, "bom dia"}}
+	if err != nil || !reflect.DeepEqual(segments, wantSegments) {
+		t.Errorf("Segments = %v, %v; want %v", segments, err, wantSegments)
+	}
+
+	w.SetLanguage("xx")
+	if _, err := w.Transcribe(make([]float32, SampleRate)); err == nil || !strings.Contains(err.Error(), "idioma") {
+		t.Errorf("idioma desconhecido: erro = %v", err)
+	}
+}
+
+func TestWhisperDetectLanguage(t *testing.T) {
+	var prompt []int64
+	w, _ := newTestWhisper(t, "auto", func(ids []int64) ([]float32, []float32) {
+		if len(ids) == 1 {
+			// Depois de <|startoftranscript|> o modelo aposta em português
+			return nil, fake.OneHot(vocabSize, ptID)
+		}
+		if len(ids) == 4 {
+			prompt = append([]int64(nil), ids...)
+		}
+		return spell("oi")(ids)
+	})
+
+	text, err := w.Transcribe(make([]float32, SampleRate))
+	if err != nil || text != "oi" {
+		t.Fatalf("Transcribe = %q, %v", text, err)
+	}
+	if prompt[1] != ptID {
+		t.Errorf("prefixo = %v, want idioma %d", prompt, ptID)
+	}
+}
+
+func TestWhisperFallback(t *testing.T) {
+	// Primeira tentativa em loop ("aaaa..."), a segunda acerta
+	attempts := 0
+	repeat := strings.Repeat("a", 80)
+	w, _ := newTestWhisper(t, "pt", func(ids []int64) ([]float32, []float32) {
+		if len(ids) == 4 {
+			attempts++
+		}
+		if attempts == 1 {
+			return spell(repeat)(ids)
+		}
+		return spell("certo")(ids)
+	})
+
+	text, err := w.Transcribe(make([]float32, SampleRate))
+	if err != nil || text != "certo" || attempts != 2 {
+		t.Errorf("Transcribe = %q, %v após %d tentativas", text, err, attempts)
+	}
+}
+
+func TestWhisperNoSpeech(t *testing.T) {
+	attempts := 0
+	w, _ := newTestWhisper(t, "pt", func(ids []int64) ([]float32, []float32) {
+		if len(ids) == 4 {
+			attempts++
+		}
+		// <|nospeech|> na posição inicial e texto sem confiança
+		last := make([]float32, vocabSize)
+		last[eotID] = 1
+		return fake.OneHot(vocabSize, noCaptionsID), last
+	})
+
+	text, err := w.Transcribe(make([]float32, SampleRate))
+	if err != nil || text != "" {
+		t.Errorf("silêncio = %q, %v", text, err)
+	}
+	if attempts != 1 {
+		t.Errorf("%d tentativas para silêncio, want 1", attempts)
+	}
+}
+
+func TestWhisperEndToEnd(t *testing.T) {
+	dir := filepath.Dir(fake.ByteLevelTokenizer(t, specials...))
+	path := filepath.Join(dir, "whisper.onnx")
+
+	// Exportação ponta a ponta com prefixo forçado
+	model := fake.Transcripts("Olá")
+	model.Inputs = append(model.Inputs, backend.TensorInfo{Name: "decoder_input_ids", Type: backend.Int32})
+	run := model.Run
+	var forced []int32
+	model.Run = func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+		forced = inputs[len(inputs)-1].Data().([]int32)
+		return run(inputs)
+	}
+	be := fake.New()
+	be.Add(path, model)
+
+	cfg := config.Default().STT
+	cfg.ModelPath = path
+	w, err := NewWhisper(be, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	text, err := w.Transcribe(make([]float32, SampleRate))
+	if err != nil || text != "Olá" {
+		t.Fatalf("Transcribe = %q, %v", text, err)
+	}
+	if want := []int32{sotID, ptID, transcribeID, noTimestampsID}; !reflect.DeepEqual(forced, want) {
+		t.Errorf("decoder_input_ids = %v, want %v", forced, want)
+	}
+}
@@ -0,0 +1,115 @@
+//go:build whispercpp
+
+package stt
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	whisper "github.com/ggerganov/whisper.cpp/bindings/go/pkg/whisper"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// WhisperCpp Speech-to-Text com whisper.cpp: modelos GGML/GGUF na CPU, sem
+// ONNX Runtime nem DirectML
+type WhisperCpp struct {
+	model   whisper.Model
+	threads int
+
+	mu       sync.Mutex // O contexto C do modelo é um só
+	language string
+}
+
+// NewWhisperCpp carrega o modelo GGML/GGUF de cfg.ModelPath
+func NewWhisperCpp(cfg config.STTConfig) (*WhisperCpp, error) {
+	model, err := whisper.New(cfg.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar modelo whisper.cpp: %w", err)
+	}
+
+	threads := cfg.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	return &WhisperCpp{model: model, threads: threads, language: cfg.Language}, nil
+}
+
+// newWhisperCpp usado por New
+func newWhisperCpp(cfg config.STTConfig) (Transcriber, error) {
+	w, err := NewWhisperCpp(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Transcribe converte áudio PCM mono de 16 kHz em texto
+func (w *WhisperCpp) Transcribe(audioData []float32) (string, error) {
+	segments, err := w.Segments(audioData)
+	if err != nil {
+		return "", err
+	}
+	return JoinSegments(segments), nil
+}
+
+// Segments trechos com os timestamps do whisper.cpp
+func (w *WhisperCpp) Segments(audioData []float32) ([]Segment, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(audioData) == 0 {
+		return nil, nil
+	}
+
+	// Parâmetros por transcrição; o modelo continua carregado
+	ctx, err := w.model.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar contexto whisper.cpp: %w", err)
+	}
+	ctx.SetThreads(uint(w.threads))
+	ctx.SetTranslate(false)
+	if ctx.IsMultilingual() {
+		language := w.language
+		if language == "" {
+			language = "auto"
+		}
+		if err := ctx.SetLanguage(language); err != nil {
+			return nil, fmt.Errorf("idioma não suportado pelo whisper.cpp: %s", language)
+		}
+	}
+
+	var segments []Segment
+	onSegment := func(s whisper.Segment) {
+		text := cleanText(s.Text)
+		// Silêncio vem como [BLANK_AUDIO], (música)...
+		if text == "" || isAnnotation(text) {
+			return
+		}
+		segments = append(segments, Segment{Start: s.Start, End: s.End, Text: text})
+	}
+	if err := ctx.Process(audioData, nil, onSegment, nil); err != nil {
+		return nil, fmt.Errorf("erro na transcrição: %w", err)
+	}
+	return segments, nil
+}
+
+// isAnnotation trecho que só descreve o áudio, entre colchetes ou parênteses
+func isAnnotation(text string) bool {
+	return (strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]")) ||
+		(strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")"))
+}
+
+// SetLanguage define o idioma para transcrição ("auto" detecta)
+func (w *WhisperCpp) SetLanguage(lang string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.language = lang
+}
+
+// Close libera o modelo
+func (w *WhisperCpp) Close() error {
+	return w.model.Close()
+}
@@ -0,0 +1,15 @@
+//go:build !whispercpp
+
+package stt
+
+import (
+	"fmt"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// newWhisperCpp sem whisper.cpp no binário: ele depende de cgo e da
+// libwhisper, então só entra com a build tag whispercpp
+func newWhisperCpp(cfg config.STTConfig) (Transcriber, error) {
+	return nil, fmt.Errorf("stt.backend %s indisponível: compile com -tags whispercpp (requer a libwhisper)", cfg.Backend)
+}
@@ -0,0 +1,307 @@
+package tokenizer
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// model converte um pedaço pré-tokenizado em IDs
+type model interface {
+	tokenize(piece string) []int64
+	tokenToID(token string) (int64, bool)
+	idToToken(id int64) (string, bool)
+	vocabSize() int
+}
+
+// maxCacheEntries limita o cache de palavras do BPE
+const maxCacheEntries = 10000
+
+// bpeModel implementa Byte-Pair Encoding (byte-level do GPT-2/Llama 3/Qwen e
+// o BPE do SentencePiece usado pelo Llama 2/Phi-3, com byte fallback)
+type bpeModel struct {
+	vocab    map[string]int64
+	vocabRev map[int64]string
+	merges   map[[2]int64]bpeMerge
+
+	unkID                   int64 // -1 se não houver
+	continuingSubwordPrefix string
+	endOfWordSuffix         string
+	fuseUnk                 bool
+	byteFallback            bool
+	ignoreMerges            bool
+
+	cache   map[string][]int64
+	cacheMu sync.RWMutex
+}
+
+// bpeMerge resultado de juntar um par de símbolos
+type bpeMerge struct {
+	rank int
+	id   int64
+}
+
+func newBPEModel(raw json.RawMessage) (*bpeModel, error) {
+	var spec struct {
+		Vocab                   map[string]int64  `json:"vocab"`
+		Merges                  []json.RawMessage `json:"merges"`
+		UnkToken                *string           `json:"unk_token"`
+		ContinuingSubwordPrefix *string           `json:"continuing_subword_prefix"`
+		EndOfWordSuffix         *string           `json:"end_of_word_suffix"`
+		FuseUnk                 bool              `json:"fuse_unk"`
+		ByteFallback            bool              `json:"byte_fallback"`
+		IgnoreMerges            bool              `json:"ignore_merges"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("modelo BPE inválido: %w", err)
+	}
+
+	m := &bpeModel{
+		vocab:        spec.Vocab,
+		vocabRev:     make(map[int64]string, len(spec.Vocab)),
+		merges:       make(map[[2]int64]bpeMerge, len(spec.Merges)),
+		unkID:        -1,
+		fuseUnk:      spec.FuseUnk,
+		byteFallback: spec.ByteFallback,
+		ignoreMerges: spec.IgnoreMerges,
+		cache:        make(map[string][]int64),
+	}
+	for tok, id := range m.vocab {
+		m.vocabRev[id] = tok
+	}
+	if spec.ContinuingSubwordPrefix != nil {
+		m.continuingSubwordPrefix = *spec.ContinuingSubwordPrefix
+	}
+	if spec.EndOfWordSuffix != nil {
+		m.endOfWordSuffix = *spec.EndOfWordSuffix
+	}
+	if spec.UnkToken != nil {
+		if id, ok := m.vocab[*spec.UnkToken]; ok {
+			m.unkID = id
+		}
+	}
+
+	prefixLen := len(m.continuingSubwordPrefix)
+	for rank, rawMerge := range spec.Merges {
+		// Formato antigo: "a b"; formato novo: ["a", "b"]
+		var pair [2]string
+		var s string
+		if err := json.Unmarshal(rawMerge, &s); err == nil {
+			parts := strings.SplitN(s, " ", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("merge inválido na linha %d: %q", rank, s)
+			}
+			pair = [2]string{parts[0], parts[1]}
+		} else {
+			var arr []string
+			if err := json.Unmarshal(rawMerge, &arr); err != nil || len(arr) != 2 {
+				return nil, fmt.Errorf("merge inválido na linha %d", rank)
+			}
+			pair = [2]string{arr[0], arr[1]}
+		}
+
+		a, okA := m.vocab[pair[0]]
+		b, okB := m.vocab[pair[1]]
+		if !okA || !okB {
+			return nil, fmt.Errorf("merge %q + %q fora do vocabulário", pair[0], pair[1])
+		}
+		merged := pair[0] + pair[1]
+		if prefixLen > 0 && strings.HasPrefix(pair[1], m.continuingSubwordPrefix) {
+			merged = pair[0] + pair[1][prefixLen:]
+		}
+		id, ok := m.vocab[merged]
+		if !ok {
+			return nil, fmt.Errorf("resultado do merge %q fora do vocabulário", merged)
+		}
+		if _, exists := m.merges[[2]int64{a, b}]; !exists {
+			m.merges[[2]int64{a, b}] = bpeMerge{rank: rank, id: id}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *bpeModel) tokenToID(token string) (int64, bool) {
+	id, ok := m.vocab[token]
+	return id, ok
+}
+
+func (m *bpeModel) idToToken(id int64) (string, bool) {
+	tok, ok := m.vocabRev[id]
+	return tok, ok
+}
+
+func (m *bpeModel) vocabSize() int {
+	return len(m.vocab)
+}
+
+// tokenize aplica os merges a uma palavra, usando cache
+func (m *bpeModel) tokenize(piece string) []int64 {
+	if piece == "" {
+		return nil
+	}
+	if m.ignoreMerges {
+		if id, ok := m.vocab[piece]; ok {
+			return []int64{id}
+		}
+	}
+
+	m.cacheMu.RLock()
+	cached, ok := m.cache[piece]
+	m.cacheMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	ids := m.mergeWord(piece)
+
+	m.cacheMu.Lock()
+	if len(m.cache) >= maxCacheEntries {
+		m.cache = make(map[string][]int64)
+	}
+	m.cache[piece] = ids
+	m.cacheMu.Unlock()
+
+	return ids
+}
+
+// bpeSymbol símbolo na lista duplamente ligada usada durante os merges
+type bpeSymbol struct {
+	id         int64
+	prev, next int
+	removed    bool
+}
+
+// mergeWord divide a palavra em caracteres e aplica os merges por ordem de rank
+func (m *bpeModel) mergeWord(word string) []int64 {
+	symbols := make([]bpeSymbol, 0, len(word))
+	pendingUnk := false
+
+	for i, r := range word {
+		ch := string(r)
+		size := utf8.RuneLen(r)
+		if i > 0 && m.continuingSubwordPrefix != "" {
+			ch = m.continuingSubwordPrefix + ch
+		}
+		if i+size == len(word) && m.endOfWordSuffix != "" {
+			ch += m.endOfWordSuffix
+		}
+
+		if id, ok := m.vocab[ch]; ok {
+			symbols = append(symbols, bpeSymbol{id: id})
+			pendingUnk = false
+			continue
+		}
+
+		if m.byteFallback {
+			var byteIDs []int64
+			for _, b := range []byte(string(r)) {
+				if id, ok := m.vocab[fmt.Sprintf("<0x%02X>", b)]; ok {
+					byteIDs = append(byteIDs, id)
+				}
+			}
+			if len(byteIDs) == size {
+				for _, id := range byteIDs {
+					symbols = append(symbols, bpeSymbol{id: id})
+				}
+				pendingUnk = false
+				continue
+			}
+		}
+
+		if m.unkID < 0 {
+			continue
+		}
+		if m.fuseUnk && pendingUnk {
+			continue
+		}
+		symbols = append(symbols, bpeSymbol{id: m.unkID})
+		pendingUnk = true
+	}
+
+	for i := range symbols {
+		symbols[i].prev = i - 1
+		symbols[i].next = i + 1
+	}
+	if len(symbols) > 0 {
+		symbols[len(symbols)-1].next = -1
+	}
+
+	// Fila de prioridade com os pares candidatos
+	pq := &mergeQueue{}
+	for i := 0; i+1 < len(symbols); i++ {
+		if mg, ok := m.merges[[2]int64{symbols[i].id, symbols[i+1].id}]; ok {
+			heap.Push(pq, mergeCandidate{pos: i, rank: mg.rank, id: mg.id})
+		}
+	}
+
+	for pq.Len() > 0 {
+		c := heap.Pop(pq).(mergeCandidate)
+		left := &symbols[c.pos]
+		if left.removed || left.next < 0 {
+			continue
+		}
+		right := &symbols[left.next]
+
+		// Descarta candidatos que ficaram obsoletos após outro merge
+		mg, ok := m.merges[[2]int64{left.id, right.id}]
+		if !ok || mg.id != c.id || mg.rank != c.rank {
+			continue
+		}
+
+		left.id = c.id
+		right.removed = true
+		left.next = right.next
+		if right.next >= 0 {
+			symbols[right.next].prev = c.pos
+		}
+
+		if left.prev >= 0 {
+			if mg, ok := m.merges[[2]int64{symbols[left.prev].id, left.id}]; ok {
+				heap.Push(pq, mergeCandidate{pos: left.prev, rank: mg.rank, id: mg.id})
+			}
+		}
+		if left.next >= 0 {
+			if mg, ok := m.merges[[2]int64{left.id, symbols[left.next].id}]; ok {
+				heap.Push(pq, mergeCandidate{pos: c.pos, rank: mg.rank, id: mg.id})
+			}
+		}
+	}
+
+	ids := make([]int64, 0, len(symbols))
+	for _, s := range symbols {
+		if !s.removed {
+			ids = append(ids, s.id)
+		}
+	}
+	return ids
+}
+
+// mergeCandidate par candidato a merge (menor rank primeiro, depois mais à esquerda)
+type mergeCandidate struct {
+	pos  int
+	rank int
+	id   int64
+}
+
+type mergeQueue []mergeCandidate
+
+func (q mergeQueue) Len() int { return len(q) }
+func (q mergeQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank < q[j].rank
+	}
+	return q[i].pos < q[j].pos
+}
+func (q mergeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *mergeQueue) Push(x interface{}) { *q = append(*q, x.(mergeCandidate)) }
+func (q *mergeQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,76 @@
+package tokenizer
+
+import "unicode/utf8"
+
+// Tabelas do byte-level BPE (GPT-2): cada byte é mapeado para um caractere
+// Unicode imprimível, de forma que o vocabulário nunca contenha bytes de
+// controle ou espaços. O espaço, por exemplo, vira "Ġ" (U+0120).
+var (
+	byteEncoder [256]rune
+	byteDecoder = make(map[rune]byte, 256)
+)
+
+func init() {
+	n := 0
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			byteEncoder[b] = rune(b)
+		} else {
+			byteEncoder[b] = rune(256 + n)
+			n++
+		}
+		byteDecoder[byteEncoder[b]] = byte(b)
+	}
+}
+
+// byteLevelEncode converte os bytes UTF-8 do texto para o alfabeto byte-level
+func byteLevelEncode(s string) string {
+	out := make([]rune, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		out = append(out, byteEncoder[s[i]])
+	}
+	return string(out)
+}
+
+// byteLevelDecode desfaz byteLevelEncode. Caracteres fora do alfabeto são
+// mantidos como estão (tokens especiais, por exemplo).
+func byteLevelDecode(s string) string {
+	buf := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := byteDecoder[r]; ok {
+			buf = append(buf, b)
+		} else {
+			buf = utf8.AppendRune(buf, r)
+		}
+	}
+	return toValidUTF8(buf)
+}
+
+// byteLevelRaw bytes de um token byte-level sem validar UTF-8; false se o
+// token tiver caracteres fora do alfabeto
+func byteLevelRaw(tok string) ([]byte, bool) {
+	buf := make([]byte, 0, len(tok))
+	for _, r := range tok {
+		b, ok := byteDecoder[r]
+		if !ok {
+			return nil, false
+		}
+		buf = append(buf, b)
+	}
+	return buf, true
+}
+
+// toValidUTF8 substitui sequências inválidas por U+FFFD, como o
+// from_utf8_lossy da biblioteca de referência
+func toValidUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	out := make([]rune, 0, len(b))
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		out = append(out, r)
+		b = b[size:]
+	}
+	return string(out)
+}
@@ -0,0 +1,104 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Config campos relevantes do tokenizer_config.json
+type Config struct {
+	BOSToken     string
+	EOSToken     string
+	PADToken     string
+	UNKToken     string
+	AddBOSToken  bool
+	AddEOSToken  bool
+	ChatTemplate string
+}
+
+// LoadConfig lê o tokenizer_config.json. Aceita o caminho do próprio arquivo
+// ou do tokenizer.json ao lado dele.
+func LoadConfig(path string) (*Config, error) {
+	if filepath.Base(path) != "tokenizer_config.json" {
+		path = filepath.Join(filepath.Dir(path), "tokenizer_config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		BOSToken     json.RawMessage `json:"bos_token"`
+		EOSToken     json.RawMessage `json:"eos_token"`
+		PADToken     json.RawMessage `json:"pad_token"`
+		UNKToken     json.RawMessage `json:"unk_token"`
+		AddBOSToken  *bool           `json:"add_bos_token"`
+		AddEOSToken  *bool           `json:"add_eos_token"`
+		ChatTemplate json.RawMessage `json:"chat_template"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		BOSToken:     tokenContent(raw.BOSToken),
+		EOSToken:     tokenContent(raw.EOSToken),
+		PADToken:     tokenContent(raw.PADToken),
+		UNKToken:     tokenContent(raw.UNKToken),
+		ChatTemplate: chatTemplate(raw.ChatTemplate),
+	}
+	if raw.AddBOSToken != nil {
+		cfg.AddBOSToken = *raw.AddBOSToken
+	}
+	if raw.AddEOSToken != nil {
+		cfg.AddEOSToken = *raw.AddEOSToken
+	}
+	return cfg, nil
+}
+
+// tokenContent aceita "<s>" ou {"content": "<s>", ...}
+func tokenContent(raw json.RawMessage) string {
+	if isNull(raw) {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var obj struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		return obj.Content
+	}
+	return ""
+}
+
+// chatTemplate aceita uma string ou a lista [{"name": "default", "template": ...}]
+func chatTemplate(raw json.RawMessage) string {
+	if isNull(raw) {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var list []struct {
+		Name     string `json:"name"`
+		Template string `json:"template"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return ""
+	}
+	for _, t := range list {
+		if t.Name == "default" {
+			return t.Template
+		}
+	}
+	if len(list) > 0 {
+		return list[0].Template
+	}
+	return ""
+}
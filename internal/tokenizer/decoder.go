@@ -0,0 +1,280 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decoder transforma a lista de tokens (strings) de volta em texto
+type decoder interface {
+	decodeChain(tokens []string) []string
+}
+
+// newDecoder cria o decoder descrito no JSON (nil se ausente)
+func newDecoder(raw json.RawMessage) (decoder, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case "Sequence":
+		var spec struct {
+			Decoders []json.RawMessage `json:"decoders"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		seq := sequenceDecoder{}
+		for _, r := range spec.Decoders {
+			d, err := newDecoder(r)
+			if err != nil {
+				return nil, err
+			}
+			if d != nil {
+				seq = append(seq, d)
+			}
+		}
+		return seq, nil
+
+	case "ByteLevel":
+		return byteLevelDecoder{}, nil
+
+	case "ByteFallback":
+		return byteFallbackDecoder{}, nil
+
+	case "Fuse":
+		return fuseDecoder{}, nil
+
+	case "Replace":
+		var spec struct {
+			Pattern pattern `json:"pattern"`
+			Content string  `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		re, err := spec.Pattern.compile()
+		if err != nil {
+			return nil, fmt.Errorf("decoder Replace: %w", err)
+		}
+		return replaceDecoder{replaceNormalizer{re: re, content: spec.Content}}, nil
+
+	case "Strip":
+		var spec struct {
+			Content string `json:"content"`
+			Start   int    `json:"start"`
+			Stop    int    `json:"stop"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return stripDecoder{content: spec.Content, start: spec.Start, stop: spec.Stop}, nil
+
+	case "Metaspace":
+		spec := struct {
+			Replacement    string `json:"replacement"`
+			PrependScheme  string `json:"prepend_scheme"`
+			AddPrefixSpace *bool  `json:"add_prefix_space"`
+		}{Replacement: "▁", PrependScheme: "always"}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		if spec.AddPrefixSpace != nil && !*spec.AddPrefixSpace {
+			spec.PrependScheme = "never"
+		}
+		return metaspaceDecoder{replacement: spec.Replacement, stripFirst: spec.PrependScheme != "never"}, nil
+
+	case "WordPiece":
+		spec := struct {
+			Prefix  string `json:"prefix"`
+			Cleanup bool   `json:"cleanup"`
+		}{Prefix: "##", Cleanup: true}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return wordPieceDecoder{prefix: spec.Prefix, cleanup: spec.Cleanup}, nil
+
+	case "BPEDecoder":
+		spec := struct {
+			Suffix string `json:"suffix"`
+		}{Suffix: "</w>"}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return bpeSuffixDecoder{suffix: spec.Suffix}, nil
+	}
+
+	return nil, fmt.Errorf("decoder não suportado: %s", head.Type)
+}
+
+type sequenceDecoder []decoder
+
+func (s sequenceDecoder) decodeChain(tokens []string) []string {
+	for _, d := range s {
+		tokens = d.decodeChain(tokens)
+	}
+	return tokens
+}
+
+// byteLevelDecoder converte "Ġ" e demais caracteres byte-level de volta em bytes
+type byteLevelDecoder struct{}
+
+func (byteLevelDecoder) decodeChain(tokens []string) []string {
+	return []string{byteLevelDecode(strings.Join(tokens, ""))}
+}
+
+// byteFallbackDecoder junta sequências <0xXX> em caracteres UTF-8
+type byteFallbackDecoder struct{}
+
+func (byteFallbackDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+	var pending []byte
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if utf8.Valid(pending) {
+			out = append(out, string(pending))
+		} else {
+			for range pending {
+				out = append(out, "�")
+			}
+		}
+		pending = nil
+	}
+
+	for _, tok := range tokens {
+		if b, ok := parseByteToken(tok); ok {
+			pending = append(pending, b)
+			continue
+		}
+		flush()
+		out = append(out, tok)
+	}
+	flush()
+	return out
+}
+
+// parseByteToken reconhece tokens no formato <0xXX>
+func parseByteToken(tok string) (byte, bool) {
+	if len(tok) != 6 || !strings.HasPrefix(tok, "<0x") || tok[5] != '>' {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(tok[3:5], 16, 8)
+	if err != nil {
+		return 0, false
+	}
+	return byte(v), true
+}
+
+type fuseDecoder struct{}
+
+func (fuseDecoder) decodeChain(tokens []string) []string {
+	return []string{strings.Join(tokens, "")}
+}
+
+type replaceDecoder struct {
+	r replaceNormalizer
+}
+
+func (d replaceDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		out[i] = d.r.normalize(tok)
+	}
+	return out
+}
+
+// stripDecoder remove até start/stop ocorrências de content nas pontas de cada token
+type stripDecoder struct {
+	content     string
+	start, stop int
+}
+
+func (d stripDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		for n := 0; n < d.start && strings.HasPrefix(tok, d.content); n++ {
+			tok = tok[len(d.content):]
+		}
+		for n := 0; n < d.stop && strings.HasSuffix(tok, d.content); n++ {
+			tok = tok[:len(tok)-len(d.content)]
+		}
+		out[i] = tok
+	}
+	return out
+}
+
+// metaspaceDecoder troca "▁" por espaço e remove o espaço inicial
+type metaspaceDecoder struct {
+	replacement string
+	stripFirst  bool
+}
+
+func (d metaspaceDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		tok = strings.ReplaceAll(tok, d.replacement, " ")
+		if i == 0 && d.stripFirst {
+			tok = strings.TrimPrefix(tok, " ")
+		}
+		out[i] = tok
+	}
+	return out
+}
+
+// wordPieceDecoder junta sub-palavras "##" e separa palavras com espaço
+type wordPieceDecoder struct {
+	prefix  string
+	cleanup bool
+}
+
+var wordPieceCleanup = strings.NewReplacer(
+	" .", ".", " ?", "?", " !", "!", " ,", ",", " ' ", "'",
+	" n't", "n't", " 'm", "'m", " do not", " don't", " 's", "'s", " 've", "'ve", " 're", "'re",
+)
+
+func (d wordPieceDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		if i > 0 {
+			if strings.HasPrefix(tok, d.prefix) {
+				tok = tok[len(d.prefix):]
+			} else {
+				tok = " " + tok
+			}
+		}
+		if d.cleanup {
+			tok = wordPieceCleanup.Replace(tok)
+		}
+		out[i] = tok
+	}
+	return out
+}
+
+// bpeSuffixDecoder troca o sufixo de fim de palavra por espaço
+type bpeSuffixDecoder struct {
+	suffix string
+}
+
+func (d bpeSuffixDecoder) decodeChain(tokens []string) []string {
+	out := make([]string, len(tokens))
+	for i, tok := range tokens {
+		replacement := " "
+		if i == len(tokens)-1 {
+			replacement = ""
+		}
+		out[i] = strings.ReplaceAll(tok, d.suffix, replacement)
+	}
+	return out
+}
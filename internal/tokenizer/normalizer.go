@@ -0,0 +1,322 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// normalizer transforma o texto antes da pré-tokenização
+type normalizer interface {
+	normalize(s string) string
+}
+
+// pattern representa o campo "pattern" do tokenizer.json: {"String": ...} ou {"Regex": ...}
+type pattern struct {
+	String *string `json:"String"`
+	Regex  *string `json:"Regex"`
+}
+
+// compile converte o pattern em regexp (strings literais são escapadas)
+func (p pattern) compile() (*regexp.Regexp, error) {
+	switch {
+	case p.String != nil:
+		return regexp.MustCompile(regexp.QuoteMeta(*p.String)), nil
+	case p.Regex != nil:
+		return compileHFRegex(*p.Regex)
+	}
+	return nil, fmt.Errorf("pattern vazio")
+}
+
+// newNormalizer cria o normalizer descrito no JSON (nil se ausente)
+func newNormalizer(raw json.RawMessage) (normalizer, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case "Sequence":
+		var spec struct {
+			Normalizers []json.RawMessage `json:"normalizers"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		seq := sequenceNormalizer{}
+		for _, r := range spec.Normalizers {
+			n, err := newNormalizer(r)
+			if err != nil {
+				return nil, err
+			}
+			if n != nil {
+				seq = append(seq, n)
+			}
+		}
+		return seq, nil
+
+	case "NFC", "NFKC":
+		return nfcNormalizer{}, nil
+
+	case "NFD", "NFKD":
+		return nfdNormalizer{}, nil
+
+	case "Lowercase":
+		return lowercaseNormalizer{}, nil
+
+	case "StripAccents":
+		return stripAccentsNormalizer{}, nil
+
+	case "Strip":
+		var spec struct {
+			Left  bool `json:"strip_left"`
+			Right bool `json:"strip_right"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return stripNormalizer{left: spec.Left, right: spec.Right}, nil
+
+	case "Prepend":
+		var spec struct {
+			Prepend string `json:"prepend"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return prependNormalizer{prefix: spec.Prepend}, nil
+
+	case "Replace":
+		var spec struct {
+			Pattern pattern `json:"pattern"`
+			Content string  `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		re, err := spec.Pattern.compile()
+		if err != nil {
+			return nil, fmt.Errorf("normalizer Replace: %w", err)
+		}
+		return replaceNormalizer{re: re, content: spec.Content}, nil
+
+	case "BertNormalizer":
+		spec := struct {
+			CleanText    bool  `json:"clean_text"`
+			ChineseChars bool  `json:"handle_chinese_chars"`
+			StripAccents *bool `json:"strip_accents"`
+			Lowercase    bool  `json:"lowercase"`
+		}{CleanText: true, ChineseChars: true, Lowercase: true}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		n := bertNormalizer{
+			cleanText:    spec.CleanText,
+			chineseChars: spec.ChineseChars,
+			lowercase:    spec.Lowercase,
+			stripAccents: spec.Lowercase,
+		}
+		if spec.StripAccents != nil {
+			n.stripAccents = *spec.StripAccents
+		}
+		return n, nil
+
+	case "Precompiled":
+		// O charsmap do SentencePiece é essencialmente NFKC; aplicamos a
+		// mesma aproximação usada para NFKC.
+		return nfcNormalizer{}, nil
+	}
+
+	return nil, fmt.Errorf("normalizer não suportado: %s", head.Type)
+}
+
+type sequenceNormalizer []normalizer
+
+func (s sequenceNormalizer) normalize(text string) string {
+	for _, n := range s {
+		text = n.normalize(text)
+	}
+	return text
+}
+
+type lowercaseNormalizer struct{}
+
+func (lowercaseNormalizer) normalize(s string) string { return strings.ToLower(s) }
+
+type stripNormalizer struct{ left, right bool }
+
+func (n stripNormalizer) normalize(s string) string {
+	if n.left {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	}
+	if n.right {
+		s = strings.TrimRightFunc(s, unicode.IsSpace)
+	}
+	return s
+}
+
+type prependNormalizer struct{ prefix string }
+
+func (n prependNormalizer) normalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return n.prefix + s
+}
+
+type replaceNormalizer struct {
+	re      *regexp.Regexp
+	content string
+}
+
+func (n replaceNormalizer) normalize(s string) string {
+	return n.re.ReplaceAllLiteralString(s, n.content)
+}
+
+type stripAccentsNormalizer struct{}
+
+func (stripAccentsNormalizer) normalize(s string) string {
+	return stripMarks(decompose(s))
+}
+
+type nfcNormalizer struct{}
+
+func (nfcNormalizer) normalize(s string) string { return compose(s) }
+
+type nfdNormalizer struct{}
+
+func (nfdNormalizer) normalize(s string) string { return decompose(s) }
+
+// bertNormalizer reproduz o BertNormalizer (limpeza, CJK, acentos, caixa)
+type bertNormalizer struct {
+	cleanText    bool
+	chineseChars bool
+	stripAccents bool
+	lowercase    bool
+}
+
+func (n bertNormalizer) normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if n.cleanText {
+			if r == 0 || r == 0xFFFD || (unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r') {
+				continue
+			}
+			if unicode.IsSpace(r) {
+				r = ' '
+			}
+		}
+		if n.chineseChars && isCJK(r) {
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	out := b.String()
+	if n.stripAccents {
+		out = stripMarks(decompose(out))
+	}
+	if n.lowercase {
+		out = strings.ToLower(out)
+	}
+	return out
+}
+
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) || (r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) || (r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) || (r >= 0x2F800 && r <= 0x2FA1F)
+}
+
+// ==================== UNICODE ====================
+//
+// A biblioteca padrão não traz normalização Unicode. Como o assistente
+// trabalha em português, cobrimos a composição/decomposição canônica das
+// letras latinas com diacríticos; demais caracteres passam inalterados.
+
+var (
+	composeTable   = make(map[[2]rune]rune)
+	decomposeTable = make(map[rune][2]rune)
+)
+
+func init() {
+	for composed, d := range knownDecompositions {
+		composeTable[d] = composed
+		decomposeTable[composed] = d
+	}
+}
+
+// decompose aplica a decomposição canônica (NFD) conhecida
+func decompose(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if d, ok := decomposeTable[r]; ok {
+			b.WriteRune(d[0])
+			b.WriteRune(d[1])
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// compose aplica a composição canônica (NFC) conhecida
+func compose(s string) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if n := len(out); n > 0 && unicode.Is(unicode.Mn, r) {
+			if c, ok := composeTable[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// stripMarks remove marcas combinantes (categoria Mn)
+func stripMarks(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// knownDecompositions decomposições canônicas de Latin-1 Supplement e Latin Extended-A
+var knownDecompositions = map[rune][2]rune{
+	'À': {'A', 0x0300}, 'Á': {'A', 0x0301}, 'Â': {'A', 0x0302}, 'Ã': {'A', 0x0303}, 'Ä': {'A', 0x0308}, 'Å': {'A', 0x030A},
+	'Ç': {'C', 0x0327}, 'È': {'E', 0x0300}, 'É': {'E', 0x0301}, 'Ê': {'E', 0x0302}, 'Ë': {'E', 0x0308},
+	'Ì': {'I', 0x0300}, 'Í': {'I', 0x0301}, 'Î': {'I', 0x0302}, 'Ï': {'I', 0x0308}, 'Ñ': {'N', 0x0303},
+	'Ò': {'O', 0x0300}, 'Ó': {'O', 0x0301}, 'Ô': {'O', 0x0302}, 'Õ': {'O', 0x0303}, 'Ö': {'O', 0x0308},
+	'Ù': {'U', 0x0300}, 'Ú': {'U', 0x0301}, 'Û': {'U', 0x0302}, 'Ü': {'U', 0x0308}, 'Ý': {'Y', 0x0301},
+	'à': {'a', 0x0300}, 'á': {'a', 0x0301}, 'â': {'a', 0x0302}, 'ã': {'a', 0x0303}, 'ä': {'a', 0x0308}, 'å': {'a', 0x030A},
+	'ç': {'c', 0x0327}, 'è': {'e', 0x0300}, 'é': {'e', 0x0301}, 'ê': {'e', 0x0302}, 'ë': {'e', 0x0308},
+	'ì': {'i', 0x0300}, 'í': {'i', 0x0301}, 'î': {'i', 0x0302}, 'ï': {'i', 0x0308}, 'ñ': {'n', 0x0303},
+	'ò': {'o', 0x0300}, 'ó': {'o', 0x0301}, 'ô': {'o', 0x0302}, 'õ': {'o', 0x0303}, 'ö': {'o', 0x0308},
+	'ù': {'u', 0x0300}, 'ú': {'u', 0x0301}, 'û': {'u', 0x0302}, 'ü': {'u', 0x0308}, 'ý': {'y', 0x0301}, 'ÿ': {'y', 0x0308},
+	'Ĉ': {'C', 0x0302}, 'ĉ': {'c', 0x0302}, 'Č': {'C', 0x030C}, 'č': {'c', 0x030C}, 'Ć': {'C', 0x0301}, 'ć': {'c', 0x0301},
+	'Ď': {'D', 0x030C}, 'ď': {'d', 0x030C}, 'Ě': {'E', 0x030C}, 'ě': {'e', 0x030C}, 'Ę': {'E', 0x0327}, 'ę': {'e', 0x0327},
+	'Ĝ': {'G', 0x0302}, 'ĝ': {'g', 0x0302}, 'Ģ': {'G', 0x0327}, 'ģ': {'g', 0x0327}, 'Ĥ': {'H', 0x0302}, 'ĥ': {'h', 0x0302},
+	'Ĩ': {'I', 0x0303}, 'ĩ': {'i', 0x0303}, 'Ĵ': {'J', 0x0302}, 'ĵ': {'j', 0x0302}, 'Ķ': {'K', 0x0327}, 'ķ': {'k', 0x0327},
+	'Ļ': {'L', 0x0327}, 'ļ': {'l', 0x0327}, 'Ń': {'N', 0x0301}, 'ń': {'n', 0x0301}, 'Ņ': {'N', 0x0327}, 'ņ': {'n', 0x0327},
+	'Ň': {'N', 0x030C}, 'ň': {'n', 0x030C}, 'Ŗ': {'R', 0x0327}, 'ŗ': {'r', 0x0327}, 'Ř': {'R', 0x030C}, 'ř': {'r', 0x030C},
+	'Ś': {'S', 0x0301}, 'ś': {'s', 0x0301}, 'Ŝ': {'S', 0x0302}, 'ŝ': {'s', 0x0302}, 'Ş': {'S', 0x0327}, 'ş': {'s', 0x0327},
+	'Š': {'S', 0x030C}, 'š': {'s', 0x030C}, 'Ţ': {'T', 0x0327}, 'ţ': {'t', 0x0327}, 'Ť': {'T', 0x030C}, 'ť': {'t', 0x030C},
+	'Ũ': {'U', 0x0303}, 'ũ': {'u', 0x0303}, 'Ů': {'U', 0x030A}, 'ů': {'u', 0x030A}, 'Ŵ': {'W', 0x0302}, 'ŵ': {'w', 0x0302},
+	'Ŷ': {'Y', 0x0302}, 'ŷ': {'y', 0x0302}, 'Ÿ': {'Y', 0x0308}, 'Ź': {'Z', 0x0301}, 'ź': {'z', 0x0301}, 'Ž': {'Z', 0x030C}, 'ž': {'z', 0x030C},
+}
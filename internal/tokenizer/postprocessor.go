@@ -0,0 +1,126 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// postProcessor adiciona tokens especiais (BOS, [CLS]/[SEP]...) à sequência
+type postProcessor interface {
+	process(ids []int64) []int64
+}
+
+// newPostProcessor cria o pós-processador descrito no JSON (nil se ausente)
+func newPostProcessor(raw json.RawMessage) (postProcessor, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case "Sequence":
+		var spec struct {
+			Processors []json.RawMessage `json:"processors"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		seq := sequencePostProcessor{}
+		for _, r := range spec.Processors {
+			p, err := newPostProcessor(r)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				seq = append(seq, p)
+			}
+		}
+		return seq, nil
+
+	case "ByteLevel":
+		// Só ajusta offsets; não altera os IDs
+		return nil, nil
+
+	case "TemplateProcessing":
+		var spec struct {
+			Single []struct {
+				SpecialToken *struct {
+					ID string `json:"id"`
+				} `json:"SpecialToken"`
+				Sequence *struct {
+					ID string `json:"id"`
+				} `json:"Sequence"`
+			} `json:"single"`
+			SpecialTokens map[string]struct {
+				IDs []int64 `json:"ids"`
+			} `json:"special_tokens"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		p := templatePostProcessor{}
+		for _, item := range spec.Single {
+			switch {
+			case item.Sequence != nil:
+				p = append(p, nil)
+			case item.SpecialToken != nil:
+				tok, ok := spec.SpecialTokens[item.SpecialToken.ID]
+				if !ok {
+					return nil, fmt.Errorf("TemplateProcessing: token especial %q sem IDs", item.SpecialToken.ID)
+				}
+				p = append(p, tok.IDs)
+			}
+		}
+		return p, nil
+
+	case "BertProcessing", "RobertaProcessing":
+		var spec struct {
+			Sep [2]json.RawMessage `json:"sep"`
+			Cls [2]json.RawMessage `json:"cls"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		var sep, cls int64
+		if err := json.Unmarshal(spec.Sep[1], &sep); err != nil {
+			return nil, fmt.Errorf("%s: sep inválido: %w", head.Type, err)
+		}
+		if err := json.Unmarshal(spec.Cls[1], &cls); err != nil {
+			return nil, fmt.Errorf("%s: cls inválido: %w", head.Type, err)
+		}
+		return templatePostProcessor{{cls}, nil, {sep}}, nil
+	}
+
+	return nil, fmt.Errorf("post_processor não suportado: %s", head.Type)
+}
+
+type sequencePostProcessor []postProcessor
+
+func (s sequencePostProcessor) process(ids []int64) []int64 {
+	for _, p := range s {
+		ids = p.process(ids)
+	}
+	return ids
+}
+
+// templatePostProcessor cada item é uma lista de IDs especiais; nil marca a
+// posição da sequência de entrada
+type templatePostProcessor [][]int64
+
+func (t templatePostProcessor) process(ids []int64) []int64 {
+	out := make([]int64, 0, len(ids)+len(t))
+	for _, item := range t {
+		if item == nil {
+			out = append(out, ids...)
+			continue
+		}
+		out = append(out, item...)
+	}
+	return out
+}
@@ -0,0 +1,481 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// gpt2Pattern regex usada pelo pré-tokenizador ByteLevel quando use_regex=true
+const gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// preTokenizer divide o texto normalizado em pedaços que o modelo tokeniza
+// isoladamente. first indica se o segmento começa no início da entrada.
+type preTokenizer interface {
+	preTokenize(pieces []string, first bool) []string
+}
+
+// newPreTokenizer cria o pré-tokenizador descrito no JSON (nil se ausente)
+func newPreTokenizer(raw json.RawMessage) (preTokenizer, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case "Sequence":
+		var spec struct {
+			PreTokenizers []json.RawMessage `json:"pretokenizers"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		seq := sequencePreTokenizer{}
+		for _, r := range spec.PreTokenizers {
+			p, err := newPreTokenizer(r)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				seq = append(seq, p)
+			}
+		}
+		return seq, nil
+
+	case "ByteLevel":
+		spec := struct {
+			AddPrefixSpace bool `json:"add_prefix_space"`
+			UseRegex       bool `json:"use_regex"`
+		}{UseRegex: true}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		p := &byteLevelPreTokenizer{addPrefixSpace: spec.AddPrefixSpace}
+		if spec.UseRegex {
+			re, err := newSplitRegex(gpt2Pattern)
+			if err != nil {
+				return nil, err
+			}
+			p.re = re
+		}
+		return p, nil
+
+	case "Split":
+		spec := struct {
+			Pattern  pattern `json:"pattern"`
+			Behavior string  `json:"behavior"`
+			Invert   bool    `json:"invert"`
+		}{Behavior: "Isolated"}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		var re *splitRegex
+		var err error
+		switch {
+		case spec.Pattern.String != nil:
+			re, err = newSplitRegex(regexp.QuoteMeta(*spec.Pattern.String))
+		case spec.Pattern.Regex != nil:
+			re, err = newSplitRegex(*spec.Pattern.Regex)
+		default:
+			err = fmt.Errorf("pattern vazio")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pre_tokenizer Split: %w", err)
+		}
+		return &splitPreTokenizer{re: re, behavior: spec.Behavior, invert: spec.Invert}, nil
+
+	case "Metaspace":
+		spec := struct {
+			Replacement    string `json:"replacement"`
+			PrependScheme  string `json:"prepend_scheme"`
+			AddPrefixSpace *bool  `json:"add_prefix_space"`
+			Split          *bool  `json:"split"`
+		}{Replacement: "▁", PrependScheme: "always"}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		if spec.AddPrefixSpace != nil && !*spec.AddPrefixSpace {
+			spec.PrependScheme = "never"
+		}
+		p := &metaspacePreTokenizer{
+			replacement: spec.Replacement,
+			prepend:     spec.PrependScheme,
+			split:       spec.Split == nil || *spec.Split,
+		}
+		return p, nil
+
+	case "Whitespace":
+		re, err := newSplitRegex(`\w+|[^\w\s]+`)
+		if err != nil {
+			return nil, err
+		}
+		return &splitPreTokenizer{re: re, behavior: "Removed", invert: true}, nil
+
+	case "WhitespaceSplit":
+		return whitespaceSplitPreTokenizer{}, nil
+
+	case "BertPreTokenizer":
+		return bertPreTokenizer{}, nil
+
+	case "Punctuation":
+		return bertPreTokenizer{keepSpaces: true}, nil
+
+	case "Digits":
+		var spec struct {
+			Individual bool `json:"individual_digits"`
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			return nil, err
+		}
+		return digitsPreTokenizer{individual: spec.Individual}, nil
+	}
+
+	return nil, fmt.Errorf("pre_tokenizer não suportado: %s", head.Type)
+}
+
+type sequencePreTokenizer []preTokenizer
+
+func (s sequencePreTokenizer) preTokenize(pieces []string, first bool) []string {
+	for _, p := range s {
+		pieces = p.preTokenize(pieces, first)
+	}
+	return pieces
+}
+
+// byteLevelPreTokenizer divide com a regex do GPT-2 e mapeia para o alfabeto byte-level
+type byteLevelPreTokenizer struct {
+	re             *splitRegex
+	addPrefixSpace bool
+}
+
+func (p *byteLevelPreTokenizer) preTokenize(pieces []string, first bool) []string {
+	out := make([]string, 0, len(pieces))
+	for _, piece := range pieces {
+		if p.addPrefixSpace && !strings.HasPrefix(piece, " ") {
+			piece = " " + piece
+		}
+		if p.re == nil {
+			out = append(out, byteLevelEncode(piece))
+			continue
+		}
+		for _, loc := range p.re.findAll(piece) {
+			out = append(out, byteLevelEncode(piece[loc[0]:loc[1]]))
+		}
+	}
+	return out
+}
+
+// splitPreTokenizer implementa o pré-tokenizador Split e seus comportamentos
+type splitPreTokenizer struct {
+	re       *splitRegex
+	behavior string
+	invert   bool
+}
+
+func (p *splitPreTokenizer) preTokenize(pieces []string, first bool) []string {
+	out := make([]string, 0, len(pieces))
+	for _, piece := range pieces {
+		out = append(out, p.split(piece)...)
+	}
+	return out
+}
+
+func (p *splitPreTokenizer) split(s string) []string {
+	// Marca cada trecho como delimitador (match) ou conteúdo
+	type span struct {
+		text  string
+		delim bool
+	}
+	var spans []span
+	pos := 0
+	for _, loc := range p.re.findAll(s) {
+		if loc[0] > pos {
+			spans = append(spans, span{s[pos:loc[0]], p.invert})
+		}
+		spans = append(spans, span{s[loc[0]:loc[1]], !p.invert})
+		pos = loc[1]
+	}
+	if pos < len(s) {
+		spans = append(spans, span{s[pos:], p.invert})
+	}
+
+	var out []string
+	switch p.behavior {
+	case "Removed":
+		for _, sp := range spans {
+			if !sp.delim {
+				out = append(out, sp.text)
+			}
+		}
+	case "MergedWithPrevious":
+		for _, sp := range spans {
+			if sp.delim && len(out) > 0 {
+				out[len(out)-1] += sp.text
+			} else {
+				out = append(out, sp.text)
+			}
+		}
+	case "MergedWithNext":
+		pending := ""
+		for _, sp := range spans {
+			if sp.delim {
+				pending += sp.text
+				continue
+			}
+			out = append(out, pending+sp.text)
+			pending = ""
+		}
+		if pending != "" {
+			out = append(out, pending)
+		}
+	case "Contiguous":
+		for i, sp := range spans {
+			if sp.delim && i > 0 && spans[i-1].delim {
+				out[len(out)-1] += sp.text
+			} else {
+				out = append(out, sp.text)
+			}
+		}
+	default: // Isolated
+		for _, sp := range spans {
+			out = append(out, sp.text)
+		}
+	}
+	return out
+}
+
+// metaspacePreTokenizer troca espaços por "▁" (SentencePiece)
+type metaspacePreTokenizer struct {
+	replacement string
+	prepend     string // always, first, never
+	split       bool
+}
+
+func (p *metaspacePreTokenizer) preTokenize(pieces []string, first bool) []string {
+	var out []string
+	for i, piece := range pieces {
+		piece = strings.ReplaceAll(piece, " ", p.replacement)
+		prepend := p.prepend == "always" || (p.prepend == "first" && first && i == 0)
+		if prepend && !strings.HasPrefix(piece, p.replacement) {
+			piece = p.replacement + piece
+		}
+		if !p.split {
+			out = append(out, piece)
+			continue
+		}
+		if piece == "" {
+			continue
+		}
+		// Divide mantendo o marcador como prefixo de cada palavra
+		start := 0
+		_, j := utf8.DecodeRuneInString(piece)
+		for j <= len(piece)-len(p.replacement) {
+			if strings.HasPrefix(piece[j:], p.replacement) {
+				out = append(out, piece[start:j])
+				start = j
+				j += len(p.replacement)
+				continue
+			}
+			_, size := utf8.DecodeRuneInString(piece[j:])
+			j += size
+		}
+		if start < len(piece) {
+			out = append(out, piece[start:])
+		}
+	}
+	return out
+}
+
+// whitespaceSplitPreTokenizer divide em espaços em branco
+type whitespaceSplitPreTokenizer struct{}
+
+func (whitespaceSplitPreTokenizer) preTokenize(pieces []string, first bool) []string {
+	var out []string
+	for _, piece := range pieces {
+		out = append(out, strings.Fields(piece)...)
+	}
+	return out
+}
+
+// bertPreTokenizer divide em espaços e isola pontuação
+type bertPreTokenizer struct {
+	keepSpaces bool // true = só isola pontuação (pré-tokenizador Punctuation)
+}
+
+func (p bertPreTokenizer) preTokenize(pieces []string, first bool) []string {
+	var out []string
+	for _, piece := range pieces {
+		var cur strings.Builder
+		flush := func() {
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		}
+		for _, r := range piece {
+			switch {
+			case !p.keepSpaces && unicode.IsSpace(r):
+				flush()
+			case isPunctuation(r):
+				flush()
+				out = append(out, string(r))
+			default:
+				cur.WriteRune(r)
+			}
+		}
+		flush()
+	}
+	return out
+}
+
+// isPunctuation segue a definição do BERT: ASCII não alfanumérico ou categoria P
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// digitsPreTokenizer isola dígitos (individualmente ou em sequência)
+type digitsPreTokenizer struct {
+	individual bool
+}
+
+func (p digitsPreTokenizer) preTokenize(pieces []string, first bool) []string {
+	var out []string
+	for _, piece := range pieces {
+		var cur strings.Builder
+		inDigits := false
+		for _, r := range piece {
+			isDigit := unicode.IsDigit(r)
+			if cur.Len() > 0 && (isDigit != inDigits || (isDigit && p.individual)) {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+			cur.WriteRune(r)
+			inDigits = isDigit
+		}
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+		}
+	}
+	return out
+}
+
+// ==================== REGEX ====================
+
+// splitRegex adapta as regexes do HuggingFace (Oniguruma) ao RE2 do Go.
+// O único recurso não suportado usado na prática é o lookahead "\s+(?!\S)"
+// dos padrões GPT-2/Llama 3/Qwen; ele é removido da regex e emulado em findAll.
+type splitRegex struct {
+	re         *regexp.Regexp
+	lookahead  bool // o padrão original tinha \s+(?!\S)
+	newlineAlt bool // o padrão tem a alternativa \s*[\r\n]+ antes de \s+
+}
+
+func newSplitRegex(p string) (*splitRegex, error) {
+	sr := &splitRegex{}
+	for _, la := range []string{`|\s+(?!\S)`, `\s+(?!\S)|`} {
+		if strings.Contains(p, la) {
+			p = strings.Replace(p, la, "", 1)
+			sr.lookahead = true
+		}
+	}
+	sr.newlineAlt = strings.Contains(p, `\s*[\r\n]+`)
+
+	re, err := compileHFRegex(p)
+	if err != nil {
+		return nil, err
+	}
+	sr.re = re
+	return sr, nil
+}
+
+// findAll retorna os intervalos [início, fim) de todos os matches
+func (sr *splitRegex) findAll(s string) [][2]int {
+	var locs [][2]int
+	pos := 0
+	for pos < len(s) {
+		loc := sr.re.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if end == start {
+			// Match vazio: avança um caractere para não entrar em loop
+			_, size := utf8.DecodeRuneInString(s[end:])
+			pos = end + size
+			continue
+		}
+
+		// Emula \s+(?!\S): uma sequência de espaços seguida de texto deixa o
+		// último espaço para o próximo token (" palavra")
+		if sr.lookahead && end < len(s) && isAllSpace(s[start:end]) {
+			next, _ := utf8.DecodeRuneInString(s[end:])
+			keepNewlines := sr.newlineAlt && strings.ContainsAny(s[start:end], "\r\n")
+			if !isSpace(next) && !keepNewlines && utf8.RuneCountInString(s[start:end]) > 1 {
+				_, size := utf8.DecodeLastRuneInString(s[start:end])
+				end -= size
+			}
+		}
+
+		locs = append(locs, [2]int{start, end})
+		pos = end
+	}
+	return locs
+}
+
+// compileHFRegex compila uma regex do tokenizer.json, estendendo \s para o
+// conjunto Unicode de espaços (como no Oniguruma)
+func compileHFRegex(p string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '\\' && i+1 < len(p) {
+			if p[i+1] == 's' {
+				if inClass {
+					b.WriteString(`\s\p{Z}\x{85}`)
+				} else {
+					b.WriteString(`[\s\p{Z}\x{85}]`)
+				}
+				i++
+				continue
+			}
+			b.WriteByte(c)
+			b.WriteByte(p[i+1])
+			i++
+			continue
+		}
+		switch c {
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+		b.WriteByte(c)
+	}
+	return regexp.Compile(b.String())
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r) || unicode.Is(unicode.Z, r)
+}
+
+func isAllSpace(s string) bool {
+	for _, r := range s {
+		if !isSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,361 @@
+// Package tokenizer implementa em Go puro os tokenizers do HuggingFace
+// (tokenizer.json): BPE byte-level, SentencePiece BPE/Unigram e WordPiece,
+// com tokens adicionados, normalizers, pré-tokenizadores e decoders.
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AddedToken token adicionado ao vocabulário (especial ou não)
+type AddedToken struct {
+	ID         int64  `json:"id"`
+	Content    string `json:"content"`
+	SingleWord bool   `json:"single_word"`
+	LStrip     bool   `json:"lstrip"`
+	RStrip     bool   `json:"rstrip"`
+	Normalized bool   `json:"normalized"`
+	Special    bool   `json:"special"`
+}
+
+// Tokenizer tokenizer completo carregado de um tokenizer.json
+type Tokenizer struct {
+	model         model
+	normalizer    normalizer
+	preTokenizer  preTokenizer
+	postProcessor postProcessor
+	decoder       decoder
+
+	added     []AddedToken // ordenados do maior para o menor conteúdo
+	addedByID map[int64]AddedToken
+	addedByTk map[string]AddedToken
+}
+
+// Load carrega um tokenizer.json do disco
+func Load(path string) (*Tokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// FromJSON cria o tokenizer a partir do conteúdo de um tokenizer.json
+func FromJSON(data []byte) (*Tokenizer, error) {
+	var spec struct {
+		AddedTokens   []AddedToken    `json:"added_tokens"`
+		Normalizer    json.RawMessage `json:"normalizer"`
+		PreTokenizer  json.RawMessage `json:"pre_tokenizer"`
+		PostProcessor json.RawMessage `json:"post_processor"`
+		Decoder       json.RawMessage `json:"decoder"`
+		Model         json.RawMessage `json:"model"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("tokenizer.json inválido: %w", err)
+	}
+
+	t := &Tokenizer{
+		addedByID: make(map[int64]AddedToken),
+		addedByTk: make(map[string]AddedToken),
+	}
+
+	var err error
+	if t.model, err = newModel(spec.Model); err != nil {
+		return nil, err
+	}
+	if t.normalizer, err = newNormalizer(spec.Normalizer); err != nil {
+		return nil, err
+	}
+	if t.preTokenizer, err = newPreTokenizer(spec.PreTokenizer); err != nil {
+		return nil, err
+	}
+	if t.postProcessor, err = newPostProcessor(spec.PostProcessor); err != nil {
+		return nil, err
+	}
+	if t.decoder, err = newDecoder(spec.Decoder); err != nil {
+		return nil, err
+	}
+
+	for _, tok := range spec.AddedTokens {
+		if tok.Content == "" {
+			continue
+		}
+		t.added = append(t.added, tok)
+		t.addedByID[tok.ID] = tok
+		t.addedByTk[tok.Content] = tok
+	}
+	sort.SliceStable(t.added, func(i, j int) bool {
+		return len(t.added[i].Content) > len(t.added[j].Content)
+	})
+
+	return t, nil
+}
+
+// newModel cria o modelo (BPE, Unigram ou WordPiece)
+func newModel(raw json.RawMessage) (model, error) {
+	if isNull(raw) {
+		return nil, fmt.Errorf("tokenizer.json sem modelo")
+	}
+
+	var head struct {
+		Type   string          `json:"type"`
+		Merges json.RawMessage `json:"merges"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, err
+	}
+
+	// Arquivos antigos omitem o tipo do modelo BPE
+	if head.Type == "" && !isNull(head.Merges) {
+		head.Type = "BPE"
+	}
+
+	switch head.Type {
+	case "BPE":
+		return newBPEModel(raw)
+	case "Unigram":
+		return newUnigramModel(raw)
+	case "WordPiece":
+		return newWordPieceModel(raw)
+	}
+	return nil, fmt.Errorf("modelo não suportado: %q", head.Type)
+}
+
+// Encode converte texto em IDs. addSpecialTokens aplica o pós-processador
+// (BOS, [CLS]/[SEP]...). Tokens especiais escritos no texto, como
+// "<|im_start|>", são reconhecidos diretamente.
+func (t *Tokenizer) Encode(text string, addSpecialTokens bool) []int64 {
+	var ids []int64
+	for _, seg := range t.splitAdded(text) {
+		if seg.added != nil {
+			ids = append(ids, seg.added.ID)
+			continue
+		}
+		ids = append(ids, t.encodeText(seg.text, seg.start == 0)...)
+	}
+
+	if addSpecialTokens && t.postProcessor != nil {
+		ids = t.postProcessor.process(ids)
+	}
+	return ids
+}
+
+// encodeText tokeniza um trecho sem tokens adicionados
+func (t *Tokenizer) encodeText(text string, first bool) []int64 {
+	if text == "" {
+		return nil
+	}
+	if t.normalizer != nil {
+		text = t.normalizer.normalize(text)
+	}
+
+	pieces := []string{text}
+	if t.preTokenizer != nil {
+		pieces = t.preTokenizer.preTokenize(pieces, first)
+	}
+
+	var ids []int64
+	for _, piece := range pieces {
+		ids = append(ids, t.model.tokenize(piece)...)
+	}
+	return ids
+}
+
+// segment trecho da entrada: texto comum ou um token adicionado
+type segment struct {
+	text  string
+	start int
+	added *AddedToken
+}
+
+// splitAdded separa a entrada nos tokens adicionados, respeitando lstrip,
+// rstrip e single_word
+func (t *Tokenizer) splitAdded(text string) []segment {
+	if len(t.added) == 0 {
+		return []segment{{text: text}}
+	}
+
+	var segs []segment
+	textStart := 0
+	for pos := 0; pos < len(text); {
+		tok := t.matchAdded(text, pos)
+		if tok == nil {
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			pos += size
+			continue
+		}
+
+		matchStart, matchEnd := pos, pos+len(tok.Content)
+		if tok.LStrip {
+			for matchStart > textStart {
+				r, size := utf8.DecodeLastRuneInString(text[:matchStart])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				matchStart -= size
+			}
+		}
+		if tok.RStrip {
+			for matchEnd < len(text) {
+				r, size := utf8.DecodeRuneInString(text[matchEnd:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				matchEnd += size
+			}
+		}
+
+		if matchStart > textStart {
+			segs = append(segs, segment{text: text[textStart:matchStart], start: textStart})
+		}
+		segs = append(segs, segment{start: matchStart, added: tok})
+		textStart, pos = matchEnd, matchEnd
+	}
+	if textStart < len(text) {
+		segs = append(segs, segment{text: text[textStart:], start: textStart})
+	}
+	return segs
+}
+
+// matchAdded retorna o maior token adicionado que começa em pos
+func (t *Tokenizer) matchAdded(text string, pos int) *AddedToken {
+	for i := range t.added {
+		tok := &t.added[i]
+		if !strings.HasPrefix(text[pos:], tok.Content) {
+			continue
+		}
+		if tok.SingleWord {
+			end := pos + len(tok.Content)
+			if pos > 0 {
+				if r, _ := utf8.DecodeLastRuneInString(text[:pos]); isWordChar(r) {
+					continue
+				}
+			}
+			if end < len(text) {
+				if r, _ := utf8.DecodeRuneInString(text[end:]); isWordChar(r) {
+					continue
+				}
+			}
+		}
+		return tok
+	}
+	return nil
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Decode converte IDs em texto. skipSpecialTokens omite tokens especiais
+// (BOS/EOS, marcadores de chat...).
+func (t *Tokenizer) Decode(ids []int64, skipSpecialTokens bool) string {
+	tokens := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if added, ok := t.addedByID[id]; ok {
+			if skipSpecialTokens && added.Special {
+				continue
+			}
+			tokens = append(tokens, added.Content)
+			continue
+		}
+		if tok, ok := t.model.idToToken(id); ok {
+			tokens = append(tokens, tok)
+		}
+	}
+
+	if t.decoder == nil {
+		return strings.Join(tokens, " ")
+	}
+	return strings.Join(t.decoder.decodeChain(tokens), "")
+}
+
+// TokenBytes retorna os bytes que o token produz no meio de um texto (sem o
+// tratamento de início de sequência, como a remoção do espaço inicial do
+// Metaspace). Tokens byte-level podem conter só parte de um caractere UTF-8.
+func (t *Tokenizer) TokenBytes(id int64) []byte {
+	if added, ok := t.addedByID[id]; ok {
+		return []byte(added.Content)
+	}
+	tok, ok := t.model.idToToken(id)
+	if !ok {
+		return nil
+	}
+	if t.decoder == nil {
+		return []byte(tok)
+	}
+
+	// Decodifica precedido de um trecho fixo e remove esse trecho
+	const sentinel = "a"
+	base := strings.Join(t.decoder.decodeChain([]string{sentinel}), "")
+	text := strings.Join(t.decoder.decodeChain([]string{sentinel, tok}), "")
+
+	// Tokens com só parte de um caractere UTF-8 viram U+FFFD no decoder, mas
+	// aqui interessam os bytes originais
+	if strings.ContainsRune(text, utf8.RuneError) && !strings.ContainsRune(tok, utf8.RuneError) {
+		if b, ok := parseByteToken(tok); ok {
+			return []byte{b}
+		}
+		if raw, ok := byteLevelRaw(tok); ok {
+			return raw
+		}
+	}
+	return []byte(strings.TrimPrefix(text, base))
+}
+
+// TokenToID retorna o ID de um token (incluindo tokens adicionados)
+func (t *Tokenizer) TokenToID(token string) (int64, bool) {
+	if added, ok := t.addedByTk[token]; ok {
+		return added.ID, true
+	}
+	return t.model.tokenToID(token)
+}
+
+// IDToToken retorna o token de um ID
+func (t *Tokenizer) IDToToken(id int64) (string, bool) {
+	if added, ok := t.addedByID[id]; ok {
+		return added.Content, true
+	}
+	return t.model.idToToken(id)
+}
+
+// IsSpecial indica se o ID é de um token especial
+func (t *Tokenizer) IsSpecial(id int64) bool {
+	added, ok := t.addedByID[id]
+	return ok && added.Special
+}
+
+// AddedTokens retorna os tokens adicionados
+func (t *Tokenizer) AddedTokens() []AddedToken {
+	out := make([]AddedToken, len(t.added))
+	copy(out, t.added)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// VocabSize retorna o tamanho do vocabulário, incluindo tokens adicionados
+func (t *Tokenizer) VocabSize() int {
+	size := t.model.vocabSize()
+	for id := range t.addedByID {
+		if _, ok := t.model.idToToken(id); !ok {
+			size++
+		}
+	}
+	return size
+}
+
+// isNull indica campo ausente ou null no JSON
+func isNull(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
@@ -0,0 +1,156 @@
+package tokenizer
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Os arquivos em testdata são tokenizer.json reduzidos, um por família; os IDs
+// esperados seguem o que o tokenizers do HuggingFace produz para eles. Os de
+// gpt2.json e llama3.json (vocabulário e merges treinados num texto curto)
+// vêm do encoder de referência do GPT-2, com a regex avaliada por um motor
+// que tem lookahead, e não da emulação daqui.
+
+func load(t *testing.T, name string) *Tokenizer {
+	t.Helper()
+	tk, err := Load(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tk
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		file string
+		text string
+		want []int64
+	}{
+		// BPE do SentencePiece (Llama 2/Phi-3): merges por rank, "ã" sem peça
+		// própria vira <0xC3><0xA3>, desconhecidos seguidos viram um só <unk>
+		{"bpe.json", "oi mão", []int64{1, 11, 12, 3, 4, 6}},
+		{"bpe.json", "oi ma", []int64{1, 11, 13}},
+		{"bpe.json", "oi xx", []int64{1, 11, 5, 0}},
+		{"bpe.json", "<|user|> oi", []int64{1, 14, 11}},
+
+		// Unigram: Viterbi escolhe ▁cas+ado em vez do prefixo mais longo ▁casa
+		{"unigram.json", "casa de", []int64{3, 9, 1}},
+		{"unigram.json", "casado", []int64{13, 14, 1}},
+		{"unigram.json", "casaz", []int64{3, 0, 1}},
+		{"unigram.json", "zz", []int64{2, 0, 1}},
+		{"unigram.json", "ç", []int64{2, 15, 16, 1}},
+
+		// WordPiece: minúsculas sem acento, pontuação isolada, ## nas sub-palavras
+		{"wordpiece.json", "Olá, Embeddings!", []int64{2, 4, 11, 7, 8, 9, 10, 6, 3}},
+		{"wordpiece.json", "xyz mundo", []int64{2, 1, 5, 3}},
+		{"wordpiece.json", "embedx", []int64{2, 1, 3}},
+
+		// BPE byte-level com merges de verdade (GPT-2): espaços repetidos
+		// deixam o último para a palavra seguinte (\s+(?!\S)), contrações
+		// separadas, números inteiros
+		{"gpt2.json", "Hello world", []int64{341, 270}},
+		{"gpt2.json", "Hello  world", []int64{341, 32, 270}},
+		{"gpt2.json", "a   b\n\nc", []int64{97, 256, 324, 10, 10, 99}},
+		{"gpt2.json", "I'm here, we'll see", []int64{73, 351, 277, 44, 271, 352, 276}},
+		{"gpt2.json", "CAN'T won't", []int64{67, 65, 78, 39, 84, 267, 110, 350}},
+		{"gpt2.json", "12345 2024", []int64{347, 342, 355}},
+		{"gpt2.json", "  lead", []int64{32, 298, 101, 97, 100}},
+		{"gpt2.json", "trail   ", []int64{116, 114, 97, 105, 108, 259}},
+		{"gpt2.json", "x \n y", []int64{120, 32, 10, 32, 121}},
+		{"gpt2.json", "def hello():\n    return 42", []int64{339, 278, 349, 358, 284, 353}},
+		{"gpt2.json", "Olá  mundo", []int64{340, 32, 297}},
+		{"gpt2.json", "tab\there", []int64{116, 97, 98, 9, 257, 264}},
+
+		// Mesmo vocabulário com o Split do Llama 3/Qwen: contrações sem
+		// diferenciar maiúsculas, números de até 3 dígitos, quebras de linha
+		// juntas e pontuação levando a quebra seguinte
+		{"llama3.json", "Hello  world", []int64{341, 32, 270}},
+		{"llama3.json", "a   b\n\nc", []int64{97, 256, 324, 359, 99}},
+		{"llama3.json", "I'm here, we'll see", []int64{73, 351, 277, 44, 271, 352, 276}},
+		{"llama3.json", "CAN'T won't", []int64{67, 65, 78, 39, 84, 267, 110, 350}},
+		{"llama3.json", "12345 2024", []int64{347, 342, 32, 346, 52}},
+		{"llama3.json", "  lead", []int64{32, 298, 101, 97, 100}},
+		{"llama3.json", "trail   ", []int64{116, 114, 97, 105, 108, 259}},
+		{"llama3.json", "x \n y", []int64{120, 32, 10, 32, 121}},
+		{"llama3.json", "def hello():\n    return 42", []int64{339, 278, 361, 259, 284, 32, 343}},
+		{"llama3.json", "tab\there", []int64{116, 97, 98, 9, 257, 264}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.text, func(t *testing.T) {
+			if got := load(t, tt.file).Encode(tt.text, true); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		file string
+		ids  []int64
+		skip bool
+		want string
+	}{
+		{"bpe.json", []int64{1, 11, 12, 3, 4, 6}, true, "oi mão"},
+		{"bpe.json", []int64{1, 11, 12, 3, 4, 6}, false, "<s> oi mão"},
+		{"bpe.json", []int64{11, 3}, true, "oi�"}, // byte solto de um caractere UTF-8
+		{"unigram.json", []int64{3, 9, 2, 15, 16, 1}, true, "casa de ç"},
+		{"unigram.json", []int64{3, 9, 1}, false, "casa de</s>"},
+		{"wordpiece.json", []int64{2, 4, 11, 7, 8, 9, 10, 6, 3}, true, "ola, embeddings!"},
+		{"wordpiece.json", []int64{2, 4, 5, 3}, false, "[CLS] ola mundo [SEP]"},
+	}
+
+	for _, tt := range tests {
+		if got := load(t, tt.file).Decode(tt.ids, tt.skip); got != tt.want {
+			t.Errorf("%s: Decode(%v, %v) = %q, want %q", tt.file, tt.ids, tt.skip, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := map[string][]string{
+		"bpe.json":       {"oi mão", "mão oi"},
+		"unigram.json":   {"casa de", "casado", "ç casa", "casa de çç"},
+		"wordpiece.json": {"ola, embeddings!", "mundo em embeddings"},
+		"gpt2.json":      {"Hello  world", "a   b\n\nc", "CAN'T won't", "trail   ", "Olá  mundo"},
+		"llama3.json":    {"def hello():\n    return 42", "x \n y", "12345 2024"},
+	}
+
+	for file, texts := range tests {
+		tk := load(t, file)
+		for _, text := range texts {
+			ids := tk.Encode(text, true)
+			if got := tk.Decode(ids, true); got != text {
+				t.Errorf("%s: Decode(Encode(%q)) = %q (IDs %v)", file, text, got, ids)
+			}
+		}
+	}
+}
+
+func TestTokenBytes(t *testing.T) {
+	tk := load(t, "bpe.json")
+
+	tests := []struct {
+		id   int64
+		want []byte
+	}{
+		{11, []byte(" oi")}, // ▁ vira espaço também no começo da peça
+		{3, []byte{0xC3}},   // só o primeiro byte de "ã"
+		{14, []byte("<|user|>")},
+	}
+	for _, tt := range tests {
+		if got := tk.TokenBytes(tt.id); !bytes.Equal(got, tt.want) {
+			t.Errorf("TokenBytes(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	if !tk.IsSpecial(14) || tk.IsSpecial(11) {
+		t.Error("IsSpecial: só os tokens adicionados marcados como especiais")
+	}
+	if id, ok := tk.TokenToID("<|user|>"); !ok || id != 14 {
+		t.Errorf("TokenToID(<|user|>) = %d, %v", id, ok)
+	}
+}
@@ -0,0 +1,195 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// unigramModel implementa o modelo Unigram do SentencePiece (Viterbi sobre
+// as probabilidades logarítmicas das peças)
+type unigramModel struct {
+	pieces       map[string]int64
+	scores       []float64
+	vocabRev     []string
+	unkID        int64
+	unkScore     float64
+	byteFallback bool
+	maxPieceLen  int // em bytes
+}
+
+func newUnigramModel(raw json.RawMessage) (*unigramModel, error) {
+	var spec struct {
+		Vocab        [][2]json.RawMessage `json:"vocab"`
+		UnkID        *int64               `json:"unk_id"`
+		ByteFallback bool                 `json:"byte_fallback"`
+	}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("modelo Unigram inválido: %w", err)
+	}
+
+	m := &unigramModel{
+		pieces:       make(map[string]int64, len(spec.Vocab)),
+		scores:       make([]float64, len(spec.Vocab)),
+		vocabRev:     make([]string, len(spec.Vocab)),
+		unkID:        -1,
+		byteFallback: spec.ByteFallback,
+	}
+
+	minScore := math.Inf(1)
+	for i, entry := range spec.Vocab {
+		var piece string
+		var score float64
+		if err := json.Unmarshal(entry[0], &piece); err != nil {
+			return nil, fmt.Errorf("peça %d inválida: %w", i, err)
+		}
+		if err := json.Unmarshal(entry[1], &score); err != nil {
+			return nil, fmt.Errorf("score da peça %q inválido: %w", piece, err)
+		}
+		m.pieces[piece] = int64(i)
+		m.scores[i] = score
+		m.vocabRev[i] = piece
+		if score < minScore {
+			minScore = score
+		}
+		if len(piece) > m.maxPieceLen {
+			m.maxPieceLen = len(piece)
+		}
+	}
+	if spec.UnkID != nil {
+		m.unkID = *spec.UnkID
+	}
+	// Mesmo valor usado pelo SentencePiece para peças desconhecidas
+	m.unkScore = minScore - 10
+
+	return m, nil
+}
+
+func (m *unigramModel) tokenToID(token string) (int64, bool) {
+	id, ok := m.pieces[token]
+	return id, ok
+}
+
+func (m *unigramModel) idToToken(id int64) (string, bool) {
+	if id < 0 || int(id) >= len(m.vocabRev) {
+		return "", false
+	}
+	return m.vocabRev[id], true
+}
+
+func (m *unigramModel) vocabSize() int {
+	return len(m.vocabRev)
+}
+
+// tokenize encontra a segmentação de maior probabilidade (Viterbi)
+func (m *unigramModel) tokenize(piece string) []int64 {
+	if piece == "" {
+		return nil
+	}
+
+	n := len(piece)
+	type node struct {
+		score float64
+		start int
+		id    int64 // -1 = caractere desconhecido
+		set   bool
+	}
+	best := make([]node, n+1)
+	best[0].set = true
+
+	for start := 0; start < n; {
+		_, charLen := utf8.DecodeRuneInString(piece[start:])
+		if !best[start].set {
+			start += charLen
+			continue
+		}
+
+		matched := false
+		for end := start + 1; end <= n && end-start <= m.maxPieceLen; end++ {
+			if !utf8.RuneStart(pieceByte(piece, end)) {
+				continue
+			}
+			id, ok := m.pieces[piece[start:end]]
+			if !ok {
+				continue
+			}
+			score := best[start].score + m.scores[id]
+			if !best[end].set || score > best[end].score {
+				best[end] = node{score: score, start: start, id: id, set: true}
+			}
+			if end-start == charLen {
+				matched = true
+			}
+		}
+
+		// Caractere sem peça própria: cai no UNK (ou nos bytes, depois)
+		if !matched {
+			end := start + charLen
+			score := best[start].score + m.unkScore
+			if !best[end].set || score > best[end].score {
+				best[end] = node{score: score, start: start, id: -1, set: true}
+			}
+		}
+		start += charLen
+	}
+
+	// Reconstrói o caminho
+	var reversed []int64
+	var unknown []string
+	for end := n; end > 0; end = best[end].start {
+		nd := best[end]
+		if nd.id >= 0 {
+			reversed = append(reversed, nd.id)
+			unknown = append(unknown, "")
+		} else {
+			reversed = append(reversed, -1)
+			unknown = append(unknown, piece[nd.start:end])
+		}
+	}
+
+	var ids []int64
+	prevUnk := false
+	for i := len(reversed) - 1; i >= 0; i-- {
+		id := reversed[i]
+		if id >= 0 {
+			ids = append(ids, id)
+			prevUnk = false
+			continue
+		}
+		if m.byteFallback {
+			if byteIDs, ok := m.byteIDs(unknown[i]); ok {
+				ids = append(ids, byteIDs...)
+				prevUnk = false
+				continue
+			}
+		}
+		// SentencePiece funde UNKs consecutivos
+		if !prevUnk && m.unkID >= 0 {
+			ids = append(ids, m.unkID)
+		}
+		prevUnk = true
+	}
+	return ids
+}
+
+// byteIDs converte um caractere nas peças <0xXX>
+func (m *unigramModel) byteIDs(s string) ([]int64, bool) {
+	ids := make([]int64, 0, len(s))
+	for _, b := range []byte(s) {
+		id, ok := m.pieces[fmt.Sprintf("<0x%02X>", b)]
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// pieceByte retorna o byte na posição i (ou um início de rune válido no fim)
+func pieceByte(s string, i int) byte {
+	if i >= len(s) {
+		return 0
+	}
+	return s[i]
+}
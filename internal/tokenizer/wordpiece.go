@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// wordPieceModel implementa o WordPiece do BERT (usado pelos modelos de
+// embedding MiniLM/E5)
+type wordPieceModel struct {
+	vocab        map[string]int64
+	vocabRev     map[int64]string
+	unkID        int64
+	prefix       string
+	maxWordChars int
+}
+
+func newWordPieceModel(raw json.RawMessage) (*wordPieceModel, error) {
+	spec := struct {
+		Vocab        map[string]int64 `json:"vocab"`
+		UnkToken     string           `json:"unk_token"`
+		Prefix       string           `json:"continuing_subword_prefix"`
+		MaxWordChars int              `json:"max_input_chars_per_word"`
+	}{UnkToken: "[UNK]", Prefix: "##", MaxWordChars: 100}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("modelo WordPiece inválido: %w", err)
+	}
+
+	unkID, ok := spec.Vocab[spec.UnkToken]
+	if !ok {
+		return nil, fmt.Errorf("token desconhecido %q fora do vocabulário", spec.UnkToken)
+	}
+
+	m := &wordPieceModel{
+		vocab:        spec.Vocab,
+		vocabRev:     make(map[int64]string, len(spec.Vocab)),
+		unkID:        unkID,
+		prefix:       spec.Prefix,
+		maxWordChars: spec.MaxWordChars,
+	}
+	for tok, id := range m.vocab {
+		m.vocabRev[id] = tok
+	}
+	return m, nil
+}
+
+func (m *wordPieceModel) tokenToID(token string) (int64, bool) {
+	id, ok := m.vocab[token]
+	return id, ok
+}
+
+func (m *wordPieceModel) idToToken(id int64) (string, bool) {
+	tok, ok := m.vocabRev[id]
+	return tok, ok
+}
+
+func (m *wordPieceModel) vocabSize() int {
+	return len(m.vocab)
+}
+
+// tokenize faz o casamento guloso do maior prefixo presente no vocabulário
+func (m *wordPieceModel) tokenize(word string) []int64 {
+	if word == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(word) > m.maxWordChars {
+		return []int64{m.unkID}
+	}
+
+	var ids []int64
+	start := 0
+	for start < len(word) {
+		end := len(word)
+		found := int64(-1)
+		for end > start {
+			sub := word[start:end]
+			if start > 0 {
+				sub = m.prefix + sub
+			}
+			if id, ok := m.vocab[sub]; ok {
+				found = id
+				break
+			}
+			_, size := utf8.DecodeLastRuneInString(word[start:end])
+			end -= size
+		}
+		if found < 0 {
+			return []int64{m.unkID}
+		}
+		ids = append(ids, found)
+		start = end
+	}
+	return ids
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
@@ -71,17 +72,18 @@ func (p *Piper) Speak(text string) error {
 		return fmt.Errorf("erro no Piper: %w", err)
 	}
 
-	// Reproduz o áudio usando PowerShell (Windows)
-	playCmd := exec.Command("powershell", "-c",
-		fmt.Sprintf(`(New-Object Media.SoundPlayer '%s').PlaySync()`, wavFile))
+	// Remove arquivo temporário
+	defer os.Remove(wavFile)
 
+	// Reproduz o áudio com o player da plataforma
+	playCmd, err := platform.Current().PlayCommand(wavFile)
+	if err != nil {
+		return fmt.Errorf("erro ao reproduzir áudio: %w", err)
+	}
 	if err := playCmd.Run(); err != nil {
 		return fmt.Errorf("erro ao reproduzir áudio: %w", err)
 	}
 
-	// Remove arquivo temporário
-	os.Remove(wavFile)
-
 	return nil
 }
 
@@ -0,0 +1,139 @@
+package tts
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Speaker qualquer motor de fala (Piper implementa)
+type Speaker interface {
+	Speak(text string) error
+}
+
+// SentenceStream recebe texto em pedaços (tokens do LLM) e fala cada frase
+// assim que ela termina, enquanto o restante ainda está sendo gerado
+type SentenceStream struct {
+	speaker Speaker
+
+	mu      sync.Mutex
+	pending strings.Builder
+	text    strings.Builder
+
+	queue chan string
+	done  chan struct{}
+	err   error
+}
+
+// NewSentenceStream cria o stream e inicia a fila de fala
+func NewSentenceStream(speaker Speaker) *SentenceStream {
+	s := &SentenceStream{
+		speaker: speaker,
+		queue:   make(chan string, 16),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// run fala as frases na ordem em que chegam; depois de uma falha, só
+// esvazia a fila
+func (s *SentenceStream) run() {
+	defer close(s.done)
+	for sentence := range s.queue {
+		s.mu.Lock()
+		failed := s.err != nil
+		s.mu.Unlock()
+		if failed {
+			continue
+		}
+
+		if err := s.speaker.Speak(sentence); err != nil {
+			fmt.Printf("Erro no TTS: %v\n", err)
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Write adiciona um trecho de texto; frases completas vão para a fila de fala.
+// Tem a assinatura de llm.TokenCallback: depois que o TTS falha, devolve o
+// erro, o que interrompe a geração em vez de produzir texto que ninguém ouve.
+func (s *SentenceStream) Write(token string) error {
+	s.mu.Lock()
+	if s.err != nil {
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+	s.text.WriteString(token)
+	s.pending.WriteString(token)
+
+	var sentences []string
+	rest := s.pending.String()
+	for {
+		end := sentenceEnd(rest)
+		if end < 0 {
+			break
+		}
+		if sentence := strings.TrimSpace(rest[:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		rest = rest[end:]
+	}
+	s.pending.Reset()
+	s.pending.WriteString(rest)
+	s.mu.Unlock()
+
+	for _, sentence := range sentences {
+		s.queue <- sentence
+	}
+	return nil
+}
+
+// Close fala o que restou e aguarda a fila terminar
+func (s *SentenceStream) Close() error {
+	s.mu.Lock()
+	rest := strings.TrimSpace(s.pending.String())
+	s.pending.Reset()
+	s.mu.Unlock()
+
+	if rest != "" {
+		s.queue <- rest
+	}
+	close(s.queue)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Text retorna todo o texto recebido até agora
+func (s *SentenceStream) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text.String()
+}
+
+// sentenceEnd retorna a posição logo após o fim da primeira frase completa,
+// ou -1. A pontuação só conta quando seguida de espaço, para não cortar
+// números como "3.5"; quebras de linha sempre encerram a frase.
+func sentenceEnd(text string) int {
+	for i, r := range text {
+		switch r {
+		case '\n':
+			return i + 1
+		case '.', '!', '?', ';', '…':
+			next := i + len(string(r))
+			if next < len(text) && unicode.IsSpace(rune(text[next])) {
+				return next
+			}
+		}
+	}
+	return -1
+}
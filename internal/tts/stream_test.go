@@ -0,0 +1,110 @@
+package tts
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder Speaker que guarda as frases faladas e avisa a cada uma
+type recorder struct {
+	mu     sync.Mutex
+	spoken []string
+	said   chan string
+	err    error
+}
+
+func newRecorder() *recorder {
+	return &recorder{said: make(chan string, 16)}
+}
+
+func (r *recorder) Speak(text string) error {
+	r.mu.Lock()
+	r.spoken = append(r.spoken, text)
+	r.mu.Unlock()
+	r.said <- text
+	return r.err
+}
+
+func (r *recorder) sentences() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.spoken...)
+}
+
+func TestSentenceStreamSplitsAcrossChunks(t *testing.T) {
+	chunks := []string{"Olá, tudo", " bem? Hoje", " faz 3", ".5 graus", ".\nSem ponto", " final"}
+	want := []string{"Olá, tudo bem?", "Hoje faz 3.5 graus.", "Sem ponto final"}
+
+	speaker := newRecorder()
+	s := NewSentenceStream(speaker)
+	for _, chunk := range chunks {
+		if err := s.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// As frases completas saem antes do fim da geração
+	for _, sentence := range want[:2] {
+		select {
+		case got := <-speaker.said:
+			if got != sentence {
+				t.Errorf("falou %q, want %q", got, sentence)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%q não foi falada antes de Close", sentence)
+		}
+	}
+
+	// O resto sem pontuação sai no Close
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := speaker.sentences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("frases = %q, want %q", got, want)
+	}
+	if got := s.Text(); got != "Olá, tudo bem? Hoje faz 3.5 graus.\nSem ponto final" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestSentenceStreamEmpty(t *testing.T) {
+	speaker := newRecorder()
+	s := NewSentenceStream(speaker)
+	s.Write("  \n")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := speaker.sentences(); len(got) != 0 {
+		t.Errorf("frases = %q, want nenhuma", got)
+	}
+}
+
+func TestSentenceStreamStopsOnSpeakError(t *testing.T) {
+	speaker := newRecorder()
+	speaker.err = errors.New("sem saída de áudio")
+
+	s := NewSentenceStream(speaker)
+	if err := s.Write("Primeira frase. "); err != nil {
+		t.Fatalf("Write() antes da falha = %v", err)
+	}
+	<-speaker.said
+
+	// A falha chega ao gerador pelo retorno de Write
+	deadline := time.Now().Add(time.Second)
+	for s.Write("mais texto") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Write() continua aceitando texto depois da falha do TTS")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Close(); !errors.Is(err, speaker.err) {
+		t.Errorf("Close() = %v, want %v", err, speaker.err)
+	}
+	if got := speaker.sentences(); len(got) != 1 {
+		t.Errorf("frases = %q, want só a primeira", got)
+	}
+}
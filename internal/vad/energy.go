@@ -0,0 +1,78 @@
+package vad
+
+import "math"
+
+const (
+	calibrationFrames = 10   // 300 ms iniciais medem o ruído
+	minEnergy         = 1e-8 // -80 dBFS: abaixo disso é silêncio digital
+	floorFall         = 0.3  // Piso desce rápido quando o quadro é mais baixo
+	floorRise         = 0.02 // e sobe devagar em quadros sem fala (~1,5 s)
+	floorLeak         = 1e-3 // Mesmo na fala, para ruído que aumentou de vez
+	slopeDB           = 2    // Largura da transição entre ruído e fala
+)
+
+// Energy VAD por energia: a probabilidade de fala cresce com a razão entre a
+// energia do quadro e o piso de ruído, e passa de 0,5 quando ela chega a
+// margin_db. O piso é calibrado nos primeiros quadros e acompanha o ruído da
+// sala enquanto não há fala.
+type Energy struct {
+	marginDB float64
+	floor    float64 // Energia média do ruído
+	frames   int     // Quadros vistos, para a calibração
+}
+
+// NewEnergy cria o detector; marginDB é a relação sinal-ruído de fala
+func NewEnergy(marginDB float32) *Energy {
+	return &Energy{marginDB: float64(marginDB)}
+}
+
+// Speech probabilidade de fala do quadro
+func (e *Energy) Speech(frame []float32) (float32, error) {
+	energy := meanSquare(frame)
+	if e.frames < calibrationFrames {
+		e.frames++
+		e.floor += (energy - e.floor) / float64(e.frames)
+		return 0, nil
+	}
+
+	snr := 10 * math.Log10(max(energy, minEnergy)/max(e.floor, minEnergy))
+	p := 1 / (1 + math.Exp(-(snr-e.marginDB)/slopeDB))
+
+	switch {
+	case energy < e.floor:
+		e.floor += (energy - e.floor) * floorFall
+	case p < 0.5:
+		e.floor += (energy - e.floor) * floorRise
+	default:
+		e.floor += (energy - e.floor) * floorLeak
+	}
+	return float32(p), nil
+}
+
+// FrameSize quadros de 30 ms
+func (e *Energy) FrameSize() int {
+	return FrameSamples
+}
+
+// NoiseFloor energia média estimada do ruído
+func (e *Energy) NoiseFloor() float64 {
+	return e.floor
+}
+
+// Reset mantém o piso de ruído: a sala é a mesma entre falas
+func (e *Energy) Reset() {}
+
+// Close não há o que liberar
+func (e *Energy) Close() error { return nil }
+
+// meanSquare energia média das amostras
+func meanSquare(samples []float32) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return sum / float64(len(samples))
+}
@@ -0,0 +1,167 @@
+package vad
+
+import (
+	"fmt"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+)
+
+const (
+	sileroWindow  = 512 // 32 ms: a janela que o Silero aceita a 16 kHz
+	sileroContext = 64  // No v5, o fim da janela anterior vai antes da atual
+)
+
+// stateOutputs saída com o estado seguinte de cada entrada de estado: h e c
+// no Silero v4, state no v5
+var stateOutputs = map[string]string{
+	"h":     "hn",
+	"c":     "cn",
+	"state": "stateN",
+}
+
+// Silero VAD neural (Silero VAD em ONNX): a rede recorrente recebe uma
+// janela de 512 amostras (32 ms) por vez e o estado dela passa de uma janela
+// para a outra. No v5 (entrada state) cada janela vai precedida das últimas
+// 64 amostras da anterior.
+type Silero struct {
+	backend backend.Backend
+	session backend.Session
+	inputs  []string
+	shapes  map[string]backend.Shape // sr e estados, com dimensões dinâmicas em 1
+	states  []string                 // Entradas de estado, na ordem das saídas
+	state   map[string][]float32
+	context []float32 // v5: fim da janela anterior (nil no v4)
+}
+
+// NewSilero carrega o modelo do Silero VAD
+func NewSilero(be backend.Backend, path string) (*Silero, error) {
+	inputs, outputs, err := be.Inspect(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inspecionar Silero VAD: %w", err)
+	}
+
+	s := &Silero{
+		backend: be,
+		shapes:  make(map[string]backend.Shape),
+		state:   make(map[string][]float32),
+	}
+	hasInput := false
+	outputNames := []string{"output"}
+	for _, in := range inputs {
+		switch in.Name {
+		case "input":
+			hasInput = true
+		case "sr":
+		case "h", "c", "state":
+			s.states = append(s.states, in.Name)
+			outputNames = append(outputNames, stateOutputs[in.Name])
+		default:
+			return nil, fmt.Errorf("entrada não suportada no Silero VAD: %s", in.Name)
+		}
+		shape := in.Shape.Clone()
+		for i, d := range shape {
+			if d < 0 {
+				shape[i] = 1
+			}
+		}
+		s.shapes[in.Name] = shape
+		s.inputs = append(s.inputs, in.Name)
+	}
+	if !hasInput || len(s.states) == 0 {
+		return nil, fmt.Errorf("modelo em %s não parece o Silero VAD (entradas input e h/c ou state)", path)
+	}
+	for _, name := range outputNames {
+		if !hasTensor(outputs, name) {
+			return nil, fmt.Errorf("Silero VAD sem a saída %s", name)
+		}
+	}
+
+	if _, ok := s.shapes["state"]; ok {
+		s.context = make([]float32, sileroContext)
+	}
+
+	s.session, err = be.Open(path, s.inputs, outputNames)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar Silero VAD: %w", err)
+	}
+	s.Reset()
+	return s, nil
+}
+
+// FrameSize janelas de 512 amostras
+func (s *Silero) FrameSize() int {
+	return sileroWindow
+}
+
+// Speech probabilidade de fala da janela, atualizando o estado da rede
+func (s *Silero) Speech(frame []float32) (float32, error) {
+	if len(frame) != sileroWindow {
+		return 0, fmt.Errorf("Silero VAD recebe janelas de %d amostras, não %d", sileroWindow, len(frame))
+	}
+	input := frame
+	if s.context != nil {
+		input = append(append(make([]float32, 0, sileroContext+sileroWindow), s.context...), frame...)
+	}
+
+	tensors := make([]backend.Tensor, len(s.inputs))
+	defer func() { backend.DestroyAll(tensors) }()
+
+	for i, name := range s.inputs {
+		var err error
+		switch name {
+		case "input":
+			tensors[i], err = s.backend.NewTensor(backend.Float32, backend.Shape{1, int64(len(input))}, input)
+		case "sr":
+			tensors[i], err = s.backend.NewTensor(backend.Int64, s.shapes[name], []int64{SampleRate})
+		default:
+			tensors[i], err = s.backend.NewTensor(backend.Float32, s.shapes[name], s.state[name])
+		}
+		if err != nil {
+			return 0, fmt.Errorf("erro ao criar tensor %s: %w", name, err)
+		}
+	}
+
+	outputs, err := s.session.Run(tensors)
+	if err != nil {
+		return 0, err
+	}
+	defer backend.DestroyAll(outputs)
+
+	prob, ok := outputs[0].Data().([]float32)
+	if !ok || len(prob) == 0 {
+		return 0, fmt.Errorf("saída do Silero VAD inválida: %s %v", outputs[0].Type(), outputs[0].Shape())
+	}
+	for i, name := range s.states {
+		next, ok := outputs[i+1].Data().([]float32)
+		if !ok || len(next) != len(s.state[name]) {
+			return 0, fmt.Errorf("estado %s do Silero VAD inválido: %v", name, outputs[i+1].Shape())
+		}
+		// A saída é destruída ao retornar
+		copy(s.state[name], next)
+	}
+	copy(s.context, frame[sileroWindow-len(s.context):])
+	return prob[0], nil
+}
+
+// Reset zera o estado da rede e o contexto
+func (s *Silero) Reset() {
+	for _, name := range s.states {
+		s.state[name] = make([]float32, s.shapes[name].Size())
+	}
+	clear(s.context)
+}
+
+// Close libera o modelo
+func (s *Silero) Close() error {
+	return s.session.Close()
+}
+
+// hasTensor se há um tensor com o nome
+func hasTensor(infos []backend.TensorInfo, name string) bool {
+	for _, info := range infos {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+//go:build ignore
+
+// gen grava os WAVs de teste do VAD: a mesma frase sintética (uma fricativa
+// fraca e três sílabas vozeadas) numa sala silenciosa e numa sala com
+// ventilador, zumbido da rede e uma batida antes da fala.
+//
+//	go run gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const rate = 16000
+
+func main() {
+	write("quiet_room.wav", room(rand.New(rand.NewSource(1)), 0.0005, 0, false))
+	write("noisy_room.wav", room(rand.New(rand.NewSource(2)), 0.02, 0.01, true))
+}
+
+// room 4 s: ruído, fricativa em 0,94 s, sílabas de 1,0 s a 2,25 s, ruído
+func room(rng *rand.Rand, noise, hum float64, knock bool) []float64 {
+	out := make([]float64, 4*rate)
+
+	// Ventilador: ruído branco passado num passa-baixas de um polo
+	var low float64
+	for i := range out {
+		low += 0.2 * (rng.NormFloat64() - low)
+		out[i] = noise*low/0.33 + hum*math.Sin(2*math.Pi*50*float64(i)/rate)
+	}
+
+	// Batida de 20 ms em 0,5 s: alta, mas curta demais para ser fala
+	if knock {
+		for i := 0; i < rate/50; i++ {
+			out[rate/2+i] += 0.5 * math.Exp(-float64(i)/80) * rng.NormFloat64()
+		}
+	}
+
+	// Fricativa ("s") fraca: ruído com a diferença de amostras (passa-altas)
+	start := rate * 94 / 100
+	prev := 0.0
+	for i := 0; i < rate*6/100; i++ {
+		n := rng.NormFloat64()
+		out[start+i] += 0.03 * (n - prev)
+		prev = n
+	}
+
+	// Três sílabas de 350 ms separadas por 100 ms
+	for k := 0; k < 3; k++ {
+		syllable(out[rate+k*rate*45/100:], 120+20*float64(k))
+	}
+	return out
+}
+
+// syllable vogal de 350 ms: harmônicos de f0 pesados por dois formantes
+func syllable(out []float64, f0 float64) {
+	n := rate * 35 / 100
+	for i := 0; i < n; i++ {
+		t := float64(i) / rate
+		env := math.Min(1, math.Min(t/0.04, (float64(n-i)/rate)/0.04))
+		var v float64
+		for h := 1; float64(h)*f0 < 4000; h++ {
+			f := float64(h) * f0
+			gain := formant(f, 700, 130) + 0.5*formant(f, 1200, 150)
+			v += gain * math.Sin(2*math.Pi*f*t)
+		}
+		out[i] += 0.12 * env * v
+	}
+}
+
+// formant ganho de uma ressonância em center com a largura dada
+func formant(f, center, width float64) float64 {
+	d := (f - center) / width
+	return 1 / (1 + d*d)
+}
+
+// write grava PCM de 16 bits mono
+func write(path string, samples []float64) {
+	pcm := make([]int16, len(samples))
+	for i, s := range samples {
+		pcm[i] = int16(math.Max(-1, math.Min(1, s)) * 32767)
+	}
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+2*len(pcm)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{rate, rate * 2})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(2*len(pcm)))
+	binary.Write(&b, binary.LittleEndian, pcm)
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
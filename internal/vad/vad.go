@@ -0,0 +1,153 @@
+// Package vad detecta fala no áudio do microfone em quadros curtos (30 ms na
+// energia, 32 ms no Silero) e decide onde cada fala começa e termina (endpointing). O detector é
+// plugável: energia com piso de ruído adaptativo ou a rede do Silero VAD.
+package vad
+
+import (
+	"fmt"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+const (
+	SampleRate   = 16000
+	FrameSamples = SampleRate * 30 / 1000 // 30 ms, quadro do detector por energia
+)
+
+// Engines aceitos em audio.vad.engine
+const (
+	EngineEnergy = "energy"
+	EngineSilero = "silero"
+)
+
+// Detector probabilidade de fala de cada quadro de FrameSize amostras, em
+// ordem: detectores podem guardar estado entre quadros
+type Detector interface {
+	Speech(frame []float32) (float32, error)
+	// FrameSize amostras por quadro
+	FrameSize() int
+	// Reset esquece o estado da fala anterior
+	Reset()
+	Close() error
+}
+
+// New cria o detector de cfg.Engine; be executa o Silero
+func New(be backend.Backend, cfg config.VADConfig) (Detector, error) {
+	switch cfg.Engine {
+	case "", EngineEnergy:
+		return NewEnergy(cfg.MarginDB), nil
+	case EngineSilero:
+		return NewSilero(be, cfg.ModelPath)
+	}
+	return nil, fmt.Errorf("audio.vad.engine desconhecido: %s", cfg.Engine)
+}
+
+// Endpointer separa as falas do áudio contínuo. O início exige
+// min_speech_ms de fala seguida (tosse e clique não abrem fala) e traz junto
+// pre_roll_ms de áudio anterior, para a primeira sílaba não ser cortada; o
+// fim vem depois de silence_ms sem fala (hangover).
+type Endpointer struct {
+	detector  Detector
+	threshold float32
+	size      int // Amostras por quadro do detector
+
+	// Em quadros
+	preRoll   int
+	minSpeech int
+	hangover  int
+
+	rest     []float32   // Amostras que ainda não fecham um quadro
+	recent   [][]float32 // Últimos quadros antes da fala (pré-roll e início)
+	run      int         // Quadros de fala seguidos antes do início
+	speech   []float32   // Fala atual, com o pré-roll
+	speaking bool
+	silent   int // Quadros sem fala desde a última fala
+}
+
+// NewEndpointer cria o endpointer sobre o detector
+func NewEndpointer(detector Detector, cfg config.AudioConfig) *Endpointer {
+	size := detector.FrameSize()
+	frames := func(ms int) int { return (ms*SampleRate/1000 + size - 1) / size }
+	return &Endpointer{
+		detector:  detector,
+		threshold: cfg.VAD.Threshold,
+		size:      size,
+		preRoll:   frames(cfg.VAD.PreRollMs),
+		minSpeech: max(frames(cfg.VAD.MinSpeechMs), 1),
+		hangover:  max(frames(cfg.SilenceMs), 1),
+	}
+}
+
+// SetThreshold probabilidade a partir da qual o quadro é fala
+func (e *Endpointer) SetThreshold(threshold float32) {
+	e.threshold = threshold
+}
+
+// Write processa amostras de 16 kHz e devolve o áudio que entrou na fala
+// (no início, com o pré-roll) e se ela terminou. Depois do fim, Speech tem a
+// fala inteira até o próximo Reset.
+func (e *Endpointer) Write(samples []float32) (speech []float32, done bool, err error) {
+	e.rest = append(e.rest, samples...)
+	for len(e.rest) >= e.size {
+		frame := e.rest[:e.size:e.size]
+		e.rest = e.rest[e.size:]
+
+		p, err := e.detector.Speech(frame)
+		if err != nil {
+			return speech, false, fmt.Errorf("erro no VAD: %w", err)
+		}
+		voiced := p >= e.threshold
+
+		if !e.speaking {
+			e.recent = append(e.recent, frame)
+			if len(e.recent) > e.preRoll+e.minSpeech {
+				e.recent = e.recent[1:]
+			}
+			if !voiced {
+				e.run = 0
+				continue
+			}
+			if e.run++; e.run < e.minSpeech {
+				continue
+			}
+			// Fala confirmada: entra com o que veio antes
+			e.speaking = true
+			start := len(e.speech)
+			for _, f := range e.recent {
+				e.speech = append(e.speech, f...)
+			}
+			e.recent = nil
+			speech = append(speech, e.speech[start:]...)
+			continue
+		}
+
+		e.speech = append(e.speech, frame...)
+		speech = append(speech, frame...)
+		if voiced {
+			e.silent = 0
+		} else if e.silent++; e.silent >= e.hangover {
+			// O que sobrou em rest fica para a próxima fala
+			return speech, true, nil
+		}
+	}
+	return speech, false, nil
+}
+
+// Speaking se há fala em andamento
+func (e *Endpointer) Speaking() bool {
+	return e.speaking
+}
+
+// Speech áudio da fala atual desde o pré-roll
+func (e *Endpointer) Speech() []float32 {
+	return e.speech
+}
+
+// Reset começa a esperar a próxima fala
+func (e *Endpointer) Reset() {
+	e.recent, e.speech = nil, nil
+	e.run, e.silent = 0, 0
+	e.speaking = false
+	e.detector.Reset()
+}
@@ -0,0 +1,230 @@
+package vad
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Nas gravações (testdata/gen.go) a fricativa começa em 0,94 s e as sílabas
+// vão de 1,0 s a 2,25 s
+const (
+	fricativeStart = 940 * time.Millisecond
+	speechEnd      = 2250 * time.Millisecond
+)
+
+func TestEndpointerRooms(t *testing.T) {
+	cfg := config.Default().Audio
+	for _, name := range []string{"quiet_room.wav", "noisy_room.wav"} {
+		t.Run(name, func(t *testing.T) {
+			samples := readWAV(t, filepath.Join("testdata", name))
+			e := NewEndpointer(NewEnergy(cfg.VAD.MarginDB), cfg)
+
+			// Blocos de 50 ms, como chegam do microfone
+			var speech []float32
+			done := false
+			for pos := 0; pos < len(samples) && !done; pos += SampleRate / 20 {
+				chunk, end, err := e.Write(samples[pos:min(pos+SampleRate/20, len(samples))])
+				if err != nil {
+					t.Fatal(err)
+				}
+				speech = append(speech, chunk...)
+				done = end
+			}
+			if !done {
+				t.Fatal("fim da fala não detectado")
+			}
+			if !slices.Equal(speech, e.Speech()) {
+				t.Error("áudio entregue aos poucos difere de Speech()")
+			}
+
+			// O pré-roll traz a fricativa fraca; a batida em 0,5 s não abre fala
+			start := offset(samples, speech)
+			if start < 600*time.Millisecond || start > fricativeStart {
+				t.Errorf("fala começa em %v, want entre 600ms e %v", start, fricativeStart)
+			}
+			// Termina depois do hangover (silence_ms), sem cortar as sílabas
+			end := start + duration(len(speech))
+			hangover := time.Duration(cfg.SilenceMs) * time.Millisecond
+			if end < speechEnd+hangover-100*time.Millisecond || end > speechEnd+hangover+100*time.Millisecond {
+				t.Errorf("fala termina em %v, want perto de %v", end, speechEnd+hangover)
+			}
+		})
+	}
+}
+
+func TestEndpointerIgnoresNoise(t *testing.T) {
+	// Ventilador, zumbido e a batida, sem fala
+	samples := readWAV(t, filepath.Join("testdata", "noisy_room.wav"))[:SampleRate*9/10]
+	cfg := config.Default().Audio
+	e := NewEndpointer(NewEnergy(cfg.VAD.MarginDB), cfg)
+	speech, done, err := e.Write(samples)
+	if err != nil || len(speech) > 0 || done || e.Speaking() {
+		t.Errorf("ruído virou fala: %d amostras, fim %v, erro %v", len(speech), done, err)
+	}
+}
+
+func TestSilero(t *testing.T) {
+	// Rede de teste: a probabilidade é a média absoluta da janela mais o
+	// estado recebido, e o estado seguinte conta as janelas
+	var srs []int64
+	var contexts [][]float32
+	be := fake.New()
+	be.Add("silero_vad.onnx", &fake.Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input", Type: backend.Float32, Shape: backend.Shape{-1, -1}},
+			{Name: "state", Type: backend.Float32, Shape: backend.Shape{2, -1, 128}},
+			{Name: "sr", Type: backend.Int64, Shape: backend.Shape{}},
+		},
+		Outputs: []backend.TensorInfo{{Name: "output", Type: backend.Float32}, {Name: "stateN", Type: backend.Float32}},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			input := inputs[0].Data().([]float32)
+			state := inputs[1].Data().([]float32)
+			srs = append(srs, inputs[2].Data().([]int64)...)
+			// v5: 64 amostras de contexto e a janela de 512
+			if shape := inputs[0].Shape(); !slices.Equal(shape, backend.Shape{1, 576}) {
+				t.Fatalf("entrada %v, want [1 576]", shape)
+			}
+			contexts = append(contexts, slices.Clone(input[:64]))
+
+			var p float32
+			for _, v := range input[64:] {
+				p += max(v, -v)
+			}
+			p = p/512 + state[0]
+			next := slices.Clone(state)
+			next[0] += 0.125
+			prob, _ := backend.NewHostTensor(backend.Float32, backend.Shape{1, 1}, []float32{p})
+			stateN, _ := backend.NewHostTensor(backend.Float32, backend.Shape{2, 1, 128}, next)
+			return []backend.Tensor{prob, stateN}, nil
+		},
+	})
+
+	cfg := config.Default().Audio
+	cfg.VAD.Engine = EngineSilero
+	cfg.VAD.ModelPath = "silero_vad.onnx"
+	detector, err := New(be, cfg.VAD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer detector.Close()
+
+	if size := detector.FrameSize(); size != 512 {
+		t.Errorf("FrameSize() = %d, want 512", size)
+	}
+	var probs []float32
+	for i, v := range []float32{0.25, 0.5, 0.75, 1} {
+		if i == 3 {
+			detector.Reset()
+		}
+		window := make([]float32, 512)
+		for j := range window {
+			window[j] = v
+		}
+		p, err := detector.Speech(window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		probs = append(probs, p)
+	}
+	if _, err := detector.Speech(make([]float32, FrameSamples)); err == nil {
+		t.Error("quadro de 480 amostras deveria falhar")
+	}
+
+	if want := []float32{0.25, 0.625, 1, 1}; !slices.Equal(probs, want) {
+		t.Errorf("probabilidades = %v, want %v (estado carregado e zerado no Reset)", probs, want)
+	}
+	// O contexto é o fim da janela anterior, zerado no Reset
+	for i, want := range []float32{0, 0.25, 0.5, 0} {
+		if slices.Min(contexts[i]) != want || slices.Max(contexts[i]) != want {
+			t.Errorf("contexto da janela %d = %v, want 64 × %v", i, contexts[i], want)
+		}
+	}
+	if !slices.Equal(srs, []int64{SampleRate, SampleRate, SampleRate, SampleRate}) {
+		t.Errorf("sr = %v", srs)
+	}
+
+	// Fala confirmada depois de min_speech_ms acima do limiar
+	loud := make([]float32, 10*512)
+	for i := range loud {
+		loud[i] = 0.8
+	}
+	e := NewEndpointer(detector, cfg)
+	speech, _, err := e.Write(loud)
+	if err != nil || !e.Speaking() || len(speech) != 10*512 {
+		t.Errorf("Silero: fala %v com %d amostras, erro %v", e.Speaking(), len(speech), err)
+	}
+
+	// v4 (h e c): a janela sozinha, sem contexto
+	be.Add("silero_v4.onnx", &fake.Model{
+		Inputs: []backend.TensorInfo{
+			{Name: "input", Type: backend.Float32, Shape: backend.Shape{-1, -1}},
+			{Name: "sr", Type: backend.Int64, Shape: backend.Shape{1}},
+			{Name: "h", Type: backend.Float32, Shape: backend.Shape{2, -1, 64}},
+			{Name: "c", Type: backend.Float32, Shape: backend.Shape{2, -1, 64}},
+		},
+		Outputs: []backend.TensorInfo{{Name: "output", Type: backend.Float32}, {Name: "hn", Type: backend.Float32}, {Name: "cn", Type: backend.Float32}},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			if shape := inputs[0].Shape(); !slices.Equal(shape, backend.Shape{1, 512}) {
+				t.Errorf("entrada do v4 %v, want [1 512]", shape)
+			}
+			prob, _ := backend.NewHostTensor(backend.Float32, backend.Shape{1, 1}, []float32{0})
+			hn, _ := backend.NewHostTensor(backend.Float32, backend.Shape{2, 1, 64}, make([]float32, 128))
+			cn, _ := backend.NewHostTensor(backend.Float32, backend.Shape{2, 1, 64}, make([]float32, 128))
+			return []backend.Tensor{prob, hn, cn}, nil
+		},
+	})
+	v4, err := NewSilero(be, "silero_v4.onnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v4.Close()
+	if _, err := v4.Speech(make([]float32, 512)); err != nil {
+		t.Error(err)
+	}
+
+	be.Add("outro.onnx", &fake.Model{Inputs: []backend.TensorInfo{{Name: "audio", Type: backend.Float32}}})
+	if _, err := NewSilero(be, "outro.onnx"); err == nil {
+		t.Error("modelo sem as entradas do Silero deveria falhar")
+	}
+}
+
+// readWAV amostras de um WAV PCM de 16 bits mono a 16 kHz
+func readWAV(t *testing.T, path string) []float32 {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[36:40]) != "data" || binary.LittleEndian.Uint32(data[24:28]) != SampleRate {
+		t.Fatalf("%s: esperado PCM 16 bits mono a 16 kHz", path)
+	}
+	pcm := data[44:]
+	samples := make([]float32, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(pcm[2*i:]))) / 32768
+	}
+	return samples
+}
+
+// offset onde o trecho começa na gravação
+func offset(samples, part []float32) time.Duration {
+	for i := 0; i+len(part) <= len(samples); i++ {
+		if slices.Equal(samples[i:i+FrameSamples], part[:FrameSamples]) {
+			return duration(i)
+		}
+	}
+	return -1
+}
+
+// duration duração de n amostras
+func duration(n int) time.Duration {
+	return time.Duration(n) * time.Second / SampleRate
+}
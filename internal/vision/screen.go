@@ -0,0 +1,8 @@
+package vision
+
+import "github.com/JoseRFJuniorLLMs/NPU-IA/internal/platform"
+
+// CaptureScreen captura a tela atual em PNG com a ferramenta da plataforma
+func (m *Model) CaptureScreen() ([]byte, error) {
+	return platform.Current().Screenshot()
+}
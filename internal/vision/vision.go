@@ -3,134 +3,88 @@ package vision
 import (
 	"context"
 	"fmt"
-	"image"
-	"image/png"
-	"os"
-	"syscall"
-	"unsafe"
 
-	ort "github.com/yalue/onnxruntime_go"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/chat"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/tokenizer"
 	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
 )
 
 // Model representa o modelo de visão MiniCPM-V
 type Model struct {
-	session *ort.DynamicAdvancedSession
-	config  config.ModelConfig
+	backend   backend.Backend
+	session   backend.Session
+	config    config.ModelConfig
+	tokenizer *tokenizer.Tokenizer
+	template  *chat.Template
 }
 
-// New cria um novo modelo de visão
-func New(cfg config.ModelConfig) (*Model, error) {
-	options, err := ort.NewSessionOptions()
-	if err != nil {
-		return nil, err
-	}
-
-	// Usa DirectML para NPU AMD
-	err = options.AppendExecutionProviderDirectML(0)
-	if err != nil {
-		fmt.Println("DirectML não disponível para Vision, usando CPU")
-	}
+// defaultContextLength janela usada quando o config não informa
+const defaultContextLength = 4096
 
+// New cria um novo modelo de visão executado pelo backend
+func New(be backend.Backend, cfg config.ModelConfig) (*Model, error) {
 	// Carrega modelo MiniCPM-V ONNX
-	session, err := ort.NewDynamicAdvancedSession(
+	session, err := be.Open(
 		cfg.Path,
 		[]string{"pixel_values", "input_ids"},
 		[]string{"logits"},
-		options,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("erro ao carregar modelo de visão: %w", err)
 	}
 
-	return &Model{
-		session: session,
-		config:  cfg,
-	}, nil
-}
-
-// CaptureScreen captura a tela atual
-func (m *Model) CaptureScreen() ([]byte, error) {
-	// Windows API para screenshot
-	user32 := syscall.NewLazyDLL("user32.dll")
-	gdi32 := syscall.NewLazyDLL("gdi32.dll")
-
-	getDC := user32.NewProc("GetDC")
-	releaseDC := user32.NewProc("ReleaseDC")
-	getSystemMetrics := user32.NewProc("GetSystemMetrics")
-	createCompatibleDC := gdi32.NewProc("CreateCompatibleDC")
-	createCompatibleBitmap := gdi32.NewProc("CreateCompatibleBitmap")
-	selectObject := gdi32.NewProc("SelectObject")
-	bitBlt := gdi32.NewProc("BitBlt")
-	deleteDC := gdi32.NewProc("DeleteDC")
-	deleteObject := gdi32.NewProc("DeleteObject")
-
-	// Obtém dimensões da tela
-	width, _, _ := getSystemMetrics.Call(0)  // SM_CXSCREEN
-	height, _, _ := getSystemMetrics.Call(1) // SM_CYSCREEN
-
-	// Obtém DC da tela
-	hdcScreen, _, _ := getDC.Call(0)
-	defer releaseDC.Call(0, hdcScreen)
-
-	// Cria DC compatível
-	hdcMem, _, _ := createCompatibleDC.Call(hdcScreen)
-	defer deleteDC.Call(hdcMem)
-
-	// Cria bitmap
-	hBitmap, _, _ := createCompatibleBitmap.Call(hdcScreen, width, height)
-	defer deleteObject.Call(hBitmap)
-
-	// Seleciona bitmap no DC
-	selectObject.Call(hdcMem, hBitmap)
-
-	// Copia tela para bitmap
-	bitBlt.Call(
-		hdcMem, 0, 0, width, height,
-		hdcScreen, 0, 0,
-		0x00CC0020, // SRCCOPY
-	)
-
-	// Converte para imagem Go
-	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
-
-	// TODO: Copiar pixels do bitmap para img
-
-	// Salva temporariamente e lê bytes
-	tmpFile := os.TempDir() + "/screenshot.png"
-	f, err := os.Create(tmpFile)
+	template, err := chat.Resolve(cfg)
 	if err != nil {
-		return nil, err
+		session.Close()
+		return nil, fmt.Errorf("erro no template de chat do modelo de visão: %w", err)
+	}
+
+	m := &Model{
+		backend:  be,
+		session:  session,
+		config:   cfg,
+		template: template,
 	}
-	defer os.Remove(tmpFile)
-	defer f.Close()
 
-	png.Encode(f, img)
+	// Carrega tokenizer do prompt
+	if cfg.TokenizerPath != "" {
+		tk, err := tokenizer.Load(cfg.TokenizerPath)
+		if err != nil {
+			session.Close()
+			return nil, fmt.Errorf("erro ao carregar tokenizer: %w", err)
+		}
+		m.tokenizer = tk
+	}
 
-	return os.ReadFile(tmpFile)
+	return m, nil
 }
 
-// Analyze analisa uma imagem com o prompt
-func (m *Model) Analyze(ctx context.Context, imageData []byte, prompt string) (string, error) {
+// Analyze analisa uma imagem com o prompt. history são os turnos anteriores
+// da conversa, para perguntas como "e o que tem do lado?".
+func (m *Model) Analyze(ctx context.Context, imageData []byte, history []chat.Message, prompt string) (string, error) {
 	// Pré-processa imagem
 	pixelValues, err := m.preprocessImage(imageData)
 	if err != nil {
 		return "", err
 	}
 
-	// Tokeniza prompt
-	inputIDs := m.tokenizePrompt(prompt)
+	// Tokeniza a conversa
+	inputIDs, err := m.tokenizeConversation(history, prompt)
+	if err != nil {
+		return "", err
+	}
 
 	// Prepara tensores
-	imgShape := ort.NewShape(1, 3, 384, 384) // MiniCPM-V input size
-	imgTensor, err := ort.NewTensor(imgShape, pixelValues)
+	imgShape := backend.Shape{1, 3, 384, 384} // MiniCPM-V input size
+	imgTensor, err := m.backend.NewTensor(backend.Float32, imgShape, pixelValues)
 	if err != nil {
 		return "", err
 	}
 	defer imgTensor.Destroy()
 
-	textShape := ort.NewShape(1, int64(len(inputIDs)))
-	textTensor, err := ort.NewTensor(textShape, inputIDs)
+	textShape := backend.Shape{1, int64(len(inputIDs))}
+	textTensor, err := m.backend.NewTensor(backend.Int64, textShape, inputIDs)
 	if err != nil {
 		return "", err
 	}
@@ -155,25 +109,38 @@ func (m *Model) preprocessImage(data []byte) ([]float32, error) {
 	return pixels, nil
 }
 
-// tokenizePrompt tokeniza o prompt
-func (m *Model) tokenizePrompt(prompt string) []int64 {
-	// TODO: Implementar tokenização real
-	tokens := make([]int64, len(prompt))
-	return tokens
+// tokenizeConversation tokeniza os turnos anteriores e o pedido no template
+// do modelo. Os turnos mais antigos saem até caber na janela deixando
+// MaxTokens para a resposta; o corte cai no começo de um turno do usuário.
+func (m *Model) tokenizeConversation(history []chat.Message, prompt string) ([]int64, error) {
+	if m.tokenizer == nil {
+		return nil, fmt.Errorf("tokenizer não configurado para %s", m.config.Name)
+	}
+
+	contextLength := m.config.ContextLength
+	if contextLength <= 0 {
+		contextLength = defaultContextLength
+	}
+	budget := contextLength - m.config.MaxTokens
+
+	var ids []int64
+	for start := 0; start <= len(history); start++ {
+		if start > 0 && start < len(history) && history[start].Role != chat.RoleUser {
+			continue
+		}
+		messages := append(append([]chat.Message(nil), history[start:]...), chat.Message{Role: chat.RoleUser, Content: prompt})
+		ids = m.tokenizer.Encode(m.template.Render(messages, true), true)
+		if len(ids) <= budget {
+			break
+		}
+	}
+	return ids, nil
 }
 
 // Close libera recursos
 func (m *Model) Close() error {
 	if m.session != nil {
-		return m.session.Destroy()
+		return m.session.Close()
 	}
 	return nil
 }
-
-// Windows constants
-const (
-	SRCCOPY = 0x00CC0020
-)
-
-// Placeholder para remover warning de unused
-var _ = unsafe.Pointer(nil)
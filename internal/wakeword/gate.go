@@ -0,0 +1,226 @@
+package wakeword
+
+import (
+	"sync"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// Origens de uma ativação
+const (
+	SourceWakeWord   = "wake_word"
+	SourcePushToTalk = "push_to_talk"
+)
+
+// Tipos de evento
+const (
+	EventDetection   = "detection"    // Ativação pela frase ou push-to-talk
+	EventNearMiss    = "near_miss"    // Frase quase reconhecida
+	EventFalseAccept = "false_accept" // Ativação pela frase sem fala depois
+	EventFalseReject = "false_reject" // Quase ativação logo antes de uma ativação: o usuário repetiu
+)
+
+// maxEvents eventos recentes guardados para a API
+const maxEvents = 100
+
+// Event ativação ou erro estimado do detector
+type Event struct {
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Source string    `json:"source,omitempty"`
+	Score  float32   `json:"score,omitempty"`
+}
+
+// Stats contadores desde o início
+type Stats struct {
+	Detections   int `json:"detections"`
+	PushToTalk   int `json:"push_to_talk"`
+	NearMisses   int `json:"near_misses"`
+	FalseAccepts int `json:"false_accepts"`
+	FalseRejects int `json:"false_rejects"`
+}
+
+// Gate decide quando o STT ouve: depois de uma ativação até a fala
+// terminar e, depois da resposta, durante follow_up_ms. As falsas
+// aceitações e rejeições são estimadas pelo que vem depois de cada
+// ativação, já que não há rótulo do que o usuário disse.
+type Gate struct {
+	spotter  *Spotter // nil: só push-to-talk
+	near     float32
+	followUp time.Duration
+	retry    time.Duration
+
+	mu           sync.Mutex
+	push         bool   // Push-to-talk ainda não atendido
+	trigger      *Event // Ativação esperando a fala
+	engagedUntil time.Time
+	inNear       bool // Pontuação ainda acima de near_threshold
+	lastNear     time.Time
+	stats        Stats
+	events       []Event
+	onEvent      func(Event)
+	now          func() time.Time
+}
+
+// NewGate cria o gate; sem spotter, só o push-to-talk ativa
+func NewGate(spotter *Spotter, cfg config.WakeWordConfig) *Gate {
+	return &Gate{
+		spotter:  spotter,
+		near:     cfg.NearThreshold,
+		followUp: time.Duration(cfg.FollowUpMs) * time.Millisecond,
+		retry:    time.Duration(cfg.RetryMs) * time.Millisecond,
+		now:      time.Now,
+	}
+}
+
+// SetEventHandler recebe cada evento assim que acontece; fn roda com o gate
+// travado e não deve chamá-lo
+func (g *Gate) SetEventHandler(fn func(Event)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onEvent = fn
+}
+
+// Write processa o áudio enquanto o gate espera a ativação e diz se ela
+// aconteceu
+func (g *Gate) Write(samples []float32) (bool, error) {
+	g.mu.Lock()
+	push := g.push
+	g.push = false
+	g.mu.Unlock()
+	if push {
+		g.activate(SourcePushToTalk, 0)
+		return true, nil
+	}
+	if g.spotter == nil {
+		return false, nil
+	}
+
+	score, detected, err := g.spotter.Write(samples)
+	if err != nil {
+		return false, err
+	}
+	if detected {
+		g.activate(SourceWakeWord, score)
+		return true, nil
+	}
+
+	g.mu.Lock()
+	wasNear := g.inNear
+	g.inNear = score >= g.near
+	if g.inNear && !wasNear {
+		g.lastNear = g.now()
+		g.stats.NearMisses++
+		g.record(Event{Kind: EventNearMiss, Score: score})
+	}
+	g.mu.Unlock()
+	return false, nil
+}
+
+// activate registra a ativação; uma quase ativação pouco antes conta como
+// falsa rejeição
+func (g *Gate) activate(source string, score float32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if !g.lastNear.IsZero() && now.Sub(g.lastNear) <= g.retry {
+		g.stats.FalseRejects++
+		g.record(Event{Kind: EventFalseReject, Source: source})
+	}
+	g.lastNear = time.Time{}
+	g.inNear = false
+
+	if source == SourcePushToTalk {
+		g.stats.PushToTalk++
+	} else {
+		g.stats.Detections++
+	}
+	event := Event{Time: now, Kind: EventDetection, Source: source, Score: score}
+	g.trigger = &event
+	g.record(event)
+}
+
+// PushToTalk ativa sem a frase, na próxima vez que o gate esperar
+func (g *Gate) PushToTalk() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.push = true
+}
+
+// Engaged se o STT deve ouvir sem esperar a ativação: logo depois dela ou
+// na janela de continuação
+func (g *Gate) Engaged() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.trigger != nil || g.now().Before(g.engagedUntil)
+}
+
+// Done a fala foi atendida; abre a janela de continuação a partir de agora
+func (g *Gate) Done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.trigger = nil
+	g.engagedUntil = g.now().Add(g.followUp)
+}
+
+// Missed nenhuma fala veio. Logo depois da frase, foi falsa aceitação; na
+// janela de continuação, ela só segue até expirar.
+func (g *Gate) Missed() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.trigger == nil {
+		return
+	}
+	if g.trigger.Source == SourceWakeWord {
+		g.stats.FalseAccepts++
+		g.record(Event{Kind: EventFalseAccept, Source: SourceWakeWord, Score: g.trigger.Score})
+	}
+	g.trigger = nil
+	g.engagedUntil = time.Time{}
+}
+
+// Reset esquece o áudio anterior; chamado ao voltar a esperar a ativação
+func (g *Gate) Reset() {
+	if g.spotter != nil {
+		g.spotter.Reset()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inNear = false
+}
+
+// Stats contadores atuais
+func (g *Gate) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// Events eventos recentes, do mais antigo ao mais novo
+func (g *Gate) Events() []Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Event(nil), g.events...)
+}
+
+// Close libera o modelo
+func (g *Gate) Close() error {
+	if g.spotter == nil {
+		return nil
+	}
+	return g.spotter.Close()
+}
+
+// record guarda o evento e avisa o handler; chamado com mu travado
+func (g *Gate) record(event Event) {
+	event.Time = g.now()
+	g.events = append(g.events, event)
+	if len(g.events) > maxEvents {
+		g.events = g.events[len(g.events)-maxEvents:]
+	}
+	if g.onEvent != nil {
+		g.onEvent(event)
+	}
+}
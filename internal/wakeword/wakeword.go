@@ -0,0 +1,174 @@
+// Package wakeword ouve o microfone o tempo todo com um modelo pequeno de
+// keyword spotting e só libera o STT depois da palavra de ativação ("Ei
+// NPU") ou do push-to-talk. Depois de cada resposta há uma janela de
+// continuação sem a frase, e as ativações viram eventos e contadores de
+// falsas aceitações e rejeições.
+package wakeword
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+const SampleRate = 16000
+
+// Spotter keyword spotting em ONNX: a rede recebe a janela mais recente de
+// áudio de 16 kHz ([1, amostras]) e devolve a probabilidade de cada rótulo
+type Spotter struct {
+	backend   backend.Backend
+	session   backend.Session
+	label     int // Posição da frase na saída
+	threshold float32
+
+	window  []float32 // Últimas size amostras
+	size    int
+	hop     int
+	pending int // Amostras desde a última avaliação
+}
+
+// NewSpotter carrega o modelo e acha cfg.Phrase entre os rótulos de
+// cfg.LabelsPath; sem o arquivo, o modelo deve ter uma saída só
+func NewSpotter(be backend.Backend, cfg config.WakeWordConfig) (*Spotter, error) {
+	inputs, outputs, err := be.Inspect(cfg.ModelPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inspecionar modelo de wake word: %w", err)
+	}
+	if len(inputs) != 1 || inputs[0].Type != backend.Float32 || len(outputs) == 0 {
+		return nil, fmt.Errorf("modelo em %s não é de keyword spotting (uma entrada de áudio float32)", cfg.ModelPath)
+	}
+
+	// Modelos com janela fixa mandam no tamanho
+	size := cfg.WindowMs * SampleRate / 1000
+	if shape := inputs[0].Shape; len(shape) > 0 && shape[len(shape)-1] > 0 {
+		size = int(shape[len(shape)-1])
+	}
+
+	labels := 1
+	if shape := outputs[0].Shape; len(shape) > 0 && shape[len(shape)-1] > 0 {
+		labels = int(shape[len(shape)-1])
+	}
+	label, err := findLabel(cfg.LabelsPath, cfg.Phrase)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && labels == 1:
+		label = 0
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("sem %s, o modelo de wake word precisa ter uma saída só (tem %d rótulos)", cfg.LabelsPath, labels)
+	case err != nil:
+		return nil, err
+	case label >= labels:
+		return nil, fmt.Errorf("%q é o rótulo %d de %s, mas o modelo tem %d", cfg.Phrase, label, cfg.LabelsPath, labels)
+	}
+
+	session, err := be.Open(cfg.ModelPath, []string{inputs[0].Name}, []string{outputs[0].Name})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar modelo de wake word: %w", err)
+	}
+
+	return &Spotter{
+		backend:   be,
+		session:   session,
+		label:     label,
+		threshold: cfg.Threshold,
+		size:      size,
+		hop:       max(cfg.HopMs*SampleRate/1000, 1),
+	}, nil
+}
+
+// Write acrescenta amostras à janela, avaliando-a a cada hop_ms, e devolve a
+// maior probabilidade da frase e se ela passou do limiar. Na ativação a
+// janela é esvaziada, para a mesma frase não ativar de novo.
+func (s *Spotter) Write(samples []float32) (score float32, detected bool, err error) {
+	for len(samples) > 0 {
+		n := min(len(samples), s.hop-s.pending)
+		s.window = append(s.window, samples[:n]...)
+		if extra := len(s.window) - s.size; extra > 0 {
+			s.window = append(s.window[:0], s.window[extra:]...)
+		}
+		samples = samples[n:]
+
+		if s.pending += n; s.pending < s.hop {
+			continue
+		}
+		s.pending = 0
+		if len(s.window) < s.size {
+			continue
+		}
+
+		p, err := s.score()
+		if err != nil {
+			return score, false, err
+		}
+		score = max(score, p)
+		if p >= s.threshold {
+			s.Reset()
+			return score, true, nil
+		}
+	}
+	return score, false, nil
+}
+
+// score probabilidade da frase na janela atual
+func (s *Spotter) score() (float32, error) {
+	input, err := s.backend.NewTensor(backend.Float32, backend.Shape{1, int64(s.size)}, s.window)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao criar tensor: %w", err)
+	}
+	defer input.Destroy()
+
+	outputs, err := s.session.Run([]backend.Tensor{input})
+	if err != nil {
+		return 0, fmt.Errorf("erro no modelo de wake word: %w", err)
+	}
+	defer backend.DestroyAll(outputs)
+
+	probs, ok := outputs[0].Data().([]float32)
+	if !ok || len(probs) <= s.label {
+		return 0, fmt.Errorf("saída do modelo de wake word inválida: %s %v", outputs[0].Type(), outputs[0].Shape())
+	}
+	return probs[s.label], nil
+}
+
+// Reset esquece o áudio anterior (o microfone ficou parado)
+func (s *Spotter) Reset() {
+	s.window = s.window[:0]
+	s.pending = 0
+}
+
+// Close libera o modelo
+func (s *Spotter) Close() error {
+	return s.session.Close()
+}
+
+// findLabel posição da frase no arquivo de rótulos, um por linha, sem
+// diferença de maiúsculas e espaços
+func findLabel(path, phrase string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	want := normalize(phrase)
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		if normalize(scanner.Text()) == want {
+			return i, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return -1, fmt.Errorf("erro ao ler %s: %w", path, err)
+	}
+	return -1, fmt.Errorf("frase %q não está entre os rótulos de %s", phrase, path)
+}
+
+// normalize minúsculas, espaços simples
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
@@ -0,0 +1,210 @@
+package wakeword
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/internal/backend/fake"
+	"github.com/JoseRFJuniorLLMs/NPU-IA/pkg/config"
+)
+
+// newBackend modelo de teste: a probabilidade da frase é a média da janela
+// e os outros rótulos dividem o resto
+func newBackend(t *testing.T, labels int64) *fake.Backend {
+	t.Helper()
+	be := fake.New()
+	be.Add("kws.onnx", &fake.Model{
+		Inputs:  []backend.TensorInfo{{Name: "audio", Type: backend.Float32, Shape: backend.Shape{1, -1}}},
+		Outputs: []backend.TensorInfo{{Name: "probs", Type: backend.Float32, Shape: backend.Shape{1, labels}}},
+		Run: func(inputs []backend.Tensor) ([]backend.Tensor, error) {
+			var p float32
+			window := inputs[0].Data().([]float32)
+			for _, v := range window {
+				p += v
+			}
+			p /= float32(len(window))
+
+			probs := make([]float32, labels)
+			probs[labels-1] = p
+			if labels > 1 {
+				probs[0] = 1 - p
+			}
+			out, err := backend.NewHostTensor(backend.Float32, backend.Shape{1, labels}, probs)
+			return []backend.Tensor{out}, err
+		},
+	})
+	return be
+}
+
+// testConfig wake word com rótulos em dir; a frase é o último rótulo
+func testConfig(t *testing.T, labels ...string) config.WakeWordConfig {
+	t.Helper()
+	cfg := config.Default().WakeWord
+	cfg.Enabled = true
+	cfg.ModelPath = "kws.onnx"
+	cfg.LabelsPath = filepath.Join(t.TempDir(), "labels.txt")
+	if len(labels) > 0 {
+		if err := os.WriteFile(cfg.LabelsPath, []byte(strings.Join(labels, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// constant n amostras com o valor v
+func constant(n int, v float32) []float32 {
+	samples := make([]float32, n)
+	for i := range samples {
+		samples[i] = v
+	}
+	return samples
+}
+
+func TestSpotter(t *testing.T) {
+	cfg := testConfig(t, "_silence_", "ei npu")
+	cfg.Phrase = "Ei  NPU"
+	cfg.WindowMs = 1000
+	s, err := NewSpotter(newBackend(t, 2), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Blocos de 50 ms, como chegam do microfone
+	feed := func(samples []float32) (time.Duration, bool) {
+		for pos := 0; pos < len(samples); pos += SampleRate / 20 {
+			_, detected, err := s.Write(samples[pos:min(pos+SampleRate/20, len(samples))])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detected {
+				return time.Duration(pos+SampleRate/20) * time.Second / SampleRate, true
+			}
+		}
+		return 0, false
+	}
+
+	if _, detected := feed(constant(2*SampleRate, 0)); detected {
+		t.Fatal("silêncio ativou")
+	}
+	// A média passa de 0,7 quando 7/8 da janela é a frase (0,8)
+	at, detected := feed(constant(2*SampleRate, 0.8))
+	if !detected || at < 850*time.Millisecond || at > time.Second {
+		t.Fatalf("ativação em %v (%v), want entre 850ms e 1s", at, detected)
+	}
+	// A janela recomeça: a mesma frase não ativa duas vezes
+	if _, detected := feed(constant(SampleRate/2, 0.8)); detected {
+		t.Error("ativou de novo com a janela esvaziada")
+	}
+}
+
+func TestSpotterLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  []string
+		outputs int64
+		wantErr string
+	}{
+		{"uma saída sem rótulos", nil, 1, ""},
+		{"várias saídas sem rótulos", nil, 3, "precisa ter uma saída só"},
+		{"frase ausente", []string{"_silence_", "ok computador"}, 2, `frase "Ei NPU" não está entre os rótulos`},
+		{"rótulo além das saídas", []string{"_silence_", "outro", "ei npu"}, 2, "o modelo tem 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSpotter(newBackend(t, tt.outputs), testConfig(t, tt.labels...))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewSpotter() erro = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGate(t *testing.T) {
+	// Janela e passo de 100 ms: cada bloco de 100 ms é avaliado sozinho
+	cfg := testConfig(t)
+	cfg.WindowMs, cfg.HopMs = 100, 100
+	spotter, err := NewSpotter(newBackend(t, 1), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate := NewGate(spotter, cfg)
+	defer gate.Close()
+
+	clock := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	gate.now = func() time.Time { return clock }
+	var kinds []string
+	gate.SetEventHandler(func(e Event) { kinds = append(kinds, e.Kind) })
+
+	write := func(score float32) bool {
+		t.Helper()
+		clock = clock.Add(100 * time.Millisecond)
+		ok, err := gate.Write(constant(SampleRate/10, score))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	// Quase ativação e, logo depois, a frase de novo: falsa rejeição
+	for _, score := range []float32{0.1, 0.5, 0.55, 0.2} {
+		if write(score) {
+			t.Fatalf("%v ativou", score)
+		}
+	}
+	if !write(0.9) || !gate.Engaged() {
+		t.Fatal("frase não ativou")
+	}
+
+	// Fala atendida: continuação sem a frase por follow_up_ms
+	gate.Done()
+	clock = clock.Add(time.Duration(cfg.FollowUpMs-1) * time.Millisecond)
+	if !gate.Engaged() {
+		t.Error("janela de continuação fechou cedo")
+	}
+	gate.Missed() // Sem fala na continuação: não é falsa aceitação
+	clock = clock.Add(2 * time.Millisecond)
+	if gate.Engaged() {
+		t.Error("janela de continuação não fechou")
+	}
+
+	// Ativação sem fala depois: falsa aceitação
+	if !write(0.95) {
+		t.Fatal("frase não ativou")
+	}
+	gate.Missed()
+	if gate.Engaged() {
+		t.Error("continua ouvindo depois da falsa aceitação")
+	}
+
+	// Push-to-talk ativa sem a frase
+	gate.PushToTalk()
+	if !write(0) {
+		t.Error("push-to-talk não ativou")
+	}
+	gate.Missed()
+
+	want := Stats{Detections: 2, PushToTalk: 1, NearMisses: 1, FalseAccepts: 1, FalseRejects: 1}
+	if got := gate.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	wantKinds := []string{EventNearMiss, EventFalseReject, EventDetection, EventDetection, EventFalseAccept, EventDetection}
+	if !slices.Equal(kinds, wantKinds) {
+		t.Errorf("eventos = %v, want %v", kinds, wantKinds)
+	}
+	if events := gate.Events(); len(events) != len(wantKinds) || events[5].Source != SourcePushToTalk {
+		t.Errorf("Events() = %+v", events)
+	}
+}
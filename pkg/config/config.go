@@ -1,7 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -9,27 +14,97 @@ import (
 
 // Config configuração principal
 type Config struct {
-	Audio   AudioConfig   `yaml:"audio"`
-	STT     STTConfig     `yaml:"stt"`
-	TTS     TTSConfig     `yaml:"tts"`
-	Models  ModelsConfig  `yaml:"models"`
-	Memory  MemoryConfig  `yaml:"memory"`
-	Actions ActionsConfig `yaml:"actions"`
+	Backend    BackendConfig    `yaml:"backend"`
+	Audio      AudioConfig      `yaml:"audio"`
+	WakeWord   WakeWordConfig   `yaml:"wake_word"`
+	STT        STTConfig        `yaml:"stt"`
+	TTS        TTSConfig        `yaml:"tts"`
+	Models     ModelsConfig     `yaml:"models"`
+	Embeddings EmbeddingsConfig `yaml:"embeddings"`
+	Intent     IntentConfig     `yaml:"intent"`
+	Session    SessionConfig    `yaml:"session"`
+	Personal   PersonalConfig   `yaml:"personal"`
+	Memory     MemoryConfig     `yaml:"memory"`
+	Actions    ActionsConfig    `yaml:"actions"`
+	Google     GoogleConfig     `yaml:"google"`
+	Server     ServerConfig     `yaml:"server"`
+	Agent      AgentConfig      `yaml:"agent"`
+	Services   ServicesConfig   `yaml:"services"`
+	Policy     PolicyConfig     `yaml:"policy"`
+}
+
+// BackendConfig configuração do runtime de inferência
+type BackendConfig struct {
+	Name        string `yaml:"name"`         // onnx
+	Provider    string `yaml:"provider"`     // cpu ou directml
+	Device      int    `yaml:"device"`       // Índice do adaptador DirectML
+	Threads     int    `yaml:"threads"`      // Threads de CPU por sessão (0 = automático)
+	LibraryPath string `yaml:"library_path"` // onnxruntime.dll/.so (vazio = padrão do sistema)
 }
 
 // AudioConfig configuração de áudio
 type AudioConfig struct {
-	SampleRate    int     `yaml:"sample_rate"`
-	VADThreshold  float32 `yaml:"vad_threshold"`
-	SilenceMs     int     `yaml:"silence_ms"`
-	MaxDurationMs int     `yaml:"max_duration_ms"`
+	SampleRate    int       `yaml:"sample_rate"`
+	SilenceMs     int       `yaml:"silence_ms"` // Hangover: silêncio que encerra a fala
+	MaxDurationMs int       `yaml:"max_duration_ms"`
+	VAD           VADConfig `yaml:"vad"`
+}
+
+// VADConfig detecção de fala no microfone, em quadros de 30 ms (energia) ou
+// 32 ms (Silero)
+type VADConfig struct {
+	Engine      string  `yaml:"engine"`        // energy ou silero
+	ModelPath   string  `yaml:"model_path"`    // silero: silero_vad.onnx
+	Threshold   float32 `yaml:"threshold"`     // Probabilidade a partir da qual o quadro é fala
+	MarginDB    float32 `yaml:"margin_db"`     // energy: relação sinal-ruído com 50% de chance de fala
+	PreRollMs   int     `yaml:"pre_roll_ms"`   // Áudio anterior ao início da fala que entra nela
+	MinSpeechMs int     `yaml:"min_speech_ms"` // Fala seguida para abrir uma fala (tosse e clique não abrem)
+	WaitMs      int     `yaml:"wait_ms"`       // Espera pelo início da fala antes de desistir
+}
+
+// WakeWordConfig palavra de ativação: um modelo pequeno de keyword spotting
+// ouve o tempo todo e o STT só entra depois da frase (ou do push-to-talk)
+type WakeWordConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	Phrase        string  `yaml:"phrase"`         // Frase entre os rótulos do modelo ("Ei NPU")
+	ModelPath     string  `yaml:"model_path"`     // Keyword spotting em ONNX: janela de áudio → probabilidade por rótulo
+	LabelsPath    string  `yaml:"labels_path"`    // Um rótulo por linha; dispensável em modelo de uma frase só
+	Threshold     float32 `yaml:"threshold"`      // Probabilidade que dispara a ativação
+	NearThreshold float32 `yaml:"near_threshold"` // Quase ativação, para estimar falsas rejeições
+	WindowMs      int     `yaml:"window_ms"`      // Áudio avaliado de cada vez (modelo com tamanho dinâmico)
+	HopMs         int     `yaml:"hop_ms"`         // Passo entre avaliações
+	FollowUpMs    int     `yaml:"follow_up_ms"`   // Depois da resposta, ouve sem a frase por esse tempo
+	RetryMs       int     `yaml:"retry_ms"`       // Quase ativação seguida de ativação nesse tempo conta como falsa rejeição
+	PushToTalk    bool    `yaml:"push_to_talk"`   // Enter no terminal (e POST /api/listen) ativa sem a frase
 }
 
 // STTConfig configuração do Speech-to-Text
 type STTConfig struct {
-	ModelPath string `yaml:"model_path"`
-	Language  string `yaml:"language"`
-	ModelSize string `yaml:"model_size"` // tiny, base, small, medium, large
+	Backend     string `yaml:"backend"`      // onnx ou whispercpp
+	ModelPath   string `yaml:"model_path"`   // ONNX: encoder ou modelo ponta a ponta; whispercpp: GGML/GGUF
+	DecoderPath string `yaml:"decoder_path"` // Vazio = decoder_model.onnx ao lado do encoder
+	Language    string `yaml:"language"`     // "auto" detecta o idioma
+	ModelSize   string `yaml:"model_size"`   // tiny, base, small, medium, large
+	Threads     int    `yaml:"threads"`      // whispercpp; 0 = todos os núcleos
+
+	// Decodificação: cada temperatura é uma nova tentativa quando a anterior
+	// repete demais (compression_ratio) ou tem baixa confiança (logprob)
+	Temperatures              []float32 `yaml:"temperatures"`
+	CompressionRatioThreshold float32   `yaml:"compression_ratio_threshold"`
+	LogProbThreshold          float32   `yaml:"logprob_threshold"`
+	NoSpeechThreshold         float32   `yaml:"no_speech_threshold"` // Acima disso (e logprob baixo) o trecho é silêncio
+
+	Stream STTStreamConfig `yaml:"stream"`
+}
+
+// STTStreamConfig transcrição enquanto o usuário ainda fala: a cada step_ms
+// de áudio novo a janela atual é transcrita de novo, e o que duas hipóteses
+// seguidas concordam vira texto estável
+type STTStreamConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	StepMs    int  `yaml:"step_ms"`    // Áudio novo entre hipóteses
+	WindowMs  int  `yaml:"window_ms"`  // Maior trecho transcrito de uma vez
+	OverlapMs int  `yaml:"overlap_ms"` // Áudio repetido ao abrir a janela seguinte
 }
 
 // TTSConfig configuração do Text-to-Speech
@@ -40,39 +115,206 @@ type TTSConfig struct {
 	SpeakRate  float32 `yaml:"speak_rate"`
 }
 
-// ModelsConfig configuração dos modelos LLM
+// ModelsConfig registro de modelos: cada entrada em models: tem um papel e o
+// router escolhe o modelo de cada pedido pelo papel, não pelo nome
 type ModelsConfig struct {
-	LoadAll bool        `yaml:"load_all"` // Carrega todos na inicialização
-	Phi     ModelConfig `yaml:"phi"`
-	Llama   ModelConfig `yaml:"llama"`
-	Qwen    ModelConfig `yaml:"qwen"`
-	Vision  ModelConfig `yaml:"vision"`
-	Coder   ModelConfig `yaml:"coder"`
+	LoadAll bool                   `yaml:"load_all"` // Carrega todos na inicialização
+	Entries map[string]ModelConfig `yaml:",inline"`  // Modelos pelo nome da entrada
+}
+
+// Papéis de modelo no registro
+const (
+	RoleChat    = "chat"    // Respostas rápidas e LLM de uso geral (sempre carregado)
+	RoleContext = "context" // Conversas longas (sem ele, o de chat atende)
+	RoleAction  = "action"  // Converte pedidos em ações JSON
+	RoleCode    = "code"    // Código
+	RoleVision  = "vision"  // Ver a tela
+	RoleEmbed   = "embed"   // Embeddings (vale pela seção embeddings:)
+	RoleSTT     = "stt"     // Transcrição (vale pela seção stt:)
+)
+
+// roles papéis aceitos, na ordem das mensagens de erro
+var roles = []string{RoleChat, RoleContext, RoleAction, RoleCode, RoleVision, RoleEmbed, RoleSTT}
+
+// ForRole nome da entrada que atende role: a marcada com default ou, sem
+// ela, a primeira em ordem alfabética
+func (m ModelsConfig) ForRole(role string) (string, bool) {
+	var names []string
+	for name, model := range m.Entries {
+		if model.Role == role {
+			if model.Default {
+				return name, true
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
 }
 
 // ModelConfig configuração de um modelo específico
 type ModelConfig struct {
 	Name          string `yaml:"name"`
+	Role          string `yaml:"role"`   // chat, context, action, code, vision, embed ou stt
+	Family        string `yaml:"family"` // phi, llama, qwen... (escolhe o template quando chat_template é auto)
 	Path          string `yaml:"path"`
 	TokenizerPath string `yaml:"tokenizer_path"`
+	Backend       string `yaml:"backend"` // Runtime do modelo (vazio = o de backend:)
 	MaxTokens     int    `yaml:"max_tokens"`
 	Temperature   float32 `yaml:"temperature"`
 	SystemPrompt  string `yaml:"system_prompt"`
+	ChatTemplate  string `yaml:"chat_template"` // phi3, llama3, chatml ou auto (detecta pelo tokenizer)
+	ContextLength int    `yaml:"context_length"` // Janela em tokens (prompt + resposta)
+	Default       bool   `yaml:"default"`   // Preferido entre os do mesmo papel
+	Preload       bool   `yaml:"preload"`   // Carrega na inicialização
+	MemoryMB      int64  `yaml:"memory_mb"` // Memória estimada (0 = tamanho do arquivo)
+}
+
+// EmbeddingsConfig configuração do modelo de embeddings e do índice vetorial
+type EmbeddingsConfig struct {
+	ModelPath     string `yaml:"model_path"`
+	TokenizerPath string `yaml:"tokenizer_path"`
+	MaxLength     int    `yaml:"max_length"`     // Tokens por texto (trunca o excedente)
+	QueryPrefix   string `yaml:"query_prefix"`   // "query: " nos modelos E5
+	PassagePrefix string `yaml:"passage_prefix"` // "passage: " nos modelos E5
+	IndexDir      string `yaml:"index_dir"`      // Vazio = ~/.npu-ia/index
+}
+
+// IntentConfig configuração do classificador de intenção
+type IntentConfig struct {
+	Classifier    string  `yaml:"classifier"`     // auto (embeddings se houver), embedding ou llm
+	ExamplesPath  string  `yaml:"examples_path"`  // Frases rotuladas por intenção
+	MinConfidence float32 `yaml:"min_confidence"` // Abaixo disso pergunta ao usuário
+	LogPath       string  `yaml:"log_path"`       // Decisões em JSON Lines (vazio = não grava)
+}
+
+// SessionConfig configuração da conversa em andamento
+type SessionConfig struct {
+	Path        string        `yaml:"path"`         // Turnos em JSON (vazio = só em memória)
+	IdleTimeout time.Duration `yaml:"idle_timeout"` // Pausa maior que isso começa outra conversa
+	MaxTurns    int           `yaml:"max_turns"`    // Turnos guardados
+}
+
+// PersonalConfig memória pessoal nos prompts e aprendizado de fatos
+type PersonalConfig struct {
+	ContextTokens *int    `yaml:"context_tokens"` // Orçamento do bloco de memória no prompt (0 = não injeta)
+	LearnFacts    bool    `yaml:"learn_facts"`    // Extrai fatos de cada turno (desligado = nada é aprendido)
+	MinConfidence float64 `yaml:"min_confidence"` // Fatos abaixo disso são descartados
+	AuditPath     string  `yaml:"audit_path"`     // O que foi aprendido de cada turno, em JSON Lines
+}
+
+// DefaultContextTokens orçamento padrão do bloco de memória no prompt
+const DefaultContextTokens = 512
+
+// Budget orçamento do bloco de memória no prompt; sem context_tokens (config
+// montada sem Load) vale DefaultContextTokens
+func (p PersonalConfig) Budget() int {
+	if p.ContextTokens == nil {
+		return DefaultContextTokens
+	}
+	return *p.ContextTokens
 }
 
 // MemoryConfig configuração de gerenciamento de memória
 type MemoryConfig struct {
-	UnloadAfter time.Duration `yaml:"unload_after"` // Tempo para descarregar modelo inativo
-	Persistent  []string      `yaml:"persistent"`   // Modelos que nunca descarrega
+	UnloadAfter       time.Duration `yaml:"unload_after"`        // Tempo para descarregar modelo inativo
+	Persistent        []string      `yaml:"persistent"`          // Modelos que nunca descarrega
+	EstimatedBudgetMB int64         `yaml:"estimated_budget_mb"` // Soma de memory_mb (ou do tamanho dos arquivos) dos modelos carregados, não a memória do processo (0 = sem limite)
 }
 
 // ActionsConfig configuração de ações
 type ActionsConfig struct {
-	AllowedCommands []string `yaml:"allowed_commands"`
-	EmailEnabled    bool     `yaml:"email_enabled"`
-	BrowserEnabled  bool     `yaml:"browser_enabled"`
+	AllowedCommands []CommandRule `yaml:"allowed_commands"` // Programas que run_command executa
+	CommandTimeout  time.Duration `yaml:"command_timeout"`  // Tempo máximo de um comando
+	MaxOutputBytes  int           `yaml:"max_output_bytes"` // Limite de stdout e de stderr (o resto é cortado)
+	WorkDir         string        `yaml:"work_dir"`         // Onde os comandos rodam; caminhos nos argumentos não saem dele
+	Sandbox         bool          `yaml:"sandbox"`          // Ambiente limpo e, no Linux, sem rede
+	EmailEnabled    bool          `yaml:"email_enabled"`
+	BrowserEnabled  bool          `yaml:"browser_enabled"`
+}
+
+// CommandRule programa permitido em run_command e os argumentos aceitos. No
+// YAML, só o nome ("- hostname") permite o programa sem argumentos.
+type CommandRule struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`     // Regex que cada argumento precisa casar por inteiro (basta uma)
+	MaxArgs int      `yaml:"max_args"` // 0 = sem limite
+}
+
+// UnmarshalYAML aceita a regra completa ou só o nome do programa
+func (r *CommandRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = CommandRule{Command: node.Value}
+		return nil
+	}
+	type plain CommandRule
+	return node.Decode((*plain)(r))
+}
+
+// GoogleConfig credenciais das integrações Google (Gmail, Calendar)
+type GoogleConfig struct {
+	CredentialsPath string `yaml:"credentials_path"` // credentials.json do OAuth
+}
+
+// ServerConfig API HTTP local (interface web, integrações com editores)
+type ServerConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Addr        string `yaml:"addr"`          // Só localhost
+	Token       string `yaml:"token"`         // Bearer token exigido em toda chamada (vazio = NPU_IA_TOKEN)
+	MaxUploadMB int64  `yaml:"max_upload_mb"` // Tamanho máximo do áudio enviado
+}
+
+// AgentConfig laço de ferramentas das ações (planeja → age → observa)
+type AgentConfig struct {
+	MaxSteps  int           `yaml:"max_steps"`  // Ferramentas por pedido (1 = uma ação, como antes)
+	Timeout   time.Duration `yaml:"timeout"`    // Tempo máximo de um pedido
+	TracePath string        `yaml:"trace_path"` // Passos de cada pedido em JSON Lines (vazio = não grava)
+}
+
+// ServicesConfig credenciais dos serviços externos (services.Hub). Mesmos
+// campos, na mesma ordem, de services.Config: main converte um no outro.
+type ServicesConfig struct {
+	GoogleCredentials     string `yaml:"google_credentials"` // credentials.json do OAuth (Gmail, Drive, Calendar)
+	MicrosoftClientID     string `yaml:"microsoft_client_id"`
+	MicrosoftClientSecret string `yaml:"microsoft_client_secret"`
+	MicrosoftTenantID     string `yaml:"microsoft_tenant_id"`
+	GitHubToken           string `yaml:"github_token"`
+	LinkedInToken         string `yaml:"linkedin_token"`
+	XBearerToken          string `yaml:"x_bearer_token"`
+	DiscordBotToken       string `yaml:"discord_bot_token"`
+	SlackBotToken         string `yaml:"slack_bot_token"`
+	TelegramBotToken      string `yaml:"telegram_bot_token"`
+	NotionAPIKey          string `yaml:"notion_api_key"`
+	TodoistAPIKey         string `yaml:"todoist_api_key"`
+	SpotifyToken          string `yaml:"spotify_token"`
 }
 
+// PolicyConfig o que as ações podem fazer sem perguntar. A decisão vem da
+// primeira regra que casa com a ação; sem regra, do nível de risco dela.
+type PolicyConfig struct {
+	Defaults       map[string]string `yaml:"defaults"`        // Decisão por risco: read_only, reversible, external
+	Rules          []PolicyRule      `yaml:"rules"`           // Regras por ação, na ordem
+	ConfirmTimeout time.Duration     `yaml:"confirm_timeout"` // Confirmações não respondidas expiram
+	AuditPath      string            `yaml:"audit_path"`      // Decisões em JSON Lines (vazio = não grava)
+}
+
+// PolicyRule decisão para uma ação, ou para várias com * no nome
+type PolicyRule struct {
+	Action   string        `yaml:"action"`   // send_email, slack_*
+	Decision string        `yaml:"decision"` // allow, ask, deny ou limit
+	Limit    int           `yaml:"limit"`    // limit: execuções sem perguntar por janela
+	Window   time.Duration `yaml:"window"`   // limit: tamanho da janela
+}
+
+// policyDecisions decisões aceitas em policy.defaults e policy.rules
+var policyDecisions = []string{"allow", "ask", "deny", "limit"}
+
+// policyRisks níveis de risco aceitos em policy.defaults
+var policyRisks = []string{"read_only", "reversible", "external"}
+
 // Load carrega configuração de um arquivo YAML
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -88,9 +330,157 @@ func Load(path string) (*Config, error) {
 	// Aplica defaults
 	cfg.applyDefaults()
 
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
 	return &cfg, nil
 }
 
+// Validate rejeita valores que os módulos não reconhecem e trocariam em
+// silêncio pelo padrão
+func (c *Config) Validate() error {
+	switch c.Intent.Classifier {
+	case "auto", "embedding", "llm":
+	default:
+		return fmt.Errorf("intent.classifier desconhecido: %q (use auto, embedding ou llm)", c.Intent.Classifier)
+	}
+	if c.Server.Enabled && !isLoopback(c.Server.Addr) {
+		return fmt.Errorf("server.addr deve ser localhost, não %q: a API executa ações no computador", c.Server.Addr)
+	}
+	switch c.STT.Backend {
+	case "onnx", "whispercpp":
+	default:
+		return fmt.Errorf("stt.backend desconhecido: %q (use onnx ou whispercpp)", c.STT.Backend)
+	}
+	switch c.Audio.VAD.Engine {
+	case "energy", "silero":
+	default:
+		return fmt.Errorf("audio.vad.engine desconhecido: %q (use energy ou silero)", c.Audio.VAD.Engine)
+	}
+	if t := c.Audio.VAD.Threshold; t <= 0 || t >= 1 {
+		return fmt.Errorf("audio.vad.threshold deve ficar entre 0 e 1, não %g", t)
+	}
+	if wake := c.WakeWord; wake.Enabled {
+		if wake.Threshold <= 0 || wake.Threshold >= 1 {
+			return fmt.Errorf("wake_word.threshold deve ficar entre 0 e 1, não %g", wake.Threshold)
+		}
+		if wake.NearThreshold < 0 || wake.NearThreshold >= wake.Threshold {
+			return fmt.Errorf("wake_word.near_threshold (%g) deve ficar abaixo de threshold (%g)", wake.NearThreshold, wake.Threshold)
+		}
+	}
+	// Cada janela precisa de duas hipóteses depois da emenda para algo ficar estável
+	if stream := c.STT.Stream; stream.WindowMs < stream.OverlapMs+2*stream.StepMs {
+		return fmt.Errorf("stt.stream.window_ms (%d) deve ser ao menos overlap_ms + 2×step_ms (%d)", stream.WindowMs, stream.OverlapMs+2*stream.StepMs)
+	}
+	if err := c.Policy.validate(); err != nil {
+		return err
+	}
+	if err := c.Actions.validate(); err != nil {
+		return err
+	}
+	return c.Models.validate()
+}
+
+// validate rejeita regras de comando sem programa ou com regex inválida
+func (a ActionsConfig) validate() error {
+	for i, rule := range a.AllowedCommands {
+		if rule.Command == "" || strings.ContainsAny(rule.Command, " \t") {
+			return fmt.Errorf("actions.allowed_commands[%d]: command deve ser só o nome do programa, não %q", i, rule.Command)
+		}
+		for _, pattern := range rule.Args {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("actions.allowed_commands[%d] (%s): %w", i, rule.Command, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate rejeita riscos e decisões desconhecidos e limites sem tamanho
+func (p PolicyConfig) validate() error {
+	for risk, decision := range p.Defaults {
+		if !containsString(policyRisks, risk) {
+			return fmt.Errorf("policy.defaults: risco desconhecido %q (use %s)", risk, strings.Join(policyRisks, ", "))
+		}
+		if !containsString(policyDecisions, decision) || decision == "limit" {
+			return fmt.Errorf("policy.defaults.%s: decisão desconhecida %q (use allow, ask ou deny)", risk, decision)
+		}
+	}
+	for i, rule := range p.Rules {
+		if rule.Action == "" {
+			return fmt.Errorf("policy.rules[%d]: action vazio", i)
+		}
+		if !containsString(policyDecisions, rule.Decision) {
+			return fmt.Errorf("policy.rules[%d] (%s): decisão desconhecida %q (use %s)", i, rule.Action, rule.Decision, strings.Join(policyDecisions, ", "))
+		}
+		if rule.Decision == "limit" && rule.Limit <= 0 {
+			return fmt.Errorf("policy.rules[%d] (%s): limit precisa de um número de execuções", i, rule.Action)
+		}
+	}
+	return nil
+}
+
+// isLoopback indica se addr (host:porta) só aceita conexões locais
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validate rejeita entradas do registro sem papel conhecido ou sem arquivo,
+// backends que não existem e dois modelos default no mesmo papel
+func (m ModelsConfig) validate() error {
+	names := make([]string, 0, len(m.Entries))
+	for name := range m.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defaults := make(map[string]string)
+	for _, name := range names {
+		model := m.Entries[name]
+		if !containsString(roles, model.Role) {
+			return fmt.Errorf("models.%s: role desconhecido %q (use %s)", name, model.Role, strings.Join(roles, ", "))
+		}
+		if model.Path == "" {
+			return fmt.Errorf("models.%s: path vazio", name)
+		}
+		switch strings.ToLower(model.Backend) {
+		case "", "onnx", "onnxruntime":
+		default:
+			return fmt.Errorf("models.%s: backend desconhecido %q (use onnx)", name, model.Backend)
+		}
+		if model.Default {
+			if other, ok := defaults[model.Role]; ok {
+				return fmt.Errorf("models.%s: %s já é o default de role %s", name, other, model.Role)
+			}
+			defaults[model.Role] = name
+		}
+	}
+
+	if _, ok := m.ForRole(RoleChat); !ok {
+		return fmt.Errorf("models: nenhum modelo com role %s", RoleChat)
+	}
+	return nil
+}
+
+// containsString indica se s está em list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Default retorna configuração padrão
 func Default() *Config {
 	cfg := &Config{}
@@ -100,21 +490,89 @@ func Default() *Config {
 
 // applyDefaults aplica valores padrão
 func (c *Config) applyDefaults() {
+	// Backend
+	if c.Backend.Name == "" {
+		c.Backend.Name = "onnx"
+	}
+	if c.Backend.Provider == "" {
+		c.Backend.Provider = "directml"
+	}
+
 	// Audio
 	if c.Audio.SampleRate == 0 {
 		c.Audio.SampleRate = 16000
 	}
-	if c.Audio.VADThreshold == 0 {
-		c.Audio.VADThreshold = 0.01
-	}
 	if c.Audio.SilenceMs == 0 {
 		c.Audio.SilenceMs = 1000 // 1 segundo
 	}
 	if c.Audio.MaxDurationMs == 0 {
 		c.Audio.MaxDurationMs = 30000 // 30 segundos
 	}
+	if c.Audio.VAD.Engine == "" {
+		c.Audio.VAD.Engine = "energy"
+	}
+	if c.Audio.VAD.ModelPath == "" {
+		c.Audio.VAD.ModelPath = "models/silero_vad.onnx"
+	}
+	if c.Audio.VAD.Threshold == 0 {
+		c.Audio.VAD.Threshold = 0.5
+	}
+	if c.Audio.VAD.MarginDB == 0 {
+		c.Audio.VAD.MarginDB = 10
+	}
+	if c.Audio.VAD.PreRollMs == 0 {
+		c.Audio.VAD.PreRollMs = 300
+	}
+	if c.Audio.VAD.MinSpeechMs == 0 {
+		c.Audio.VAD.MinSpeechMs = 150
+	}
+	if c.Audio.VAD.WaitMs == 0 {
+		c.Audio.VAD.WaitMs = 5000
+	}
+
+	// Wake word
+	if c.WakeWord.Phrase == "" {
+		c.WakeWord.Phrase = "Ei NPU"
+	}
+	if c.WakeWord.ModelPath == "" {
+		c.WakeWord.ModelPath = "models/kws.onnx"
+	}
+	if c.WakeWord.LabelsPath == "" {
+		c.WakeWord.LabelsPath = "models/kws_labels.txt"
+	}
+	if c.WakeWord.Threshold == 0 {
+		c.WakeWord.Threshold = 0.7
+	}
+	if c.WakeWord.NearThreshold == 0 {
+		c.WakeWord.NearThreshold = 0.4
+	}
+	if c.WakeWord.WindowMs == 0 {
+		c.WakeWord.WindowMs = 1500
+	}
+	if c.WakeWord.HopMs == 0 {
+		c.WakeWord.HopMs = 100
+	}
+	if c.WakeWord.FollowUpMs == 0 {
+		c.WakeWord.FollowUpMs = 8000
+	}
+	if c.WakeWord.RetryMs == 0 {
+		c.WakeWord.RetryMs = 3000
+	}
+
+	// Models (antes de STT e Embeddings: entradas stt e embed preenchem as seções)
+	c.Models.applyDefaults()
+	if name, ok := c.Models.ForRole(RoleSTT); ok && c.STT.ModelPath == "" {
+		c.STT.ModelPath = c.Models.Entries[name].Path
+	}
+	if name, ok := c.Models.ForRole(RoleEmbed); ok && c.Embeddings.ModelPath == "" {
+		c.Embeddings.ModelPath = c.Models.Entries[name].Path
+		c.Embeddings.TokenizerPath = c.Models.Entries[name].TokenizerPath
+	}
 
 	// STT
+	if c.STT.Backend == "" {
+		c.STT.Backend = "onnx"
+	}
 	if c.STT.Language == "" {
 		c.STT.Language = "pt"
 	}
@@ -124,6 +582,27 @@ func (c *Config) applyDefaults() {
 	if c.STT.ModelPath == "" {
 		c.STT.ModelPath = "models/whisper-medium.onnx"
 	}
+	if len(c.STT.Temperatures) == 0 {
+		c.STT.Temperatures = []float32{0, 0.2, 0.4, 0.6, 0.8, 1}
+	}
+	if c.STT.CompressionRatioThreshold == 0 {
+		c.STT.CompressionRatioThreshold = 2.4
+	}
+	if c.STT.LogProbThreshold == 0 {
+		c.STT.LogProbThreshold = -1
+	}
+	if c.STT.NoSpeechThreshold == 0 {
+		c.STT.NoSpeechThreshold = 0.6
+	}
+	if c.STT.Stream.StepMs == 0 {
+		c.STT.Stream.StepMs = 1000
+	}
+	if c.STT.Stream.WindowMs == 0 {
+		c.STT.Stream.WindowMs = 10000
+	}
+	if c.STT.Stream.OverlapMs == 0 {
+		c.STT.Stream.OverlapMs = 4000
+	}
 
 	// TTS
 	if c.TTS.VoiceName == "" {
@@ -133,35 +612,43 @@ func (c *Config) applyDefaults() {
 		c.TTS.SpeakRate = 1.0
 	}
 
-	// Models
-	if c.Models.Phi.Name == "" {
-		c.Models.Phi.Name = "phi-3.5-mini"
-		c.Models.Phi.Path = "models/phi-3.5-mini.onnx"
-		c.Models.Phi.MaxTokens = 512
-		c.Models.Phi.Temperature = 0.7
+	// Embeddings
+	if c.Embeddings.ModelPath == "" {
+		c.Embeddings.ModelPath = "models/multilingual-e5-small.onnx"
+		c.Embeddings.TokenizerPath = "models/multilingual-e5-small-tokenizer.json"
+		c.Embeddings.QueryPrefix = "query: "
+		c.Embeddings.PassagePrefix = "passage: "
+	}
+	if c.Embeddings.MaxLength == 0 {
+		c.Embeddings.MaxLength = 512
+	}
+
+	// Intent
+	if c.Intent.Classifier == "" {
+		c.Intent.Classifier = "auto"
 	}
-	if c.Models.Llama.Name == "" {
-		c.Models.Llama.Name = "llama-3.2-3b"
-		c.Models.Llama.Path = "models/llama-3.2-3b.onnx"
-		c.Models.Llama.MaxTokens = 1024
-		c.Models.Llama.Temperature = 0.7
+	if c.Intent.ExamplesPath == "" {
+		c.Intent.ExamplesPath = "configs/intents.yaml"
 	}
-	if c.Models.Qwen.Name == "" {
-		c.Models.Qwen.Name = "qwen-2.5-3b"
-		c.Models.Qwen.Path = "models/qwen-2.5-3b.onnx"
-		c.Models.Qwen.MaxTokens = 512
-		c.Models.Qwen.Temperature = 0.3 // Mais determinístico para ações
+	if c.Intent.MinConfidence == 0 {
+		c.Intent.MinConfidence = 0.5
 	}
-	if c.Models.Vision.Name == "" {
-		c.Models.Vision.Name = "minicpm-v"
-		c.Models.Vision.Path = "models/minicpm-v.onnx"
-		c.Models.Vision.MaxTokens = 256
+
+	// Session
+	if c.Session.IdleTimeout == 0 {
+		c.Session.IdleTimeout = 30 * time.Minute
 	}
-	if c.Models.Coder.Name == "" {
-		c.Models.Coder.Name = "qwen-coder-3b"
-		c.Models.Coder.Path = "models/qwen-coder-3b.onnx"
-		c.Models.Coder.MaxTokens = 1024
-		c.Models.Coder.Temperature = 0.2 // Bem determinístico para código
+	if c.Session.MaxTurns == 0 {
+		c.Session.MaxTurns = 200
+	}
+
+	// Personal
+	if c.Personal.ContextTokens == nil {
+		tokens := DefaultContextTokens
+		c.Personal.ContextTokens = &tokens
+	}
+	if c.Personal.MinConfidence == 0 {
+		c.Personal.MinConfidence = 0.7
 	}
 
 	// Memory
@@ -172,12 +659,62 @@ func (c *Config) applyDefaults() {
 		c.Memory.Persistent = []string{"whisper", "phi"} // Sempre na memória
 	}
 
+	// Server
+	if c.Server.Addr == "" {
+		c.Server.Addr = "127.0.0.1:8765"
+	}
+	if c.Server.MaxUploadMB == 0 {
+		c.Server.MaxUploadMB = 25
+	}
+
+	// Agent
+	if c.Agent.MaxSteps == 0 {
+		c.Agent.MaxSteps = 5
+	}
+	if c.Agent.Timeout == 0 {
+		c.Agent.Timeout = 60 * time.Second
+	}
+
+	// Policy: lê e desfaz sem perguntar, o resto pede confirmação
+	if c.Policy.Defaults == nil {
+		c.Policy.Defaults = map[string]string{}
+	}
+	for risk, decision := range map[string]string{"read_only": "allow", "reversible": "allow", "external": "ask"} {
+		if c.Policy.Defaults[risk] == "" {
+			c.Policy.Defaults[risk] = decision
+		}
+	}
+	for i := range c.Policy.Rules {
+		if c.Policy.Rules[i].Decision == "limit" && c.Policy.Rules[i].Window == 0 {
+			c.Policy.Rules[i].Window = time.Hour
+		}
+	}
+	if c.Policy.ConfirmTimeout == 0 {
+		c.Policy.ConfirmTimeout = 2 * time.Minute
+	}
+
 	// Actions
 	if len(c.Actions.AllowedCommands) == 0 {
-		c.Actions.AllowedCommands = []string{"dir", "echo", "date", "time", "hostname"}
+		c.Actions.AllowedCommands = []CommandRule{
+			{Command: "echo", Args: []string{".*"}},
+			{Command: "dir", Args: []string{`[\w.\-/\\]+`}, MaxArgs: 1},
+			{Command: "hostname"},
+			{Command: "whoami"},
+		}
+	}
+	if c.Actions.CommandTimeout == 0 {
+		c.Actions.CommandTimeout = 10 * time.Second
+	}
+	if c.Actions.MaxOutputBytes == 0 {
+		c.Actions.MaxOutputBytes = 64 << 10
 	}
 	c.Actions.EmailEnabled = true
 	c.Actions.BrowserEnabled = true
+
+	// Google
+	if c.Google.CredentialsPath == "" {
+		c.Google.CredentialsPath = "configs/google_credentials.json"
+	}
 }
 
 // Save salva configuração em arquivo YAML
@@ -188,3 +725,70 @@ func (c *Config) Save(path string) error {
 	}
 	return os.WriteFile(path, data, 0644)
 }
+
+// legacyRoles papel das entradas com os nomes de antes do registro, quando
+// o config não diz o role
+var legacyRoles = map[string]string{
+	"phi":    RoleChat,
+	"llama":  RoleContext,
+	"qwen":   RoleAction,
+	"vision": RoleVision,
+	"coder":  RoleCode,
+}
+
+// defaultModels modelos usados nos papéis que o config não preenche
+var defaultModels = map[string]ModelConfig{
+	"phi": {
+		Name: "phi-3.5-mini", Role: RoleChat, Path: "models/phi-3.5-mini.onnx",
+		MaxTokens: 512, Temperature: 0.7,
+	},
+	"llama": {
+		Name: "llama-3.2-3b", Role: RoleContext, Path: "models/llama-3.2-3b.onnx",
+		MaxTokens: 1024, Temperature: 0.7,
+	},
+	"qwen": {
+		Name: "qwen-2.5-3b", Role: RoleAction, Path: "models/qwen-2.5-3b.onnx",
+		MaxTokens: 512, Temperature: 0.3, // Mais determinístico para ações
+	},
+	"vision": {
+		Name: "minicpm-v", Role: RoleVision, Path: "models/minicpm-v.onnx",
+		MaxTokens: 256,
+	},
+	"coder": {
+		Name: "qwen-coder-3b", Role: RoleCode, Path: "models/qwen-coder-3b.onnx",
+		MaxTokens: 1024, Temperature: 0.2, // Bem determinístico para código
+	},
+}
+
+// applyDefaults completa o registro: role pelo nome nas entradas antigas,
+// os modelos padrão nos papéis vazios e a janela dos LLMs de texto
+func (m *ModelsConfig) applyDefaults() {
+	if m.Entries == nil {
+		m.Entries = make(map[string]ModelConfig)
+	}
+	for name, model := range m.Entries {
+		if model.Role == "" {
+			model.Role = legacyRoles[name]
+			m.Entries[name] = model
+		}
+	}
+
+	for name, model := range defaultModels {
+		if _, ok := m.ForRole(model.Role); ok {
+			continue
+		}
+		if _, taken := m.Entries[name]; !taken {
+			m.Entries[name] = model
+		}
+	}
+
+	for name, model := range m.Entries {
+		switch model.Role {
+		case RoleChat, RoleContext, RoleAction:
+			if model.ContextLength == 0 {
+				model.ContextLength = 4096 // Limite comum dos exports ONNX para NPU
+				m.Entries[name] = model
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadIntentClassifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{"padrão", "intent: {}\n", "auto", ""},
+		{"llm", "intent:\n  classifier: llm\n", "llm", ""},
+		{"valor desconhecido", "intent:\n  classifier: embeddings\n", "", `intent.classifier desconhecido: "embeddings"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Intent.Classifier != tt.want {
+				t.Errorf("Classifier = %q, want %q", cfg.Intent.Classifier, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadModels(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    map[string]string // papel -> entrada escolhida
+		wantErr string
+	}{
+		{"nomes antigos", "models:\n  phi:\n    name: phi\n    path: phi.onnx\n",
+			map[string]string{RoleChat: "phi", RoleContext: "llama", RoleCode: "coder"}, ""},
+		{"gemma no lugar do llama", "models:\n  gemma:\n    role: context\n    family: gemma\n    path: gemma.onnx\n",
+			map[string]string{RoleChat: "phi", RoleContext: "gemma"}, ""},
+		{"segundo coder como default", "models:\n  coder:\n    role: code\n    path: a.onnx\n  deepseek:\n    role: code\n    path: b.onnx\n    default: true\n",
+			map[string]string{RoleCode: "deepseek"}, ""},
+		{"stt pelo registro", "models:\n  whisper:\n    role: stt\n    path: whisper-small.onnx\n",
+			map[string]string{RoleSTT: "whisper"}, ""},
+		{"role desconhecido", "models:\n  gemma:\n    role: conversa\n    path: g.onnx\n", nil, `models.gemma: role desconhecido "conversa"`},
+		{"sem role e nome novo", "models:\n  gemma:\n    path: g.onnx\n", nil, `models.gemma: role desconhecido ""`},
+		{"sem path", "models:\n  gemma:\n    role: chat\n", nil, "models.gemma: path vazio"},
+		{"backend desconhecido", "models:\n  gemma:\n    role: chat\n    path: g.onnx\n    backend: tensorrt\n", nil, `models.gemma: backend desconhecido "tensorrt"`},
+		{"dois default", "models:\n  a:\n    role: code\n    path: a.onnx\n    default: true\n  b:\n    role: code\n    path: b.onnx\n    default: true\n",
+			nil, "models.b: a já é o default de role code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for role, want := range tt.want {
+				if got, _ := cfg.Models.ForRole(role); got != want {
+					t.Errorf("ForRole(%s) = %q, want %q", role, got, want)
+				}
+			}
+			if tt.want[RoleSTT] != "" && cfg.STT.ModelPath != "whisper-small.onnx" {
+				t.Errorf("STT.ModelPath = %q, want o do registro", cfg.STT.ModelPath)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"padrão", "policy: {}\n", ""},
+		{"regras", "policy:\n  defaults: {reversible: ask}\n  rules:\n    - {action: slack_*, decision: limit, limit: 3}\n", ""},
+		{"risco desconhecido", "policy:\n  defaults: {perigoso: deny}\n", `risco desconhecido "perigoso"`},
+		{"decisão desconhecida", "policy:\n  rules:\n    - {action: volume, decision: talvez}\n", `decisão desconhecida "talvez"`},
+		{"limit sem número", "policy:\n  rules:\n    - {action: volume, decision: limit}\n", "limit precisa de um número"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Policy.Defaults["external"] != "ask" || cfg.Policy.ConfirmTimeout == 0 {
+				t.Errorf("padrões = %+v", cfg.Policy)
+			}
+			for _, rule := range cfg.Policy.Rules {
+				if rule.Decision == "limit" && rule.Window == 0 {
+					t.Errorf("regra %s sem janela", rule.Action)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAllowedCommands(t *testing.T) {
+	yaml := `actions:
+  allowed_commands:
+    - hostname
+    - command: git
+      args: ["status", "log", "-\\d+"]
+      max_args: 2
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := cfg.Actions.AllowedCommands
+	if len(rules) != 2 || rules[0].Command != "hostname" || rules[0].Args != nil {
+		t.Fatalf("regras = %+v", rules)
+	}
+	if rules[1].Command != "git" || len(rules[1].Args) != 3 || rules[1].MaxArgs != 2 {
+		t.Errorf("git = %+v", rules[1])
+	}
+
+	if err := os.WriteFile(path, []byte("actions:\n  allowed_commands:\n    - command: ls\n      args: ['[a-']\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "allowed_commands[0] (ls)") {
+		t.Errorf("regex inválida: erro = %v", err)
+	}
+}
+
+func TestLoadSTT(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{"padrão", "stt: {}\n", "onnx", ""},
+		{"whisper.cpp", "stt:\n  backend: whispercpp\n  model_path: models/ggml-small.bin\n  threads: 4\n", "whispercpp", ""},
+		{"valor desconhecido", "stt:\n  backend: vosk\n", "", `stt.backend desconhecido: "vosk"`},
+		{"janela curta para o passo", "stt:\n  stream:\n    step_ms: 2000\n    window_ms: 6000\n", "", "stt.stream.window_ms (6000) deve ser ao menos overlap_ms + 2×step_ms (8000)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.STT.Backend != tt.want {
+				t.Errorf("Backend = %q, want %q", cfg.STT.Backend, tt.want)
+			}
+			// Fallback de temperatura sempre tem ao menos a tentativa greedy
+			if len(cfg.STT.Temperatures) == 0 || cfg.STT.Temperatures[0] != 0 || cfg.STT.LogProbThreshold != -1 {
+				t.Errorf("decodificação = %+v", cfg.STT)
+			}
+		})
+	}
+}
+
+func TestLoadVAD(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{"padrão", "audio: {}\n", "energy", ""},
+		{"silero", "audio:\n  vad:\n    engine: silero\n    threshold: 0.6\n", "silero", ""},
+		{"engine desconhecido", "audio:\n  vad:\n    engine: webrtc\n", "", `audio.vad.engine desconhecido: "webrtc"`},
+		{"limiar fora de 0 a 1", "audio:\n  vad:\n    threshold: 1.5\n", "", "audio.vad.threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Audio.VAD.Engine != tt.want {
+				t.Errorf("Engine = %q, want %q", cfg.Audio.VAD.Engine, tt.want)
+			}
+			// Pré-roll e fala mínima sempre têm padrão
+			if cfg.Audio.VAD.PreRollMs == 0 || cfg.Audio.VAD.MinSpeechMs == 0 || cfg.Audio.SilenceMs == 0 {
+				t.Errorf("audio = %+v", cfg.Audio)
+			}
+		})
+	}
+}
+
+func TestLoadWakeWord(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"padrão", "wake_word:\n  enabled: true\n", ""},
+		{"desligado ignora limiares", "wake_word:\n  threshold: 2\n", ""},
+		{"limiar fora de 0 a 1", "wake_word:\n  enabled: true\n  threshold: 1.2\n", "wake_word.threshold"},
+		{"quase ativação acima do limiar", "wake_word:\n  enabled: true\n  threshold: 0.6\n  near_threshold: 0.8\n", "wake_word.near_threshold (0.8) deve ficar abaixo de threshold (0.6)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() erro = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wake := cfg.WakeWord; wake.Phrase != "Ei NPU" || wake.FollowUpMs == 0 || wake.HopMs == 0 {
+				t.Errorf("wake_word = %+v", wake)
+			}
+		})
+	}
+}